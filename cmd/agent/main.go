@@ -9,6 +9,7 @@ import (
 	"os"
 	"os/signal"
 	"path/filepath"
+	"runtime"
 	"syscall"
 	"time"
 
@@ -81,19 +82,45 @@ func run() error {
 	catalogSvc := catalog.NewService(repo, logger)
 	playbackSvc := playback.NewServer(logger)
 
+	// linkedCloud stays nil when cloud sync is off so the API and tray hide
+	// sign-in. A static HEIMDEX_CLOUD_TOKEN is optional once device login
+	// is available.
 	var cloudClient cloud.Client
-	if cfg.CloudEnabled() && cfg.CloudBaseURL() != "" && cfg.CloudToken() != "" {
-		cloudClient = cloud.NewHTTPClient(cfg.CloudBaseURL(), cfg.CloudToken(), cfg.CloudOrgSlug(), logger)
-		if httpClient, ok := cloudClient.(*cloud.HTTPClient); ok {
-			httpClient.SetDeviceID(deviceID)
+	var linkedCloud cloud.Client
+	var cloudMonitor *cloud.Monitor
+	if cfg.CloudEnabled() && cfg.CloudBaseURL() != "" {
+		httpClient := cloud.NewHTTPClient(cfg.CloudBaseURL(), cfg.CloudToken(), cfg.CloudOrgSlug(), logger)
+		httpClient.SetDeviceID(deviceID)
+		if err := httpClient.SetCompression(cfg.CloudCompression()); err != nil {
+			logger.Warn("cloud compression disabled", "error", err)
 		}
-		logger.Info("cloud sync enabled", "base_url", cfg.CloudBaseURL(), "org_slug", cfg.CloudOrgSlug())
+		httpClient.SetUploadLimit(cfg.CloudUploadKbps())
+		if err := httpClient.ConfigureTransport(cloud.TransportConfig{
+			ProxyURL:   cfg.CloudProxy(),
+			CABundle:   cfg.CloudCABundle(),
+			ClientCert: cfg.CloudClientCert(),
+			ClientKey:  cfg.CloudClientKey(),
+		}); err != nil {
+			// Keep the agent running; cloud requests fail until the
+			// settings are fixed and /cloud/doctor reports why.
+			logger.Error("invalid cloud transport settings, cloud sync degraded", "error", err)
+		}
+		if err := httpClient.SetTokenStore(context.Background(), repo); err != nil {
+			logger.Warn("failed to load stored cloud credentials", "error", err)
+		}
+		cloudClient = httpClient
+		linkedCloud = httpClient
+		cloudMonitor = cloud.NewMonitor(httpClient.Ping, logger)
+		logger.Info("cloud sync enabled",
+			"base_url", cfg.CloudBaseURL(),
+			"org_slug", cfg.CloudOrgSlug(),
+			"upload_kbps", cfg.CloudUploadKbps(),
+			"signed_in", cloud.SignedIn(httpClient),
+		)
 	} else {
 		cloudClient = cloud.NewStubClient(logger)
 	}
 
-	cloudClient.RegisterDevice(deviceID)
-
 	pipeCfg := pipelines.Config{
 		PythonPath:    cfg.PipelinesPython(),
 		ModuleName:    cfg.PipelinesModule(),
@@ -134,8 +161,44 @@ func run() error {
 
 	runner := catalog.NewRunner(catalogSvc, repo, pipeRunner, ffmpeg, doctor, logger)
 	runner.SetOCRConfig(cfg)
+	runner.SetProxyConfig(catalog.ProxyConfig{
+		Dir:    filepath.Join(cfg.CacheDir(), "proxies"),
+		Height: cfg.ProxyHeight(),
+	})
+
+	// HLS segments, clips, frames and proxies share one cache budget.
+	hls := playback.NewHLS(ffmpeg, filepath.Join(cfg.CacheDir(), "hls"), logger)
+	clips := playback.NewClips(ffmpeg, filepath.Join(cfg.CacheDir(), "clips"), logger)
+	frames := playback.NewFrames(ffmpeg, filepath.Join(cfg.CacheDir(), "frames"), logger)
+	cacheBudget := playback.NewCacheBudget(cfg.CacheMaxBytes(), logger)
+	cacheBudget.Add(hls)
+	cacheBudget.Add(clips)
+	cacheBudget.Add(frames)
+	runner.SetCacheBudget(cacheBudget)
+	go cacheBudget.Run(ctx)
 	if cfg.CloudEnabled() {
 		runner.SetCloudClient(cloudClient, cfg.CloudLibraryID())
+		runner.SetLibraryPolicy(cfg.CloudLibraryPolicy())
+
+		outbox := runner.Outbox()
+		if err := outbox.LoadSettings(ctx); err != nil {
+			logger.Warn("failed to load cloud sync settings", "error", err)
+		}
+		if cloudMonitor != nil {
+			cloudMonitor.OnChange(func(online bool) { outbox.SetReachable(ctx, online) })
+			go cloudMonitor.Run(ctx)
+		}
+
+		hostname, _ := os.Hostname()
+		heartbeat := catalog.NewHeartbeat(repo, runner, doctor, cloudClient, cloud.DeviceInfo{
+			DeviceID:     deviceID,
+			Hostname:     hostname,
+			OS:           runtime.GOOS,
+			Arch:         runtime.GOARCH,
+			AgentVersion: Version,
+		}, logger)
+		go heartbeat.Run(ctx)
+		go catalog.NewAnnotationSync(repo, cloudClient, logger).Run(ctx)
 	}
 	go runner.Start(ctx)
 
@@ -144,12 +207,17 @@ func run() error {
 		ArtifactsDir:   pipeCfg.ArtifactsBase,
 		CatalogService: catalogSvc,
 		PlaybackServer: playbackSvc,
+		HLSServer:      hls,
+		ClipServer:     clips,
+		FrameServer:    frames,
+		MediaProber:    playback.NewProber(ffmpeg),
 		Repository:     repo,
 		Runner:         runner,
 		Doctor:         doctor,
 		Logger:         logger,
 		StartTime:      startTime,
 		DeviceID:       deviceID,
+		CloudClient:    linkedCloud,
 	})
 
 	go func() {
@@ -175,9 +243,14 @@ func run() error {
 	if cfg.Headless() {
 		logger.Info("running in headless mode (no system tray)")
 	} else {
+		var cloudAuth cloud.AuthService
+		if linkedCloud != nil {
+			cloudAuth = linkedCloud.Auth()
+		}
 		tray := ui.NewTray(ui.TrayConfig{
 			CatalogService: catalogSvc,
 			Runner:         runner,
+			CloudAuth:      cloudAuth,
 			Logger:         logger,
 			OnAddFolder: func() error {
 				logger.Info("add folder requested from tray (file dialog not implemented in v0)")
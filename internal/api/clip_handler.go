@@ -0,0 +1,94 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"strconv"
+
+	"github.com/heimdex/heimdex-agent/internal/catalog"
+	"github.com/heimdex/heimdex-agent/internal/playback"
+)
+
+// playbackSceneHandler serves part of a file as its own video: a scene by
+// scene_id, or any range by file_id, start_ms and end_ms.
+func playbackSceneHandler(cfg ServerConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if cfg.ClipServer == nil {
+			WriteError(w, http.StatusServiceUnavailable, "clip playback is not available", "CLIPS_UNAVAILABLE")
+			return
+		}
+
+		claims := playbackClaimsFrom(r)
+
+		q := r.URL.Query()
+		sceneID := q.Get("scene_id")
+		fileID := q.Get("file_id")
+		if fileID == "" && sceneID != "" {
+			fileID = catalog.SceneFileID(sceneID)
+		}
+		if fileID == "" {
+			WriteError(w, http.StatusBadRequest, "scene_id or file_id is required", "BAD_REQUEST")
+			return
+		}
+
+		if claims != nil && !claims.matchesFile(fileID) {
+			writeTokenScopeError(w)
+			return
+		}
+
+		file := lookupPlaybackFile(w, r, cfg, fileID)
+		if file == nil {
+			return
+		}
+
+		var startMs, endMs int
+		if sceneID != "" {
+			scenes, err := catalog.LoadScenes(r.Context(), cfg.Repository, cfg.ArtifactsDir, file.ID)
+			if err != nil {
+				WriteError(w, http.StatusInternalServerError, err.Error(), "INTERNAL_ERROR")
+				return
+			}
+			scene := findScene(scenes, sceneID)
+			if scene == nil {
+				WriteError(w, http.StatusNotFound, "scene not found", "SCENE_NOT_FOUND")
+				return
+			}
+			startMs, endMs = scene.StartMs, scene.EndMs
+		} else {
+			var err1, err2 error
+			startMs, err1 = strconv.Atoi(q.Get("start_ms"))
+			endMs, err2 = strconv.Atoi(q.Get("end_ms"))
+			if err1 != nil || err2 != nil {
+				WriteError(w, http.StatusBadRequest, "start_ms and end_ms are required with file_id", "BAD_REQUEST")
+				return
+			}
+		}
+
+		if claims != nil && !claims.allowsRange(file.ID, startMs, endMs) {
+			writeTokenScopeError(w)
+			return
+		}
+
+		if err := cfg.ClipServer.ServeClip(w, r, file.ID, file.Path, startMs, endMs); err != nil {
+			switch {
+			case errors.Is(err, playback.ErrInvalidClip):
+				WriteError(w, http.StatusBadRequest, err.Error(), "BAD_REQUEST")
+			case os.IsNotExist(err):
+				WriteError(w, http.StatusNotFound, "file missing on disk", "NOT_FOUND")
+			default:
+				cfg.Logger.Error("clip playback error", "error", err, "file_id", file.ID)
+				WriteError(w, http.StatusInternalServerError, "failed to cut clip", "CLIP_FAILED")
+			}
+		}
+	}
+}
+
+func findScene(scenes []catalog.Scene, sceneID string) *catalog.Scene {
+	for i := range scenes {
+		if scenes[i].SceneID == sceneID {
+			return &scenes[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/heimdex/heimdex-agent/internal/pipelines"
+	"github.com/heimdex/heimdex-agent/internal/playback"
+)
+
+type fakeClips struct {
+	fileID         string
+	startMs, endMs int
+}
+
+func (f *fakeClips) ServeClip(w http.ResponseWriter, r *http.Request, fileID, filePath string, startMs, endMs int) error {
+	if endMs <= startMs {
+		return playback.ErrInvalidClip
+	}
+	f.fileID, f.startMs, f.endMs = fileID, startMs, endMs
+	w.Header().Set("Content-Type", "video/mp4")
+	return nil
+}
+
+func TestPlaybackScene_LooksUpSceneBounds(t *testing.T) {
+	artifacts := t.TempDir()
+	dir := filepath.Join(artifacts, "file-1", "scenes")
+	os.MkdirAll(dir, 0o755)
+	data, _ := json.Marshal(pipelines.SceneOutputPayload{
+		VideoID: "file-1",
+		Scenes: []pipelines.SceneBoundary{
+			{SceneID: "file-1_scene_0", EndMs: 4000},
+			{SceneID: "file-1_scene_1", StartMs: 4000, EndMs: 9500},
+		},
+	})
+	os.WriteFile(filepath.Join(dir, "result.json"), data, 0o644)
+
+	clips := &fakeClips{}
+	cfg := hlsTestConfig("/media/a.mp4", nil)
+	cfg.ArtifactsDir = artifacts
+	cfg.ClipServer = clips
+	router := NewRouter(cfg)
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, loopbackGet("/playback/scene?scene_id=file-1_scene_1"))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rr.Code, rr.Body.String())
+	}
+	if clips.fileID != "file-1" || clips.startMs != 4000 || clips.endMs != 9500 {
+		t.Errorf("clip = %+v, want file-1 4000-9500", clips)
+	}
+
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, loopbackGet("/playback/scene?scene_id=file-1_scene_7"))
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("unknown scene status = %d, want 404", rr.Code)
+	}
+}
+
+func TestPlaybackScene_Range(t *testing.T) {
+	clips := &fakeClips{}
+	cfg := hlsTestConfig("/media/a.mp4", nil)
+	cfg.ClipServer = clips
+	router := NewRouter(cfg)
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, loopbackGet("/playback/scene?file_id=file-1&start_ms=1000&end_ms=3000"))
+	if rr.Code != http.StatusOK || clips.startMs != 1000 || clips.endMs != 3000 {
+		t.Fatalf("status = %d, clip = %+v", rr.Code, clips)
+	}
+
+	for _, target := range []string{
+		"/playback/scene?file_id=file-1&start_ms=3000&end_ms=1000",
+		"/playback/scene?file_id=file-1&start_ms=abc&end_ms=1000",
+		"/playback/scene",
+	} {
+		rr = httptest.NewRecorder()
+		router.ServeHTTP(rr, loopbackGet(target))
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("%s status = %d, want 400", target, rr.Code)
+		}
+	}
+}
+
+func TestPlaybackScene_Unavailable(t *testing.T) {
+	router := NewRouter(hlsTestConfig("/media/a.mp4", nil))
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, loopbackGet("/playback/scene?file_id=file-1&start_ms=0&end_ms=1000"))
+	if rr.Code != http.StatusServiceUnavailable {
+		t.Fatalf("status = %d, want %d", rr.Code, http.StatusServiceUnavailable)
+	}
+}
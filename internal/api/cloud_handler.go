@@ -0,0 +1,277 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/heimdex/heimdex-agent/internal/cloud"
+)
+
+func cloudLoginHandler(cfg ServerConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if cfg.CloudClient == nil {
+			WriteError(w, http.StatusServiceUnavailable, "cloud sync is not configured", "CLOUD_DISABLED")
+			return
+		}
+
+		auth := cfg.CloudClient.Auth()
+		if auth.IsAuthenticated() {
+			WriteJSON(w, http.StatusOK, CloudLoginResponse{Status: "authenticated"})
+			return
+		}
+
+		// A second click while the first code is still valid shows the same code.
+		code, _, err := auth.ResumeOrLogin(cfg.Logger, nil)
+		if err != nil {
+			WriteError(w, http.StatusBadGateway, "failed to start cloud login: "+err.Error(), "CLOUD_ERROR")
+			return
+		}
+
+		WriteJSON(w, http.StatusAccepted, CloudLoginResponse{
+			Status:                  "pending",
+			UserCode:                code.UserCode,
+			VerificationURI:         code.VerificationURI,
+			VerificationURIComplete: code.VerificationURIComplete,
+			ExpiresIn:               code.ExpiresIn,
+		})
+	}
+}
+
+func cloudLogoutHandler(cfg ServerConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if cfg.CloudClient == nil {
+			WriteError(w, http.StatusServiceUnavailable, "cloud sync is not configured", "CLOUD_DISABLED")
+			return
+		}
+
+		if err := cfg.CloudClient.Auth().Logout(r.Context()); err != nil {
+			if errors.Is(err, cloud.ErrCloudDisabled) {
+				WriteError(w, http.StatusServiceUnavailable, err.Error(), "CLOUD_DISABLED")
+				return
+			}
+			WriteError(w, http.StatusInternalServerError, "failed to sign out", "INTERNAL_ERROR")
+			return
+		}
+
+		WriteJSON(w, http.StatusOK, map[string]string{"status": "signed_out"})
+	}
+}
+
+// cloudResyncHandler forces every indexed file's scene docs to be sent
+// again, ignoring what the agent believes the cloud already has.
+func cloudResyncHandler(cfg ServerConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if cfg.CloudClient == nil || cfg.Runner == nil {
+			WriteError(w, http.StatusServiceUnavailable, "cloud sync is not configured", "CLOUD_DISABLED")
+			return
+		}
+
+		queued, err := cfg.Runner.Resync(r.Context())
+		if err != nil {
+			if errors.Is(err, cloud.ErrCloudDisabled) {
+				WriteError(w, http.StatusServiceUnavailable, err.Error(), "CLOUD_DISABLED")
+				return
+			}
+			WriteError(w, http.StatusInternalServerError, "failed to start resync", "INTERNAL_ERROR")
+			return
+		}
+
+		WriteJSON(w, http.StatusAccepted, CloudResyncResponse{Status: "queued", Queued: queued})
+	}
+}
+
+// cloudSyncHandler pauses or resumes cloud sync and toggles metered mode.
+func cloudSyncHandler(cfg ServerConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if cfg.CloudClient == nil || cfg.Runner == nil || cfg.Runner.Outbox() == nil {
+			WriteError(w, http.StatusServiceUnavailable, "cloud sync is not configured", "CLOUD_DISABLED")
+			return
+		}
+		outbox := cfg.Runner.Outbox()
+
+		var req CloudSyncRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			WriteError(w, http.StatusBadRequest, "invalid request body", "BAD_REQUEST")
+			return
+		}
+
+		if req.Paused != nil {
+			if err := outbox.SetPaused(r.Context(), *req.Paused); err != nil {
+				WriteError(w, http.StatusInternalServerError, "failed to save sync setting", "INTERNAL_ERROR")
+				return
+			}
+		}
+		if req.Metered != nil {
+			if err := outbox.SetMetered(r.Context(), *req.Metered); err != nil {
+				WriteError(w, http.StatusInternalServerError, "failed to save sync setting", "INTERNAL_ERROR")
+				return
+			}
+		}
+
+		st := outbox.Status(r.Context())
+		WriteJSON(w, http.StatusOK, CloudSyncResponse{
+			Paused:      st.Paused,
+			Metered:     st.Metered,
+			Offline:     st.Offline,
+			PauseReason: st.PauseReason,
+		})
+	}
+}
+
+// cloudDoctorHandler checks each step of the path to the SaaS and reports
+// which one fails. It always answers 200; the verdict is in the body.
+func cloudDoctorHandler(cfg ServerConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if cfg.CloudClient == nil {
+			WriteError(w, http.StatusServiceUnavailable, "cloud sync is not configured", "CLOUD_DISABLED")
+			return
+		}
+		diagnoser, ok := cfg.CloudClient.(cloud.Diagnoser)
+		if !ok {
+			WriteError(w, http.StatusNotImplemented, "cloud client does not support diagnostics", "NOT_SUPPORTED")
+			return
+		}
+
+		WriteJSON(w, http.StatusOK, DiagnosisToResponse(diagnoser.Diagnose(r.Context())))
+	}
+}
+
+func listCloudLibrariesHandler(cfg ServerConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if cfg.CloudClient == nil {
+			WriteError(w, http.StatusServiceUnavailable, "cloud sync is not configured", "CLOUD_DISABLED")
+			return
+		}
+
+		libraries, err := cfg.CloudClient.Libraries().List(r.Context())
+		if err != nil {
+			WriteError(w, http.StatusBadGateway, "failed to list cloud libraries: "+err.Error(), "CLOUD_UNAVAILABLE")
+			return
+		}
+
+		resp := CloudLibrariesResponse{Libraries: make([]CloudLibraryResponse, len(libraries))}
+		for i, l := range libraries {
+			resp.Libraries[i] = CloudLibraryResponse{ID: l.ID, Name: l.Name}
+		}
+		WriteJSON(w, http.StatusOK, resp)
+	}
+}
+
+func createCloudLibraryHandler(cfg ServerConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if cfg.CloudClient == nil {
+			WriteError(w, http.StatusServiceUnavailable, "cloud sync is not configured", "CLOUD_DISABLED")
+			return
+		}
+
+		var req CreateCloudLibraryRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			WriteError(w, http.StatusBadRequest, "invalid request body", "BAD_REQUEST")
+			return
+		}
+		name := strings.TrimSpace(req.Name)
+		if name == "" {
+			WriteError(w, http.StatusBadRequest, "name is required", "BAD_REQUEST")
+			return
+		}
+
+		library, err := cfg.CloudClient.Libraries().GetOrCreate(r.Context(), name)
+		if err != nil {
+			WriteError(w, http.StatusBadGateway, "failed to create cloud library: "+err.Error(), "CLOUD_UNAVAILABLE")
+			return
+		}
+
+		status := http.StatusOK
+		if library.Created {
+			status = http.StatusCreated
+		}
+		WriteJSON(w, status, CloudLibraryResponse{ID: library.ID, Name: library.Name, Created: library.Created})
+	}
+}
+
+// setSourceCloudLibraryHandler maps a source to a cloud library or opts it
+// out of sync. The library must exist in the org, which keeps typos from
+// silently creating orphaned uploads. Files already indexed are re-queued so
+// they move to the new library.
+func setSourceCloudLibraryHandler(cfg ServerConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if cfg.CloudClient == nil {
+			WriteError(w, http.StatusServiceUnavailable, "cloud sync is not configured", "CLOUD_DISABLED")
+			return
+		}
+
+		var req SourceCloudLibraryRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			WriteError(w, http.StatusBadRequest, "invalid request body", "BAD_REQUEST")
+			return
+		}
+		syncEnabled := req.Sync == nil || *req.Sync
+
+		if syncEnabled && req.LibraryID != "" {
+			libraries, err := cfg.CloudClient.Libraries().List(r.Context())
+			if err != nil {
+				WriteError(w, http.StatusBadGateway, "failed to list cloud libraries: "+err.Error(), "CLOUD_UNAVAILABLE")
+				return
+			}
+			found := false
+			for _, l := range libraries {
+				if l.ID == req.LibraryID {
+					found = true
+					break
+				}
+			}
+			if !found {
+				WriteError(w, http.StatusBadRequest, "unknown cloud library", "UNKNOWN_LIBRARY")
+				return
+			}
+		}
+
+		source, err := cfg.CatalogService.SetSourceCloudLibrary(r.Context(), chi.URLParam(r, "id"), req.LibraryID, !syncEnabled)
+		if err != nil {
+			WriteError(w, http.StatusInternalServerError, err.Error(), "INTERNAL_ERROR")
+			return
+		}
+		if source == nil {
+			WriteError(w, http.StatusNotFound, "source not found", "NOT_FOUND")
+			return
+		}
+
+		if syncEnabled && cfg.Runner != nil {
+			if _, err := cfg.Runner.QueueSourceUploads(r.Context(), source.ID); err != nil && !errors.Is(err, cloud.ErrCloudDisabled) {
+				cfg.Logger.Warn("failed to queue uploads after library change", "source_id", source.ID, "error", err)
+			}
+		}
+
+		WriteJSON(w, http.StatusOK, SourceToResponse(source))
+	}
+}
+
+func cloudStatus(ctx context.Context, cfg ServerConfig) *CloudStatusResponse {
+	if cfg.CloudClient == nil {
+		return nil
+	}
+
+	auth := cfg.CloudClient.Auth()
+	resp := &CloudStatusResponse{Authenticated: cloud.SignedIn(cfg.CloudClient)}
+	if code := auth.PendingLogin(); code != nil {
+		resp.LoginPending = true
+		resp.UserCode = code.UserCode
+		resp.VerificationURI = code.VerificationURI
+	}
+	if cfg.Runner != nil {
+		if outbox := cfg.Runner.Outbox(); outbox != nil {
+			st := outbox.Status(ctx)
+			resp.OutboxPending = st.Pending
+			resp.OutboxFailed = st.Failed
+			resp.Offline = st.Offline
+			resp.SyncPaused = st.PauseReason != ""
+			resp.Metered = st.Metered
+			resp.PauseReason = st.PauseReason
+		}
+	}
+	return resp
+}
@@ -0,0 +1,275 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/heimdex/heimdex-agent/internal/catalog"
+	"github.com/heimdex/heimdex-agent/internal/cloud"
+	"github.com/heimdex/heimdex-agent/internal/db"
+)
+
+func TestCloudLoginHandler_Disabled(t *testing.T) {
+	cfg := testStatusConfig(nil)
+
+	rr := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/cloud/login", nil)
+
+	cloudLoginHandler(cfg).ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusServiceUnavailable {
+		t.Fatalf("status code = %d, want %d", rr.Code, http.StatusServiceUnavailable)
+	}
+}
+
+func TestCloudLoginHandler_ReturnsDeviceCode(t *testing.T) {
+	saas := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/auth/device/code" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error":"authorization_pending"}`))
+			return
+		}
+		json.NewEncoder(w).Encode(cloud.DeviceCode{
+			DeviceCode:      "dev-code",
+			UserCode:        "WXYZ-1234",
+			VerificationURI: "https://heimdex.example/device",
+			ExpiresIn:       1,
+			Interval:        5,
+		})
+	}))
+	defer saas.Close()
+
+	cfg := testStatusConfig(nil)
+	cfg.CloudClient = cloud.NewHTTPClient(saas.URL, "", "", slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	rr := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/cloud/login", nil)
+
+	cloudLoginHandler(cfg).ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusAccepted {
+		t.Fatalf("status code = %d, want %d", rr.Code, http.StatusAccepted)
+	}
+	body := decodeJSONBody(t, rr)
+	if body["user_code"] != "WXYZ-1234" {
+		t.Fatalf("user_code = %v, want WXYZ-1234", body["user_code"])
+	}
+
+	status := httptest.NewRecorder()
+	statusHandler(cfg).ServeHTTP(status, httptest.NewRequest(http.MethodGet, "/status", nil))
+	cloudMap, ok := decodeJSONBody(t, status)["cloud"].(map[string]interface{})
+	if !ok {
+		t.Fatal("cloud section missing from status")
+	}
+	if cloudMap["login_pending"] != true {
+		t.Fatalf("cloud.login_pending = %v, want true", cloudMap["login_pending"])
+	}
+}
+
+func TestCloudStatus_StaticTokenCountsAsSignedIn(t *testing.T) {
+	cfg := testStatusConfig(nil)
+	cfg.CloudClient = cloud.NewHTTPClient("http://saas.invalid", "static-token", "", slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	status := httptest.NewRecorder()
+	statusHandler(cfg).ServeHTTP(status, httptest.NewRequest(http.MethodGet, "/status", nil))
+	cloudMap, ok := decodeJSONBody(t, status)["cloud"].(map[string]interface{})
+	if !ok {
+		t.Fatal("cloud section missing from status")
+	}
+	if cloudMap["authenticated"] != true {
+		t.Fatalf("cloud.authenticated = %v, want true with a static token", cloudMap["authenticated"])
+	}
+}
+
+func TestCloudResyncHandler_Disabled(t *testing.T) {
+	cfg := testStatusConfig(nil)
+
+	rr := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/cloud/resync", nil)
+
+	cloudResyncHandler(cfg).ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusServiceUnavailable {
+		t.Fatalf("status code = %d, want %d", rr.Code, http.StatusServiceUnavailable)
+	}
+}
+
+func librarySaaS(t *testing.T) *httptest.Server {
+	t.Helper()
+	saas := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/libraries" || r.Method != http.MethodGet {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(`{"libraries":[{"id":"lib-a","name":"Footage"},{"id":"lib-b","name":"Archive"}]}`))
+	}))
+	t.Cleanup(saas.Close)
+	return saas
+}
+
+func TestListCloudLibrariesHandler(t *testing.T) {
+	cfg := testStatusConfig(nil)
+	cfg.CloudClient = cloud.NewHTTPClient(librarySaaS(t).URL, "", "", slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	rr := httptest.NewRecorder()
+	listCloudLibrariesHandler(cfg).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/cloud/libraries", nil))
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status code = %d, want %d", rr.Code, http.StatusOK)
+	}
+	var resp CloudLibrariesResponse
+	if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(resp.Libraries) != 2 || resp.Libraries[1].Name != "Archive" {
+		t.Fatalf("libraries = %+v", resp.Libraries)
+	}
+}
+
+func TestListCloudLibrariesHandler_Disabled(t *testing.T) {
+	cfg := testStatusConfig(nil)
+
+	rr := httptest.NewRecorder()
+	listCloudLibrariesHandler(cfg).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/cloud/libraries", nil))
+
+	if rr.Code != http.StatusServiceUnavailable {
+		t.Fatalf("status code = %d, want %d", rr.Code, http.StatusServiceUnavailable)
+	}
+}
+
+func TestCreateCloudLibraryHandler_RequiresName(t *testing.T) {
+	cfg := testStatusConfig(nil)
+	cfg.CloudClient = cloud.NewHTTPClient(librarySaaS(t).URL, "", "", slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	rr := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/cloud/libraries", strings.NewReader(`{"name":"  "}`))
+	createCloudLibraryHandler(cfg).ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("status code = %d, want %d", rr.Code, http.StatusBadRequest)
+	}
+}
+
+func TestSetSourceCloudLibraryHandler_UnknownLibrary(t *testing.T) {
+	cfg := testStatusConfig(nil)
+	cfg.CloudClient = cloud.NewHTTPClient(librarySaaS(t).URL, "", "", slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	r := chi.NewRouter()
+	r.Put("/sources/{id}/cloud-library", setSourceCloudLibraryHandler(cfg))
+
+	rr := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPut, "/sources/src-1/cloud-library", strings.NewReader(`{"library_id":"lib-missing"}`))
+	r.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("status code = %d, want %d", rr.Code, http.StatusBadRequest)
+	}
+	if body := decodeJSONBody(t, rr); body["code"] != "UNKNOWN_LIBRARY" {
+		t.Fatalf("code = %v, want UNKNOWN_LIBRARY", body["code"])
+	}
+}
+
+func TestSetSourceCloudLibraryHandler_SourceNotFound(t *testing.T) {
+	cfg := testStatusConfig(nil)
+	cfg.CloudClient = cloud.NewHTTPClient(librarySaaS(t).URL, "", "", slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	r := chi.NewRouter()
+	r.Put("/sources/{id}/cloud-library", setSourceCloudLibraryHandler(cfg))
+
+	rr := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPut, "/sources/src-1/cloud-library", strings.NewReader(`{"library_id":"lib-a"}`))
+	r.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusNotFound {
+		t.Fatalf("status code = %d, want %d", rr.Code, http.StatusNotFound)
+	}
+}
+
+func TestSetSourceCloudLibraryHandler_OptOutDropsQueuedUploads(t *testing.T) {
+	database, err := db.New(filepath.Join(t.TempDir(), "test.db"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer database.Close()
+	repo := catalog.NewRepository(database.Conn())
+	svc := catalog.NewService(repo, nil)
+	ctx := context.Background()
+
+	source, err := svc.AddFolder(ctx, t.TempDir(), "Footage")
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := &catalog.File{ID: catalog.NewID(), SourceID: source.ID, Path: filepath.Join(source.Path, "a.mp4"), Filename: "a.mp4", Mtime: time.Now(), CreatedAt: time.Now()}
+	if err := repo.CreateFile(ctx, file); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := repo.EnqueueOutbox(ctx, &catalog.OutboxEntry{
+		ID: catalog.NewID(), Kind: catalog.OutboxKindScenes, FileID: file.ID, Payload: "{}",
+		IdempotencyKey: "k1", Status: catalog.OutboxStatusPending, NextAttemptAt: time.Now(),
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := testStatusConfig(nil)
+	cfg.CatalogService = svc
+	cfg.CloudClient = cloud.NewHTTPClient(librarySaaS(t).URL, "", "", slog.New(slog.NewTextHandler(io.Discard, nil)))
+	r := chi.NewRouter()
+	r.Put("/sources/{id}/cloud-library", setSourceCloudLibraryHandler(cfg))
+
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, httptest.NewRequest(http.MethodPut, "/sources/"+source.ID+"/cloud-library", strings.NewReader(`{"sync":false}`)))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status code = %d, want %d: %s", rr.Code, http.StatusOK, rr.Body.String())
+	}
+	if n, _ := repo.CountOutbox(ctx, catalog.OutboxStatusPending); n != 0 {
+		t.Errorf("pending uploads after opting out = %d, want 0", n)
+	}
+	if n, _ := repo.CountOutbox(ctx, catalog.OutboxStatusSuperseded); n != 1 {
+		t.Errorf("superseded uploads = %d, want 1", n)
+	}
+}
+
+func TestCloudSyncHandler_Disabled(t *testing.T) {
+	cfg := testStatusConfig(nil)
+
+	rr := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPut, "/cloud/sync", strings.NewReader(`{"paused":true}`))
+	cloudSyncHandler(cfg).ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusServiceUnavailable {
+		t.Fatalf("status code = %d, want %d", rr.Code, http.StatusServiceUnavailable)
+	}
+}
+
+func TestCloudDoctorHandler(t *testing.T) {
+	saas := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer saas.Close()
+
+	cfg := testStatusConfig(nil)
+	cfg.CloudClient = cloud.NewHTTPClient(saas.URL, "", "", slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	rr := httptest.NewRecorder()
+	cloudDoctorHandler(cfg).ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/cloud/doctor", nil))
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status code = %d, want %d", rr.Code, http.StatusOK)
+	}
+	var resp CloudDoctorResponse
+	if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if !resp.OK || len(resp.Steps) != 7 {
+		t.Fatalf("response = %+v, want 7 passing or skipped steps", resp)
+	}
+}
@@ -2,14 +2,26 @@ package api
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"os"
 	"path/filepath"
 	"strings"
 
+	"github.com/heimdex/heimdex-agent/internal/catalog"
 	"github.com/heimdex/heimdex-agent/internal/export"
+	"github.com/heimdex/heimdex-agent/internal/subtitles"
 )
 
+// exportExtensions maps each export format to the extension it is written
+// with. xmeml is Final Cut Pro 7 XML, which Premiere Pro imports natively.
+var exportExtensions = map[string]string{
+	"edl":    ".edl",
+	"fcpxml": ".fcpxml",
+	"xmeml":  ".xml",
+	"otio":   ".otio",
+}
+
 func exportPremiereHandler(cfg ServerConfig) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		var req export.ExportRequest
@@ -19,8 +31,16 @@ func exportPremiereHandler(cfg ServerConfig) http.HandlerFunc {
 			return
 		}
 
-		if strings.ToLower(req.Format) != "edl" {
-			WriteError(w, http.StatusBadRequest, "format must be edl", "BAD_REQUEST")
+		format := strings.ToLower(req.Format)
+		ext, ok := exportExtensions[format]
+		if !ok {
+			WriteError(w, http.StatusBadRequest, "format must be edl, fcpxml, xmeml or otio", "BAD_REQUEST")
+			return
+		}
+
+		subtitleFormat := strings.ToLower(req.Subtitles)
+		if subtitleFormat != "" && subtitleFormat != subtitles.FormatSRT && subtitleFormat != subtitles.FormatVTT {
+			WriteError(w, http.StatusBadRequest, "subtitles must be srt or vtt", "BAD_REQUEST")
 			return
 		}
 
@@ -39,11 +59,6 @@ func exportPremiereHandler(cfg ServerConfig) http.HandlerFunc {
 			projectName = "heimdex_export"
 		}
 
-		frameRate := req.FrameRate
-		if frameRate <= 0 {
-			frameRate = 30.0
-		}
-
 		resolvedClips := make([]export.ResolvedClip, 0, len(req.Clips))
 		unresolvedClips := make([]string, 0)
 
@@ -72,13 +87,17 @@ func exportPremiereHandler(cfg ServerConfig) http.HandlerFunc {
 				clipName = clip.VideoID
 			}
 
-			resolvedClips = append(resolvedClips, export.ResolvedClip{
+			resolved := export.ResolvedClip{
+				VideoID:   file.ID,
 				ClipName:  clipName,
 				MediaPath: file.Path,
 				StartMs:   clip.StartMs,
 				EndMs:     clip.EndMs,
 				SceneID:   clip.SceneID,
-			})
+				HasAudio:  true,
+			}
+			describeExportMedia(r, cfg, file.ID, &resolved)
+			resolvedClips = append(resolvedClips, resolved)
 		}
 
 		if len(resolvedClips) == 0 {
@@ -86,19 +105,139 @@ func exportPremiereHandler(cfg ServerConfig) http.HandlerFunc {
 			return
 		}
 
-		edl := export.GenerateEDL(resolvedClips, projectName, frameRate)
-		outputPath := filepath.Join(req.OutputDir, projectName+".edl")
-		if err := os.WriteFile(outputPath, []byte(edl), 0o644); err != nil {
+		frameRate := req.FrameRate
+		if frameRate <= 0 {
+			frameRate = resolvedClips[0].FrameRate
+		}
+		if frameRate <= 0 {
+			frameRate = 30.0
+		}
+
+		var content string
+		switch format {
+		case "fcpxml":
+			doc, err := export.GenerateFCPXML(resolvedClips, projectName, frameRate)
+			if err != nil {
+				WriteError(w, http.StatusInternalServerError, err.Error(), "INTERNAL_ERROR")
+				return
+			}
+			content = doc
+		case "xmeml":
+			doc, err := export.GenerateXMEML(resolvedClips, projectName, frameRate, req.IncludeAudio)
+			if err != nil {
+				WriteError(w, http.StatusInternalServerError, err.Error(), "INTERNAL_ERROR")
+				return
+			}
+			content = doc
+		case "otio":
+			doc, err := export.GenerateOTIO(resolvedClips, projectName, frameRate)
+			if err != nil {
+				WriteError(w, http.StatusInternalServerError, err.Error(), "INTERNAL_ERROR")
+				return
+			}
+			content = doc
+		default:
+			content = export.GenerateEDL(resolvedClips, projectName, frameRate)
+		}
+
+		outputPath := filepath.Join(req.OutputDir, projectName+ext)
+		if err := os.WriteFile(outputPath, []byte(content), 0o644); err != nil {
 			WriteError(w, http.StatusInternalServerError, "failed to write export file", "INTERNAL_ERROR")
 			return
 		}
 
+		var subtitlePath string
+		if subtitleFormat != "" {
+			subtitlePath = filepath.Join(req.OutputDir, projectName+"."+subtitleFormat)
+			if err := writeExportSubtitles(r, cfg, subtitlePath, subtitleFormat, resolvedClips, frameRate); err != nil {
+				WriteError(w, http.StatusInternalServerError, "failed to write subtitles", "INTERNAL_ERROR")
+				return
+			}
+		}
+
 		WriteJSON(w, http.StatusOK, export.ExportResponse{
 			Status:          "ok",
-			Format:          "edl",
+			Format:          format,
 			OutputPath:      outputPath,
 			ClipCount:       len(resolvedClips),
 			UnresolvedClips: unresolvedClips,
+			SubtitlePath:    subtitlePath,
 		})
 	}
 }
+
+// describeExportMedia fills in what the export formats need beyond the
+// catalog: the source's probed format, its timecode and reel, and markers
+// for the scenes starting inside the clip. Each is best effort: an unprobed
+// file falls back to the sequence format with no timecode or reel, and a
+// file without scene output gets no markers.
+func describeExportMedia(r *http.Request, cfg ServerConfig, fileID string, clip *export.ResolvedClip) {
+	if cfg.MediaProber != nil {
+		probe, err := cfg.MediaProber.Probe(clip.MediaPath)
+		if err != nil {
+			cfg.Logger.Warn("export probe failed", "file_id", fileID, "error", err)
+		} else if probe != nil {
+			clip.FrameRate = probe.FrameRate
+			clip.Width = probe.Width
+			clip.Height = probe.Height
+			clip.DurationMs = int(probe.Duration * 1000)
+			clip.HasAudio = probe.AudioCodec != ""
+			clip.AudioChannels = probe.AudioChannels
+			clip.SampleRate = probe.AudioSample
+			clip.StartTimecode = probe.Timecode
+			clip.ReelName = probe.ReelName
+		}
+	}
+
+	if cfg.Repository == nil || cfg.ArtifactsDir == "" {
+		return
+	}
+	scenes, err := catalog.LoadScenes(r.Context(), cfg.Repository, cfg.ArtifactsDir, fileID)
+	if err != nil {
+		cfg.Logger.Warn("export scenes unavailable", "file_id", fileID, "error", err)
+		return
+	}
+	for _, scene := range scenes {
+		if scene.StartMs < clip.StartMs || scene.StartMs >= clip.EndMs {
+			continue
+		}
+		clip.Markers = append(clip.Markers, export.Marker{
+			SceneID:    scene.SceneID,
+			StartMs:    scene.StartMs,
+			Name:       fmt.Sprintf("Scene %d", scene.Index+1),
+			Tags:       scene.Tags,
+			Transcript: scene.Transcript,
+		})
+	}
+}
+
+// writeExportSubtitles writes the clips' captions cut to each clip and moved
+// to where the clip sits on the export timeline. Clips of files without a
+// transcript leave a gap.
+func writeExportSubtitles(r *http.Request, cfg ServerConfig, path, format string, clips []export.ResolvedClip, frameRate float64) error {
+	offsets := export.TimelineOffsets(clips, frameRate)
+	captions := make(map[string][]subtitles.Cue)
+	var cues []subtitles.Cue
+	for i, clip := range clips {
+		source, ok := captions[clip.VideoID]
+		if !ok && cfg.Repository != nil && cfg.ArtifactsDir != "" {
+			var err error
+			source, err = catalog.LoadCaptions(r.Context(), cfg.Repository, cfg.ArtifactsDir, clip.VideoID)
+			if err != nil {
+				cfg.Logger.Warn("export captions unavailable", "file_id", clip.VideoID, "error", err)
+			}
+			captions[clip.VideoID] = source
+		}
+		cues = append(cues, subtitles.Rebase(source, clip.StartMs, clip.EndMs, offsets[i])...)
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := subtitles.Write(f, format, cues); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
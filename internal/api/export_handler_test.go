@@ -16,6 +16,8 @@ import (
 
 	"github.com/heimdex/heimdex-agent/internal/catalog"
 	exportpkg "github.com/heimdex/heimdex-agent/internal/export"
+	"github.com/heimdex/heimdex-agent/internal/pipeline"
+	"github.com/heimdex/heimdex-agent/internal/pipelines"
 )
 
 type fakeServiceForExport struct {
@@ -148,11 +150,300 @@ func TestExportPremiere_AllUnresolved(t *testing.T) {
 	}
 }
 
+type fakeProber struct {
+	probe *pipeline.ProbeResult
+}
+
+func (f *fakeProber) Probe(string) (*pipeline.ProbeResult, error) {
+	return f.probe, nil
+}
+
+// probedExportConfig serves file v1 as a probed 4K 23.976 fps file with
+// three scenes, the middle one tagged "beach".
+func probedExportConfig(t *testing.T) ServerConfig {
+	t.Helper()
+	artifacts := t.TempDir()
+	dir := filepath.Join(artifacts, "v1", "scenes")
+	os.MkdirAll(dir, 0o755)
+	data, _ := json.Marshal(pipelines.SceneOutputPayload{
+		VideoID: "v1",
+		Scenes: []pipelines.SceneBoundary{
+			{SceneID: "v1_scene_0", Index: 0, EndMs: 1000},
+			{SceneID: "v1_scene_1", Index: 1, StartMs: 1000, EndMs: 4000, KeywordTags: []string{"beach"}, TranscriptRaw: "waves"},
+			{SceneID: "v1_scene_2", Index: 2, StartMs: 4000, EndMs: 9000},
+		},
+	})
+	os.WriteFile(filepath.Join(dir, "result.json"), data, 0o644)
+
+	cfg := exportTestConfig(&fakeServiceForExport{files: map[string]*catalog.File{
+		"v1": {ID: "v1", Path: "/media/alpha.mov"},
+	}})
+	cfg.ArtifactsDir = artifacts
+	cfg.Repository = &fakeRepo{}
+	cfg.MediaProber = &fakeProber{probe: &pipeline.ProbeResult{
+		Duration: 9, Width: 3840, Height: 2160, FrameRate: 23.976, AudioCodec: "aac", AudioChannels: 1,
+	}}
+	return cfg
+}
+
+func TestExportPremiere_FCPXML(t *testing.T) {
+	outDir := t.TempDir()
+	cfg := probedExportConfig(t)
+
+	req := newExportRequest(t, exportpkg.ExportRequest{
+		ProjectName: "Cut",
+		Format:      "FCPXML",
+		OutputDir:   outDir,
+		Clips:       []exportpkg.ClipInput{{VideoID: "v1", ClipName: "Beach", StartMs: 500, EndMs: 4000}},
+	})
+	rr := httptest.NewRecorder()
+
+	exportPremiereHandler(cfg).ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rr.Code, rr.Body.String())
+	}
+	var resp exportpkg.ExportResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("response unmarshal error: %v", err)
+	}
+	if resp.Format != "fcpxml" || filepath.Ext(resp.OutputPath) != ".fcpxml" {
+		t.Fatalf("format = %q, output = %q", resp.Format, resp.OutputPath)
+	}
+
+	content, err := os.ReadFile(resp.OutputPath)
+	if err != nil {
+		t.Fatalf("failed reading output FCPXML: %v", err)
+	}
+	for _, want := range []string{
+		`frameDuration="1001/24000s"`,
+		`width="3840" height="2160"`,
+		`src="file:///media/alpha.mov"`,
+		`hasAudio="1"`,
+		`value="Scene 2" note="beach"`,
+	} {
+		if !strings.Contains(string(content), want) {
+			t.Errorf("FCPXML missing %s:\n%s", want, content)
+		}
+	}
+	if strings.Contains(string(content), "Scene 1") || strings.Contains(string(content), "Scene 3") {
+		t.Errorf("FCPXML has markers outside the clip:\n%s", content)
+	}
+}
+
+func TestExportPremiere_XMEML(t *testing.T) {
+	cfg := probedExportConfig(t)
+	req := newExportRequest(t, exportpkg.ExportRequest{
+		ProjectName:  "Cut",
+		Format:       "xmeml",
+		OutputDir:    t.TempDir(),
+		IncludeAudio: true,
+		Clips:        []exportpkg.ClipInput{{VideoID: "v1", ClipName: "Beach", StartMs: 500, EndMs: 4000}},
+	})
+	rr := httptest.NewRecorder()
+
+	exportPremiereHandler(cfg).ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rr.Code, rr.Body.String())
+	}
+	var resp exportpkg.ExportResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("response unmarshal error: %v", err)
+	}
+	if resp.Format != "xmeml" || filepath.Ext(resp.OutputPath) != ".xml" {
+		t.Fatalf("format = %q, output = %q", resp.Format, resp.OutputPath)
+	}
+
+	content, err := os.ReadFile(resp.OutputPath)
+	if err != nil {
+		t.Fatalf("failed reading output xmeml: %v", err)
+	}
+	for _, want := range []string{
+		"<timebase>24</timebase>",
+		"<ntsc>TRUE</ntsc>",
+		"<pathurl>file://localhost/media/alpha.mov</pathurl>",
+		"<name>Scene 2</name>",
+		"<comment>Tags: beach&#xA;waves</comment>",
+		"<channelcount>1</channelcount>",
+		"<mediatype>audio</mediatype>",
+	} {
+		if !strings.Contains(string(content), want) {
+			t.Errorf("xmeml missing %s:\n%s", want, content)
+		}
+	}
+}
+
+func TestExportPremiere_OTIO(t *testing.T) {
+	cfg := probedExportConfig(t)
+	req := newExportRequest(t, exportpkg.ExportRequest{
+		ProjectName: "Cut",
+		Format:      "otio",
+		OutputDir:   t.TempDir(),
+		Clips:       []exportpkg.ClipInput{{VideoID: "v1", SceneID: "v1_scene_1", ClipName: "Beach", StartMs: 500, EndMs: 4000}},
+	})
+	rr := httptest.NewRecorder()
+
+	exportPremiereHandler(cfg).ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rr.Code, rr.Body.String())
+	}
+	var resp exportpkg.ExportResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("response unmarshal error: %v", err)
+	}
+	if resp.Format != "otio" || filepath.Ext(resp.OutputPath) != ".otio" {
+		t.Fatalf("format = %q, output = %q", resp.Format, resp.OutputPath)
+	}
+
+	content, err := os.ReadFile(resp.OutputPath)
+	if err != nil {
+		t.Fatalf("failed reading output otio: %v", err)
+	}
+	var doc struct {
+		Tracks struct {
+			Children []struct {
+				Children []struct {
+					Metadata struct {
+						Heimdex struct {
+							VideoID string `json:"video_id"`
+							Scenes  []struct {
+								SceneID string   `json:"scene_id"`
+								Tags    []string `json:"tags"`
+							} `json:"scenes"`
+						} `json:"heimdex"`
+					} `json:"metadata"`
+					SourceRange struct {
+						StartTime struct {
+							Rate  float64 `json:"rate"`
+							Value float64 `json:"value"`
+						} `json:"start_time"`
+					} `json:"source_range"`
+				} `json:"children"`
+			} `json:"children"`
+		} `json:"tracks"`
+	}
+	if err := json.Unmarshal(content, &doc); err != nil {
+		t.Fatalf("otio is not valid JSON: %v", err)
+	}
+	clip := doc.Tracks.Children[0].Children[0]
+	if clip.Metadata.Heimdex.VideoID != "v1" {
+		t.Errorf("video_id = %q", clip.Metadata.Heimdex.VideoID)
+	}
+	if scenes := clip.Metadata.Heimdex.Scenes; len(scenes) != 1 || scenes[0].SceneID != "v1_scene_1" || scenes[0].Tags[0] != "beach" {
+		t.Errorf("scenes = %+v", scenes)
+	}
+	if st := clip.SourceRange.StartTime; st.Value != 12 || st.Rate < 23.97 || st.Rate > 23.98 {
+		t.Errorf("start_time = %+v, want frame 12 at 23.976", st)
+	}
+}
+
+func TestExportPremiere_EDLUsesProbedTimecode(t *testing.T) {
+	cfg := probedExportConfig(t)
+	cfg.MediaProber.(*fakeProber).probe.Timecode = "01:00:00;00"
+	cfg.MediaProber.(*fakeProber).probe.FrameRate = 29.97
+	cfg.MediaProber.(*fakeProber).probe.ReelName = "A001"
+	req := newExportRequest(t, exportpkg.ExportRequest{
+		ProjectName: "Cut",
+		Format:      "edl",
+		OutputDir:   t.TempDir(),
+		Clips:       []exportpkg.ClipInput{{VideoID: "v1", ClipName: "Beach", StartMs: 0, EndMs: 60060}},
+	})
+	rr := httptest.NewRecorder()
+
+	exportPremiereHandler(cfg).ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rr.Code, rr.Body.String())
+	}
+	var resp exportpkg.ExportResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("response unmarshal error: %v", err)
+	}
+	content, err := os.ReadFile(resp.OutputPath)
+	if err != nil {
+		t.Fatalf("failed reading output EDL: %v", err)
+	}
+	if !bytes.Contains(content, []byte("FCM: DROP FRAME")) ||
+		!bytes.Contains(content, []byte("001  A001     V     C        01:00:00;00 01:01:00;02 00:00:00;00 00:01:00;02")) {
+		t.Fatalf("EDL should use the probed rate, timecode and reel:\n%s", content)
+	}
+}
+
+func TestExportPremiere_Subtitles(t *testing.T) {
+	artifacts := t.TempDir()
+	writeSpeechResult(t, artifacts, "v1",
+		map[string]any{"start": 0.5, "end": 1.5, "text": "cut away"},
+		map[string]any{"start": 2.0, "end": 3.0, "text": "first line"},
+		map[string]any{"start": 10.5, "end": 11.0, "text": "second line"},
+	)
+	cfg := exportTestConfig(&fakeServiceForExport{files: map[string]*catalog.File{
+		"v1": {ID: "v1", Path: "/media/a.mp4"},
+		"v2": {ID: "v2", Path: "/media/b.mp4"},
+	}})
+	cfg.ArtifactsDir = artifacts
+	cfg.Repository = &fakeRepo{}
+
+	req := newExportRequest(t, exportpkg.ExportRequest{
+		ProjectName: "Captioned",
+		Format:      "edl",
+		FrameRate:   25,
+		OutputDir:   t.TempDir(),
+		Subtitles:   "srt",
+		Clips: []exportpkg.ClipInput{
+			{VideoID: "v1", StartMs: 2000, EndMs: 4000},
+			{VideoID: "v2", StartMs: 0, EndMs: 1000},
+			{VideoID: "v1", StartMs: 10000, EndMs: 12000},
+		},
+	})
+	rr := httptest.NewRecorder()
+
+	exportPremiereHandler(cfg).ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rr.Code, rr.Body.String())
+	}
+	var resp exportpkg.ExportResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("response unmarshal error: %v", err)
+	}
+	if filepath.Base(resp.SubtitlePath) != "Captioned.srt" {
+		t.Fatalf("subtitle_path = %q", resp.SubtitlePath)
+	}
+	content, err := os.ReadFile(resp.SubtitlePath)
+	if err != nil {
+		t.Fatalf("failed reading subtitles: %v", err)
+	}
+	want := "1\n00:00:00,000 --> 00:00:01,000\nfirst line\n\n2\n00:00:03,500 --> 00:00:04,000\nsecond line\n\n"
+	if string(content) != want {
+		t.Errorf("subtitles = %q, want %q", content, want)
+	}
+}
+
+func TestExportPremiere_InvalidSubtitles(t *testing.T) {
+	cfg := exportTestConfig(&fakeServiceForExport{files: map[string]*catalog.File{}})
+	req := newExportRequest(t, exportpkg.ExportRequest{
+		ProjectName: "Bad",
+		Format:      "edl",
+		Subtitles:   "ass",
+		OutputDir:   t.TempDir(),
+		Clips:       []exportpkg.ClipInput{{VideoID: "v1", StartMs: 0, EndMs: 1000}},
+	})
+	rr := httptest.NewRecorder()
+
+	exportPremiereHandler(cfg).ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want %d", rr.Code, http.StatusBadRequest)
+	}
+}
+
 func TestExportPremiere_InvalidFormat(t *testing.T) {
 	cfg := exportTestConfig(&fakeServiceForExport{files: map[string]*catalog.File{}})
 	req := newExportRequest(t, exportpkg.ExportRequest{
 		ProjectName: "Bad",
-		Format:      "fcpxml",
+		Format:      "aaf",
 		OutputDir:   t.TempDir(),
 		Clips:       []exportpkg.ClipInput{{VideoID: "v1", StartMs: 0, EndMs: 1000}},
 	})
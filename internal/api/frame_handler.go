@@ -0,0 +1,74 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"strconv"
+
+	"github.com/heimdex/heimdex-agent/internal/playback"
+)
+
+// playbackFrameHandler renders the frame at t_ms, optionally w pixels wide,
+// as WebP or JPEG.
+func playbackFrameHandler(cfg ServerConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if cfg.FrameServer == nil {
+			WriteError(w, http.StatusServiceUnavailable, "frame rendering is not available", "FRAMES_UNAVAILABLE")
+			return
+		}
+
+		q := r.URL.Query()
+		fileID := q.Get("file_id")
+		if fileID == "" {
+			WriteError(w, http.StatusBadRequest, "file_id is required", "BAD_REQUEST")
+			return
+		}
+		atMs, err := strconv.Atoi(q.Get("t_ms"))
+		if err != nil {
+			WriteError(w, http.StatusBadRequest, "t_ms is required", "BAD_REQUEST")
+			return
+		}
+		width := 0
+		if v := q.Get("w"); v != "" {
+			if width, err = strconv.Atoi(v); err != nil || width <= 0 {
+				WriteError(w, http.StatusBadRequest, "w must be a positive integer", "BAD_REQUEST")
+				return
+			}
+		}
+		format, err := playback.FrameFormat(q.Get("format"), r.Header.Get("Accept"))
+		if err != nil {
+			WriteError(w, http.StatusBadRequest, err.Error(), "BAD_REQUEST")
+			return
+		}
+		if q.Get("format") == "" {
+			w.Header().Set("Vary", "Accept")
+		}
+		if claims := playbackClaimsFrom(r); claims != nil && !claims.allowsRange(fileID, atMs, atMs) {
+			writeTokenScopeError(w)
+			return
+		}
+
+		file := lookupPlaybackFile(w, r, cfg, fileID)
+		if file == nil {
+			return
+		}
+
+		if err := cfg.FrameServer.ServeFrame(w, r, file.ID, file.Path, atMs, width, format); err != nil {
+			switch {
+			case errors.Is(err, playback.ErrInvalidFrame):
+				WriteError(w, http.StatusBadRequest, err.Error(), "BAD_REQUEST")
+			case errors.Is(err, playback.ErrFrameBusy):
+				w.Header().Set("Retry-After", "1")
+				WriteError(w, http.StatusServiceUnavailable, err.Error(), "FRAMES_BUSY")
+			case os.IsNotExist(err):
+				WriteError(w, http.StatusNotFound, "file missing on disk", "NOT_FOUND")
+			case r.Context().Err() != nil:
+				// The client gave up; nobody is listening for an answer.
+			default:
+				cfg.Logger.Error("frame render error", "error", err, "file_id", file.ID)
+				WriteError(w, http.StatusInternalServerError, "failed to render frame", "FRAME_FAILED")
+			}
+		}
+	}
+}
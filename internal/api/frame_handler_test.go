@@ -0,0 +1,84 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/heimdex/heimdex-agent/internal/playback"
+)
+
+type fakeFrames struct {
+	atMs, width int
+	format      string
+	err         error
+}
+
+func (f *fakeFrames) ServeFrame(w http.ResponseWriter, r *http.Request, fileID, filePath string, atMs, width int, format string) error {
+	if f.err != nil {
+		return f.err
+	}
+	f.atMs, f.width, f.format = atMs, width, format
+	return nil
+}
+
+func TestPlaybackFrame_NegotiatesFormat(t *testing.T) {
+	frames := &fakeFrames{}
+	cfg := hlsTestConfig("/media/a.mov", nil)
+	cfg.FrameServer = frames
+	router := NewRouter(cfg)
+
+	req := loopbackGet("/playback/frame?file_id=file-1&t_ms=2500&w=320")
+	req.Header.Set("Accept", "image/webp,*/*")
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rr.Code, rr.Body.String())
+	}
+	if frames.atMs != 2500 || frames.width != 320 || frames.format != playback.FrameFormatWebP {
+		t.Errorf("frame = %+v, want 2500ms at 320px as webp", frames)
+	}
+	if rr.Header().Get("Vary") != "Accept" {
+		t.Errorf("Vary = %q, want Accept", rr.Header().Get("Vary"))
+	}
+
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, loopbackGet("/playback/frame?file_id=file-1&t_ms=0&format=jpeg"))
+	if rr.Code != http.StatusOK || frames.format != playback.FrameFormatJPEG || frames.width != 0 {
+		t.Errorf("status = %d, frame = %+v; want native-size jpeg", rr.Code, frames)
+	}
+}
+
+func TestPlaybackFrame_Errors(t *testing.T) {
+	frames := &fakeFrames{}
+	cfg := hlsTestConfig("/media/a.mov", nil)
+	cfg.FrameServer = frames
+	router := NewRouter(cfg)
+
+	for _, target := range []string{
+		"/playback/frame?file_id=file-1",
+		"/playback/frame?file_id=file-1&t_ms=0&w=-5",
+		"/playback/frame?file_id=file-1&t_ms=0&format=png",
+		"/playback/frame?t_ms=0",
+	} {
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, loopbackGet(target))
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("%s status = %d, want 400", target, rr.Code)
+		}
+	}
+
+	frames.err = playback.ErrFrameBusy
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, loopbackGet("/playback/frame?file_id=file-1&t_ms=0"))
+	if rr.Code != http.StatusServiceUnavailable || rr.Header().Get("Retry-After") == "" {
+		t.Errorf("busy status = %d, Retry-After = %q", rr.Code, rr.Header().Get("Retry-After"))
+	}
+
+	cfg.FrameServer = nil
+	rr = httptest.NewRecorder()
+	NewRouter(cfg).ServeHTTP(rr, loopbackGet("/playback/frame?file_id=file-1&t_ms=0"))
+	if rr.Code != http.StatusServiceUnavailable {
+		t.Errorf("unconfigured status = %d, want 503", rr.Code)
+	}
+}
@@ -0,0 +1,138 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"os"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/heimdex/heimdex-agent/internal/catalog"
+	"github.com/heimdex/heimdex-agent/internal/pipeline"
+	"github.com/heimdex/heimdex-agent/internal/playback"
+)
+
+// playbackInfoHandler tells the player how to play a file: directly from
+// /playback/file, or through the HLS transcoder when the browser cannot
+// decode it.
+func playbackInfoHandler(cfg ServerConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		fileID := r.URL.Query().Get("file_id")
+		if fileID == "" {
+			WriteError(w, http.StatusBadRequest, "file_id is required", "BAD_REQUEST")
+			return
+		}
+		if claims := playbackClaimsFrom(r); claims != nil && !claims.matchesFile(fileID) {
+			writeTokenScopeError(w)
+			return
+		}
+
+		file := lookupPlaybackFile(w, r, cfg, fileID)
+		if file == nil {
+			return
+		}
+
+		resp := PlaybackInfoResponse{
+			FileID:     file.ID,
+			DirectPlay: true,
+			URL:        withPlaybackToken(r, "/playback/file?file_id="+url.QueryEscape(file.ID)),
+		}
+		var probe *pipeline.ProbeResult
+		if cfg.HLSServer != nil {
+			probe, resp.DirectPlay, resp.Reason = directPlay(cfg, file)
+			resp.HLSURL = withPlaybackToken(r, hlsPlaylistURL(file.ID))
+			if !resp.DirectPlay {
+				resp.URL = resp.HLSURL
+			}
+		}
+		if probe != nil {
+			resp.DurationS = probe.Duration
+			resp.Width = probe.Width
+			resp.Height = probe.Height
+			resp.VideoCodec = probe.Codec
+			resp.AudioCodec = probe.AudioCodec
+			resp.Container = probe.Container
+		}
+		WriteJSON(w, http.StatusOK, resp)
+	}
+}
+
+func hlsPlaylistHandler(cfg ServerConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if cfg.HLSServer == nil {
+			WriteError(w, http.StatusServiceUnavailable, "transcoding is not available", "HLS_UNAVAILABLE")
+			return
+		}
+		fileID := chi.URLParam(r, "file_id")
+		if claims := playbackClaimsFrom(r); claims != nil && !claims.allowsFile(fileID) {
+			writeTokenScopeError(w)
+			return
+		}
+		file := lookupPlaybackFile(w, r, cfg, fileID)
+		if file == nil {
+			return
+		}
+
+		if err := cfg.HLSServer.ServePlaylist(w, r, file.Path); err != nil {
+			writeHLSError(w, cfg, file.ID, err)
+		}
+	}
+}
+
+func hlsSegmentHandler(cfg ServerConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if cfg.HLSServer == nil {
+			WriteError(w, http.StatusServiceUnavailable, "transcoding is not available", "HLS_UNAVAILABLE")
+			return
+		}
+		index, ok := playback.ParseSegmentName(chi.URLParam(r, "segment"))
+		if !ok {
+			WriteError(w, http.StatusNotFound, "segment not found", "NOT_FOUND")
+			return
+		}
+		fileID := chi.URLParam(r, "file_id")
+		if claims := playbackClaimsFrom(r); claims != nil && !claims.allowsFile(fileID) {
+			writeTokenScopeError(w)
+			return
+		}
+		file := lookupPlaybackFile(w, r, cfg, fileID)
+		if file == nil {
+			return
+		}
+
+		if err := cfg.HLSServer.ServeSegment(w, r, file.ID, file.Path, index); err != nil {
+			writeHLSError(w, cfg, file.ID, err)
+		}
+	}
+}
+
+func writeHLSError(w http.ResponseWriter, cfg ServerConfig, fileID string, err error) {
+	switch {
+	case errors.Is(err, playback.ErrSegmentOutOfRange):
+		WriteError(w, http.StatusNotFound, "segment not found", "NOT_FOUND")
+	case os.IsNotExist(err):
+		WriteError(w, http.StatusNotFound, "file missing on disk", "NOT_FOUND")
+	case errors.Is(err, playback.ErrUnknownDuration):
+		WriteError(w, http.StatusUnprocessableEntity, "media duration could not be read", "UNREADABLE_MEDIA")
+	default:
+		cfg.Logger.Error("hls error", "error", err, "file_id", fileID)
+		WriteError(w, http.StatusInternalServerError, "transcoding failed", "TRANSCODE_FAILED")
+	}
+}
+
+// directPlay reports whether the browser can play file without transcoding.
+// Probe failures fall back to direct play, since ffmpeg could not transcode
+// the file either.
+func directPlay(cfg ServerConfig, file *catalog.File) (*pipeline.ProbeResult, bool, string) {
+	probe, err := cfg.HLSServer.Probe(file.Path)
+	if err != nil {
+		cfg.Logger.Warn("probe failed", "file_id", file.ID, "error", err)
+		return nil, true, ""
+	}
+	direct, reason := playback.DirectPlayable(file.Path, probe)
+	return probe, direct, reason
+}
+
+func hlsPlaylistURL(fileID string) string {
+	return "/playback/hls/" + url.PathEscape(fileID) + "/index.m3u8"
+}
@@ -0,0 +1,196 @@
+package api
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/heimdex/heimdex-agent/internal/catalog"
+	"github.com/heimdex/heimdex-agent/internal/pipeline"
+	"github.com/heimdex/heimdex-agent/internal/playback"
+)
+
+type fakeHLS struct {
+	probe    *pipeline.ProbeResult
+	segments []int
+}
+
+func (f *fakeHLS) Probe(string) (*pipeline.ProbeResult, error) {
+	return f.probe, nil
+}
+
+func (f *fakeHLS) ServePlaylist(w http.ResponseWriter, r *http.Request, filePath string) error {
+	w.Header().Set("Content-Type", "application/vnd.apple.mpegurl")
+	w.Write([]byte("#EXTM3U\n"))
+	return nil
+}
+
+func (f *fakeHLS) ServeSegment(w http.ResponseWriter, r *http.Request, fileID, filePath string, index int) error {
+	f.segments = append(f.segments, index)
+	w.Header().Set("Content-Type", "video/mp2t")
+	return nil
+}
+
+func hlsTestConfig(path string, hls *fakeHLS) ServerConfig {
+	cfg := ServerConfig{
+		CatalogService: &fakeServiceWithFile{
+			file: &catalog.File{ID: "file-1", SourceID: "src-1", Path: path},
+		},
+		PlaybackServer: &fakePlayback{},
+		Repository:     &fakeRepoWithAuthToken{},
+		Logger:         slog.New(slog.NewTextHandler(io.Discard, nil)),
+		StartTime:      time.Now(),
+	}
+	if hls != nil {
+		cfg.HLSServer = hls
+	}
+	return cfg
+}
+
+// loopbackGet is a playback request from localhost carrying the bearer
+// token of fakeRepoWithAuthToken.
+func loopbackGet(target string) *http.Request {
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	req.RemoteAddr = "127.0.0.1:12345"
+	req.Header.Set("Authorization", "Bearer test-token")
+	return req
+}
+
+// loopbackGetNoAuth is a playback request from localhost with no bearer
+// token, as a <video> tag playing a signed URL makes it.
+func loopbackGetNoAuth(target string) *http.Request {
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	req.RemoteAddr = "127.0.0.1:12345"
+	return req
+}
+
+func TestPlaybackInfo_FallsBackToHLSForProRes(t *testing.T) {
+	hls := &fakeHLS{probe: &pipeline.ProbeResult{Duration: 12, Codec: "prores", AudioCodec: "pcm_s16le"}}
+	router := NewRouter(hlsTestConfig("/media/a.mov", hls))
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, loopbackGet("/playback/info?file_id=file-1"))
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rr.Code, rr.Body.String())
+	}
+	var resp PlaybackInfoResponse
+	if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if resp.DirectPlay {
+		t.Error("DirectPlay = true, want false for prores")
+	}
+	if resp.URL != "/playback/hls/file-1/index.m3u8" {
+		t.Errorf("URL = %q", resp.URL)
+	}
+	if resp.Reason != "video codec prores" {
+		t.Errorf("Reason = %q", resp.Reason)
+	}
+}
+
+func TestPlaybackInfo_DirectPlayForH264(t *testing.T) {
+	hls := &fakeHLS{probe: &pipeline.ProbeResult{Duration: 12, Codec: "h264", AudioCodec: "aac"}}
+	router := NewRouter(hlsTestConfig("/media/a.mp4", hls))
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, loopbackGet("/playback/info?file_id=file-1"))
+
+	var resp PlaybackInfoResponse
+	if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if !resp.DirectPlay || resp.URL != "/playback/file?file_id=file-1" {
+		t.Errorf("resp = %+v, want direct play", resp)
+	}
+	if resp.HLSURL == "" {
+		t.Error("HLSURL should be offered even when direct play works")
+	}
+}
+
+func TestPlaybackFile_AutoRedirectsToHLS(t *testing.T) {
+	hls := &fakeHLS{probe: &pipeline.ProbeResult{Duration: 12, Codec: "hevc"}}
+	router := NewRouter(hlsTestConfig("/media/a.mp4", hls))
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, loopbackGet("/playback/file?file_id=file-1&auto=1"))
+
+	if rr.Code != http.StatusTemporaryRedirect {
+		t.Fatalf("status = %d, want %d", rr.Code, http.StatusTemporaryRedirect)
+	}
+	if loc := rr.Header().Get("Location"); loc != "/playback/hls/file-1/index.m3u8" {
+		t.Errorf("Location = %q", loc)
+	}
+}
+
+func TestHLSSegment_ParsesIndex(t *testing.T) {
+	hls := &fakeHLS{probe: &pipeline.ProbeResult{Duration: 30}}
+	router := NewRouter(hlsTestConfig("/media/a.mkv", hls))
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, loopbackGet("/playback/hls/file-1/segment_00003.ts"))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rr.Code, rr.Body.String())
+	}
+	if len(hls.segments) != 1 || hls.segments[0] != 3 {
+		t.Errorf("segments = %v, want [3]", hls.segments)
+	}
+
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, loopbackGet("/playback/hls/file-1/../../etc/passwd"))
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("bad segment name status = %d, want 404", rr.Code)
+	}
+}
+
+func TestHLSPlaylist_Unavailable(t *testing.T) {
+	router := NewRouter(hlsTestConfig("/media/a.mkv", nil))
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, loopbackGet("/playback/hls/file-1/index.m3u8"))
+	if rr.Code != http.StatusServiceUnavailable {
+		t.Fatalf("status = %d, want %d", rr.Code, http.StatusServiceUnavailable)
+	}
+}
+
+func TestPlaybackFile_ETagFromFingerprint(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "a.mp4")
+	if err := os.WriteFile(path, []byte("0123456789"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	mtime := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	if err := os.Chtimes(path, mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+	// The catalog's mtime is stale; the validator follows the file on disk.
+	cfg := hlsTestConfig(path, nil)
+	cfg.CatalogService = &fakeServiceWithFile{
+		file: &catalog.File{ID: "file-1", SourceID: "src-1", Path: path, Fingerprint: "deadbeef", Mtime: mtime.Add(-time.Hour)},
+	}
+	router := NewRouter(cfg)
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, loopbackGet("/playback/file?file_id=file-1"))
+	if got, want := rr.Header().Get("ETag"), playback.ETag("deadbeef", 10, mtime); got != want {
+		t.Errorf("ETag = %q, want %q", got, want)
+	}
+
+	// Rewriting the file past the fingerprinted head changes the ETag.
+	if err := os.WriteFile(path, []byte("0123456789abc"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, loopbackGet("/playback/file?file_id=file-1"))
+	if got, want := rr.Header().Get("ETag"), playback.ETag("deadbeef", 13, mtime); got != want {
+		t.Errorf("ETag after rewrite = %q, want %q", got, want)
+	}
+}
@@ -3,6 +3,7 @@ package api
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"log/slog"
 	"net"
 	"net/http"
@@ -16,7 +17,10 @@ import (
 
 type contextKey string
 
-const RequestIDKey contextKey = "request_id"
+const (
+	RequestIDKey      contextKey = "request_id"
+	playbackClaimsKey contextKey = "playback_claims"
+)
 
 var heimdexSubdomainRe = regexp.MustCompile(`^https?://[a-z0-9]([a-z0-9-]*[a-z0-9])?\.app\.heimdex\.(co|local)(:[0-9]+)?$`)
 
@@ -79,8 +83,8 @@ func CORSAllowlistMethods(methods string) func(http.Handler) http.Handler {
 				w.Header().Set("Access-Control-Allow-Origin", origin)
 				w.Header().Add("Vary", "Origin")
 				w.Header().Set("Access-Control-Allow-Methods", methods)
-				w.Header().Set("Access-Control-Allow-Headers", "Range, Content-Type, Authorization, X-Heimdex-Request-Id, X-Heimdex-Device-Id")
-				w.Header().Set("Access-Control-Expose-Headers", "Content-Range, Accept-Ranges, Content-Length, Content-Type")
+				w.Header().Set("Access-Control-Allow-Headers", "Range, Content-Type, Authorization, X-Heimdex-Request-Id, X-Heimdex-Device-Id, If-Range, If-None-Match, If-Modified-Since")
+				w.Header().Set("Access-Control-Expose-Headers", "Content-Range, Accept-Ranges, Content-Length, Content-Type, ETag, Last-Modified")
 			}
 
 			if r.Method == http.MethodOptions {
@@ -108,37 +112,90 @@ func LoopbackGuard() func(http.Handler) http.Handler {
 func AuthMiddleware(repo catalog.Repository, logger *slog.Logger) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			auth := r.Header.Get("Authorization")
-			if auth == "" {
-				WriteError(w, http.StatusUnauthorized, "missing authorization header", "UNAUTHORIZED")
+			if !checkBearer(w, r, repo, logger) {
 				return
 			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
 
-			if !strings.HasPrefix(auth, "Bearer ") {
-				WriteError(w, http.StatusUnauthorized, "invalid authorization format", "UNAUTHORIZED")
+// PlaybackAuthMiddleware admits playback requests that carry either the
+// bearer token or a signed playback token in the token query parameter.
+// The verified claims are put on the request context for the handler to
+// check against the file and range it serves. Preflight requests pass.
+func PlaybackAuthMiddleware(repo catalog.Repository, logger *slog.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodOptions {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if r.Header.Get("Authorization") != "" {
+				if !checkBearer(w, r, repo, logger) {
+					return
+				}
+				next.ServeHTTP(w, r)
 				return
 			}
 
-			token := strings.TrimPrefix(auth, "Bearer ")
-
-			storedToken, err := repo.GetConfig(r.Context(), "auth_token")
-			if err != nil || storedToken == "" {
+			token := r.URL.Query().Get("token")
+			if token == "" {
+				WriteError(w, http.StatusUnauthorized, "playback requires a token or authorization header", "UNAUTHORIZED")
+				return
+			}
+			key, err := repo.GetConfig(r.Context(), "auth_token")
+			if err != nil || key == "" {
 				logger.Error("failed to get auth token from config", "error", err)
 				WriteError(w, http.StatusInternalServerError, "auth configuration error", "INTERNAL_ERROR")
 				return
 			}
-
-			if token != storedToken {
-				logger.Warn("invalid auth token", "provided", logging.SanitizeToken(token))
-				WriteError(w, http.StatusUnauthorized, "invalid token", "UNAUTHORIZED")
+			claims, err := verifyPlaybackToken(key, token, time.Now())
+			if errors.Is(err, errPlaybackTokenExpired) {
+				WriteError(w, http.StatusUnauthorized, err.Error(), "TOKEN_EXPIRED")
 				return
 			}
-
-			next.ServeHTTP(w, r)
+			if err != nil {
+				logger.Warn("invalid playback token", "provided", logging.SanitizeToken(token))
+				WriteError(w, http.StatusUnauthorized, err.Error(), "UNAUTHORIZED")
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), playbackClaimsKey, claims)))
 		})
 	}
 }
 
+// checkBearer validates the Authorization header against the stored auth
+// token, writing the error response when it does not match.
+func checkBearer(w http.ResponseWriter, r *http.Request, repo catalog.Repository, logger *slog.Logger) bool {
+	auth := r.Header.Get("Authorization")
+	if auth == "" {
+		WriteError(w, http.StatusUnauthorized, "missing authorization header", "UNAUTHORIZED")
+		return false
+	}
+
+	if !strings.HasPrefix(auth, "Bearer ") {
+		WriteError(w, http.StatusUnauthorized, "invalid authorization format", "UNAUTHORIZED")
+		return false
+	}
+
+	token := strings.TrimPrefix(auth, "Bearer ")
+
+	storedToken, err := repo.GetConfig(r.Context(), "auth_token")
+	if err != nil || storedToken == "" {
+		logger.Error("failed to get auth token from config", "error", err)
+		WriteError(w, http.StatusInternalServerError, "auth configuration error", "INTERNAL_ERROR")
+		return false
+	}
+
+	if token != storedToken {
+		logger.Warn("invalid auth token", "provided", logging.SanitizeToken(token))
+		WriteError(w, http.StatusUnauthorized, "invalid token", "UNAUTHORIZED")
+		return false
+	}
+	return true
+}
+
 func LoggingMiddleware(logger *slog.Logger) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -212,14 +212,14 @@ func TestCORSAllowlist_RangeHeaders(t *testing.T) {
 	handler.ServeHTTP(rr, req)
 
 	allowHeaders := rr.Header().Get("Access-Control-Allow-Headers")
-	for _, h := range []string{"Range", "Content-Type", "Authorization", "X-Heimdex-Request-Id", "X-Heimdex-Device-Id"} {
+	for _, h := range []string{"Range", "Content-Type", "Authorization", "X-Heimdex-Request-Id", "X-Heimdex-Device-Id", "If-Range", "If-None-Match"} {
 		if !containsHeader(allowHeaders, h) {
 			t.Errorf("Access-Control-Allow-Headers missing %q, got %q", h, allowHeaders)
 		}
 	}
 
 	exposeHeaders := rr.Header().Get("Access-Control-Expose-Headers")
-	for _, h := range []string{"Content-Range", "Accept-Ranges", "Content-Length", "Content-Type"} {
+	for _, h := range []string{"Content-Range", "Accept-Ranges", "Content-Length", "Content-Type", "ETag"} {
 		if !containsHeader(exposeHeaders, h) {
 			t.Errorf("Access-Control-Expose-Headers missing %q, got %q", h, exposeHeaders)
 		}
@@ -446,7 +446,7 @@ func TestPlaybackRoute_Loopback_Rejection_Integration(t *testing.T) {
 			},
 		},
 		PlaybackServer: &fakePlayback{},
-		Repository:     &fakeRepo{},
+		Repository:     &fakeRepoWithAuthToken{},
 		Logger:         logger,
 		StartTime:      time.Now(),
 		DeviceID:       "test-device",
@@ -457,6 +457,7 @@ func TestPlaybackRoute_Loopback_Rejection_Integration(t *testing.T) {
 	defer server.Close()
 
 	req, _ := http.NewRequest(http.MethodGet, server.URL+"/playback/file?file_id=file-1", nil)
+	req.Header.Set("Authorization", "Bearer test-token")
 	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
 		t.Fatalf("request error: %v", err)
@@ -506,7 +507,7 @@ func TestHEAD_PlaybackFile_NoBody(t *testing.T) {
 			},
 		},
 		PlaybackServer: &fakePlayback{},
-		Repository:     &fakeRepo{},
+		Repository:     &fakeRepoWithAuthToken{},
 		Logger:         logger,
 		StartTime:      time.Now(),
 		DeviceID:       "test-device",
@@ -517,6 +518,7 @@ func TestHEAD_PlaybackFile_NoBody(t *testing.T) {
 	defer server.Close()
 
 	req, _ := http.NewRequest(http.MethodHead, server.URL+"/playback/file?file_id=file-1", nil)
+	req.Header.Set("Authorization", "Bearer test-token")
 	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
 		t.Fatalf("request error: %v", err)
@@ -538,7 +540,7 @@ func TestHEAD_PlaybackFile_MissingFileID(t *testing.T) {
 	cfg := ServerConfig{
 		CatalogService: &fakeService{},
 		PlaybackServer: &fakePlayback{},
-		Repository:     &fakeRepo{},
+		Repository:     &fakeRepoWithAuthToken{},
 		Logger:         logger,
 		StartTime:      time.Now(),
 		DeviceID:       "test-device",
@@ -549,6 +551,7 @@ func TestHEAD_PlaybackFile_MissingFileID(t *testing.T) {
 	defer server.Close()
 
 	req, _ := http.NewRequest(http.MethodHead, server.URL+"/playback/file", nil)
+	req.Header.Set("Authorization", "Bearer test-token")
 	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
 		t.Fatalf("request error: %v", err)
@@ -0,0 +1,187 @@
+package api
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	defaultPlaybackTokenTTL = 15 * time.Minute
+	maxPlaybackTokenTTL     = 24 * time.Hour
+)
+
+var (
+	errPlaybackTokenInvalid = errors.New("invalid playback token")
+	errPlaybackTokenExpired = errors.New("playback token expired")
+)
+
+// playbackClaims is what a playback token grants: one file, optionally
+// only the range StartMs..EndMs, until Expires (Unix seconds).
+type playbackClaims struct {
+	FileID  string `json:"f"`
+	StartMs int    `json:"s,omitempty"`
+	EndMs   int    `json:"e,omitempty"`
+	Expires int64  `json:"x"`
+}
+
+func (c *playbackClaims) ranged() bool {
+	return c.EndMs > 0
+}
+
+// matchesFile reports whether the token is for fileID, whole or in part.
+func (c *playbackClaims) matchesFile(fileID string) bool {
+	return c.FileID == fileID
+}
+
+// allowsFile reports whether the token grants the whole file.
+func (c *playbackClaims) allowsFile(fileID string) bool {
+	return c.FileID == fileID && !c.ranged()
+}
+
+// allowsRange reports whether the token grants startMs..endMs of the file.
+func (c *playbackClaims) allowsRange(fileID string, startMs, endMs int) bool {
+	if c.FileID != fileID {
+		return false
+	}
+	return !c.ranged() || (startMs >= c.StartMs && endMs <= c.EndMs)
+}
+
+// signPlaybackToken encodes claims as base64url(JSON) "." base64url(HMAC).
+// The key is the agent's auth token, so rotating it revokes every URL.
+func signPlaybackToken(key string, c playbackClaims) string {
+	payload, _ := json.Marshal(c)
+	body := base64.RawURLEncoding.EncodeToString(payload)
+	return body + "." + base64.RawURLEncoding.EncodeToString(playbackMAC(key, body))
+}
+
+func verifyPlaybackToken(key, token string, now time.Time) (*playbackClaims, error) {
+	body, sig, ok := strings.Cut(token, ".")
+	if !ok {
+		return nil, errPlaybackTokenInvalid
+	}
+	got, err := base64.RawURLEncoding.DecodeString(sig)
+	if err != nil || !hmac.Equal(got, playbackMAC(key, body)) {
+		return nil, errPlaybackTokenInvalid
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(body)
+	if err != nil {
+		return nil, errPlaybackTokenInvalid
+	}
+	var c playbackClaims
+	if err := json.Unmarshal(payload, &c); err != nil || c.FileID == "" {
+		return nil, errPlaybackTokenInvalid
+	}
+	if now.Unix() >= c.Expires {
+		return nil, errPlaybackTokenExpired
+	}
+	return &c, nil
+}
+
+func playbackMAC(key, body string) []byte {
+	mac := hmac.New(sha256.New, []byte("heimdex-playback:"+key))
+	mac.Write([]byte(body))
+	return mac.Sum(nil)
+}
+
+// playbackTokenHandler mints a signed URL that plays one file, or one
+// range of it, without the bearer token. Native players and <video> tags
+// cannot send Authorization headers.
+func playbackTokenHandler(cfg ServerConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req PlaybackTokenRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			WriteError(w, http.StatusBadRequest, "invalid request body", "BAD_REQUEST")
+			return
+		}
+		if req.FileID == "" {
+			WriteError(w, http.StatusBadRequest, "file_id is required", "BAD_REQUEST")
+			return
+		}
+		if (req.StartMs == nil) != (req.EndMs == nil) {
+			WriteError(w, http.StatusBadRequest, "start_ms and end_ms must be given together", "BAD_REQUEST")
+			return
+		}
+		if req.StartMs != nil && (*req.StartMs < 0 || *req.EndMs <= *req.StartMs) {
+			WriteError(w, http.StatusBadRequest, "start_ms must be >= 0 and less than end_ms", "BAD_REQUEST")
+			return
+		}
+		ttl := defaultPlaybackTokenTTL
+		if req.TTLSeconds != 0 {
+			ttl = time.Duration(req.TTLSeconds) * time.Second
+			if ttl <= 0 || ttl > maxPlaybackTokenTTL {
+				WriteError(w, http.StatusBadRequest, fmt.Sprintf("ttl_seconds must be between 1 and %d", int(maxPlaybackTokenTTL.Seconds())), "BAD_REQUEST")
+				return
+			}
+		}
+
+		file, err := cfg.CatalogService.GetFile(r.Context(), req.FileID)
+		if err != nil {
+			WriteError(w, http.StatusInternalServerError, err.Error(), "INTERNAL_ERROR")
+			return
+		}
+		if file == nil {
+			WriteError(w, http.StatusNotFound, "file not found", "NOT_FOUND")
+			return
+		}
+
+		key, err := cfg.Repository.GetConfig(r.Context(), "auth_token")
+		if err != nil || key == "" {
+			WriteError(w, http.StatusInternalServerError, "auth configuration error", "INTERNAL_ERROR")
+			return
+		}
+
+		expires := time.Now().Add(ttl)
+		claims := playbackClaims{FileID: file.ID, Expires: expires.Unix()}
+		q := url.Values{"file_id": {file.ID}}
+		path := "/playback/file"
+		if req.StartMs != nil {
+			claims.StartMs, claims.EndMs = *req.StartMs, *req.EndMs
+			q.Set("start_ms", fmt.Sprint(*req.StartMs))
+			q.Set("end_ms", fmt.Sprint(*req.EndMs))
+			path = "/playback/scene"
+		}
+		token := signPlaybackToken(key, claims)
+		q.Set("token", token)
+
+		WriteJSON(w, http.StatusOK, PlaybackTokenResponse{
+			Token:     token,
+			URL:       path + "?" + q.Encode(),
+			ExpiresAt: expires.UTC().Format(time.RFC3339),
+		})
+	}
+}
+
+// playbackClaimsFrom returns the playback token PlaybackAuthMiddleware
+// verified, or nil when the request was authorized by the bearer token and
+// may play anything.
+func playbackClaimsFrom(r *http.Request) *playbackClaims {
+	claims, _ := r.Context().Value(playbackClaimsKey).(*playbackClaims)
+	return claims
+}
+
+// withPlaybackToken appends the request's playback token to a URL the
+// response points the player at, so follow-up requests from a tokenized
+// URL are authorized too.
+func withPlaybackToken(r *http.Request, target string) string {
+	token := r.URL.Query().Get("token")
+	if token == "" || playbackClaimsFrom(r) == nil {
+		return target
+	}
+	sep := "?"
+	if strings.Contains(target, "?") {
+		sep = "&"
+	}
+	return target + sep + "token=" + url.QueryEscape(token)
+}
+
+func writeTokenScopeError(w http.ResponseWriter) {
+	WriteError(w, http.StatusForbidden, "playback token does not cover this request", "TOKEN_SCOPE")
+}
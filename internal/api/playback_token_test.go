@@ -0,0 +1,241 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/heimdex/heimdex-agent/internal/catalog"
+	"github.com/heimdex/heimdex-agent/internal/pipeline"
+)
+
+type fakeRepoWithAuthToken struct {
+	fakeRepo
+}
+
+func (f *fakeRepoWithAuthToken) GetConfig(ctx context.Context, key string) (string, error) {
+	if key == "auth_token" {
+		return "test-token", nil
+	}
+	return "", nil
+}
+
+func TestPlaybackToken_SignAndVerify(t *testing.T) {
+	now := time.Now()
+	token := signPlaybackToken("key", playbackClaims{FileID: "file-1", StartMs: 1000, EndMs: 5000, Expires: now.Add(time.Minute).Unix()})
+
+	claims, err := verifyPlaybackToken("key", token, now)
+	if err != nil {
+		t.Fatalf("verifyPlaybackToken() error = %v", err)
+	}
+	if !claims.allowsRange("file-1", 1000, 4000) || claims.allowsRange("file-1", 0, 4000) || claims.allowsFile("file-1") {
+		t.Errorf("claims = %+v, want only 1000-5000 of file-1", claims)
+	}
+
+	if _, err := verifyPlaybackToken("other-key", token, now); err != errPlaybackTokenInvalid {
+		t.Errorf("wrong key error = %v, want invalid", err)
+	}
+	body, sig, _ := strings.Cut(token, ".")
+	forged := signPlaybackToken("attacker", playbackClaims{FileID: "file-2", Expires: now.Add(time.Hour).Unix()})
+	forgedBody, _, _ := strings.Cut(forged, ".")
+	if _, err := verifyPlaybackToken("key", forgedBody+"."+sig, now); err != errPlaybackTokenInvalid {
+		t.Errorf("swapped payload error = %v, want invalid", err)
+	}
+	if _, err := verifyPlaybackToken("key", body, now); err != errPlaybackTokenInvalid {
+		t.Errorf("unsigned token error = %v, want invalid", err)
+	}
+	if _, err := verifyPlaybackToken("key", token, now.Add(2*time.Minute)); err != errPlaybackTokenExpired {
+		t.Errorf("expired token error = %v, want expired", err)
+	}
+}
+
+func mintPlaybackToken(t *testing.T, router http.Handler, body string) PlaybackTokenResponse {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/playback/tokens", bytes.NewBufferString(body))
+	req.Header.Set("Authorization", "Bearer test-token")
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("mint status = %d, body = %s", rr.Code, rr.Body.String())
+	}
+	var resp PlaybackTokenResponse
+	if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	return resp
+}
+
+func TestPlaybackTokens_FileURL(t *testing.T) {
+	cfg := hlsTestConfig("/media/a.mp4", nil)
+	cfg.Repository = &fakeRepoWithAuthToken{}
+	router := NewRouter(cfg)
+
+	resp := mintPlaybackToken(t, router, `{"file_id": "file-1", "ttl_seconds": 60}`)
+	if !strings.HasPrefix(resp.URL, "/playback/file?") || resp.Token == "" {
+		t.Fatalf("resp = %+v", resp)
+	}
+	if exp, err := time.Parse(time.RFC3339, resp.ExpiresAt); err != nil || time.Until(exp) > time.Minute {
+		t.Errorf("ExpiresAt = %q", resp.ExpiresAt)
+	}
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, loopbackGetNoAuth(resp.URL))
+	if rr.Code != http.StatusOK {
+		t.Errorf("signed URL status = %d, body = %s", rr.Code, rr.Body.String())
+	}
+
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, loopbackGetNoAuth("/playback/file?file_id=file-2&token="+resp.Token))
+	if rr.Code != http.StatusForbidden {
+		t.Errorf("other file status = %d, want 403", rr.Code)
+	}
+
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, loopbackGetNoAuth("/playback/file?file_id=file-1&token=bogus.token"))
+	if rr.Code != http.StatusUnauthorized {
+		t.Errorf("bad token status = %d, want 401", rr.Code)
+	}
+
+	// The loopback guard still applies to signed URLs.
+	req := httptest.NewRequest(http.MethodGet, resp.URL, nil)
+	req.RemoteAddr = "192.168.1.20:5000"
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	if rr.Code != http.StatusForbidden {
+		t.Errorf("remote status = %d, want 403", rr.Code)
+	}
+}
+
+func TestPlaybackTokens_RangeURL(t *testing.T) {
+	clips := &fakeClips{}
+	cfg := hlsTestConfig("/media/a.mp4", nil)
+	cfg.Repository = &fakeRepoWithAuthToken{}
+	cfg.ClipServer = clips
+	router := NewRouter(cfg)
+
+	resp := mintPlaybackToken(t, router, `{"file_id": "file-1", "start_ms": 2000, "end_ms": 8000}`)
+	if !strings.HasPrefix(resp.URL, "/playback/scene?") {
+		t.Fatalf("URL = %q, want a scene URL", resp.URL)
+	}
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, loopbackGetNoAuth(resp.URL))
+	if rr.Code != http.StatusOK || clips.startMs != 2000 || clips.endMs != 8000 {
+		t.Errorf("signed range status = %d, clip = %+v", rr.Code, clips)
+	}
+
+	for _, target := range []string{
+		"/playback/scene?file_id=file-1&start_ms=0&end_ms=8000&token=" + resp.Token,
+		"/playback/file?file_id=file-1&token=" + resp.Token,
+	} {
+		rr = httptest.NewRecorder()
+		router.ServeHTTP(rr, loopbackGetNoAuth(target))
+		if rr.Code != http.StatusForbidden {
+			t.Errorf("%s status = %d, want 403", target, rr.Code)
+		}
+	}
+}
+
+func TestPlaybackRoutes_RequireCredentials(t *testing.T) {
+	cfg := hlsTestConfig("/media/a.mp4", &fakeHLS{probe: &pipeline.ProbeResult{Duration: 12}})
+	router := NewRouter(cfg)
+
+	for _, target := range []string{
+		"/playback/file?file_id=file-1",
+		"/playback/scene?file_id=file-1&start_ms=0&end_ms=1000",
+		"/playback/info?file_id=file-1",
+		"/playback/hls/file-1/index.m3u8",
+		"/playback/frame?file_id=file-1&t_ms=0",
+		"/playback/sprites/file-1.vtt",
+	} {
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, loopbackGetNoAuth(target))
+		if rr.Code != http.StatusUnauthorized {
+			t.Errorf("%s status = %d, want 401", target, rr.Code)
+		}
+	}
+}
+
+func TestPlaybackTokens_ScopeAndPropagation(t *testing.T) {
+	artifacts := t.TempDir()
+	dir := catalog.SpriteDir(artifacts, "file-1")
+	os.MkdirAll(dir, 0o755)
+	os.WriteFile(filepath.Join(dir, catalog.SpriteTrackName), []byte("WEBVTT\n\n00:00.000 --> 00:05.000\nsprite_000.jpg#xywh=0,0,160,90\n"), 0o644)
+
+	cfg := hlsTestConfig("/media/a.mp4", &fakeHLS{probe: &pipeline.ProbeResult{Duration: 12, Codec: "hevc"}})
+	cfg.ArtifactsDir = artifacts
+	cfg.FrameServer = &fakeFrames{}
+	router := NewRouter(cfg)
+
+	whole := mintPlaybackToken(t, router, `{"file_id": "file-1"}`)
+	ranged := mintPlaybackToken(t, router, `{"file_id": "file-1", "start_ms": 2000, "end_ms": 8000}`)
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, loopbackGetNoAuth("/playback/file?file_id=file-1&auto=1&token="+whole.Token))
+	if loc := rr.Header().Get("Location"); loc != "/playback/hls/file-1/index.m3u8?token="+whole.Token {
+		t.Errorf("redirect status = %d, Location = %q", rr.Code, loc)
+	}
+
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, loopbackGetNoAuth("/playback/sprites/file-1.vtt?token="+whole.Token))
+	if !strings.Contains(rr.Body.String(), "sprite_000.jpg?token="+whole.Token+"#xywh") {
+		t.Errorf("sprite track = %q, want sheet refs carrying the token", rr.Body.String())
+	}
+
+	tests := []struct {
+		target string
+		want   int
+	}{
+		{"/playback/hls/file-1/index.m3u8?token=" + whole.Token, http.StatusOK},
+		{"/playback/hls/file-1/index.m3u8?token=" + ranged.Token, http.StatusForbidden},
+		{"/playback/hls/file-2/index.m3u8?token=" + whole.Token, http.StatusForbidden},
+		{"/playback/frame?file_id=file-1&t_ms=3000&token=" + ranged.Token, http.StatusOK},
+		{"/playback/frame?file_id=file-1&t_ms=9000&token=" + ranged.Token, http.StatusForbidden},
+		{"/playback/sprites/file-1/sprite_000.jpg?token=" + ranged.Token, http.StatusNotFound},
+		{"/playback/sprites/file-2.vtt?token=" + ranged.Token, http.StatusForbidden},
+		{"/playback/thumbnail?file_id=file-2&token=" + whole.Token, http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, loopbackGetNoAuth(tt.target))
+		if rr.Code != tt.want {
+			t.Errorf("%s status = %d, want %d", tt.target, rr.Code, tt.want)
+		}
+	}
+}
+
+func TestPlaybackTokens_Validation(t *testing.T) {
+	cfg := hlsTestConfig("/media/a.mp4", nil)
+	cfg.Repository = &fakeRepoWithAuthToken{}
+	router := NewRouter(cfg)
+
+	tests := map[string]int{
+		`{}`:                                   http.StatusBadRequest,
+		`{"file_id": "file-1", "start_ms": 5}`: http.StatusBadRequest,
+		`{"file_id": "file-1", "ttl_seconds": -1}`: http.StatusBadRequest,
+		`{"file_id": "missing"}`:                   http.StatusNotFound,
+	}
+	for body, want := range tests {
+		req := httptest.NewRequest(http.MethodPost, "/playback/tokens", bytes.NewBufferString(body))
+		req.Header.Set("Authorization", "Bearer test-token")
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		if rr.Code != want {
+			t.Errorf("%s status = %d, want %d", body, rr.Code, want)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/playback/tokens", bytes.NewBufferString(`{"file_id": "file-1"}`))
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	if rr.Code != http.StatusUnauthorized {
+		t.Errorf("unauthenticated status = %d, want 401", rr.Code)
+	}
+}
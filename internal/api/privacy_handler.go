@@ -0,0 +1,92 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/heimdex/heimdex-agent/internal/catalog"
+	"github.com/heimdex/heimdex-agent/internal/cloud"
+)
+
+// setSourcePrivacyHandler replaces a source's privacy policy and optionally
+// switches it to local-only. Indexed files are re-queued so the cloud copy
+// of their scene docs follows the new policy.
+func setSourcePrivacyHandler(cfg ServerConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req SourcePrivacyRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			WriteError(w, http.StatusBadRequest, "invalid request body", "BAD_REQUEST")
+			return
+		}
+		for _, action := range []string{req.Transcript, req.OCR, req.Faces} {
+			if action != "" && !catalog.ValidPrivacyAction(action) {
+				WriteError(w, http.StatusBadRequest, "privacy actions must be send, hash or drop", "BAD_REQUEST")
+				return
+			}
+		}
+
+		id := chi.URLParam(r, "id")
+		source, err := cfg.CatalogService.SetSourcePrivacy(r.Context(), id, catalog.PrivacyPolicy{
+			Transcript: req.Transcript,
+			OCR:        req.OCR,
+			Faces:      req.Faces,
+		})
+		if err != nil {
+			WriteError(w, http.StatusInternalServerError, err.Error(), "INTERNAL_ERROR")
+			return
+		}
+		if source == nil {
+			WriteError(w, http.StatusNotFound, "source not found", "NOT_FOUND")
+			return
+		}
+
+		if req.LocalOnly != nil {
+			updated, err := cfg.CatalogService.SetSourceCloudLibrary(r.Context(), id, source.CloudLibraryID, *req.LocalOnly)
+			if err != nil {
+				WriteError(w, http.StatusInternalServerError, err.Error(), "INTERNAL_ERROR")
+				return
+			}
+			if updated != nil {
+				source.CloudSyncDisabled = updated.CloudSyncDisabled
+			}
+		}
+
+		if !source.CloudSyncDisabled && cfg.Runner != nil {
+			if _, err := cfg.Runner.QueueSourceUploads(r.Context(), source.ID); err != nil && !errors.Is(err, cloud.ErrCloudDisabled) {
+				cfg.Logger.Warn("failed to queue uploads after privacy change", "source_id", source.ID, "error", err)
+			}
+		}
+
+		WriteJSON(w, http.StatusOK, SourceToResponse(source))
+	}
+}
+
+// fileCloudAuditHandler lists what the cloud accepted for a file, newest
+// first.
+func fileCloudAuditHandler(cfg ServerConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		file, err := cfg.CatalogService.GetFile(r.Context(), chi.URLParam(r, "id"))
+		if err != nil {
+			WriteError(w, http.StatusInternalServerError, err.Error(), "INTERNAL_ERROR")
+			return
+		}
+		if file == nil {
+			WriteError(w, http.StatusNotFound, "file not found", "NOT_FOUND")
+			return
+		}
+
+		records, err := cfg.Repository.ListCloudAudit(r.Context(), file.ID)
+		if err != nil {
+			WriteError(w, http.StatusInternalServerError, err.Error(), "INTERNAL_ERROR")
+			return
+		}
+
+		resp := CloudAuditListResponse{FileID: file.ID, Records: make([]CloudAuditResponse, len(records))}
+		for i, a := range records {
+			resp.Records[i] = CloudAuditToResponse(a)
+		}
+		WriteJSON(w, http.StatusOK, resp)
+	}
+}
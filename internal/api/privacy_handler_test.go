@@ -0,0 +1,87 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/heimdex/heimdex-agent/internal/catalog"
+)
+
+type fakeRepoWithAudit struct {
+	fakeRepo
+	records []*catalog.CloudAudit
+}
+
+func (f *fakeRepoWithAudit) ListCloudAudit(ctx context.Context, fileID string) ([]*catalog.CloudAudit, error) {
+	return f.records, nil
+}
+
+func TestSetSourcePrivacyHandler_RejectsUnknownAction(t *testing.T) {
+	cfg := testStatusConfig(nil)
+
+	r := chi.NewRouter()
+	r.Put("/sources/{id}/privacy", setSourcePrivacyHandler(cfg))
+
+	rr := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPut, "/sources/src-1/privacy", strings.NewReader(`{"transcript":"encrypt"}`))
+	r.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("status code = %d, want %d", rr.Code, http.StatusBadRequest)
+	}
+}
+
+func TestSetSourcePrivacyHandler_SourceNotFound(t *testing.T) {
+	cfg := testStatusConfig(nil)
+
+	r := chi.NewRouter()
+	r.Put("/sources/{id}/privacy", setSourcePrivacyHandler(cfg))
+
+	rr := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPut, "/sources/src-1/privacy", strings.NewReader(`{"transcript":"drop"}`))
+	r.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusNotFound {
+		t.Fatalf("status code = %d, want %d", rr.Code, http.StatusNotFound)
+	}
+}
+
+func TestFileCloudAuditHandler(t *testing.T) {
+	cfg := exportTestConfig(&fakeServiceForExport{files: map[string]*catalog.File{"v1": {ID: "v1"}}})
+	cfg.Repository = &fakeRepoWithAudit{records: []*catalog.CloudAudit{{
+		FileID:      "v1",
+		Kind:        catalog.OutboxKindScenes,
+		PayloadHash: "abc",
+		Fields:      []string{"scene_timing", "transcript:hashed"},
+		SentAt:      time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC),
+	}}}
+
+	r := chi.NewRouter()
+	r.Get("/files/{id}/cloud-audit", fileCloudAuditHandler(cfg))
+
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/files/v1/cloud-audit", nil))
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status code = %d, want %d", rr.Code, http.StatusOK)
+	}
+	var resp CloudAuditListResponse
+	if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(resp.Records) != 1 || resp.Records[0].Fields[1] != "transcript:hashed" || resp.Records[0].SentAt != "2026-03-01T12:00:00Z" {
+		t.Fatalf("records = %+v", resp.Records)
+	}
+
+	rr = httptest.NewRecorder()
+	r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/files/missing/cloud-audit", nil))
+	if rr.Code != http.StatusNotFound {
+		t.Fatalf("missing file status = %d, want %d", rr.Code, http.StatusNotFound)
+	}
+}
@@ -0,0 +1,57 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/heimdex/heimdex-agent/internal/catalog"
+)
+
+// serveProxy streams the file's low-resolution proxy. A missing, evicted or
+// stale proxy is queued for generation and reported as not ready, so the
+// player can fall back to the original and retry later.
+func serveProxy(w http.ResponseWriter, r *http.Request, cfg ServerConfig, file *catalog.File) {
+	if cfg.Runner == nil || !cfg.Runner.ProxyEnabled() {
+		WriteError(w, http.StatusNotFound, "proxies are disabled", "PROXY_DISABLED")
+		return
+	}
+
+	proxy, err := cfg.Runner.ReadyProxy(r.Context(), file)
+	if err != nil {
+		WriteError(w, http.StatusInternalServerError, err.Error(), "INTERNAL_ERROR")
+		return
+	}
+	if proxy == nil {
+		if _, err := cfg.Runner.EnsureProxy(r.Context(), file.ID); err != nil {
+			cfg.Logger.Warn("failed to queue proxy", "file_id", file.ID, "error", err)
+		}
+		WriteError(w, http.StatusNotFound, "proxy not ready", "PROXY_NOT_READY")
+		return
+	}
+
+	if err := cfg.PlaybackServer.ServeFile(w, r, proxy.Path); err != nil {
+		cfg.Logger.Error("playback error", "error", err, "file_id", file.ID, "variant", "proxy")
+	}
+}
+
+// fileProxyHandler reports a file's proxy state.
+func fileProxyHandler(cfg ServerConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		file, err := cfg.CatalogService.GetFile(r.Context(), chi.URLParam(r, "id"))
+		if err != nil {
+			WriteError(w, http.StatusInternalServerError, err.Error(), "INTERNAL_ERROR")
+			return
+		}
+		if file == nil {
+			WriteError(w, http.StatusNotFound, "file not found", "NOT_FOUND")
+			return
+		}
+
+		proxy, err := cfg.Repository.GetFileProxy(r.Context(), file.ID)
+		if err != nil {
+			WriteError(w, http.StatusInternalServerError, err.Error(), "INTERNAL_ERROR")
+			return
+		}
+		WriteJSON(w, http.StatusOK, FileProxyToResponse(file.ID, proxy))
+	}
+}
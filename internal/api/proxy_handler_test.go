@@ -0,0 +1,90 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/heimdex/heimdex-agent/internal/catalog"
+)
+
+type fakeRepoWithProxy struct {
+	fakeRepo
+	proxy *catalog.FileProxy
+}
+
+func (f *fakeRepoWithProxy) GetFileProxy(ctx context.Context, fileID string) (*catalog.FileProxy, error) {
+	return f.proxy, nil
+}
+
+func TestPlaybackFile_ProxyVariantDisabled(t *testing.T) {
+	router := NewRouter(hlsTestConfig("/media/a.mov", nil))
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, loopbackGet("/playback/file?file_id=file-1&variant=proxy"))
+	if rr.Code != http.StatusNotFound {
+		t.Fatalf("status = %d, want %d", rr.Code, http.StatusNotFound)
+	}
+	if body := decodeJSONBody(t, rr); body["code"] != "PROXY_DISABLED" {
+		t.Errorf("code = %v, want PROXY_DISABLED", body["code"])
+	}
+}
+
+func TestPlaybackFile_UnknownVariant(t *testing.T) {
+	router := NewRouter(hlsTestConfig("/media/a.mov", nil))
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, loopbackGet("/playback/file?file_id=file-1&variant=thumbnail"))
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want %d", rr.Code, http.StatusBadRequest)
+	}
+}
+
+func TestFileProxyHandler(t *testing.T) {
+	cfg := hlsTestConfig("/media/a.mov", nil)
+	cfg.Repository = &fakeRepoWithProxy{proxy: &catalog.FileProxy{
+		FileID:    "file-1",
+		Status:    catalog.ProxyStatusReady,
+		Height:    540,
+		SizeBytes: 4096,
+		UpdatedAt: time.Now(),
+	}}
+
+	r := chi.NewRouter()
+	r.Get("/files/{id}/proxy", fileProxyHandler(cfg))
+
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/files/file-1/proxy", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rr.Code, rr.Body.String())
+	}
+
+	var resp FileProxyResponse
+	if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if resp.Status != catalog.ProxyStatusReady || resp.Height != 540 {
+		t.Errorf("resp = %+v", resp)
+	}
+	if resp.URL != "/playback/file?file_id=file-1&variant=proxy" {
+		t.Errorf("URL = %q", resp.URL)
+	}
+}
+
+func TestFileProxyHandler_None(t *testing.T) {
+	cfg := hlsTestConfig("/media/a.mov", nil)
+
+	r := chi.NewRouter()
+	r.Get("/files/{id}/proxy", fileProxyHandler(cfg))
+
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/files/file-1/proxy", nil))
+
+	if body := decodeJSONBody(t, rr); body["status"] != "none" {
+		t.Errorf("status = %v, want none", body["status"])
+	}
+}
@@ -9,6 +9,7 @@ import (
 
 	"github.com/go-chi/chi/v5"
 	"github.com/heimdex/heimdex-agent/internal/catalog"
+	"github.com/heimdex/heimdex-agent/internal/playback"
 )
 
 func NewRouter(cfg ServerConfig) *chi.Mux {
@@ -27,12 +28,32 @@ func NewRouter(cfg ServerConfig) *chi.Mux {
 	r.Group(func(r chi.Router) {
 		r.Use(CORSAllowlist())
 		r.Use(LoopbackGuard())
+		r.Use(PlaybackAuthMiddleware(cfg.Repository, cfg.Logger))
 		r.Get("/playback/file", playbackHandler(cfg))
 		r.Head("/playback/file", playbackHandler(cfg))
 		r.Options("/playback/file", noContent)
 		r.Get("/playback/thumbnail", thumbnailHandler(cfg))
 		r.Head("/playback/thumbnail", thumbnailHandler(cfg))
 		r.Options("/playback/thumbnail", noContent)
+		r.Get("/playback/frame", playbackFrameHandler(cfg))
+		r.Head("/playback/frame", playbackFrameHandler(cfg))
+		r.Options("/playback/frame", noContent)
+		r.Get("/playback/sprites/{file_id}.vtt", spriteTrackHandler(cfg))
+		r.Options("/playback/sprites/{file_id}.vtt", noContent)
+		r.Get("/playback/sprites/{file_id}/{sheet}", spriteSheetHandler(cfg))
+		r.Head("/playback/sprites/{file_id}/{sheet}", spriteSheetHandler(cfg))
+		r.Options("/playback/sprites/{file_id}/{sheet}", noContent)
+		r.Get("/playback/scene", playbackSceneHandler(cfg))
+		r.Head("/playback/scene", playbackSceneHandler(cfg))
+		r.Options("/playback/scene", noContent)
+		r.Get("/playback/info", playbackInfoHandler(cfg))
+		r.Options("/playback/info", noContent)
+		r.Get("/playback/hls/{file_id}/index.m3u8", hlsPlaylistHandler(cfg))
+		r.Head("/playback/hls/{file_id}/index.m3u8", hlsPlaylistHandler(cfg))
+		r.Options("/playback/hls/{file_id}/index.m3u8", noContent)
+		r.Get("/playback/hls/{file_id}/{segment}", hlsSegmentHandler(cfg))
+		r.Head("/playback/hls/{file_id}/{segment}", hlsSegmentHandler(cfg))
+		r.Options("/playback/hls/{file_id}/{segment}", noContent)
 	})
 
 	r.Group(func(r chi.Router) {
@@ -49,10 +70,25 @@ func NewRouter(cfg ServerConfig) *chi.Mux {
 		r.Get("/sources", listSourcesHandler(cfg))
 		r.Post("/sources/folders", addFolderHandler(cfg))
 		r.Delete("/sources/{id}", deleteSourceHandler(cfg))
+		r.Put("/sources/{id}/cloud-library", setSourceCloudLibraryHandler(cfg))
+		r.Put("/sources/{id}/privacy", setSourcePrivacyHandler(cfg))
 		r.Get("/sources/{id}/files", listFilesHandler(cfg))
+		r.Get("/files/{id}/scenes", fileScenesHandler(cfg))
+		r.Get("/files/{id}/cloud-audit", fileCloudAuditHandler(cfg))
+		r.Get("/files/{id}/proxy", fileProxyHandler(cfg))
+		r.Get("/files/{id}/waveform", fileWaveformHandler(cfg))
+		r.Get("/files/{id}/subtitles", fileSubtitlesHandler(cfg))
+		r.Post("/playback/tokens", playbackTokenHandler(cfg))
 		r.Post("/scan", scanHandler(cfg))
 		r.Get("/jobs", listJobsHandler(cfg))
 		r.Get("/jobs/{id}", getJobHandler(cfg))
+		r.Post("/cloud/login", cloudLoginHandler(cfg))
+		r.Post("/cloud/logout", cloudLogoutHandler(cfg))
+		r.Post("/cloud/resync", cloudResyncHandler(cfg))
+		r.Put("/cloud/sync", cloudSyncHandler(cfg))
+		r.Get("/cloud/doctor", cloudDoctorHandler(cfg))
+		r.Get("/cloud/libraries", listCloudLibrariesHandler(cfg))
+		r.Post("/cloud/libraries", createCloudLibraryHandler(cfg))
 	})
 
 	return r
@@ -136,6 +172,7 @@ func statusHandler(cfg ServerConfig) http.HandlerFunc {
 		}
 
 		resp.Constraints = &ConstraintsResponse{ScenesRequiresSpeech: true}
+		resp.Cloud = cloudStatus(r.Context(), cfg)
 
 		WriteJSON(w, http.StatusOK, resp)
 	}
@@ -296,30 +333,67 @@ func playbackHandler(cfg ServerConfig) http.HandlerFunc {
 			return
 		}
 
-		file, err := cfg.CatalogService.GetFile(r.Context(), fileID)
-		if err != nil {
-			WriteError(w, http.StatusInternalServerError, err.Error(), "INTERNAL_ERROR")
+		if claims := playbackClaimsFrom(r); claims != nil && !claims.allowsFile(fileID) {
+			writeTokenScopeError(w)
 			return
 		}
+
+		file := lookupPlaybackFile(w, r, cfg, fileID)
 		if file == nil {
-			WriteError(w, http.StatusNotFound, "file not found", "NOT_FOUND")
 			return
 		}
 
-		source, _ := cfg.CatalogService.GetSource(r.Context(), file.SourceID)
-		if source != nil && !source.Present {
-			WriteError(w, http.StatusNotFound,
-				"file not available - drive '"+source.DriveNickname+"' is disconnected",
-				"DRIVE_DISCONNECTED")
+		switch r.URL.Query().Get("variant") {
+		case "", "original":
+		case "proxy":
+			serveProxy(w, r, cfg, file)
 			return
+		default:
+			WriteError(w, http.StatusBadRequest, "variant must be original or proxy", "BAD_REQUEST")
+			return
+		}
+
+		if r.URL.Query().Get("auto") == "1" && cfg.HLSServer != nil {
+			if _, direct, _ := directPlay(cfg, file); !direct {
+				http.Redirect(w, r, withPlaybackToken(r, hlsPlaylistURL(file.ID)), http.StatusTemporaryRedirect)
+				return
+			}
 		}
 
+		if file.Fingerprint != "" {
+			if info, err := os.Stat(file.Path); err == nil {
+				w.Header().Set("ETag", playback.ETag(file.Fingerprint, info.Size(), info.ModTime()))
+			}
+		}
 		if err := cfg.PlaybackServer.ServeFile(w, r, file.Path); err != nil {
 			cfg.Logger.Error("playback error", "error", err, "file_id", fileID)
 		}
 	}
 }
 
+// lookupPlaybackFile loads a file for streaming. It writes the error
+// response and returns nil when the file is unknown or its drive is gone.
+func lookupPlaybackFile(w http.ResponseWriter, r *http.Request, cfg ServerConfig, fileID string) *catalog.File {
+	file, err := cfg.CatalogService.GetFile(r.Context(), fileID)
+	if err != nil {
+		WriteError(w, http.StatusInternalServerError, err.Error(), "INTERNAL_ERROR")
+		return nil
+	}
+	if file == nil {
+		WriteError(w, http.StatusNotFound, "file not found", "NOT_FOUND")
+		return nil
+	}
+
+	source, _ := cfg.CatalogService.GetSource(r.Context(), file.SourceID)
+	if source != nil && !source.Present {
+		WriteError(w, http.StatusNotFound,
+			"file not available - drive '"+source.DriveNickname+"' is disconnected",
+			"DRIVE_DISCONNECTED")
+		return nil
+	}
+	return file
+}
+
 func thumbnailHandler(cfg ServerConfig) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		fileID := r.URL.Query().Get("file_id")
@@ -327,22 +401,13 @@ func thumbnailHandler(cfg ServerConfig) http.HandlerFunc {
 			WriteError(w, http.StatusBadRequest, "file_id is required", "BAD_REQUEST")
 			return
 		}
-
-		file, err := cfg.CatalogService.GetFile(r.Context(), fileID)
-		if err != nil {
-			WriteError(w, http.StatusInternalServerError, err.Error(), "INTERNAL_ERROR")
-			return
-		}
-		if file == nil {
-			WriteError(w, http.StatusNotFound, "file not found", "NOT_FOUND")
+		if claims := playbackClaimsFrom(r); claims != nil && !claims.matchesFile(fileID) {
+			writeTokenScopeError(w)
 			return
 		}
 
-		source, _ := cfg.CatalogService.GetSource(r.Context(), file.SourceID)
-		if source != nil && !source.Present {
-			WriteError(w, http.StatusNotFound,
-				"file not available - drive '"+source.DriveNickname+"' is disconnected",
-				"DRIVE_DISCONNECTED")
+		file := lookupPlaybackFile(w, r, cfg, fileID)
+		if file == nil {
 			return
 		}
 
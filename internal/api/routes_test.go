@@ -200,6 +200,14 @@ func (f *fakeService) GetFiles(ctx context.Context, sourceID string) ([]*catalog
 	return []*catalog.File{}, nil
 }
 
+func (f *fakeService) SetSourceCloudLibrary(ctx context.Context, id, libraryID string, syncDisabled bool) (*catalog.Source, error) {
+	return nil, nil
+}
+
+func (f *fakeService) SetSourcePrivacy(ctx context.Context, id string, policy catalog.PrivacyPolicy) (*catalog.Source, error) {
+	return nil, nil
+}
+
 func (f *fakeService) GetFile(ctx context.Context, id string) (*catalog.File, error) {
 	return nil, nil
 }
@@ -246,6 +254,38 @@ func (f *fakeRepo) UpdateSourceCloudLibraryID(ctx context.Context, id, cloudLibr
 	return nil
 }
 
+func (f *fakeRepo) UpdateSourceCloudSync(ctx context.Context, id, cloudLibraryID string, disabled bool) error {
+	return nil
+}
+
+func (f *fakeRepo) UpdateSourcePrivacy(ctx context.Context, id string, policy catalog.PrivacyPolicy) error {
+	return nil
+}
+
+func (f *fakeRepo) InsertCloudAudit(ctx context.Context, a *catalog.CloudAudit) error {
+	return nil
+}
+
+func (f *fakeRepo) ListCloudAudit(ctx context.Context, fileID string) ([]*catalog.CloudAudit, error) {
+	return nil, nil
+}
+
+func (f *fakeRepo) UpsertFileProxy(ctx context.Context, p *catalog.FileProxy) error {
+	return nil
+}
+
+func (f *fakeRepo) GetFileProxy(ctx context.Context, fileID string) (*catalog.FileProxy, error) {
+	return nil, nil
+}
+
+func (f *fakeRepo) ListFileProxies(ctx context.Context) ([]*catalog.FileProxy, error) {
+	return nil, nil
+}
+
+func (f *fakeRepo) TouchFileProxy(ctx context.Context, fileID string, at time.Time) error {
+	return nil
+}
+
 func (f *fakeRepo) CreateFile(ctx context.Context, file *catalog.File) error {
 	return nil
 }
@@ -274,6 +314,10 @@ func (f *fakeRepo) CountFiles(ctx context.Context) (int, error) {
 	return 0, nil
 }
 
+func (f *fakeRepo) CountFilesBySource(ctx context.Context) (map[string]int, error) {
+	return nil, nil
+}
+
 func (f *fakeRepo) CreateJob(ctx context.Context, job *catalog.Job) error {
 	return nil
 }
@@ -290,6 +334,10 @@ func (f *fakeRepo) ListPendingJobs(ctx context.Context) ([]*catalog.Job, error)
 	return []*catalog.Job{}, nil
 }
 
+func (f *fakeRepo) ListJobFileIDs(ctx context.Context, jobType string, statuses ...string) ([]string, error) {
+	return nil, nil
+}
+
 func (f *fakeRepo) UpdateJobStatus(ctx context.Context, id, status, errorMsg string) error {
 	return nil
 }
@@ -306,6 +354,70 @@ func (f *fakeRepo) SetConfig(ctx context.Context, key, value string) error {
 	return nil
 }
 
+func (f *fakeRepo) UpsertThumbnailUpload(ctx context.Context, upload *catalog.ThumbnailUpload) error {
+	return nil
+}
+
+func (f *fakeRepo) ListThumbnailUploads(ctx context.Context, fileID string) ([]*catalog.ThumbnailUpload, error) {
+	return nil, nil
+}
+
+func (f *fakeRepo) GetThumbnailUploadBySHA256(ctx context.Context, sha256 string) (*catalog.ThumbnailUpload, error) {
+	return nil, nil
+}
+
+func (f *fakeRepo) EnqueueOutbox(ctx context.Context, entry *catalog.OutboxEntry) (bool, error) {
+	return true, nil
+}
+
+func (f *fakeRepo) ListDueOutbox(ctx context.Context, now time.Time, limit int) ([]*catalog.OutboxEntry, error) {
+	return nil, nil
+}
+
+func (f *fakeRepo) UpdateOutboxEntry(ctx context.Context, entry *catalog.OutboxEntry) error {
+	return nil
+}
+
+func (f *fakeRepo) CountOutbox(ctx context.Context, status string) (int, error) {
+	return 0, nil
+}
+
+func (f *fakeRepo) SupersedeOutbox(ctx context.Context, kind, fileID string) error {
+	return nil
+}
+
+func (f *fakeRepo) SupersedeSourceOutbox(ctx context.Context, sourceID string) (int, error) {
+	return 0, nil
+}
+
+func (f *fakeRepo) GetCloudSyncState(ctx context.Context, fileID, kind string) (*catalog.CloudSyncState, error) {
+	return nil, nil
+}
+
+func (f *fakeRepo) UpsertCloudSyncState(ctx context.Context, state *catalog.CloudSyncState) error {
+	return nil
+}
+
+func (f *fakeRepo) ClearCloudSyncState(ctx context.Context, kind string) (int, error) {
+	return 0, nil
+}
+
+func (f *fakeRepo) UpsertSceneAnnotation(ctx context.Context, a *catalog.SceneAnnotation) (bool, error) {
+	return true, nil
+}
+
+func (f *fakeRepo) ListSceneAnnotations(ctx context.Context, fileID string) ([]*catalog.SceneAnnotation, error) {
+	return nil, nil
+}
+
+func (f *fakeRepo) UpsertPersonName(ctx context.Context, p *catalog.PersonName) (bool, error) {
+	return true, nil
+}
+
+func (f *fakeRepo) ListPersonNames(ctx context.Context, fileID string) ([]*catalog.PersonName, error) {
+	return nil, nil
+}
+
 type fakeDoctorPipelineRunner struct {
 	caps *pipelines.Capabilities
 }
@@ -0,0 +1,38 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/heimdex/heimdex-agent/internal/catalog"
+)
+
+// fileScenesHandler returns a file's scenes with edits pulled from the cloud
+// (people names, tag changes, notes) applied over the pipeline output.
+func fileScenesHandler(cfg ServerConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		fileID := chi.URLParam(r, "id")
+
+		file, err := cfg.CatalogService.GetFile(r.Context(), fileID)
+		if err != nil {
+			WriteError(w, http.StatusInternalServerError, err.Error(), "INTERNAL_ERROR")
+			return
+		}
+		if file == nil {
+			WriteError(w, http.StatusNotFound, "file not found", "NOT_FOUND")
+			return
+		}
+
+		scenes, err := catalog.LoadScenes(r.Context(), cfg.Repository, cfg.ArtifactsDir, file.ID)
+		if err != nil {
+			WriteError(w, http.StatusInternalServerError, err.Error(), "INTERNAL_ERROR")
+			return
+		}
+
+		resp := ScenesResponse{FileID: file.ID, Scenes: make([]SceneResponse, len(scenes))}
+		for i, s := range scenes {
+			resp.Scenes[i] = SceneToResponse(s)
+		}
+		WriteJSON(w, http.StatusOK, resp)
+	}
+}
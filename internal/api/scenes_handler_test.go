@@ -0,0 +1,92 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/heimdex/heimdex-agent/internal/catalog"
+	"github.com/heimdex/heimdex-agent/internal/pipelines"
+)
+
+type fakeRepoWithAnnotations struct {
+	fakeRepo
+	annotations []*catalog.SceneAnnotation
+	names       []*catalog.PersonName
+}
+
+func (f *fakeRepoWithAnnotations) ListSceneAnnotations(ctx context.Context, fileID string) ([]*catalog.SceneAnnotation, error) {
+	return f.annotations, nil
+}
+
+func (f *fakeRepoWithAnnotations) ListPersonNames(ctx context.Context, fileID string) ([]*catalog.PersonName, error) {
+	return f.names, nil
+}
+
+func serveFileScenes(cfg ServerConfig, fileID string) *httptest.ResponseRecorder {
+	r := chi.NewRouter()
+	r.Get("/files/{id}/scenes", fileScenesHandler(cfg))
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/files/"+fileID+"/scenes", nil))
+	return rr
+}
+
+func TestFileScenesHandler_MergesCloudEdits(t *testing.T) {
+	artifacts := t.TempDir()
+	dir := filepath.Join(artifacts, "v1", "scenes")
+	os.MkdirAll(dir, 0o755)
+	data, _ := json.Marshal(pipelines.SceneOutputPayload{
+		VideoID: "v1",
+		Scenes: []pipelines.SceneBoundary{{
+			SceneID:          "v1_scene_0",
+			EndMs:            4000,
+			PeopleClusterIDs: []string{"p1"},
+			KeywordTags:      []string{"beach", "sunset"},
+		}},
+	})
+	os.WriteFile(filepath.Join(dir, "result.json"), data, 0o644)
+
+	cfg := exportTestConfig(&fakeServiceForExport{files: map[string]*catalog.File{"v1": {ID: "v1"}}})
+	cfg.ArtifactsDir = artifacts
+	cfg.Repository = &fakeRepoWithAnnotations{
+		annotations: []*catalog.SceneAnnotation{{SceneID: "v1_scene_0", TagsAdded: []string{"surf"}, TagsRemoved: []string{"sunset"}, Note: "opening shot", UpdatedAt: time.Now()}},
+		names:       []*catalog.PersonName{{FileID: "v1", ClusterID: "p1", Name: "Ana", UpdatedAt: time.Now()}},
+	}
+
+	rr := serveFileScenes(cfg, "v1")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status code = %d, want 200: %s", rr.Code, rr.Body.String())
+	}
+
+	var resp ScenesResponse
+	json.NewDecoder(rr.Body).Decode(&resp)
+	if len(resp.Scenes) != 1 {
+		t.Fatalf("scenes = %d, want 1", len(resp.Scenes))
+	}
+	s := resp.Scenes[0]
+	if len(s.Tags) != 2 || s.Tags[0] != "beach" || s.Tags[1] != "surf" {
+		t.Errorf("tags = %v, want [beach surf]", s.Tags)
+	}
+	if s.Note != "opening shot" || !s.Edited {
+		t.Errorf("note = %q edited = %v", s.Note, s.Edited)
+	}
+	if len(s.People) != 1 || s.People[0].Name != "Ana" {
+		t.Errorf("people = %+v, want p1 named Ana", s.People)
+	}
+}
+
+func TestFileScenesHandler_UnknownFile(t *testing.T) {
+	cfg := exportTestConfig(&fakeServiceForExport{})
+	cfg.Repository = &fakeRepo{}
+
+	rr := serveFileScenes(cfg, "missing")
+	if rr.Code != http.StatusNotFound {
+		t.Fatalf("status code = %d, want 404", rr.Code)
+	}
+}
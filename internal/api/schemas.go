@@ -1,9 +1,11 @@
 package api
 
 import (
+	"net/url"
 	"time"
 
 	"github.com/heimdex/heimdex-agent/internal/catalog"
+	"github.com/heimdex/heimdex-agent/internal/cloud"
 )
 
 type HealthResponse struct {
@@ -22,6 +24,7 @@ type StatusResponse struct {
 	ActiveJob    *JobResponse            `json:"active_job,omitempty"`
 	Pipelines    *PipelineStatusResponse `json:"pipelines,omitempty"`
 	Constraints  *ConstraintsResponse    `json:"constraints,omitempty"`
+	Cloud        *CloudStatusResponse    `json:"cloud,omitempty"`
 }
 
 type PipelineStatusResponse struct {
@@ -34,6 +37,93 @@ type PipelineStatusResponse struct {
 	DepsTotal   int    `json:"deps_total"`
 }
 
+type CloudStatusResponse struct {
+	Authenticated   bool   `json:"authenticated"`
+	LoginPending    bool   `json:"login_pending"`
+	UserCode        string `json:"user_code,omitempty"`
+	VerificationURI string `json:"verification_uri,omitempty"`
+	OutboxPending   int    `json:"outbox_pending"`
+	OutboxFailed    int    `json:"outbox_failed"`
+	Offline         bool   `json:"offline"`
+	SyncPaused      bool   `json:"sync_paused"`
+	Metered         bool   `json:"metered"`
+	PauseReason     string `json:"pause_reason,omitempty"`
+}
+
+type CloudLoginResponse struct {
+	Status                  string `json:"status"`
+	UserCode                string `json:"user_code,omitempty"`
+	VerificationURI         string `json:"verification_uri,omitempty"`
+	VerificationURIComplete string `json:"verification_uri_complete,omitempty"`
+	ExpiresIn               int    `json:"expires_in,omitempty"`
+}
+
+type CloudResyncResponse struct {
+	Status string `json:"status"`
+	Queued int    `json:"queued"`
+}
+
+// CloudSyncRequest changes cloud sync settings. Omitted fields are left as
+// they are.
+type CloudSyncRequest struct {
+	Paused  *bool `json:"paused"`
+	Metered *bool `json:"metered"`
+}
+
+type CloudSyncResponse struct {
+	Paused      bool   `json:"paused"`
+	Metered     bool   `json:"metered"`
+	Offline     bool   `json:"offline"`
+	PauseReason string `json:"pause_reason,omitempty"`
+}
+
+type CloudDoctorResponse struct {
+	OK    bool                      `json:"ok"`
+	Steps []CloudDoctorStepResponse `json:"steps"`
+}
+
+type CloudDoctorStepResponse struct {
+	Name       string `json:"name"`
+	Status     string `json:"status"`
+	Detail     string `json:"detail,omitempty"`
+	DurationMs int64  `json:"duration_ms"`
+}
+
+func DiagnosisToResponse(d *cloud.Diagnosis) CloudDoctorResponse {
+	resp := CloudDoctorResponse{OK: d.OK, Steps: make([]CloudDoctorStepResponse, len(d.Steps))}
+	for i, s := range d.Steps {
+		resp.Steps[i] = CloudDoctorStepResponse{
+			Name:       s.Name,
+			Status:     s.Status,
+			Detail:     s.Detail,
+			DurationMs: s.Duration.Milliseconds(),
+		}
+	}
+	return resp
+}
+
+type CloudLibraryResponse struct {
+	ID      string `json:"id"`
+	Name    string `json:"name"`
+	Created bool   `json:"created,omitempty"`
+}
+
+type CloudLibrariesResponse struct {
+	Libraries []CloudLibraryResponse `json:"libraries"`
+}
+
+type CreateCloudLibraryRequest struct {
+	Name string `json:"name"`
+}
+
+// SourceCloudLibraryRequest assigns a source to a cloud library. Sync
+// defaults to true; false keeps the source out of the cloud. An empty
+// LibraryID with sync on clears the mapping.
+type SourceCloudLibraryRequest struct {
+	LibraryID string `json:"library_id"`
+	Sync      *bool  `json:"sync,omitempty"`
+}
+
 type ConstraintsResponse struct {
 	ScenesRequiresSpeech bool `json:"scenes_requires_speech"`
 }
@@ -48,13 +138,72 @@ type AddFolderResponse struct {
 }
 
 type SourceResponse struct {
-	ID            string `json:"id"`
-	Type          string `json:"type"`
-	Path          string `json:"path"`
-	DisplayName   string `json:"display_name"`
-	DriveNickname string `json:"drive_nickname,omitempty"`
-	Present       bool   `json:"present"`
-	CreatedAt     string `json:"created_at"`
+	ID             string                `json:"id"`
+	Type           string                `json:"type"`
+	Path           string                `json:"path"`
+	DisplayName    string                `json:"display_name"`
+	DriveNickname  string                `json:"drive_nickname,omitempty"`
+	CloudLibraryID string                `json:"cloud_library_id,omitempty"`
+	CloudSync      bool                  `json:"cloud_sync"`
+	Privacy        PrivacyPolicyResponse `json:"privacy"`
+	Present        bool                  `json:"present"`
+	CreatedAt      string                `json:"created_at"`
+}
+
+type PrivacyPolicyResponse struct {
+	Transcript string `json:"transcript"`
+	OCR        string `json:"ocr"`
+	Faces      string `json:"faces"`
+}
+
+// SourcePrivacyRequest sets a source's privacy policy. Each field is send,
+// hash or drop; omitted fields mean send. LocalOnly, when present, turns
+// cloud sync off or back on for the source.
+type SourcePrivacyRequest struct {
+	Transcript string `json:"transcript"`
+	OCR        string `json:"ocr"`
+	Faces      string `json:"faces"`
+	LocalOnly  *bool  `json:"local_only"`
+}
+
+type CloudAuditResponse struct {
+	Kind        string   `json:"kind"`
+	PayloadHash string   `json:"payload_hash"`
+	Fields      []string `json:"fields"`
+	SentAt      string   `json:"sent_at"`
+}
+
+type CloudAuditListResponse struct {
+	FileID  string               `json:"file_id"`
+	Records []CloudAuditResponse `json:"records"`
+}
+
+// FileProxyResponse is the playback proxy state of a file. Status is
+// "none" when no proxy was ever requested.
+type FileProxyResponse struct {
+	FileID         string `json:"file_id"`
+	Status         string `json:"status"`
+	Height         int    `json:"height,omitempty"`
+	SizeBytes      int64  `json:"size_bytes,omitempty"`
+	Error          string `json:"error,omitempty"`
+	URL            string `json:"url,omitempty"`
+	LastAccessedAt string `json:"last_accessed_at,omitempty"`
+	UpdatedAt      string `json:"updated_at,omitempty"`
+}
+
+// PlaybackTokenRequest asks for a signed playback URL. StartMs and EndMs,
+// when both set, limit the URL to that range of the file.
+type PlaybackTokenRequest struct {
+	FileID     string `json:"file_id"`
+	StartMs    *int   `json:"start_ms"`
+	EndMs      *int   `json:"end_ms"`
+	TTLSeconds int    `json:"ttl_seconds"`
+}
+
+type PlaybackTokenResponse struct {
+	Token     string `json:"token"`
+	URL       string `json:"url"`
+	ExpiresAt string `json:"expires_at"`
 }
 
 type SourcesResponse struct {
@@ -99,6 +248,47 @@ type FilesResponse struct {
 	Files []FileResponse `json:"files"`
 }
 
+// PlaybackInfoResponse tells the player which URL to load. URL is the
+// direct file stream when the browser can decode it, otherwise the HLS
+// playlist; Reason names what forced the fallback.
+type PlaybackInfoResponse struct {
+	FileID     string  `json:"file_id"`
+	DirectPlay bool    `json:"direct_play"`
+	Reason     string  `json:"reason,omitempty"`
+	URL        string  `json:"url"`
+	HLSURL     string  `json:"hls_url,omitempty"`
+	DurationS  float64 `json:"duration_s,omitempty"`
+	Width      int     `json:"width,omitempty"`
+	Height     int     `json:"height,omitempty"`
+	VideoCodec string  `json:"video_codec,omitempty"`
+	AudioCodec string  `json:"audio_codec,omitempty"`
+	Container  string  `json:"container,omitempty"`
+}
+
+type SceneResponse struct {
+	SceneID     string                `json:"scene_id"`
+	Index       int                   `json:"index"`
+	StartMs     int                   `json:"start_ms"`
+	EndMs       int                   `json:"end_ms"`
+	KeyframeMs  int                   `json:"keyframe_ms"`
+	Transcript  string                `json:"transcript,omitempty"`
+	People      []ScenePersonResponse `json:"people"`
+	Tags        []string              `json:"tags"`
+	ProductTags []string              `json:"product_tags,omitempty"`
+	Note        string                `json:"note,omitempty"`
+	Edited      bool                  `json:"edited"`
+}
+
+type ScenePersonResponse struct {
+	ClusterID string `json:"cluster_id"`
+	Name      string `json:"name,omitempty"`
+}
+
+type ScenesResponse struct {
+	FileID string          `json:"file_id"`
+	Scenes []SceneResponse `json:"scenes"`
+}
+
 type ErrorResponse struct {
 	Error string `json:"error"`
 	Code  string `json:"code,omitempty"`
@@ -106,16 +296,68 @@ type ErrorResponse struct {
 
 func SourceToResponse(s *catalog.Source) SourceResponse {
 	return SourceResponse{
-		ID:            s.ID,
-		Type:          s.Type,
-		Path:          s.Path,
-		DisplayName:   s.DisplayName,
-		DriveNickname: s.DriveNickname,
-		Present:       s.Present,
-		CreatedAt:     s.CreatedAt.Format(time.RFC3339),
+		ID:             s.ID,
+		Type:           s.Type,
+		Path:           s.Path,
+		DisplayName:    s.DisplayName,
+		DriveNickname:  s.DriveNickname,
+		CloudLibraryID: s.CloudLibraryID,
+		CloudSync:      !s.CloudSyncDisabled,
+		Privacy:        PrivacyToResponse(s.Privacy),
+		Present:        s.Present,
+		CreatedAt:      s.CreatedAt.Format(time.RFC3339),
 	}
 }
 
+func PrivacyToResponse(p catalog.PrivacyPolicy) PrivacyPolicyResponse {
+	return PrivacyPolicyResponse{
+		Transcript: privacyOrSend(p.Transcript),
+		OCR:        privacyOrSend(p.OCR),
+		Faces:      privacyOrSend(p.Faces),
+	}
+}
+
+func privacyOrSend(action string) string {
+	if action == "" {
+		return catalog.PrivacySend
+	}
+	return action
+}
+
+func CloudAuditToResponse(a *catalog.CloudAudit) CloudAuditResponse {
+	fields := a.Fields
+	if fields == nil {
+		fields = []string{}
+	}
+	return CloudAuditResponse{
+		Kind:        a.Kind,
+		PayloadHash: a.PayloadHash,
+		Fields:      fields,
+		SentAt:      a.SentAt.Format(time.RFC3339),
+	}
+}
+
+func FileProxyToResponse(fileID string, p *catalog.FileProxy) FileProxyResponse {
+	if p == nil {
+		return FileProxyResponse{FileID: fileID, Status: "none"}
+	}
+	resp := FileProxyResponse{
+		FileID:    p.FileID,
+		Status:    p.Status,
+		Height:    p.Height,
+		SizeBytes: p.SizeBytes,
+		Error:     p.Error,
+		UpdatedAt: p.UpdatedAt.Format(time.RFC3339),
+	}
+	if p.Status == catalog.ProxyStatusReady {
+		resp.URL = "/playback/file?file_id=" + url.QueryEscape(p.FileID) + "&variant=proxy"
+	}
+	if !p.LastAccessedAt.IsZero() {
+		resp.LastAccessedAt = p.LastAccessedAt.Format(time.RFC3339)
+	}
+	return resp
+}
+
 func JobToResponse(j *catalog.Job) JobResponse {
 	return JobResponse{
 		ID:        j.ID,
@@ -141,3 +383,23 @@ func FileToResponse(f *catalog.File) FileResponse {
 		CreatedAt:   f.CreatedAt.Format(time.RFC3339),
 	}
 }
+
+func SceneToResponse(s catalog.Scene) SceneResponse {
+	people := make([]ScenePersonResponse, len(s.People))
+	for i, p := range s.People {
+		people[i] = ScenePersonResponse{ClusterID: p.ClusterID, Name: p.Name}
+	}
+	return SceneResponse{
+		SceneID:     s.SceneID,
+		Index:       s.Index,
+		StartMs:     s.StartMs,
+		EndMs:       s.EndMs,
+		KeyframeMs:  s.KeyframeMs,
+		Transcript:  s.Transcript,
+		People:      people,
+		Tags:        s.Tags,
+		ProductTags: s.ProductTags,
+		Note:        s.Note,
+		Edited:      s.Edited,
+	}
+}
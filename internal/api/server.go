@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/heimdex/heimdex-agent/internal/catalog"
+	"github.com/heimdex/heimdex-agent/internal/cloud"
 	"github.com/heimdex/heimdex-agent/internal/pipelines"
 	"github.com/heimdex/heimdex-agent/internal/playback"
 )
@@ -22,12 +23,17 @@ type ServerConfig struct {
 	ArtifactsDir   string
 	CatalogService catalog.CatalogService
 	PlaybackServer playback.PlaybackService
+	HLSServer      playback.HLSService
+	ClipServer     playback.ClipService
+	FrameServer    playback.FrameService
+	MediaProber    playback.Prober
 	Repository     catalog.Repository
 	Runner         *catalog.Runner
 	Doctor         *pipelines.CachedDoctor
 	Logger         *slog.Logger
 	StartTime      time.Time
 	DeviceID       string
+	CloudClient    cloud.Client
 }
 
 func NewServer(cfg ServerConfig) *Server {
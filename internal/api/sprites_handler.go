@@ -0,0 +1,84 @@
+package api
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/heimdex/heimdex-agent/internal/catalog"
+)
+
+var (
+	spriteSheetName = regexp.MustCompile(`^sprite_\d{3,}\.jpg$`)
+	spriteSheetRef  = regexp.MustCompile(`(sprite_\d{3,}\.jpg)#`)
+)
+
+// spriteTrackHandler serves a file's WebVTT thumbnail track. Cues point at
+// sheets relative to the track: {file_id}/sprite_000.jpg#xywh=...
+func spriteTrackHandler(cfg ServerConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		fileID := chi.URLParam(r, "file_id")
+		if claims := playbackClaimsFrom(r); claims != nil && !claims.matchesFile(fileID) {
+			writeTokenScopeError(w)
+			return
+		}
+		file := lookupPlaybackFile(w, r, cfg, fileID)
+		if file == nil {
+			return
+		}
+
+		path := filepath.Join(catalog.SpriteDir(cfg.ArtifactsDir, file.ID), catalog.SpriteTrackName)
+		track, err := os.ReadFile(path)
+		if os.IsNotExist(err) {
+			WriteError(w, http.StatusNotFound, "sprites not available", "NOT_FOUND")
+			return
+		}
+		if err != nil {
+			WriteError(w, http.StatusInternalServerError, err.Error(), "INTERNAL_ERROR")
+			return
+		}
+
+		// Sheet URLs in the track are fixed when it is generated; a track
+		// fetched with a playback token hands the token on to its sheets.
+		if tokenized := withPlaybackToken(r, ""); tokenized != "" {
+			track = spriteSheetRef.ReplaceAll(track, []byte("${1}"+tokenized+"#"))
+		}
+
+		w.Header().Set("Content-Type", "text/vtt; charset=utf-8")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Write(track)
+	}
+}
+
+// spriteSheetHandler serves one sprite sheet image.
+func spriteSheetHandler(cfg ServerConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		name := chi.URLParam(r, "sheet")
+		if !spriteSheetName.MatchString(name) {
+			WriteError(w, http.StatusNotFound, "sprite sheet not found", "NOT_FOUND")
+			return
+		}
+		fileID := chi.URLParam(r, "file_id")
+		if claims := playbackClaimsFrom(r); claims != nil && !claims.matchesFile(fileID) {
+			writeTokenScopeError(w)
+			return
+		}
+
+		file := lookupPlaybackFile(w, r, cfg, fileID)
+		if file == nil {
+			return
+		}
+
+		path := filepath.Join(catalog.SpriteDir(cfg.ArtifactsDir, file.ID), name)
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			WriteError(w, http.StatusNotFound, "sprite sheet not found", "NOT_FOUND")
+			return
+		}
+
+		w.Header().Set("Content-Type", "image/jpeg")
+		w.Header().Set("Cache-Control", "no-cache")
+		http.ServeFile(w, r, path)
+	}
+}
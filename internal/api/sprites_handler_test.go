@@ -0,0 +1,46 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/heimdex/heimdex-agent/internal/catalog"
+)
+
+func TestSpriteRoutes(t *testing.T) {
+	artifacts := t.TempDir()
+	dir := catalog.SpriteDir(artifacts, "file-1")
+	os.MkdirAll(dir, 0o755)
+	os.WriteFile(filepath.Join(dir, catalog.SpriteTrackName), []byte("WEBVTT\n"), 0o644)
+	os.WriteFile(filepath.Join(dir, "sprite_000.jpg"), []byte("jpg"), 0o644)
+
+	cfg := hlsTestConfig("/media/a.mp4", nil)
+	cfg.ArtifactsDir = artifacts
+	router := NewRouter(cfg)
+
+	tests := []struct {
+		target      string
+		wantStatus  int
+		contentType string
+	}{
+		{"/playback/sprites/file-1.vtt", http.StatusOK, "text/vtt; charset=utf-8"},
+		{"/playback/sprites/file-1/sprite_000.jpg", http.StatusOK, "image/jpeg"},
+		{"/playback/sprites/file-1/sprite_001.jpg", http.StatusNotFound, ""},
+		{"/playback/sprites/file-1/sprites.vtt", http.StatusNotFound, ""},
+		{"/playback/sprites/missing.vtt", http.StatusNotFound, ""},
+	}
+	for _, tt := range tests {
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, loopbackGet(tt.target))
+		if rr.Code != tt.wantStatus {
+			t.Errorf("%s status = %d, want %d", tt.target, rr.Code, tt.wantStatus)
+			continue
+		}
+		if tt.contentType != "" && rr.Header().Get("Content-Type") != tt.contentType {
+			t.Errorf("%s Content-Type = %q", tt.target, rr.Header().Get("Content-Type"))
+		}
+	}
+}
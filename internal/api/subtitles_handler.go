@@ -0,0 +1,54 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/heimdex/heimdex-agent/internal/catalog"
+	"github.com/heimdex/heimdex-agent/internal/subtitles"
+)
+
+// subtitleContentTypes maps each subtitle format to its media type.
+var subtitleContentTypes = map[string]string{
+	subtitles.FormatSRT: "application/x-subrip; charset=utf-8",
+	subtitles.FormatVTT: "text/vtt; charset=utf-8",
+}
+
+// fileSubtitlesHandler serves a file's transcript as SubRip (default) or
+// WebVTT captions.
+func fileSubtitlesHandler(cfg ServerConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		format := r.URL.Query().Get("format")
+		if format == "" {
+			format = subtitles.FormatSRT
+		}
+		contentType, ok := subtitleContentTypes[format]
+		if !ok {
+			WriteError(w, http.StatusBadRequest, "format must be srt or vtt", "BAD_REQUEST")
+			return
+		}
+
+		file, err := cfg.CatalogService.GetFile(r.Context(), chi.URLParam(r, "id"))
+		if err != nil {
+			WriteError(w, http.StatusInternalServerError, err.Error(), "INTERNAL_ERROR")
+			return
+		}
+		if file == nil {
+			WriteError(w, http.StatusNotFound, "file not found", "NOT_FOUND")
+			return
+		}
+
+		cues, err := catalog.LoadCaptions(r.Context(), cfg.Repository, cfg.ArtifactsDir, file.ID)
+		if err != nil {
+			WriteError(w, http.StatusInternalServerError, err.Error(), "INTERNAL_ERROR")
+			return
+		}
+		if cues == nil {
+			WriteError(w, http.StatusNotFound, "file has no transcript", "SUBTITLES_NOT_READY")
+			return
+		}
+
+		w.Header().Set("Content-Type", contentType)
+		subtitles.Write(w, format, cues)
+	}
+}
@@ -0,0 +1,81 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/heimdex/heimdex-agent/internal/catalog"
+)
+
+func serveFileSubtitles(cfg ServerConfig, target string) *httptest.ResponseRecorder {
+	r := chi.NewRouter()
+	r.Get("/files/{id}/subtitles", fileSubtitlesHandler(cfg))
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, target, nil))
+	return rr
+}
+
+// writeSpeechResult stores a speech transcript for fileID with segments
+// timed in seconds, as the speech pipeline writes them.
+func writeSpeechResult(t *testing.T, artifacts, fileID string, segments ...map[string]any) {
+	t.Helper()
+	dir := filepath.Join(artifacts, fileID, "speech")
+	os.MkdirAll(dir, 0o755)
+	data, _ := json.Marshal(map[string]any{"segments": segments})
+	os.WriteFile(filepath.Join(dir, "result.json"), data, 0o644)
+}
+
+func TestFileSubtitlesHandler(t *testing.T) {
+	artifacts := t.TempDir()
+	writeSpeechResult(t, artifacts, "v1",
+		map[string]any{"start": 1.0, "end": 2.5, "text": "Hello there"},
+		map[string]any{"start": 3.0, "end": 4.0, "text": "<b>bold</b>"},
+	)
+	cfg := exportTestConfig(&fakeServiceForExport{files: map[string]*catalog.File{"v1": {ID: "v1"}, "v2": {ID: "v2"}}})
+	cfg.ArtifactsDir = artifacts
+	cfg.Repository = &fakeRepo{}
+
+	rr := serveFileSubtitles(cfg, "/files/v1/subtitles")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rr.Code, rr.Body.String())
+	}
+	if ct := rr.Header().Get("Content-Type"); ct != "application/x-subrip; charset=utf-8" {
+		t.Errorf("srt Content-Type = %q", ct)
+	}
+	wantSRT := "1\n00:00:01,000 --> 00:00:02,500\nHello there\n\n2\n00:00:03,000 --> 00:00:04,000\n<b>bold</b>\n\n"
+	if rr.Body.String() != wantSRT {
+		t.Errorf("srt body = %q", rr.Body.String())
+	}
+
+	rr = serveFileSubtitles(cfg, "/files/v1/subtitles?format=vtt")
+	if ct := rr.Header().Get("Content-Type"); ct != "text/vtt; charset=utf-8" {
+		t.Errorf("vtt Content-Type = %q", ct)
+	}
+	wantVTT := "WEBVTT\n\n00:00:01.000 --> 00:00:02.500\nHello there\n\n00:00:03.000 --> 00:00:04.000\n&lt;b&gt;bold&lt;/b&gt;\n\n"
+	if rr.Body.String() != wantVTT {
+		t.Errorf("vtt body = %q", rr.Body.String())
+	}
+
+	tests := []struct {
+		target string
+		status int
+		code   string
+	}{
+		{"/files/v1/subtitles?format=ass", http.StatusBadRequest, "BAD_REQUEST"},
+		{"/files/v2/subtitles", http.StatusNotFound, "SUBTITLES_NOT_READY"},
+		{"/files/nope/subtitles", http.StatusNotFound, "NOT_FOUND"},
+	}
+	for _, tt := range tests {
+		rr := serveFileSubtitles(cfg, tt.target)
+		var resp ErrorResponse
+		json.NewDecoder(rr.Body).Decode(&resp)
+		if rr.Code != tt.status || resp.Code != tt.code {
+			t.Errorf("%s = %d %s, want %d %s", tt.target, rr.Code, resp.Code, tt.status, tt.code)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/heimdex/heimdex-agent/internal/catalog"
+	"github.com/heimdex/heimdex-agent/internal/waveform"
+)
+
+// fileWaveformHandler serves a file's audio peaks in audiowaveform's JSON
+// (default) or binary .dat format, optionally at a coarser resolution.
+func fileWaveformHandler(cfg ServerConfig) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		format := q.Get("format")
+		if format != "" && format != "json" && format != "dat" {
+			WriteError(w, http.StatusBadRequest, "format must be json or dat", "BAD_REQUEST")
+			return
+		}
+		samplesPerPixel := 0
+		if v := q.Get("samples_per_pixel"); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil || n <= 0 {
+				WriteError(w, http.StatusBadRequest, "samples_per_pixel must be a positive integer", "BAD_REQUEST")
+				return
+			}
+			samplesPerPixel = n
+		}
+
+		file, err := cfg.CatalogService.GetFile(r.Context(), chi.URLParam(r, "id"))
+		if err != nil {
+			WriteError(w, http.StatusInternalServerError, err.Error(), "INTERNAL_ERROR")
+			return
+		}
+		if file == nil {
+			WriteError(w, http.StatusNotFound, "file not found", "NOT_FOUND")
+			return
+		}
+
+		f, err := os.Open(catalog.WaveformPath(cfg.ArtifactsDir, file.ID))
+		if os.IsNotExist(err) {
+			WriteError(w, http.StatusNotFound, "waveform not generated yet", "WAVEFORM_NOT_READY")
+			return
+		}
+		if err != nil {
+			WriteError(w, http.StatusInternalServerError, err.Error(), "INTERNAL_ERROR")
+			return
+		}
+		defer f.Close()
+
+		peaks, err := waveform.ReadBinary(f)
+		if err != nil {
+			WriteError(w, http.StatusInternalServerError, err.Error(), "INTERNAL_ERROR")
+			return
+		}
+		if samplesPerPixel != 0 {
+			peaks, err = peaks.Downsample(samplesPerPixel)
+			if errors.Is(err, waveform.ErrBadScale) {
+				WriteError(w, http.StatusBadRequest, "samples_per_pixel must be a multiple of "+strconv.Itoa(catalog.WaveformSamplesPerPixel), "BAD_REQUEST")
+				return
+			}
+		}
+
+		if format == "dat" {
+			w.Header().Set("Content-Type", "application/octet-stream")
+			peaks.WriteBinary(w)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		peaks.WriteJSON(w)
+	}
+}
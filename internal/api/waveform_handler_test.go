@@ -0,0 +1,77 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/heimdex/heimdex-agent/internal/catalog"
+	"github.com/heimdex/heimdex-agent/internal/waveform"
+)
+
+func serveFileWaveform(cfg ServerConfig, target string) *httptest.ResponseRecorder {
+	r := chi.NewRouter()
+	r.Get("/files/{id}/waveform", fileWaveformHandler(cfg))
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, target, nil))
+	return rr
+}
+
+func TestFileWaveformHandler(t *testing.T) {
+	artifacts := t.TempDir()
+	path := catalog.WaveformPath(artifacts, "v1")
+	os.MkdirAll(filepath.Dir(path), 0o755)
+	f, _ := os.Create(path)
+	spp := catalog.WaveformSamplesPerPixel
+	(&waveform.Peaks{SampleRate: 16000, SamplesPerPixel: spp, Data: []int16{-1, 2, -7, 3, -4, 9}}).WriteBinary(f)
+	f.Close()
+
+	cfg := exportTestConfig(&fakeServiceForExport{files: map[string]*catalog.File{"v1": {ID: "v1"}, "v2": {ID: "v2"}}})
+	cfg.ArtifactsDir = artifacts
+
+	rr := serveFileWaveform(cfg, "/files/v1/waveform")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rr.Code, rr.Body.String())
+	}
+	var resp struct {
+		Version         int     `json:"version"`
+		SamplesPerPixel int     `json:"samples_per_pixel"`
+		Length          int     `json:"length"`
+		Data            []int16 `json:"data"`
+	}
+	json.NewDecoder(rr.Body).Decode(&resp)
+	if resp.Version != 2 || resp.SamplesPerPixel != spp || resp.Length != 3 {
+		t.Errorf("resp = %+v", resp)
+	}
+
+	rr = serveFileWaveform(cfg, "/files/v1/waveform?samples_per_pixel="+strconv.Itoa(2*spp))
+	json.NewDecoder(rr.Body).Decode(&resp)
+	if resp.Length != 2 || resp.Data[0] != -7 || resp.Data[1] != 3 {
+		t.Errorf("downsampled = %+v", resp)
+	}
+
+	rr = serveFileWaveform(cfg, "/files/v1/waveform?format=dat")
+	if rr.Header().Get("Content-Type") != "application/octet-stream" {
+		t.Errorf("dat Content-Type = %q", rr.Header().Get("Content-Type"))
+	}
+	if p, err := waveform.ReadBinary(rr.Body); err != nil || p.Length() != 3 {
+		t.Errorf("dat = %+v, %v", p, err)
+	}
+
+	tests := map[string]int{
+		"/files/v2/waveform":                                          http.StatusNotFound,
+		"/files/missing/waveform":                                     http.StatusNotFound,
+		"/files/v1/waveform?format=png":                               http.StatusBadRequest,
+		"/files/v1/waveform?samples_per_pixel=" + strconv.Itoa(spp+1): http.StatusBadRequest,
+	}
+	for target, want := range tests {
+		if rr := serveFileWaveform(cfg, target); rr.Code != want {
+			t.Errorf("%s status = %d, want %d", target, rr.Code, want)
+		}
+	}
+}
@@ -0,0 +1,287 @@
+package catalog
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/heimdex/heimdex-agent/internal/cloud"
+	"github.com/heimdex/heimdex-agent/internal/pipelines"
+)
+
+const (
+	defaultAnnotationSyncInterval = 5 * time.Minute
+	annotationPageSize            = 200
+	annotationMaxPages            = 100
+
+	// annotationCursorKey stores the sync-down cursor in the config table.
+	annotationCursorKey = "cloud_annotations_cursor"
+)
+
+// AnnotationSync pulls edits made in the web app (people names, tag changes,
+// scene notes) into the local catalog.
+type AnnotationSync struct {
+	repo     Repository
+	client   cloud.Client
+	interval time.Duration
+	logger   *slog.Logger
+}
+
+func NewAnnotationSync(repo Repository, client cloud.Client, logger *slog.Logger) *AnnotationSync {
+	return &AnnotationSync{
+		repo:     repo,
+		client:   client,
+		interval: defaultAnnotationSyncInterval,
+		logger:   logger,
+	}
+}
+
+func (s *AnnotationSync) SetInterval(d time.Duration) {
+	if d > 0 {
+		s.interval = d
+	}
+}
+
+// Run syncs once and then every interval until ctx is cancelled.
+func (s *AnnotationSync) Run(ctx context.Context) {
+	s.tick(ctx)
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.tick(ctx)
+		}
+	}
+}
+
+func (s *AnnotationSync) tick(ctx context.Context) {
+	applied, err := s.Sync(ctx)
+	if err != nil {
+		if cloud.IsNetworkError(err) {
+			s.logger.Debug("annotation sync skipped: cloud unreachable", "error", err)
+			return
+		}
+		s.logger.Warn("annotation sync failed", "error", err)
+		return
+	}
+	if applied > 0 {
+		s.logger.Info("annotation sync applied cloud edits", "count", applied)
+	}
+}
+
+// Sync pulls every page changed since the stored cursor and returns how many
+// edits were applied. The cursor is saved after each page, so an interrupted
+// sync resumes where it stopped.
+func (s *AnnotationSync) Sync(ctx context.Context) (int, error) {
+	cursor, err := s.repo.GetConfig(ctx, annotationCursorKey)
+	if err != nil {
+		return 0, fmt.Errorf("read annotation cursor: %w", err)
+	}
+
+	applied := 0
+	for i := 0; i < annotationMaxPages; i++ {
+		pullCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+		page, err := s.client.Annotations().Pull(pullCtx, cursor, annotationPageSize)
+		cancel()
+		if err != nil {
+			return applied, err
+		}
+
+		n, err := s.apply(ctx, page)
+		applied += n
+		if err != nil {
+			return applied, err
+		}
+
+		if page.Cursor != "" && page.Cursor != cursor {
+			cursor = page.Cursor
+			if err := s.repo.SetConfig(ctx, annotationCursorKey, cursor); err != nil {
+				return applied, fmt.Errorf("save annotation cursor: %w", err)
+			}
+		} else if page.HasMore {
+			// A server that claims more pages without moving the cursor
+			// would loop forever.
+			return applied, fmt.Errorf("annotation cursor did not advance")
+		}
+		if !page.HasMore {
+			break
+		}
+	}
+	return applied, nil
+}
+
+func (s *AnnotationSync) apply(ctx context.Context, page *cloud.AnnotationPage) (int, error) {
+	applied := 0
+	for _, video := range page.Videos {
+		file, err := s.repo.GetFile(ctx, video.VideoID)
+		if err != nil {
+			return applied, err
+		}
+		if file == nil {
+			s.logger.Debug("annotation for unknown video ignored", "video_id", video.VideoID)
+			continue
+		}
+
+		for _, p := range video.People {
+			ok, err := s.repo.UpsertPersonName(ctx, &PersonName{
+				FileID:    file.ID,
+				ClusterID: p.ClusterID,
+				Name:      strings.TrimSpace(p.Name),
+				UpdatedAt: p.UpdatedAt,
+			})
+			if err != nil {
+				return applied, fmt.Errorf("store person name: %w", err)
+			}
+			if ok {
+				applied++
+			}
+		}
+
+		for _, a := range video.Scenes {
+			ok, err := s.repo.UpsertSceneAnnotation(ctx, &SceneAnnotation{
+				SceneID:     a.SceneID,
+				FileID:      file.ID,
+				TagsAdded:   a.TagsAdded,
+				TagsRemoved: a.TagsRemoved,
+				Note:        a.Note,
+				UpdatedAt:   a.UpdatedAt,
+			})
+			if err != nil {
+				return applied, fmt.Errorf("store scene annotation: %w", err)
+			}
+			if ok {
+				applied++
+			}
+		}
+	}
+	return applied, nil
+}
+
+// LoadScenes reads a file's scene output and applies cloud edits. It returns
+// nil when the file has not been through scene detection.
+func LoadScenes(ctx context.Context, repo Repository, artifactsDir, fileID string) ([]Scene, error) {
+	data, err := os.ReadFile(filepath.Join(artifactsDir, fileID, "scenes", "result.json"))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read scene output: %w", err)
+	}
+
+	var output pipelines.SceneOutputPayload
+	if err := json.Unmarshal(data, &output); err != nil {
+		return nil, fmt.Errorf("invalid scene JSON: %w", err)
+	}
+
+	annotations, err := repo.ListSceneAnnotations(ctx, fileID)
+	if err != nil {
+		return nil, err
+	}
+	names, err := repo.ListPersonNames(ctx, fileID)
+	if err != nil {
+		return nil, err
+	}
+	secret, err := repo.GetConfig(ctx, privacySecretKey)
+	if err != nil {
+		return nil, err
+	}
+	if secret != "" {
+		unhashClusterIDs(names, output.Scenes, []byte(secret))
+	}
+	return MergeScenes(output.Scenes, annotations, names), nil
+}
+
+// SceneFileID returns the ID of the file a scene belongs to, or "" when
+// sceneID is not of the pipeline's "<file_id>_scene_<n>" form.
+func SceneFileID(sceneID string) string {
+	i := strings.LastIndex(sceneID, "_scene_")
+	if i <= 0 {
+		return ""
+	}
+	return sceneID[:i]
+}
+
+// MergeScenes applies cloud edits to pipeline scenes:
+//   - tags are the pipeline keyword tags plus added tags, minus removed tags;
+//     a tag both added and removed stays removed, and matching ignores case
+//   - a named people cluster shows its name, others keep only the cluster ID
+//   - annotations for scenes no longer in the pipeline output are ignored,
+//     which happens when re-indexing changes scene boundaries
+func MergeScenes(raw []pipelines.SceneBoundary, annotations []*SceneAnnotation, names []*PersonName) []Scene {
+	bySceneID := make(map[string]*SceneAnnotation, len(annotations))
+	for _, a := range annotations {
+		bySceneID[a.SceneID] = a
+	}
+	nameByCluster := make(map[string]string, len(names))
+	for _, n := range names {
+		if n.Name != "" {
+			nameByCluster[n.ClusterID] = n.Name
+		}
+	}
+
+	scenes := make([]Scene, 0, len(raw))
+	for _, b := range raw {
+		scene := Scene{
+			SceneID:     b.SceneID,
+			Index:       b.Index,
+			StartMs:     b.StartMs,
+			EndMs:       b.EndMs,
+			KeyframeMs:  b.KeyframeTimestampMs,
+			Transcript:  b.TranscriptRaw,
+			People:      make([]ScenePerson, 0, len(b.PeopleClusterIDs)),
+			Tags:        b.KeywordTags,
+			ProductTags: b.ProductTags,
+		}
+
+		for _, id := range b.PeopleClusterIDs {
+			name, ok := nameByCluster[id]
+			if ok {
+				scene.Edited = true
+			}
+			scene.People = append(scene.People, ScenePerson{ClusterID: id, Name: name})
+		}
+
+		if a, ok := bySceneID[b.SceneID]; ok {
+			scene.Tags = mergeTags(b.KeywordTags, a.TagsAdded, a.TagsRemoved)
+			scene.Note = a.Note
+			scene.Edited = true
+		}
+		if scene.Tags == nil {
+			scene.Tags = []string{}
+		}
+		scenes = append(scenes, scene)
+	}
+	return scenes
+}
+
+func mergeTags(base, added, removed []string) []string {
+	drop := make(map[string]bool, len(removed))
+	for _, t := range removed {
+		drop[strings.ToLower(strings.TrimSpace(t))] = true
+	}
+
+	seen := make(map[string]bool)
+	tags := make([]string, 0, len(base)+len(added))
+	for _, list := range [][]string{base, added} {
+		for _, t := range list {
+			t = strings.TrimSpace(t)
+			key := strings.ToLower(t)
+			if t == "" || drop[key] || seen[key] {
+				continue
+			}
+			seen[key] = true
+			tags = append(tags, t)
+		}
+	}
+	return tags
+}
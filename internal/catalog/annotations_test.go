@@ -0,0 +1,121 @@
+package catalog
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/heimdex/heimdex-agent/internal/cloud"
+	"github.com/heimdex/heimdex-agent/internal/pipelines"
+)
+
+type fakeAnnotationService struct {
+	pages   []*cloud.AnnotationPage
+	cursors []string
+}
+
+func (f *fakeAnnotationService) Pull(ctx context.Context, cursor string, limit int) (*cloud.AnnotationPage, error) {
+	f.cursors = append(f.cursors, cursor)
+	if len(f.pages) == 0 {
+		return &cloud.AnnotationPage{Cursor: cursor}, nil
+	}
+	page := f.pages[0]
+	f.pages = f.pages[1:]
+	return page, nil
+}
+
+func TestAnnotationSync_AppliesPagesAndSavesCursor(t *testing.T) {
+	runner, repo := setupRunnerTest(t, &fakePipeRunner{}, &pipelines.Capabilities{})
+	_, file := createTestJobAndFile(t, repo)
+
+	edited := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	svc := &fakeAnnotationService{pages: []*cloud.AnnotationPage{
+		{
+			Videos: []cloud.VideoAnnotations{{
+				VideoID: file.ID,
+				People:  []cloud.PersonAnnotation{{ClusterID: "p1", Name: " Ana ", UpdatedAt: edited}},
+			}},
+			Cursor:  "c1",
+			HasMore: true,
+		},
+		{
+			Videos: []cloud.VideoAnnotations{
+				{VideoID: file.ID, Scenes: []cloud.SceneAnnotation{{SceneID: "video-1_scene_0", TagsAdded: []string{"surf"}, Note: "intro", UpdatedAt: edited}}},
+				{VideoID: "unknown-video", Scenes: []cloud.SceneAnnotation{{SceneID: "x", UpdatedAt: edited}}},
+			},
+			Cursor: "c2",
+		},
+	}}
+	sync := NewAnnotationSync(repo, &fakeCloudClient{annotations: svc}, runner.logger)
+
+	applied, err := sync.Sync(context.Background())
+	if err != nil {
+		t.Fatalf("Sync: %v", err)
+	}
+	if applied != 2 {
+		t.Errorf("applied = %d, want 2", applied)
+	}
+	if len(svc.cursors) != 2 || svc.cursors[0] != "" || svc.cursors[1] != "c1" {
+		t.Errorf("pulled with cursors %v, want [\"\" c1]", svc.cursors)
+	}
+	if cursor, _ := repo.GetConfig(context.Background(), annotationCursorKey); cursor != "c2" {
+		t.Errorf("stored cursor = %q, want c2", cursor)
+	}
+
+	names, _ := repo.ListPersonNames(context.Background(), file.ID)
+	if len(names) != 1 || names[0].Name != "Ana" {
+		t.Errorf("person names = %+v, want p1 named Ana", names)
+	}
+	annotations, _ := repo.ListSceneAnnotations(context.Background(), file.ID)
+	if len(annotations) != 1 || annotations[0].Note != "intro" || len(annotations[0].TagsAdded) != 1 {
+		t.Errorf("scene annotations = %+v", annotations)
+	}
+}
+
+func TestAnnotationSync_OlderEditDoesNotOverwrite(t *testing.T) {
+	_, repo := setupRunnerTest(t, &fakePipeRunner{}, &pipelines.Capabilities{})
+	_, file := createTestJobAndFile(t, repo)
+	ctx := context.Background()
+
+	newer := time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)
+	repo.UpsertSceneAnnotation(ctx, &SceneAnnotation{SceneID: "s0", FileID: file.ID, Note: "new", UpdatedAt: newer})
+
+	ok, err := repo.UpsertSceneAnnotation(ctx, &SceneAnnotation{SceneID: "s0", FileID: file.ID, Note: "stale", UpdatedAt: newer.Add(-time.Hour)})
+	if err != nil {
+		t.Fatalf("upsert: %v", err)
+	}
+	if ok {
+		t.Error("older edit reported as applied")
+	}
+	annotations, _ := repo.ListSceneAnnotations(ctx, file.ID)
+	if annotations[0].Note != "new" {
+		t.Errorf("note = %q, want new", annotations[0].Note)
+	}
+}
+
+func TestMergeScenes_ConflictRules(t *testing.T) {
+	raw := []pipelines.SceneBoundary{
+		{SceneID: "s0", PeopleClusterIDs: []string{"p1", "p2"}, KeywordTags: []string{"Beach", "sunset"}},
+		{SceneID: "s1", KeywordTags: []string{"city"}},
+	}
+	annotations := []*SceneAnnotation{
+		{SceneID: "s0", TagsAdded: []string{"beach", "surf", "night"}, TagsRemoved: []string{"SUNSET", "night"}},
+		{SceneID: "gone", Note: "scene removed by re-index"},
+	}
+	names := []*PersonName{{ClusterID: "p1", Name: "Ana"}, {ClusterID: "p2", Name: ""}}
+
+	scenes := MergeScenes(raw, annotations, names)
+
+	if got := scenes[0].Tags; len(got) != 2 || got[0] != "Beach" || got[1] != "surf" {
+		t.Errorf("s0 tags = %v, want [Beach surf]", got)
+	}
+	if scenes[0].People[0].Name != "Ana" || scenes[0].People[1].Name != "" {
+		t.Errorf("s0 people = %+v", scenes[0].People)
+	}
+	if scenes[1].Edited || len(scenes[1].Tags) != 1 {
+		t.Errorf("s1 = %+v, want untouched", scenes[1])
+	}
+	if len(scenes) != 2 {
+		t.Errorf("scenes = %d, want 2 (orphaned annotation ignored)", len(scenes))
+	}
+}
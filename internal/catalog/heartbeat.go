@@ -0,0 +1,173 @@
+package catalog
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/heimdex/heimdex-agent/internal/cloud"
+	"github.com/heimdex/heimdex-agent/internal/pipelines"
+)
+
+const defaultHeartbeatInterval = 60 * time.Second
+
+// Heartbeat registers this device with the cloud and then periodically
+// reports its sources, drive presence and job queue depth.
+type Heartbeat struct {
+	repo     Repository
+	runner   *Runner
+	doctor   *pipelines.CachedDoctor
+	client   cloud.Client
+	device   cloud.DeviceInfo
+	interval time.Duration
+	logger   *slog.Logger
+
+	registered bool
+	// registeredCaps are the capabilities last sent on registration; the
+	// device registers again when they change.
+	registeredCaps cloud.DeviceCapabilities
+}
+
+// NewHeartbeat creates a heartbeat loop for the given device. runner and
+// doctor may be nil; queue state and capabilities are then reported empty.
+func NewHeartbeat(repo Repository, runner *Runner, doctor *pipelines.CachedDoctor, client cloud.Client, device cloud.DeviceInfo, logger *slog.Logger) *Heartbeat {
+	return &Heartbeat{
+		repo:     repo,
+		runner:   runner,
+		doctor:   doctor,
+		client:   client,
+		device:   device,
+		interval: defaultHeartbeatInterval,
+		logger:   logger,
+	}
+}
+
+func (h *Heartbeat) SetInterval(d time.Duration) {
+	if d > 0 {
+		h.interval = d
+	}
+}
+
+// Run registers the device and sends a heartbeat every interval until ctx is
+// cancelled. Failures are logged and retried on the next tick.
+func (h *Heartbeat) Run(ctx context.Context) {
+	h.tick(ctx)
+
+	ticker := time.NewTicker(h.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			h.tick(ctx)
+		}
+	}
+}
+
+func (h *Heartbeat) tick(ctx context.Context) {
+	if !h.registered || h.capabilities() != h.registeredCaps {
+		if err := h.register(ctx); err != nil {
+			h.logger.Warn("device registration failed, will retry", "error", err)
+			return
+		}
+	}
+
+	payload, err := h.buildPayload(ctx)
+	if err != nil {
+		h.logger.Warn("heartbeat skipped: cannot collect state", "error", err)
+		return
+	}
+
+	sendCtx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	defer cancel()
+
+	if err := h.client.Heartbeat(sendCtx, payload); err != nil {
+		// The SaaS forgets devices it has not seen for a while; register
+		// again on the next tick instead of heartbeating into the void.
+		var uploadErr *cloud.UploadError
+		if errors.As(err, &uploadErr) && uploadErr.StatusCode == http.StatusNotFound {
+			h.registered = false
+		}
+		h.logger.Warn("heartbeat failed", "error", err)
+	}
+}
+
+func (h *Heartbeat) register(ctx context.Context) error {
+	info := h.device
+	info.Capabilities = h.capabilities()
+
+	regCtx, cancel := context.WithTimeout(ctx, 15*time.Second)
+	defer cancel()
+
+	if err := h.client.RegisterDevice(regCtx, info); err != nil {
+		return err
+	}
+	h.registered = true
+	h.registeredCaps = info.Capabilities
+	return nil
+}
+
+// capabilities reports what the pipelines can currently do, from the
+// doctor's last probe.
+func (h *Heartbeat) capabilities() cloud.DeviceCapabilities {
+	if h.doctor == nil {
+		return cloud.DeviceCapabilities{}
+	}
+	caps := h.doctor.Peek()
+	if caps == nil {
+		return cloud.DeviceCapabilities{}
+	}
+	return cloud.DeviceCapabilities{
+		Faces:            caps.HasFaces,
+		Speech:           caps.HasSpeech,
+		Scenes:           caps.HasScenes,
+		OCR:              caps.HasOCR,
+		PipelinesVersion: caps.PackageVersion,
+	}
+}
+
+func (h *Heartbeat) buildPayload(ctx context.Context) (cloud.HeartbeatPayload, error) {
+	payload := cloud.HeartbeatPayload{
+		DeviceID:     h.device.DeviceID,
+		AgentVersion: h.device.AgentVersion,
+		Sources:      []cloud.HeartbeatSource{},
+		SentAt:       time.Now().UTC(),
+	}
+
+	sources, err := h.repo.ListSources(ctx)
+	if err != nil {
+		return payload, err
+	}
+	fileCounts, err := h.repo.CountFilesBySource(ctx)
+	if err != nil {
+		return payload, err
+	}
+	for _, s := range sources {
+		payload.Sources = append(payload.Sources, cloud.HeartbeatSource{
+			SourceID:       s.ID,
+			DisplayName:    s.DisplayName,
+			SourceType:     resolveSourceType(s),
+			CloudLibraryID: s.CloudLibraryID,
+			DriveNickname:  s.DriveNickname,
+			Present:        s.Present,
+			FileCount:      fileCounts[s.ID],
+		})
+	}
+
+	pending, err := h.repo.ListPendingJobs(ctx)
+	if err != nil {
+		return payload, err
+	}
+	payload.QueueDepth = len(pending)
+
+	if h.runner != nil {
+		payload.JobsRunning = h.runner.GetActiveJobCount(ctx)
+		payload.Paused = h.runner.IsPaused()
+	}
+
+	return payload, nil
+}
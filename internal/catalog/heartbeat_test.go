@@ -0,0 +1,112 @@
+package catalog
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/heimdex/heimdex-agent/internal/cloud"
+	"github.com/heimdex/heimdex-agent/internal/pipelines"
+)
+
+func TestHeartbeat_RegistersThenReportsSources(t *testing.T) {
+	fake := &fakePipeRunner{}
+	caps := &pipelines.Capabilities{HasSpeech: true, HasScenes: true, PackageVersion: "0.3.0", ProbedAt: time.Now()}
+	runner, repo := setupRunnerTest(t, fake, caps)
+	if _, err := runner.doctor.Refresh(context.Background()); err != nil {
+		t.Fatalf("doctor refresh: %v", err)
+	}
+
+	_, file := createTestJobAndFile(t, repo)
+	if err := repo.UpdateSourcePresent(context.Background(), file.SourceID, false); err != nil {
+		t.Fatalf("update present: %v", err)
+	}
+
+	cc := &fakeCloudClient{scenes: &fakeSceneUploader{}}
+	hb := NewHeartbeat(repo, runner, runner.doctor, cc, cloud.DeviceInfo{DeviceID: "device-1", AgentVersion: "0.1.0"}, runner.logger)
+
+	hb.tick(context.Background())
+
+	if len(cc.registered) != 1 {
+		t.Fatalf("registrations = %d, want 1", len(cc.registered))
+	}
+	reg := cc.registered[0]
+	if !reg.Capabilities.Speech || !reg.Capabilities.Scenes || reg.Capabilities.Faces {
+		t.Errorf("capabilities = %+v, want speech+scenes", reg.Capabilities)
+	}
+	if reg.Capabilities.PipelinesVersion != "0.3.0" {
+		t.Errorf("pipelines_version = %q, want 0.3.0", reg.Capabilities.PipelinesVersion)
+	}
+
+	if len(cc.heartbeats) != 1 {
+		t.Fatalf("heartbeats = %d, want 1", len(cc.heartbeats))
+	}
+	payload := cc.heartbeats[0]
+	if payload.QueueDepth != 1 {
+		t.Errorf("queue_depth = %d, want 1 (pending index job)", payload.QueueDepth)
+	}
+	if len(payload.Sources) != 1 {
+		t.Fatalf("sources = %d, want 1", len(payload.Sources))
+	}
+	if payload.Sources[0].Present {
+		t.Error("source should be reported as not present")
+	}
+	if payload.Sources[0].FileCount != 1 {
+		t.Errorf("file_count = %d, want 1", payload.Sources[0].FileCount)
+	}
+
+	hb.tick(context.Background())
+	if len(cc.registered) != 1 {
+		t.Errorf("registrations = %d, want 1 (no re-register while known)", len(cc.registered))
+	}
+}
+
+func TestHeartbeat_ReRegistersAfterUnknownDevice(t *testing.T) {
+	fake := &fakePipeRunner{}
+	caps := &pipelines.Capabilities{HasSpeech: true, ProbedAt: time.Now()}
+	runner, repo := setupRunnerTest(t, fake, caps)
+
+	cc := &fakeCloudClient{
+		scenes: &fakeSceneUploader{},
+		heartbeatFn: func(_ context.Context, _ cloud.HeartbeatPayload) error {
+			return &cloud.UploadError{StatusCode: http.StatusNotFound, Body: "unknown device"}
+		},
+	}
+	hb := NewHeartbeat(repo, runner, nil, cc, cloud.DeviceInfo{DeviceID: "device-1"}, runner.logger)
+
+	hb.tick(context.Background())
+	hb.tick(context.Background())
+
+	if len(cc.registered) != 2 {
+		t.Errorf("registrations = %d, want 2 after 404 heartbeat", len(cc.registered))
+	}
+}
+
+func TestHeartbeat_ReRegistersWhenCapabilitiesChange(t *testing.T) {
+	fake := &fakePipeRunner{}
+	caps := &pipelines.Capabilities{HasSpeech: true, ProbedAt: time.Now()}
+	runner, repo := setupRunnerTest(t, fake, caps)
+	if _, err := runner.doctor.Refresh(context.Background()); err != nil {
+		t.Fatalf("doctor refresh: %v", err)
+	}
+
+	cc := &fakeCloudClient{scenes: &fakeSceneUploader{}}
+	hb := NewHeartbeat(repo, runner, runner.doctor, cc, cloud.DeviceInfo{DeviceID: "device-1"}, runner.logger)
+	hb.tick(context.Background())
+
+	// The faces model was installed since the last probe.
+	caps.HasFaces = true
+	if _, err := runner.doctor.Refresh(context.Background()); err != nil {
+		t.Fatalf("doctor refresh: %v", err)
+	}
+	hb.tick(context.Background())
+	hb.tick(context.Background())
+
+	if len(cc.registered) != 2 {
+		t.Fatalf("registrations = %d, want 2 (once more after the change)", len(cc.registered))
+	}
+	if !cc.registered[1].Capabilities.Faces {
+		t.Errorf("capabilities = %+v, want faces after re-registering", cc.registered[1].Capabilities)
+	}
+}
@@ -7,14 +7,47 @@ import (
 )
 
 type Source struct {
-	ID             string    `json:"id"`
-	Type           string    `json:"type"`
-	Path           string    `json:"path"`
-	DisplayName    string    `json:"display_name"`
-	DriveNickname  string    `json:"drive_nickname,omitempty"`
-	CloudLibraryID string    `json:"cloud_library_id,omitempty"`
-	Present        bool      `json:"present"`
-	CreatedAt      time.Time `json:"created_at"`
+	ID                string        `json:"id"`
+	Type              string        `json:"type"`
+	Path              string        `json:"path"`
+	DisplayName       string        `json:"display_name"`
+	DriveNickname     string        `json:"drive_nickname,omitempty"`
+	CloudLibraryID    string        `json:"cloud_library_id,omitempty"`
+	CloudSyncDisabled bool          `json:"cloud_sync_disabled"`
+	Privacy           PrivacyPolicy `json:"privacy"`
+	Present           bool          `json:"present"`
+	CreatedAt         time.Time     `json:"created_at"`
+}
+
+// Privacy actions for a field class.
+const (
+	PrivacySend = "send"
+	PrivacyHash = "hash"
+	PrivacyDrop = "drop"
+)
+
+// PrivacyPolicy controls what of a source's analysis may leave the machine.
+// Hashing keeps a stable HMAC-SHA256 digest keyed with a per-install
+// secret, so the cloud can still group equal values without learning
+// them. The zero value sends everything.
+type PrivacyPolicy struct {
+	Transcript string `json:"transcript"`
+	OCR        string `json:"ocr"`
+	Faces      string `json:"faces"`
+}
+
+// ValidPrivacyAction reports whether action is send, hash or drop.
+func ValidPrivacyAction(action string) bool {
+	return action == PrivacySend || action == PrivacyHash || action == PrivacyDrop
+}
+
+// stricterThan reports whether p withholds anything old would have sent:
+// some field moves from send to hash or drop, or from hash to drop.
+func (p PrivacyPolicy) stricterThan(old PrivacyPolicy) bool {
+	rank := map[string]int{PrivacySend: 0, PrivacyHash: 1, PrivacyDrop: 2}
+	return rank[privacyAction(p.Transcript)] > rank[privacyAction(old.Transcript)] ||
+		rank[privacyAction(p.OCR)] > rank[privacyAction(old.OCR)] ||
+		rank[privacyAction(p.Faces)] > rank[privacyAction(old.Faces)]
 }
 
 type File struct {
@@ -33,6 +66,11 @@ const (
 	JobTypeIndex              = "index"
 	JobTypeUploadScenes       = "upload_scenes"
 	JobTypeGenerateThumbnails = "generate_thumbnails"
+	JobTypeUploadArtifacts    = "upload_artifacts"
+	JobTypeUploadThumbnails   = "upload_thumbnails"
+	JobTypeGenerateProxy      = "generate_proxy"
+	JobTypeGenerateSprites    = "generate_sprites"
+	JobTypeGenerateWaveform   = "generate_waveform"
 
 	JobStatusPending   = "pending"
 	JobStatusRunning   = "running"
@@ -52,6 +90,133 @@ type Job struct {
 	UpdatedAt time.Time `json:"updated_at"`
 }
 
+const (
+	OutboxKindScenes   = "scenes"
+	OutboxKindMetadata = "metadata"
+
+	OutboxStatusPending    = "pending"
+	OutboxStatusSent       = "sent"
+	OutboxStatusFailed     = "failed"
+	OutboxStatusSuperseded = "superseded"
+)
+
+// Cloud library policies for sources without a library mapping.
+const (
+	LibraryPolicyAuto     = "auto"
+	LibraryPolicyExplicit = "explicit"
+)
+
+// OutboxEntry is one cloud mutation waiting to be delivered. Payload holds
+// the JSON request body.
+type OutboxEntry struct {
+	ID             string    `json:"id"`
+	Kind           string    `json:"kind"`
+	FileID         string    `json:"file_id,omitempty"`
+	Payload        string    `json:"payload"`
+	IdempotencyKey string    `json:"idempotency_key"`
+	Status         string    `json:"status"`
+	Attempts       int       `json:"attempts"`
+	NextAttemptAt  time.Time `json:"next_attempt_at"`
+	LastError      string    `json:"last_error,omitempty"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// CloudSyncState records the hash of the last payload of a kind the cloud
+// accepted for a file.
+type CloudSyncState struct {
+	FileID      string    `json:"file_id"`
+	Kind        string    `json:"kind"`
+	PayloadHash string    `json:"payload_hash"`
+	SyncedAt    time.Time `json:"synced_at"`
+}
+
+// CloudAudit records one payload the cloud accepted for a file and the
+// fields it carried.
+type CloudAudit struct {
+	ID          string    `json:"id"`
+	FileID      string    `json:"file_id"`
+	Kind        string    `json:"kind"`
+	PayloadHash string    `json:"payload_hash"`
+	Fields      []string  `json:"fields"`
+	SentAt      time.Time `json:"sent_at"`
+}
+
+// SceneAnnotation holds cloud-side edits to a scene as a delta against the
+// pipeline output.
+type SceneAnnotation struct {
+	SceneID     string    `json:"scene_id"`
+	FileID      string    `json:"file_id"`
+	TagsAdded   []string  `json:"tags_added"`
+	TagsRemoved []string  `json:"tags_removed"`
+	Note        string    `json:"note,omitempty"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// Scene is a detected scene as shown to users: pipeline output with cloud
+// edits merged in. Edited is true when any cloud edit applies.
+type Scene struct {
+	SceneID     string        `json:"scene_id"`
+	Index       int           `json:"index"`
+	StartMs     int           `json:"start_ms"`
+	EndMs       int           `json:"end_ms"`
+	KeyframeMs  int           `json:"keyframe_ms"`
+	Transcript  string        `json:"transcript,omitempty"`
+	People      []ScenePerson `json:"people"`
+	Tags        []string      `json:"tags"`
+	ProductTags []string      `json:"product_tags,omitempty"`
+	Note        string        `json:"note,omitempty"`
+	Edited      bool          `json:"edited"`
+}
+
+type ScenePerson struct {
+	ClusterID string `json:"cluster_id"`
+	Name      string `json:"name,omitempty"`
+}
+
+// PersonName is a display name given to a people cluster in the web app.
+type PersonName struct {
+	FileID    string    `json:"file_id"`
+	ClusterID string    `json:"cluster_id"`
+	Name      string    `json:"name"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// ThumbnailUpload records that a scene's keyframe JPEG is stored in the
+// cloud under ObjectKey.
+type ThumbnailUpload struct {
+	SceneID    string    `json:"scene_id"`
+	FileID     string    `json:"file_id"`
+	SHA256     string    `json:"sha256"`
+	ObjectKey  string    `json:"object_key"`
+	URL        string    `json:"url,omitempty"`
+	UploadedAt time.Time `json:"uploaded_at"`
+}
+
+// Proxy states. An evicted proxy was deleted to stay within the cache
+// budget and is regenerated when next requested.
+const (
+	ProxyStatusPending = "pending"
+	ProxyStatusReady   = "ready"
+	ProxyStatusFailed  = "failed"
+	ProxyStatusEvicted = "evicted"
+)
+
+// FileProxy is a low-resolution H.264 copy of a file kept in the cache for
+// smooth playback. Fingerprint is the source file's fingerprint when the
+// proxy was made, so a changed file is detected as a stale proxy.
+type FileProxy struct {
+	FileID         string    `json:"file_id"`
+	Status         string    `json:"status"`
+	Path           string    `json:"path,omitempty"`
+	Height         int       `json:"height"`
+	SizeBytes      int64     `json:"size_bytes"`
+	Fingerprint    string    `json:"fingerprint,omitempty"`
+	Error          string    `json:"error,omitempty"`
+	LastAccessedAt time.Time `json:"last_accessed_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
 type ConfigEntry struct {
 	Key   string `json:"key"`
 	Value string `json:"value"`
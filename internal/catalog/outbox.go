@@ -0,0 +1,576 @@
+package catalog
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/heimdex/heimdex-agent/internal/cloud"
+)
+
+const (
+	outboxFlushInterval  = 5 * time.Second
+	outboxOfflineBackoff = 30 * time.Second
+	outboxMaxAttempts    = 8
+	outboxFlushLimit     = 50
+	outboxBatchSize      = 10
+	outboxBatchMaxBytes  = 4 << 20
+
+	syncPausedKey       = "cloud_sync_paused"
+	syncMeteredKey      = "cloud_sync_metered"
+	resyncGenerationKey = "cloud_resync_generation"
+)
+
+// Reasons reported by Outbox.PauseReason.
+const (
+	SyncPauseUser    = "paused"
+	SyncPauseOffline = "offline"
+	SyncPauseMetered = "metered"
+)
+
+// Outbox delivers queued cloud mutations. Entries live in the cloud_outbox
+// table so nothing is lost across restarts. Server errors are retried with
+// uploadBackoff; when the SaaS is unreachable the outbox pauses instead of
+// spending attempts.
+//
+// Sync can also be paused by the user, or put in metered mode, where the
+// small scene and metadata documents still flow but bulk transfers such as
+// sidecars and thumbnails wait for an unmetered connection.
+type Outbox struct {
+	repo     Repository
+	client   cloud.Client
+	logger   *slog.Logger
+	interval time.Duration
+	now      func() time.Time
+
+	flushMu sync.Mutex
+
+	mu           sync.Mutex
+	offlineUntil time.Time
+	unreachable  bool
+	paused       bool
+	metered      bool
+	// generation counts forced resyncs. It is part of every idempotency
+	// key so a resync is not dropped by the SaaS as a replay.
+	generation int
+}
+
+// OutboxStatus summarises delivery state for /status.
+type OutboxStatus struct {
+	Pending     int
+	Failed      int
+	Offline     bool
+	Paused      bool
+	Metered     bool
+	PauseReason string
+}
+
+func NewOutbox(repo Repository, client cloud.Client, logger *slog.Logger) *Outbox {
+	return &Outbox{
+		repo:     repo,
+		client:   client,
+		logger:   logger,
+		interval: outboxFlushInterval,
+		now:      time.Now,
+	}
+}
+
+// Enqueue queues payload for delivery and reports whether anything was
+// queued. The idempotency key is derived from the payload hash and the
+// resync generation, so queueing an unchanged payload twice is a no-op, and
+// a payload identical to the last one the cloud accepted for the file is
+// skipped entirely.
+func (o *Outbox) Enqueue(ctx context.Context, kind, fileID string, payload interface{}) (bool, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return false, fmt.Errorf("marshal outbox payload: %w", err)
+	}
+	hash := payloadHash(body)
+
+	if fileID != "" {
+		state, err := o.repo.GetCloudSyncState(ctx, fileID, kind)
+		if err != nil {
+			return false, fmt.Errorf("get sync state: %w", err)
+		}
+		if state != nil && state.PayloadHash == hash {
+			// A newer payload may still be queued; the cloud already holds
+			// the current one, so drop it.
+			if err := o.repo.SupersedeOutbox(ctx, kind, fileID); err != nil {
+				return false, fmt.Errorf("supersede outbox: %w", err)
+			}
+			o.logger.Debug("outbox payload unchanged, skipping", "kind", kind, "file_id", fileID)
+			return false, nil
+		}
+	}
+
+	entry := &OutboxEntry{
+		ID:             NewID(),
+		Kind:           kind,
+		FileID:         fileID,
+		Payload:        string(body),
+		IdempotencyKey: o.idempotencyKey(kind, fileID, hash),
+		Status:         OutboxStatusPending,
+		NextAttemptAt:  o.now(),
+	}
+	queued, err := o.repo.EnqueueOutbox(ctx, entry)
+	if err != nil {
+		return false, fmt.Errorf("enqueue outbox: %w", err)
+	}
+	return queued, nil
+}
+
+func (o *Outbox) idempotencyKey(kind, fileID, hash string) string {
+	o.mu.Lock()
+	generation := o.generation
+	o.mu.Unlock()
+	if generation == 0 {
+		return fmt.Sprintf("%s:%s:%s", kind, fileID, hash[:32])
+	}
+	return fmt.Sprintf("%s:%s:%s:r%d", kind, fileID, hash[:32], generation)
+}
+
+// nextGeneration starts a new resync generation, so payloads queued from
+// now on carry keys the SaaS has not seen. The counter persists.
+func (o *Outbox) nextGeneration(ctx context.Context) (int, error) {
+	raw, err := o.repo.GetConfig(ctx, resyncGenerationKey)
+	if err != nil {
+		return 0, err
+	}
+	generation, _ := strconv.Atoi(raw)
+	generation++
+	if err := o.repo.SetConfig(ctx, resyncGenerationKey, strconv.Itoa(generation)); err != nil {
+		return 0, err
+	}
+	o.mu.Lock()
+	o.generation = generation
+	o.mu.Unlock()
+	return generation, nil
+}
+
+func payloadHash(body []byte) string {
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:])
+}
+
+// Run flushes the outbox every interval until ctx is cancelled.
+func (o *Outbox) Run(ctx context.Context) {
+	ticker := time.NewTicker(o.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := o.Flush(ctx); err != nil {
+				o.logger.Warn("outbox flush failed", "error", err)
+			}
+		}
+	}
+}
+
+func (o *Outbox) Offline() bool {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.offlineLocked()
+}
+
+func (o *Outbox) offlineLocked() bool {
+	return o.unreachable || o.now().Before(o.offlineUntil)
+}
+
+// SetReachable records the connectivity monitor's view of the SaaS. When it
+// comes back the offline backoff is cleared and queued entries are flushed
+// right away.
+func (o *Outbox) SetReachable(ctx context.Context, online bool) {
+	o.mu.Lock()
+	o.unreachable = !online
+	if online {
+		o.offlineUntil = time.Time{}
+	}
+	o.mu.Unlock()
+
+	if !online {
+		return
+	}
+	o.logger.Info("cloud reachable, resuming outbox")
+	if _, err := o.Flush(ctx); err != nil {
+		o.logger.Warn("outbox flush failed", "error", err)
+	}
+}
+
+// LoadSettings restores the pause and metered flags saved by SetPaused and
+// SetMetered.
+func (o *Outbox) LoadSettings(ctx context.Context) error {
+	paused, err := o.repo.GetConfig(ctx, syncPausedKey)
+	if err != nil {
+		return err
+	}
+	metered, err := o.repo.GetConfig(ctx, syncMeteredKey)
+	if err != nil {
+		return err
+	}
+	generation, err := o.repo.GetConfig(ctx, resyncGenerationKey)
+	if err != nil {
+		return err
+	}
+	o.mu.Lock()
+	o.paused = paused == "true"
+	o.metered = metered == "true"
+	o.generation, _ = strconv.Atoi(generation)
+	o.mu.Unlock()
+	return nil
+}
+
+// SetPaused stops or restarts all cloud deliveries. The setting persists.
+func (o *Outbox) SetPaused(ctx context.Context, paused bool) error {
+	if err := o.repo.SetConfig(ctx, syncPausedKey, strconv.FormatBool(paused)); err != nil {
+		return err
+	}
+	o.mu.Lock()
+	o.paused = paused
+	o.mu.Unlock()
+	o.logger.Info("cloud sync pause changed", "paused", paused)
+	return nil
+}
+
+// SetMetered turns metered mode on or off. The setting persists.
+func (o *Outbox) SetMetered(ctx context.Context, metered bool) error {
+	if err := o.repo.SetConfig(ctx, syncMeteredKey, strconv.FormatBool(metered)); err != nil {
+		return err
+	}
+	o.mu.Lock()
+	o.metered = metered
+	o.mu.Unlock()
+	o.logger.Info("cloud sync metered mode changed", "metered", metered)
+	return nil
+}
+
+// PauseReason explains why deliveries are held back, or returns "" when
+// everything may be sent. Metered mode only holds back bulk transfers.
+func (o *Outbox) PauseReason() string {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	switch {
+	case o.paused:
+		return SyncPauseUser
+	case o.offlineLocked():
+		return SyncPauseOffline
+	case o.metered:
+		return SyncPauseMetered
+	default:
+		return ""
+	}
+}
+
+// AllowBulk reports whether large uploads such as sidecars and thumbnails
+// may run now.
+func (o *Outbox) AllowBulk() bool {
+	return o.PauseReason() == ""
+}
+
+func (o *Outbox) allowDocs() bool {
+	reason := o.PauseReason()
+	return reason == "" || reason == SyncPauseMetered
+}
+
+func (o *Outbox) setOffline(err error) {
+	o.mu.Lock()
+	o.offlineUntil = o.now().Add(outboxOfflineBackoff)
+	o.mu.Unlock()
+	o.logger.Warn("cloud unreachable, pausing outbox", "retry_in", outboxOfflineBackoff, "error", err)
+}
+
+func (o *Outbox) Status(ctx context.Context) OutboxStatus {
+	pending, _ := o.repo.CountOutbox(ctx, OutboxStatusPending)
+	failed, _ := o.repo.CountOutbox(ctx, OutboxStatusFailed)
+	reason := o.PauseReason()
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return OutboxStatus{
+		Pending:     pending,
+		Failed:      failed,
+		Offline:     o.offlineLocked(),
+		Paused:      o.paused,
+		Metered:     o.metered,
+		PauseReason: reason,
+	}
+}
+
+// Flush delivers due entries once and returns how many were sent. Scene
+// entries are batched when the server supports it.
+func (o *Outbox) Flush(ctx context.Context) (int, error) {
+	o.flushMu.Lock()
+	defer o.flushMu.Unlock()
+
+	if !o.allowDocs() {
+		return 0, nil
+	}
+
+	entries, err := o.repo.ListDueOutbox(ctx, o.now(), outboxFlushLimit)
+	if err != nil {
+		return 0, err
+	}
+
+	delivered := 0
+	var scenes []*OutboxEntry
+	optedOut := map[string]bool{}
+	for _, e := range entries {
+		if o.sourceOptedOut(ctx, e, optedOut) {
+			e.Status = OutboxStatusSuperseded
+			if err := o.repo.UpdateOutboxEntry(ctx, e); err != nil {
+				o.logger.Error("failed to update outbox entry", "id", e.ID, "error", err)
+			}
+			continue
+		}
+		if e.Kind == OutboxKindScenes {
+			scenes = append(scenes, e)
+			continue
+		}
+		ok, offline := o.record(ctx, e, o.deliver(ctx, e))
+		if ok {
+			delivered++
+		}
+		if offline {
+			return delivered, nil
+		}
+	}
+
+	sent, _ := o.deliverScenes(ctx, scenes)
+	return delivered + sent, nil
+}
+
+// sourceOptedOut reports whether the entry's source has switched to
+// local-only since it was queued. Results are cached per source in seen for
+// the duration of one flush.
+func (o *Outbox) sourceOptedOut(ctx context.Context, e *OutboxEntry, seen map[string]bool) bool {
+	if e.FileID == "" {
+		return false
+	}
+	file, err := o.repo.GetFile(ctx, e.FileID)
+	if err != nil || file == nil {
+		return false
+	}
+	disabled, ok := seen[file.SourceID]
+	if !ok {
+		source, err := o.repo.GetSource(ctx, file.SourceID)
+		disabled = err == nil && source != nil && source.CloudSyncDisabled
+		seen[file.SourceID] = disabled
+	}
+	return disabled
+}
+
+// deliver sends one non-batched entry.
+func (o *Outbox) deliver(ctx context.Context, e *OutboxEntry) error {
+	ctx, cancel := context.WithTimeout(cloud.WithIdempotencyKey(ctx, e.IdempotencyKey), outboxTimeout(len(e.Payload)))
+	defer cancel()
+
+	switch e.Kind {
+	case OutboxKindScenes:
+		var payload cloud.SceneIngestPayload
+		if err := json.Unmarshal([]byte(e.Payload), &payload); err != nil {
+			return errOutboxCorrupt
+		}
+		return o.client.Scenes().UploadScenes(ctx, payload)
+	case OutboxKindMetadata:
+		var metadata map[string]interface{}
+		if err := json.Unmarshal([]byte(e.Payload), &metadata); err != nil {
+			return errOutboxCorrupt
+		}
+		return o.client.Upload().UploadMetadata(ctx, e.FileID, metadata)
+	default:
+		return errOutboxUnknownKind
+	}
+}
+
+var (
+	errOutboxCorrupt      = errors.New("outbox payload is not valid JSON")
+	errOutboxUnknownKind  = errors.New("unknown outbox kind")
+	errBatchResultMissing = errors.New("entry missing from batch response")
+)
+
+// deliverScenes sends scene entries in batches, falling back to one request
+// per video when the server has no batch endpoint.
+func (o *Outbox) deliverScenes(ctx context.Context, entries []*OutboxEntry) (int, bool) {
+	delivered := 0
+	pending := make([]sceneOutboxEntry, 0, len(entries))
+	for _, e := range entries {
+		var payload cloud.SceneIngestPayload
+		if err := json.Unmarshal([]byte(e.Payload), &payload); err != nil {
+			o.record(ctx, e, errOutboxCorrupt)
+			continue
+		}
+		pending = append(pending, sceneOutboxEntry{entry: e, payload: payload})
+	}
+
+	batching := true
+	for len(pending) > 0 {
+		batch := nextOutboxBatch(pending)
+		pending = pending[len(batch):]
+
+		if !batching || len(batch) == 1 {
+			for _, se := range batch {
+				ok, offline := o.record(ctx, se.entry, o.deliver(ctx, se.entry))
+				if ok {
+					delivered++
+				}
+				if offline {
+					return delivered, true
+				}
+			}
+			continue
+		}
+
+		results, err := o.sendSceneBatch(ctx, batch)
+		if errors.Is(err, cloud.ErrBatchUnsupported) {
+			batching = false
+			pending = append(batch, pending...)
+			continue
+		}
+
+		for _, se := range batch {
+			entryErr := err
+			if err == nil {
+				entryErr = batchResultError(results, se.entry.IdempotencyKey)
+			}
+			ok, offline := o.record(ctx, se.entry, entryErr)
+			if ok {
+				delivered++
+			}
+			if offline {
+				return delivered, true
+			}
+		}
+	}
+	return delivered, false
+}
+
+type sceneOutboxEntry struct {
+	entry   *OutboxEntry
+	payload cloud.SceneIngestPayload
+}
+
+func (o *Outbox) sendSceneBatch(ctx context.Context, batch []sceneOutboxEntry) (map[string]cloud.SceneBatchResult, error) {
+	items := make([]cloud.SceneBatchItem, 0, len(batch))
+	size := 0
+	for _, se := range batch {
+		items = append(items, cloud.SceneBatchItem{IdempotencyKey: se.entry.IdempotencyKey, Payload: se.payload})
+		size += len(se.entry.Payload)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, outboxTimeout(size))
+	defer cancel()
+
+	results, err := o.client.Scenes().UploadScenesBatch(ctx, items)
+	if err != nil {
+		return nil, err
+	}
+	byKey := make(map[string]cloud.SceneBatchResult, len(results))
+	for _, r := range results {
+		byKey[r.IdempotencyKey] = r
+	}
+	return byKey, nil
+}
+
+func batchResultError(results map[string]cloud.SceneBatchResult, key string) error {
+	r, ok := results[key]
+	if !ok {
+		return errBatchResultMissing
+	}
+	if r.StatusCode >= 200 && r.StatusCode < 300 {
+		return nil
+	}
+	return &cloud.UploadError{StatusCode: r.StatusCode, Body: r.Error}
+}
+
+// nextOutboxBatch returns the leading entries that fit in one batch request.
+// Payloads large enough to be chunked always travel alone.
+func nextOutboxBatch(entries []sceneOutboxEntry) []sceneOutboxEntry {
+	size := 0
+	for i, se := range entries {
+		large := len(se.entry.Payload) > cloud.SceneChunkThreshold
+		if i == 0 && large {
+			return entries[:1]
+		}
+		size += len(se.entry.Payload)
+		if i > 0 && (large || i >= outboxBatchSize || size > outboxBatchMaxBytes) {
+			return entries[:i]
+		}
+	}
+	return entries
+}
+
+// record stores the outcome of a delivery attempt. It reports whether the
+// entry was delivered and whether the outbox has gone offline.
+func (o *Outbox) record(ctx context.Context, e *OutboxEntry, err error) (bool, bool) {
+	switch {
+	case err == nil:
+		e.Status = OutboxStatusSent
+		e.LastError = ""
+		if e.FileID != "" {
+			hash := payloadHash([]byte(e.Payload))
+			state := &CloudSyncState{FileID: e.FileID, Kind: e.Kind, PayloadHash: hash, SyncedAt: o.now()}
+			if syncErr := o.repo.UpsertCloudSyncState(ctx, state); syncErr != nil {
+				o.logger.Error("failed to record sync state", "file_id", e.FileID, "error", syncErr)
+			}
+			recordCloudAudit(ctx, o.repo, o.logger, &CloudAudit{
+				FileID:      e.FileID,
+				Kind:        e.Kind,
+				PayloadHash: hash,
+				Fields:      auditFields(e.Kind, []byte(e.Payload)),
+				SentAt:      o.now(),
+			})
+		}
+	case cloud.IsNetworkError(err):
+		o.setOffline(err)
+		return false, true
+	case errors.Is(err, errOutboxCorrupt), errors.Is(err, errOutboxUnknownKind):
+		e.Status = OutboxStatusFailed
+		e.LastError = err.Error()
+	default:
+		var uploadErr *cloud.UploadError
+		e.Attempts++
+		e.LastError = err.Error()
+		if errors.As(err, &uploadErr) && !uploadErr.IsRetryable() {
+			e.Status = OutboxStatusFailed
+		} else if e.Attempts >= outboxMaxAttempts {
+			e.Status = OutboxStatusFailed
+		} else {
+			e.NextAttemptAt = o.now().Add(uploadBackoff(e.Attempts - 1))
+		}
+	}
+
+	if updateErr := o.repo.UpdateOutboxEntry(ctx, e); updateErr != nil {
+		o.logger.Error("failed to update outbox entry", "id", e.ID, "error", updateErr)
+	}
+
+	if err != nil {
+		o.logger.Warn("outbox delivery failed",
+			"kind", e.Kind,
+			"file_id", e.FileID,
+			"attempts", e.Attempts,
+			"status", e.Status,
+			"error", err,
+		)
+	}
+	return err == nil, false
+}
+
+// outboxTimeout scales the request deadline with payload size, assuming at
+// least 128 KiB/s upstream.
+func outboxTimeout(bytes int) time.Duration {
+	const maxTimeout = 5 * time.Minute
+	timeout := 30*time.Second + time.Duration(bytes/(128<<10))*time.Second
+	if timeout > maxTimeout {
+		timeout = maxTimeout
+	}
+	return timeout
+}
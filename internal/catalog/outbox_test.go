@@ -0,0 +1,363 @@
+package catalog
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/heimdex/heimdex-agent/internal/cloud"
+	"github.com/heimdex/heimdex-agent/internal/pipelines"
+)
+
+func enqueueTestScenes(t *testing.T, outbox *Outbox, videoIDs ...string) {
+	t.Helper()
+	for _, id := range videoIDs {
+		payload := cloud.SceneIngestPayload{
+			VideoID: id,
+			Scenes:  []cloud.SceneIngestDoc{{SceneID: id + "_scene_0", StartMs: 0, EndMs: 1000}},
+		}
+		if _, err := outbox.Enqueue(context.Background(), OutboxKindScenes, "", payload); err != nil {
+			t.Fatalf("enqueue %s: %v", id, err)
+		}
+	}
+}
+
+func TestOutbox_BatchesScenes(t *testing.T) {
+	runner, repo := setupRunnerTest(t, &fakePipeRunner{}, &pipelines.Capabilities{})
+
+	var batches [][]string
+	singles := 0
+	client := &fakeCloudClient{scenes: &fakeSceneUploader{
+		uploadFn: func(context.Context, cloud.SceneIngestPayload) error {
+			singles++
+			return nil
+		},
+		batchFn: func(_ context.Context, items []cloud.SceneBatchItem) ([]cloud.SceneBatchResult, error) {
+			var ids []string
+			var results []cloud.SceneBatchResult
+			for _, item := range items {
+				ids = append(ids, item.Payload.VideoID)
+				status := 200
+				if item.Payload.VideoID == "vid-bad" {
+					status = 422
+				}
+				results = append(results, cloud.SceneBatchResult{IdempotencyKey: item.IdempotencyKey, StatusCode: status})
+			}
+			batches = append(batches, ids)
+			return results, nil
+		},
+	}}
+	outbox := NewOutbox(repo, client, runner.logger)
+	enqueueTestScenes(t, outbox, "vid-1", "vid-2", "vid-bad")
+
+	sent, err := outbox.Flush(context.Background())
+	if err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+	if sent != 2 {
+		t.Errorf("sent = %d, want 2", sent)
+	}
+	if len(batches) != 1 || len(batches[0]) != 3 || singles != 0 {
+		t.Fatalf("batches = %v singles = %d, want one batch of 3", batches, singles)
+	}
+	if n, _ := repo.CountOutbox(context.Background(), OutboxStatusFailed); n != 1 {
+		t.Errorf("failed = %d, want 1 for per-item 422", n)
+	}
+}
+
+func TestOutbox_FallsBackWhenBatchUnsupported(t *testing.T) {
+	runner, repo := setupRunnerTest(t, &fakePipeRunner{}, &pipelines.Capabilities{})
+
+	var uploaded []string
+	client := &fakeCloudClient{scenes: &fakeSceneUploader{
+		uploadFn: func(_ context.Context, payload cloud.SceneIngestPayload) error {
+			uploaded = append(uploaded, payload.VideoID)
+			return nil
+		},
+	}}
+	outbox := NewOutbox(repo, client, runner.logger)
+	enqueueTestScenes(t, outbox, "vid-1", "vid-2")
+
+	sent, err := outbox.Flush(context.Background())
+	if err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+	if sent != 2 || len(uploaded) != 2 {
+		t.Fatalf("sent = %d uploaded = %v, want both videos sent individually", sent, uploaded)
+	}
+}
+
+func TestOutbox_MaxAttemptsFailsEntry(t *testing.T) {
+	runner, repo := setupRunnerTest(t, &fakePipeRunner{}, &pipelines.Capabilities{})
+
+	client := &fakeCloudClient{scenes: &fakeSceneUploader{
+		uploadFn: func(context.Context, cloud.SceneIngestPayload) error {
+			return &cloud.UploadError{StatusCode: 503}
+		},
+	}}
+	outbox := NewOutbox(repo, client, runner.logger)
+	clock := time.Now()
+	outbox.now = func() time.Time { return clock }
+	enqueueTestScenes(t, outbox, "vid-1")
+
+	for i := 0; i < outboxMaxAttempts; i++ {
+		outbox.Flush(context.Background())
+		clock = clock.Add(time.Hour)
+	}
+
+	if n, _ := repo.CountOutbox(context.Background(), OutboxStatusFailed); n != 1 {
+		t.Fatalf("failed = %d, want 1 after %d attempts", n, outboxMaxAttempts)
+	}
+}
+
+func TestOutbox_EnqueueSupersedesOlderPayload(t *testing.T) {
+	runner, repo := setupRunnerTest(t, &fakePipeRunner{}, &pipelines.Capabilities{})
+	_, file := createTestJobAndFile(t, repo)
+
+	outbox := NewOutbox(repo, &fakeCloudClient{scenes: &fakeSceneUploader{}}, runner.logger)
+	ctx := context.Background()
+	outbox.Enqueue(ctx, OutboxKindScenes, file.ID, cloud.SceneIngestPayload{VideoID: file.ID, VideoTitle: "v1"})
+	outbox.Enqueue(ctx, OutboxKindScenes, file.ID, cloud.SceneIngestPayload{VideoID: file.ID, VideoTitle: "v2"})
+
+	if n, _ := repo.CountOutbox(ctx, OutboxStatusPending); n != 1 {
+		t.Errorf("pending = %d, want 1", n)
+	}
+	if n, _ := repo.CountOutbox(ctx, OutboxStatusSuperseded); n != 1 {
+		t.Errorf("superseded = %d, want 1", n)
+	}
+}
+
+func TestOutboxTimeout_ScalesWithSize(t *testing.T) {
+	if got := outboxTimeout(1024); got != 30*time.Second {
+		t.Errorf("small payload timeout = %v, want 30s", got)
+	}
+	if got := outboxTimeout(10 << 20); got != 110*time.Second {
+		t.Errorf("10MiB payload timeout = %v, want 110s", got)
+	}
+	if got := outboxTimeout(1 << 30); got != 5*time.Minute {
+		t.Errorf("huge payload timeout = %v, want 5m cap", got)
+	}
+}
+
+func TestUploadScenesToCloud_SkipsUnchangedPayload(t *testing.T) {
+	fake := &fakePipeRunner{artifacts: t.TempDir()}
+	runner, repo := setupRunnerTest(t, fake, &pipelines.Capabilities{ProbedAt: time.Now()})
+
+	calls := 0
+	runner.SetCloudClient(&fakeCloudClient{scenes: &fakeSceneUploader{
+		uploadFn: func(context.Context, cloud.SceneIngestPayload) error {
+			calls++
+			return nil
+		},
+	}}, "lib-1")
+
+	job, file := createTestJobAndFile(t, repo)
+	writeSceneResult(t, fake.artifacts, file.ID)
+	base := filepath.Join(fake.artifacts, file.ID)
+
+	runner.uploadScenesToCloud(context.Background(), job, file, base)
+	runner.uploadScenesToCloud(context.Background(), job, file, base)
+
+	if calls != 1 {
+		t.Fatalf("upload calls = %d, want 1 (second payload is identical)", calls)
+	}
+	state, _ := repo.GetCloudSyncState(context.Background(), file.ID, OutboxKindScenes)
+	if state == nil || state.PayloadHash == "" {
+		t.Fatal("expected sync state recorded after successful upload")
+	}
+}
+
+func TestOutbox_RevertedPayloadIsResent(t *testing.T) {
+	runner, repo := setupRunnerTest(t, &fakePipeRunner{}, &pipelines.Capabilities{})
+	_, file := createTestJobAndFile(t, repo)
+
+	var titles []string
+	outbox := NewOutbox(repo, &fakeCloudClient{scenes: &fakeSceneUploader{
+		uploadFn: func(_ context.Context, payload cloud.SceneIngestPayload) error {
+			titles = append(titles, payload.VideoTitle)
+			return nil
+		},
+	}}, runner.logger)
+	ctx := context.Background()
+
+	for _, title := range []string{"a", "b", "a"} {
+		if _, err := outbox.Enqueue(ctx, OutboxKindScenes, file.ID, cloud.SceneIngestPayload{VideoID: file.ID, VideoTitle: title}); err != nil {
+			t.Fatalf("enqueue %s: %v", title, err)
+		}
+		outbox.Flush(ctx)
+	}
+
+	if len(titles) != 3 || titles[2] != "a" {
+		t.Fatalf("sent = %v, want [a b a]", titles)
+	}
+}
+
+func TestRunnerResync_RequeuesSyncedFiles(t *testing.T) {
+	fake := &fakePipeRunner{artifacts: t.TempDir()}
+	runner, repo := setupRunnerTest(t, fake, &pipelines.Capabilities{ProbedAt: time.Now()})
+
+	var keys []string
+	runner.SetCloudClient(&fakeCloudClient{scenes: &fakeSceneUploader{
+		uploadFn: func(ctx context.Context, _ cloud.SceneIngestPayload) error {
+			keys = append(keys, cloud.IdempotencyKey(ctx))
+			return nil
+		},
+	}}, "lib-1")
+
+	ctx := context.Background()
+	job, file := createTestJobAndFile(t, repo)
+	repo.CreateJob(ctx, &Job{ID: NewID(), Type: JobTypeIndex, Status: JobStatusCompleted, FileID: file.ID, CreatedAt: time.Now(), UpdatedAt: time.Now()})
+	writeSceneResult(t, fake.artifacts, file.ID)
+	runner.uploadScenesToCloud(ctx, job, file, filepath.Join(fake.artifacts, file.ID))
+
+	queued, err := runner.Resync(ctx)
+	if err != nil {
+		t.Fatalf("Resync: %v", err)
+	}
+	if queued != 1 {
+		t.Fatalf("queued = %d, want 1", queued)
+	}
+
+	pending, _ := repo.ListPendingJobs(ctx)
+	for _, j := range pending {
+		if j.Type == JobTypeUploadScenes {
+			runner.processUploadScenesJob(ctx, j)
+		}
+	}
+	if len(keys) != 2 {
+		t.Fatalf("upload calls = %d, want 2 (resync resends identical payload)", len(keys))
+	}
+	// The SaaS drops a key it has seen; a forced push must use a new one.
+	if keys[0] == "" || keys[1] == "" || keys[0] == keys[1] {
+		t.Errorf("idempotency keys = %q, want two distinct keys", keys)
+	}
+}
+
+func TestOutbox_PausedHoldsDeliveries(t *testing.T) {
+	runner, repo := setupRunnerTest(t, &fakePipeRunner{}, &pipelines.Capabilities{})
+
+	uploads := 0
+	client := &fakeCloudClient{scenes: &fakeSceneUploader{
+		uploadFn: func(context.Context, cloud.SceneIngestPayload) error {
+			uploads++
+			return nil
+		},
+	}}
+	ctx := context.Background()
+	outbox := NewOutbox(repo, client, runner.logger)
+	if err := outbox.SetPaused(ctx, true); err != nil {
+		t.Fatalf("SetPaused: %v", err)
+	}
+	enqueueTestScenes(t, outbox, "vid-1")
+
+	if sent, _ := outbox.Flush(ctx); sent != 0 || uploads != 0 {
+		t.Fatalf("sent = %d uploads = %d while paused, want 0", sent, uploads)
+	}
+	if reason := outbox.PauseReason(); reason != SyncPauseUser {
+		t.Errorf("PauseReason = %q, want %q", reason, SyncPauseUser)
+	}
+
+	// The setting survives a restart.
+	restored := NewOutbox(repo, client, runner.logger)
+	if err := restored.LoadSettings(ctx); err != nil {
+		t.Fatalf("LoadSettings: %v", err)
+	}
+	if !restored.Status(ctx).Paused {
+		t.Error("restored outbox is not paused")
+	}
+
+	if err := restored.SetPaused(ctx, false); err != nil {
+		t.Fatalf("SetPaused: %v", err)
+	}
+	if sent, _ := restored.Flush(ctx); sent != 1 {
+		t.Errorf("sent = %d after resume, want 1", sent)
+	}
+}
+
+func TestOutbox_MeteredSendsDocsOnly(t *testing.T) {
+	runner, repo := setupRunnerTest(t, &fakePipeRunner{}, &pipelines.Capabilities{})
+
+	client := &fakeCloudClient{scenes: &fakeSceneUploader{}}
+	ctx := context.Background()
+	outbox := NewOutbox(repo, client, runner.logger)
+	if err := outbox.SetMetered(ctx, true); err != nil {
+		t.Fatalf("SetMetered: %v", err)
+	}
+	enqueueTestScenes(t, outbox, "vid-1")
+
+	if sent, _ := outbox.Flush(ctx); sent != 1 {
+		t.Errorf("sent = %d in metered mode, want scene docs delivered", sent)
+	}
+	if outbox.AllowBulk() {
+		t.Error("AllowBulk = true in metered mode")
+	}
+
+	runner.outbox = outbox
+	now := time.Now()
+	jobs := []*Job{
+		{ID: "a", Type: JobTypeUploadArtifacts, CreatedAt: now},
+		{ID: "b", Type: JobTypeUploadThumbnails, CreatedAt: now},
+		{ID: "c", Type: JobTypeIndex, CreatedAt: now},
+	}
+	if job := runner.nextRunnableJob(jobs); job == nil || job.ID != "c" {
+		t.Fatalf("nextRunnableJob = %v, want the index job ahead of deferred uploads", job)
+	}
+}
+
+func TestOutbox_ResumesWhenReachable(t *testing.T) {
+	runner, repo := setupRunnerTest(t, &fakePipeRunner{}, &pipelines.Capabilities{})
+
+	uploads := 0
+	client := &fakeCloudClient{scenes: &fakeSceneUploader{
+		uploadFn: func(context.Context, cloud.SceneIngestPayload) error {
+			uploads++
+			return nil
+		},
+	}}
+	ctx := context.Background()
+	outbox := NewOutbox(repo, client, runner.logger)
+	outbox.SetReachable(ctx, false)
+	enqueueTestScenes(t, outbox, "vid-1")
+
+	if sent, _ := outbox.Flush(ctx); sent != 0 {
+		t.Fatalf("sent = %d while unreachable, want 0", sent)
+	}
+	if reason := outbox.PauseReason(); reason != SyncPauseOffline {
+		t.Errorf("PauseReason = %q, want %q", reason, SyncPauseOffline)
+	}
+
+	outbox.SetReachable(ctx, true)
+	if uploads != 1 {
+		t.Errorf("uploads = %d after connectivity returned, want 1", uploads)
+	}
+}
+
+func TestOutbox_DropsEntriesOfLocalOnlySources(t *testing.T) {
+	runner, repo := setupRunnerTest(t, &fakePipeRunner{}, &pipelines.Capabilities{})
+	_, file := createTestJobAndFile(t, repo)
+
+	uploaded := 0
+	client := &fakeCloudClient{scenes: &fakeSceneUploader{
+		uploadFn: func(context.Context, cloud.SceneIngestPayload) error {
+			uploaded++
+			return nil
+		},
+	}}
+	outbox := NewOutbox(repo, client, runner.logger)
+	ctx := context.Background()
+	outbox.Enqueue(ctx, OutboxKindScenes, file.ID, cloud.SceneIngestPayload{VideoID: file.ID})
+
+	// Flipped behind the service's back, so only the delivery-time check
+	// stands between the entry and the cloud.
+	if err := repo.UpdateSourceCloudSync(ctx, file.SourceID, "", true); err != nil {
+		t.Fatalf("UpdateSourceCloudSync: %v", err)
+	}
+
+	if sent, _ := outbox.Flush(ctx); sent != 0 || uploaded != 0 {
+		t.Errorf("sent = %d uploaded = %d, want nothing for a local-only source", sent, uploaded)
+	}
+	if n, _ := repo.CountOutbox(ctx, OutboxStatusSuperseded); n != 1 {
+		t.Errorf("superseded = %d, want 1", n)
+	}
+}
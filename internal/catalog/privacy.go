@@ -0,0 +1,197 @@
+package catalog
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/heimdex/heimdex-agent/internal/cloud"
+	"github.com/heimdex/heimdex-agent/internal/pipelines"
+)
+
+// hashedPrefix marks values replaced by their digest, so the cloud (and the
+// audit log) can tell a hash from real text.
+const hashedPrefix = "hmac-sha256:"
+
+// privacySecretKey is the config key of the per-install secret hashed
+// values are keyed with. Without it a plain digest of a short transcript or
+// a cluster ID could be reversed by hashing guesses.
+const privacySecretKey = "privacy_hash_secret"
+
+var privacySecretMu sync.Mutex
+
+// privacySecret returns the install's hashing secret, generating and storing
+// it on first use.
+func privacySecret(ctx context.Context, repo Repository) ([]byte, error) {
+	privacySecretMu.Lock()
+	defer privacySecretMu.Unlock()
+
+	secret, err := repo.GetConfig(ctx, privacySecretKey)
+	if err != nil {
+		return nil, fmt.Errorf("get privacy secret: %w", err)
+	}
+	if secret == "" {
+		buf := make([]byte, 32)
+		if _, err := rand.Read(buf); err != nil {
+			return nil, fmt.Errorf("generate privacy secret: %w", err)
+		}
+		secret = hex.EncodeToString(buf)
+		if err := repo.SetConfig(ctx, privacySecretKey, secret); err != nil {
+			return nil, fmt.Errorf("store privacy secret: %w", err)
+		}
+	}
+	return []byte(secret), nil
+}
+
+// applyPrivacy rewrites scene docs in place according to policy.
+func applyPrivacy(scenes []cloud.SceneIngestDoc, policy PrivacyPolicy, secret []byte) {
+	for i := range scenes {
+		s := &scenes[i]
+		s.TranscriptRaw = applyPrivacyText(s.TranscriptRaw, policy.Transcript, secret)
+		s.OCRTextRaw = applyPrivacyText(s.OCRTextRaw, policy.OCR, secret)
+
+		switch policy.Faces {
+		case PrivacyDrop:
+			s.PeopleClusterIDs = nil
+		case PrivacyHash:
+			for j, id := range s.PeopleClusterIDs {
+				s.PeopleClusterIDs[j] = hashValue(secret, id)
+			}
+		}
+	}
+}
+
+func applyPrivacyText(value, action string, secret []byte) string {
+	if value == "" {
+		return ""
+	}
+	switch action {
+	case PrivacyDrop:
+		return ""
+	case PrivacyHash:
+		return hashValue(secret, value)
+	default:
+		return value
+	}
+}
+
+func hashValue(secret []byte, v string) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(v))
+	return hashedPrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+// unhashClusterIDs rewrites person names the cloud keyed by hashed cluster
+// IDs back to the local IDs in raw, so names given to hashed faces still
+// show up locally.
+func unhashClusterIDs(names []*PersonName, raw []pipelines.SceneBoundary, secret []byte) {
+	local := map[string]string{}
+	for _, b := range raw {
+		for _, id := range b.PeopleClusterIDs {
+			local[hashValue(secret, id)] = id
+		}
+	}
+	for _, n := range names {
+		if id, ok := local[n.ClusterID]; ok {
+			n.ClusterID = id
+		}
+	}
+}
+
+// sidecarAllowed reports whether a pipeline sidecar may be uploaded. The
+// speech and faces sidecars carry the raw transcript and face data, so they
+// are only sent when the matching field is sent in the clear.
+func sidecarAllowed(kind string, policy PrivacyPolicy) bool {
+	switch kind {
+	case "speech":
+		return privacyAction(policy.Transcript) == PrivacySend
+	case "faces":
+		return privacyAction(policy.Faces) == PrivacySend
+	default:
+		return true
+	}
+}
+
+// auditFields lists the fields present in an outbox payload. Hashed values
+// are reported as "<field>:hashed".
+func auditFields(kind string, payload []byte) []string {
+	switch kind {
+	case OutboxKindScenes:
+		var p cloud.SceneIngestPayload
+		if err := json.Unmarshal(payload, &p); err != nil {
+			return nil
+		}
+		set := map[string]bool{}
+		for _, s := range p.Scenes {
+			addAuditText(set, "transcript", s.TranscriptRaw)
+			addAuditText(set, "ocr_text", s.OCRTextRaw)
+			for _, id := range s.PeopleClusterIDs {
+				addAuditText(set, "people_cluster_ids", id)
+			}
+			if len(s.KeywordTags) > 0 {
+				set["keyword_tags"] = true
+			}
+			if len(s.ProductTags) > 0 || len(s.ProductEntities) > 0 {
+				set["product_tags"] = true
+			}
+			if s.ThumbnailKey != "" {
+				set["thumbnail_key"] = true
+			}
+		}
+		if len(p.Scenes) > 0 {
+			set["scene_timing"] = true
+		}
+		if p.VideoTitle != "" {
+			set["video_title"] = true
+		}
+		return sortedKeys(set)
+	case OutboxKindMetadata:
+		var m map[string]interface{}
+		if err := json.Unmarshal(payload, &m); err != nil {
+			return nil
+		}
+		set := make(map[string]bool, len(m))
+		for k := range m {
+			set[k] = true
+		}
+		return sortedKeys(set)
+	default:
+		return nil
+	}
+}
+
+func addAuditText(set map[string]bool, field, value string) {
+	switch {
+	case value == "":
+	case strings.HasPrefix(value, hashedPrefix):
+		set[field+":hashed"] = true
+	default:
+		set[field] = true
+	}
+}
+
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for k := range set {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// recordCloudAudit stores what the cloud accepted for a file. Failures are
+// logged; they never fail the upload itself.
+func recordCloudAudit(ctx context.Context, repo Repository, logger *slog.Logger, audit *CloudAudit) {
+	audit.ID = NewID()
+	if err := repo.InsertCloudAudit(ctx, audit); err != nil {
+		logger.Error("failed to record cloud audit", "file_id", audit.FileID, "kind", audit.Kind, "error", err)
+	}
+}
@@ -0,0 +1,173 @@
+package catalog
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/heimdex/heimdex-agent/internal/cloud"
+	"github.com/heimdex/heimdex-agent/internal/pipelines"
+)
+
+func TestApplyPrivacy(t *testing.T) {
+	scenes := []cloud.SceneIngestDoc{{
+		TranscriptRaw:    "confidential launch date",
+		OCRTextRaw:       "PROJECT ATLAS",
+		PeopleClusterIDs: []string{"cluster-1"},
+	}}
+
+	secret := []byte("install-secret")
+	applyPrivacy(scenes, PrivacyPolicy{Transcript: PrivacyDrop, OCR: PrivacyHash, Faces: PrivacyHash}, secret)
+
+	s := scenes[0]
+	if s.TranscriptRaw != "" {
+		t.Errorf("transcript = %q, want dropped", s.TranscriptRaw)
+	}
+	if s.OCRTextRaw != hashValue(secret, "PROJECT ATLAS") || !strings.HasPrefix(s.OCRTextRaw, hashedPrefix) {
+		t.Errorf("ocr = %q, want hashed", s.OCRTextRaw)
+	}
+	if len(s.PeopleClusterIDs) != 1 || s.PeopleClusterIDs[0] != hashValue(secret, "cluster-1") {
+		t.Errorf("people = %v, want hashed cluster ID", s.PeopleClusterIDs)
+	}
+	if hashValue([]byte("other-install"), "PROJECT ATLAS") == s.OCRTextRaw {
+		t.Error("digest does not depend on the install secret")
+	}
+}
+
+func TestPrivacySecret_GeneratedOnce(t *testing.T) {
+	_, repo := setupRunnerTest(t, &fakePipeRunner{}, &pipelines.Capabilities{})
+	ctx := context.Background()
+
+	first, err := privacySecret(ctx, repo)
+	if err != nil {
+		t.Fatalf("privacySecret: %v", err)
+	}
+	second, _ := privacySecret(ctx, repo)
+	if len(first) != 64 || string(first) != string(second) {
+		t.Errorf("secrets = %q, %q, want one stable 32-byte hex secret", first, second)
+	}
+}
+
+func TestLoadScenes_NamesHashedClusters(t *testing.T) {
+	artifacts := t.TempDir()
+	_, repo := setupRunnerTest(t, &fakePipeRunner{}, &pipelines.Capabilities{})
+	ctx := context.Background()
+	_, file := createTestJobAndFile(t, repo)
+	writeSceneResult(t, artifacts, file.ID)
+
+	secret, _ := privacySecret(ctx, repo)
+	repo.UpsertPersonName(ctx, &PersonName{FileID: file.ID, ClusterID: hashValue(secret, "cluster-abc"), Name: "Mina", UpdatedAt: time.Now()})
+
+	scenes, err := LoadScenes(ctx, repo, artifacts, file.ID)
+	if err != nil {
+		t.Fatalf("LoadScenes: %v", err)
+	}
+	if len(scenes) != 1 || scenes[0].People[0] != (ScenePerson{ClusterID: "cluster-abc", Name: "Mina"}) {
+		t.Errorf("people = %+v, want cluster-abc named from its hashed ID", scenes[0].People)
+	}
+}
+
+func TestAuditFields_Scenes(t *testing.T) {
+	payload := []byte(`{"video_id":"v","video_title":"clip","scenes":[{"scene_id":"s","start_ms":0,"end_ms":1,
+		"transcript_raw":"hmac-sha256:abc","ocr_text_raw":"menu","keyword_tags":["cta"]}]}`)
+
+	got := auditFields(OutboxKindScenes, payload)
+	want := []string{"keyword_tags", "ocr_text", "scene_timing", "transcript:hashed", "video_title"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("auditFields = %v, want %v", got, want)
+	}
+}
+
+func TestSidecarAllowed(t *testing.T) {
+	policy := PrivacyPolicy{Transcript: PrivacyHash, Faces: PrivacySend}
+	if sidecarAllowed("speech", policy) {
+		t.Error("speech sidecar allowed while transcripts are hashed")
+	}
+	if !sidecarAllowed("faces", policy) {
+		t.Error("faces sidecar withheld while faces are sent")
+	}
+	if !sidecarAllowed("speech", PrivacyPolicy{}) {
+		t.Error("default policy withholds the speech sidecar")
+	}
+}
+
+func TestUploadScenes_AppliesPrivacyAndAudits(t *testing.T) {
+	fake := &fakePipeRunner{artifacts: t.TempDir()}
+	runner, repo := setupRunnerTest(t, fake, &pipelines.Capabilities{HasScenes: true, ProbedAt: time.Now()})
+
+	var sent cloud.SceneIngestPayload
+	runner.SetCloudClient(&fakeCloudClient{scenes: &fakeSceneUploader{
+		uploadFn: func(_ context.Context, payload cloud.SceneIngestPayload) error {
+			sent = payload
+			return nil
+		},
+	}}, "lib-1")
+
+	ctx := context.Background()
+	_, file := createTestJobAndFile(t, repo)
+	writeSceneResult(t, fake.artifacts, file.ID)
+	if err := repo.UpdateSourcePrivacy(ctx, file.SourceID, PrivacyPolicy{Transcript: PrivacyDrop, Faces: PrivacyHash}); err != nil {
+		t.Fatalf("UpdateSourcePrivacy: %v", err)
+	}
+
+	if err := runner.enqueueScenes(ctx, file, filepath.Join(fake.artifacts, file.ID)); err != nil {
+		t.Fatalf("enqueueScenes: %v", err)
+	}
+
+	if len(sent.Scenes) != 1 {
+		t.Fatalf("scenes sent = %d, want 1", len(sent.Scenes))
+	}
+	if sent.Scenes[0].TranscriptRaw != "" {
+		t.Errorf("transcript sent despite drop policy: %q", sent.Scenes[0].TranscriptRaw)
+	}
+	if ids := sent.Scenes[0].PeopleClusterIDs; len(ids) != 2 || !strings.HasPrefix(ids[0], hashedPrefix) {
+		t.Errorf("people = %v, want hashed IDs", ids)
+	}
+
+	records, err := repo.ListCloudAudit(ctx, file.ID)
+	if err != nil {
+		t.Fatalf("ListCloudAudit: %v", err)
+	}
+	if len(records) != 1 || records[0].Kind != OutboxKindScenes {
+		t.Fatalf("audit records = %+v, want one scenes record", records)
+	}
+	fields := strings.Join(records[0].Fields, ",")
+	if strings.Contains(fields, "transcript") || !strings.Contains(fields, "people_cluster_ids:hashed") {
+		t.Errorf("audit fields = %v", records[0].Fields)
+	}
+}
+
+func TestUploadArtifacts_WithholdsSidecarsByPolicy(t *testing.T) {
+	fake := &fakePipeRunner{artifacts: t.TempDir()}
+	runner, repo := setupRunnerTest(t, fake, &pipelines.Capabilities{ProbedAt: time.Now()})
+
+	upload := &fakeUploadService{}
+	runner.SetCloudClient(&fakeCloudClient{upload: upload}, "lib-1")
+
+	ctx := context.Background()
+	_, file := createTestJobAndFile(t, repo)
+	writeSidecarResult(t, fake.artifacts, file.ID, "speech")
+	writeSidecarResult(t, fake.artifacts, file.ID, "faces")
+	if err := repo.UpdateSourcePrivacy(ctx, file.SourceID, PrivacyPolicy{Transcript: PrivacyHash}); err != nil {
+		t.Fatalf("UpdateSourcePrivacy: %v", err)
+	}
+
+	if _, err := runner.uploadArtifacts(ctx, file); err != nil {
+		t.Fatalf("uploadArtifacts: %v", err)
+	}
+	if !reflect.DeepEqual(upload.sidecars, []string{"faces"}) {
+		t.Fatalf("sidecars = %v, want only faces", upload.sidecars)
+	}
+
+	records, _ := repo.ListCloudAudit(ctx, file.ID)
+	kinds := map[string]bool{}
+	for _, r := range records {
+		kinds[r.Kind] = true
+	}
+	if !kinds["sidecar:faces"] || kinds["sidecar:speech"] {
+		t.Errorf("audit kinds = %v, want sidecar:faces only among sidecars", kinds)
+	}
+}
@@ -0,0 +1,279 @@
+package catalog
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/heimdex/heimdex-agent/internal/playback"
+)
+
+// ProxyConfig controls playback proxy generation. Proxies are disabled
+// when Dir is empty or Height is 0.
+type ProxyConfig struct {
+	Dir    string
+	Height int
+}
+
+func (c ProxyConfig) enabled() bool {
+	return c.Dir != "" && c.Height > 0
+}
+
+// SetProxyConfig enables generate_proxy jobs after indexing.
+func (r *Runner) SetProxyConfig(cfg ProxyConfig) {
+	r.proxy = cfg
+}
+
+// SetCacheBudget puts proxies under the budget shared with the other
+// playback caches.
+func (r *Runner) SetCacheBudget(b *playback.CacheBudget) {
+	r.cacheBudget = b
+	b.Add(proxyCache{r})
+}
+
+// ProxyEnabled reports whether proxies are generated.
+func (r *Runner) ProxyEnabled() bool {
+	return r.proxy.enabled()
+}
+
+// ReadyProxy returns the file's proxy when it can be served, or nil, and
+// records the access so recently played proxies are evicted last.
+func (r *Runner) ReadyProxy(ctx context.Context, file *File) (*FileProxy, error) {
+	p, err := r.repo.GetFileProxy(ctx, file.ID)
+	if err != nil || !r.proxyUsable(p, file) {
+		return nil, err
+	}
+	now := time.Now()
+	if err := r.repo.TouchFileProxy(ctx, file.ID, now); err != nil {
+		r.logger.Warn("failed to record proxy access", "file_id", file.ID, "error", err)
+	}
+	p.LastAccessedAt = now
+	return p, nil
+}
+
+// EnsureProxy queues a generate_proxy job for a file whose proxy is missing,
+// evicted, failed or stale. It reports whether a job was queued.
+func (r *Runner) EnsureProxy(ctx context.Context, fileID string) (bool, error) {
+	if !r.proxy.enabled() {
+		return false, nil
+	}
+	file, err := r.repo.GetFile(ctx, fileID)
+	if err != nil || file == nil {
+		return false, err
+	}
+	existing, err := r.repo.GetFileProxy(ctx, fileID)
+	if err != nil {
+		return false, err
+	}
+	if existing != nil && existing.Status == ProxyStatusPending {
+		return false, nil
+	}
+	if r.proxyUsable(existing, file) {
+		return false, nil
+	}
+	return true, r.queueProxy(ctx, file)
+}
+
+func (r *Runner) queueProxy(ctx context.Context, file *File) error {
+	now := time.Now()
+	if err := r.repo.UpsertFileProxy(ctx, &FileProxy{
+		FileID:      file.ID,
+		Status:      ProxyStatusPending,
+		Height:      r.proxy.Height,
+		Fingerprint: file.Fingerprint,
+		UpdatedAt:   now,
+	}); err != nil {
+		return err
+	}
+	r.enqueueFileJob(ctx, JobTypeGenerateProxy, file.ID)
+	return nil
+}
+
+// proxyUsable reports whether p is a ready proxy of the file's current
+// contents, at the configured height, that is still on disk.
+func (r *Runner) proxyUsable(p *FileProxy, file *File) bool {
+	if p == nil || p.Status != ProxyStatusReady || p.Fingerprint != file.Fingerprint || p.Height != r.proxy.Height {
+		return false
+	}
+	_, err := os.Stat(p.Path)
+	return err == nil
+}
+
+// backfillProxies queues proxies for files indexed before proxies were
+// enabled, or whose proxy was lost.
+func (r *Runner) backfillProxies(ctx context.Context) {
+	files, err := r.indexedFiles(ctx)
+	if err != nil {
+		r.logger.Warn("proxy backfill: cannot list files", "error", err)
+		return
+	}
+
+	queued := 0
+	for _, file := range files {
+		fileID := file.ID
+		existing, err := r.repo.GetFileProxy(ctx, fileID)
+		if err != nil {
+			continue
+		}
+		// Evicted and failed proxies are made again on demand, not here,
+		// or the budget would churn on every start.
+		if existing != nil && existing.Status != ProxyStatusReady {
+			continue
+		}
+		if ok, err := r.EnsureProxy(ctx, fileID); err != nil {
+			r.logger.Warn("proxy backfill: queue failed", "file_id", fileID, "error", err)
+		} else if ok {
+			queued++
+		}
+	}
+	if queued > 0 {
+		r.logger.Info("proxy backfill queued", "count", queued)
+	}
+}
+
+func (r *Runner) processGenerateProxyJob(ctx context.Context, job *Job) {
+	if !r.proxy.enabled() || r.ffmpeg == nil {
+		r.repo.UpdateJobStatus(ctx, job.ID, JobStatusFailed, "proxy generation not configured")
+		return
+	}
+
+	r.repo.UpdateJobStatus(ctx, job.ID, JobStatusRunning, "")
+
+	file, err := r.repo.GetFile(ctx, job.FileID)
+	if err != nil || file == nil {
+		r.repo.UpdateJobStatus(ctx, job.ID, JobStatusFailed, "file not found")
+		return
+	}
+
+	existing, _ := r.repo.GetFileProxy(ctx, file.ID)
+	if r.proxyUsable(existing, file) {
+		r.repo.UpdateJobStatus(ctx, job.ID, JobStatusCompleted, "")
+		return
+	}
+
+	proxy, err := r.generateProxy(ctx, file)
+	if err != nil {
+		r.logger.Warn("proxy generation failed", "job_id", job.ID, "file_id", file.ID, "error", err)
+		r.repo.UpsertFileProxy(ctx, &FileProxy{
+			FileID:      file.ID,
+			Status:      ProxyStatusFailed,
+			Height:      r.proxy.Height,
+			Fingerprint: file.Fingerprint,
+			Error:       truncateStr(err.Error(), 512),
+			UpdatedAt:   time.Now(),
+		})
+		r.repo.UpdateJobStatus(ctx, job.ID, JobStatusFailed, err.Error())
+		return
+	}
+
+	r.logger.Info("proxy generated", "file_id", file.ID, "height", proxy.Height, "bytes", proxy.SizeBytes)
+	r.repo.UpdateJobStatus(ctx, job.ID, JobStatusCompleted, "")
+	if r.cacheBudget != nil {
+		r.cacheBudget.Enforce()
+	}
+}
+
+func (r *Runner) generateProxy(ctx context.Context, file *File) (*FileProxy, error) {
+	outPath := filepath.Join(r.proxy.Dir, file.ID, fmt.Sprintf("proxy_%dp.mp4", r.proxy.Height))
+	tmpPath := outPath + ".tmp"
+	if err := os.MkdirAll(filepath.Dir(outPath), 0o755); err != nil {
+		return nil, fmt.Errorf("create proxy dir: %w", err)
+	}
+	if err := r.ffmpeg.GenerateProxy(ctx, file.Path, tmpPath, r.proxy.Height); err != nil {
+		os.Remove(tmpPath)
+		return nil, err
+	}
+	info, err := os.Stat(tmpPath)
+	if err != nil || info.Size() == 0 {
+		os.Remove(tmpPath)
+		return nil, fmt.Errorf("proxy output missing or empty")
+	}
+	if max := r.cacheMaxBytes(); max > 0 && info.Size() > max {
+		os.Remove(tmpPath)
+		return nil, fmt.Errorf("proxy of %d bytes exceeds the cache budget", info.Size())
+	}
+	if err := os.Rename(tmpPath, outPath); err != nil {
+		os.Remove(tmpPath)
+		return nil, fmt.Errorf("store proxy: %w", err)
+	}
+	removeOtherProxies(filepath.Dir(outPath), outPath)
+
+	proxy := &FileProxy{
+		FileID:      file.ID,
+		Status:      ProxyStatusReady,
+		Path:        outPath,
+		Height:      r.proxy.Height,
+		SizeBytes:   info.Size(),
+		Fingerprint: file.Fingerprint,
+		UpdatedAt:   time.Now(),
+	}
+	if err := r.repo.UpsertFileProxy(ctx, proxy); err != nil {
+		return nil, err
+	}
+	return proxy, nil
+}
+
+// removeOtherProxies deletes proxies of other heights left in dir after the
+// configured height changed.
+func removeOtherProxies(dir, keep string) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	for _, e := range entries {
+		path := filepath.Join(dir, e.Name())
+		if path != keep {
+			os.Remove(path)
+		}
+	}
+}
+
+func (r *Runner) cacheMaxBytes() int64 {
+	if r.cacheBudget == nil {
+		return 0
+	}
+	return r.cacheBudget.MaxBytes()
+}
+
+// proxyCache exposes ready proxies to the cache budget. A proxy was last
+// used when it was last played, or else when it was made.
+type proxyCache struct {
+	r *Runner
+}
+
+func (c proxyCache) CacheEntries() ([]playback.CacheEntry, error) {
+	proxies, err := c.r.repo.ListFileProxies(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	var entries []playback.CacheEntry
+	for _, p := range proxies {
+		if p.Status != ProxyStatusReady {
+			continue
+		}
+		used := p.LastAccessedAt
+		if used.IsZero() {
+			used = p.UpdatedAt
+		}
+		entries = append(entries, playback.CacheEntry{Key: p.FileID, Size: p.SizeBytes, LastUsed: used})
+	}
+	return entries, nil
+}
+
+func (c proxyCache) EvictCacheEntry(fileID string) error {
+	ctx := context.Background()
+	p, err := c.r.repo.GetFileProxy(ctx, fileID)
+	if err != nil || p == nil || p.Status != ProxyStatusReady {
+		return err
+	}
+	if err := os.Remove(p.Path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	p.Status = ProxyStatusEvicted
+	p.Path = ""
+	p.SizeBytes = 0
+	p.UpdatedAt = time.Now()
+	return c.r.repo.UpsertFileProxy(ctx, p)
+}
@@ -0,0 +1,217 @@
+package catalog
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/heimdex/heimdex-agent/internal/pipeline"
+	"github.com/heimdex/heimdex-agent/internal/pipelines"
+	"github.com/heimdex/heimdex-agent/internal/playback"
+)
+
+// fakeProxyFFmpeg writes a proxy of a fixed size instead of transcoding.
+type fakeProxyFFmpeg struct {
+	*pipeline.StubFFmpeg
+	size  int
+	calls int
+}
+
+func (f *fakeProxyFFmpeg) GenerateProxy(ctx context.Context, filePath, outputPath string, height int) error {
+	f.calls++
+	return os.WriteFile(outputPath, []byte(strings.Repeat("x", f.size)), 0o644)
+}
+
+func setupProxyTest(t *testing.T, maxBytes int64) (*Runner, Repository, *fakeProxyFFmpeg) {
+	t.Helper()
+	runner, repo := setupRunnerTest(t, &fakePipeRunner{artifacts: t.TempDir()}, &pipelines.Capabilities{})
+	ff := &fakeProxyFFmpeg{StubFFmpeg: pipeline.NewStubFFmpeg(slog.New(slog.NewTextHandler(io.Discard, nil))), size: 100}
+	runner.ffmpeg = ff
+	runner.SetProxyConfig(ProxyConfig{Dir: t.TempDir(), Height: 540})
+	runner.SetCacheBudget(playback.NewCacheBudget(maxBytes, slog.New(slog.NewTextHandler(io.Discard, nil))))
+	return runner, repo, ff
+}
+
+// runPendingProxyJob runs the oldest pending generate_proxy job.
+func runPendingProxyJob(t *testing.T, runner *Runner, repo Repository) {
+	t.Helper()
+	ctx := context.Background()
+	jobs, err := repo.ListPendingJobs(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, j := range jobs {
+		if j.Type == JobTypeGenerateProxy {
+			runner.processGenerateProxyJob(ctx, j)
+			return
+		}
+	}
+	t.Fatal("no pending generate_proxy job")
+}
+
+func TestRunner_GenerateProxy(t *testing.T) {
+	runner, repo, ff := setupProxyTest(t, 0)
+	ctx := context.Background()
+	_, file := createTestJobAndFile(t, repo)
+
+	queued, err := runner.EnsureProxy(ctx, file.ID)
+	if err != nil || !queued {
+		t.Fatalf("EnsureProxy = %v, %v; want queued", queued, err)
+	}
+	if p, _ := repo.GetFileProxy(ctx, file.ID); p == nil || p.Status != ProxyStatusPending {
+		t.Fatalf("proxy = %+v, want pending", p)
+	}
+
+	runPendingProxyJob(t, runner, repo)
+
+	p, err := repo.GetFileProxy(ctx, file.ID)
+	if err != nil || p == nil {
+		t.Fatalf("GetFileProxy: %v, %v", p, err)
+	}
+	if p.Status != ProxyStatusReady || p.SizeBytes != 100 || p.Height != 540 || p.Fingerprint != file.Fingerprint {
+		t.Errorf("proxy = %+v", p)
+	}
+	if filepath.Base(p.Path) != "proxy_540p.mp4" {
+		t.Errorf("Path = %q", p.Path)
+	}
+	if _, err := os.Stat(p.Path); err != nil {
+		t.Errorf("proxy file missing: %v", err)
+	}
+
+	// A ready proxy is not queued again.
+	if queued, _ := runner.EnsureProxy(ctx, file.ID); queued {
+		t.Error("EnsureProxy queued a ready proxy")
+	}
+
+	ready, err := runner.ReadyProxy(ctx, file)
+	if err != nil || ready == nil {
+		t.Fatalf("ReadyProxy = %v, %v", ready, err)
+	}
+	if p, _ := repo.GetFileProxy(ctx, file.ID); p.LastAccessedAt.IsZero() {
+		t.Error("ReadyProxy should record the access")
+	}
+	if ff.calls != 1 {
+		t.Errorf("GenerateProxy called %d times, want 1", ff.calls)
+	}
+}
+
+func TestRunner_BackfillProxies(t *testing.T) {
+	runner, repo, _ := setupProxyTest(t, 0)
+	ctx := context.Background()
+	_, file := createTestJobAndFile(t, repo)
+	unindexed := &File{ID: NewID(), SourceID: file.SourceID, Path: "/test/videos/b.mp4", Filename: "b.mp4", Mtime: time.Now(), CreatedAt: time.Now()}
+	repo.CreateFile(ctx, unindexed)
+	writeSidecarResult(t, runner.pipeRunner.ArtifactsDir(), file.ID, "faces")
+
+	runner.backfillProxies(ctx)
+
+	if p, _ := repo.GetFileProxy(ctx, file.ID); p == nil || p.Status != ProxyStatusPending {
+		t.Errorf("indexed file proxy = %+v, want pending", p)
+	}
+	if p, _ := repo.GetFileProxy(ctx, unindexed.ID); p != nil {
+		t.Errorf("unindexed file proxy = %+v, want none", p)
+	}
+}
+
+func TestRunner_EnsureProxy_RequeuesStaleProxy(t *testing.T) {
+	runner, repo, _ := setupProxyTest(t, 0)
+	ctx := context.Background()
+	_, file := createTestJobAndFile(t, repo)
+
+	runner.EnsureProxy(ctx, file.ID)
+	runPendingProxyJob(t, runner, repo)
+
+	file.Fingerprint = "changed"
+	if err := repo.UpsertFile(ctx, file); err != nil {
+		t.Fatal(err)
+	}
+	if ready, _ := runner.ReadyProxy(ctx, file); ready != nil {
+		t.Error("ReadyProxy served a proxy of the old contents")
+	}
+	if queued, err := runner.EnsureProxy(ctx, file.ID); err != nil || !queued {
+		t.Errorf("EnsureProxy = %v, %v; want stale proxy queued", queued, err)
+	}
+}
+
+func TestRunner_ProxyBudgetEvictsLeastRecentlyPlayed(t *testing.T) {
+	runner, repo, _ := setupProxyTest(t, 250)
+	ctx := context.Background()
+
+	_, first := createTestJobAndFile(t, repo)
+	files := []*File{first}
+	for _, name := range []string{"b.mp4", "c.mp4"} {
+		f := &File{
+			ID:          NewID(),
+			SourceID:    first.SourceID,
+			Path:        "/test/videos/" + name,
+			Filename:    name,
+			Fingerprint: name,
+			Mtime:       time.Now(),
+			CreatedAt:   time.Now(),
+		}
+		if err := repo.CreateFile(ctx, f); err != nil {
+			t.Fatal(err)
+		}
+		files = append(files, f)
+	}
+
+	runner.EnsureProxy(ctx, files[0].ID)
+	runPendingProxyJob(t, runner, repo)
+	runner.EnsureProxy(ctx, files[1].ID)
+	runPendingProxyJob(t, runner, repo)
+
+	// Playing the first proxy after the second makes the second the
+	// eviction candidate. Both were played before the third is made;
+	// stored times are only precise to the second.
+	repo.TouchFileProxy(ctx, files[1].ID, time.Now().Add(-2*time.Minute))
+	repo.TouchFileProxy(ctx, files[0].ID, time.Now().Add(-time.Minute))
+
+	runner.EnsureProxy(ctx, files[2].ID)
+	runPendingProxyJob(t, runner, repo)
+
+	want := map[string]string{
+		files[0].ID: ProxyStatusReady,
+		files[1].ID: ProxyStatusEvicted,
+		files[2].ID: ProxyStatusReady,
+	}
+	for id, status := range want {
+		p, _ := repo.GetFileProxy(ctx, id)
+		if p == nil || p.Status != status {
+			t.Errorf("proxy %s = %+v, want %s", id, p, status)
+		}
+	}
+}
+
+func TestRunner_ProxyLargerThanBudgetFails(t *testing.T) {
+	runner, repo, _ := setupProxyTest(t, 50)
+	ctx := context.Background()
+	_, file := createTestJobAndFile(t, repo)
+
+	runner.EnsureProxy(ctx, file.ID)
+	runPendingProxyJob(t, runner, repo)
+
+	p, _ := repo.GetFileProxy(ctx, file.ID)
+	if p == nil || p.Status != ProxyStatusFailed || !strings.Contains(p.Error, "budget") {
+		t.Errorf("proxy = %+v, want failed over budget", p)
+	}
+}
+
+func TestRunner_NextRunnableJob_ProxyJobsRunLast(t *testing.T) {
+	runner, _, _ := setupProxyTest(t, 0)
+
+	jobs := []*Job{
+		{ID: "a", Type: JobTypeGenerateProxy},
+		{ID: "b", Type: JobTypeIndex},
+	}
+	if job := runner.nextRunnableJob(jobs); job == nil || job.ID != "b" {
+		t.Fatalf("nextRunnableJob = %v, want the index job ahead of the proxy", job)
+	}
+	if job := runner.nextRunnableJob(jobs[:1]); job == nil || job.ID != "a" {
+		t.Fatalf("nextRunnableJob = %v, want the proxy job when nothing else waits", job)
+	}
+}
@@ -3,6 +3,8 @@ package catalog
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
+	"strings"
 	"time"
 )
 
@@ -14,6 +16,8 @@ type Repository interface {
 	DeleteSource(ctx context.Context, id string) error
 	UpdateSourcePresent(ctx context.Context, id string, present bool) error
 	UpdateSourceCloudLibraryID(ctx context.Context, id, cloudLibraryID string) error
+	UpdateSourceCloudSync(ctx context.Context, id, cloudLibraryID string, disabled bool) error
+	UpdateSourcePrivacy(ctx context.Context, id string, policy PrivacyPolicy) error
 
 	CreateFile(ctx context.Context, file *File) error
 	GetFile(ctx context.Context, id string) (*File, error)
@@ -22,16 +26,46 @@ type Repository interface {
 	DeleteFilesBySource(ctx context.Context, sourceID string) error
 	UpsertFile(ctx context.Context, file *File) error
 	CountFiles(ctx context.Context) (int, error)
+	CountFilesBySource(ctx context.Context) (map[string]int, error)
 
 	CreateJob(ctx context.Context, job *Job) error
 	GetJob(ctx context.Context, id string) (*Job, error)
 	ListJobs(ctx context.Context, limit int) ([]*Job, error)
 	ListPendingJobs(ctx context.Context) ([]*Job, error)
+	ListJobFileIDs(ctx context.Context, jobType string, statuses ...string) ([]string, error)
 	UpdateJobStatus(ctx context.Context, id, status, errorMsg string) error
 	UpdateJobProgress(ctx context.Context, id string, progress int) error
 
 	GetConfig(ctx context.Context, key string) (string, error)
 	SetConfig(ctx context.Context, key, value string) error
+
+	UpsertThumbnailUpload(ctx context.Context, upload *ThumbnailUpload) error
+	ListThumbnailUploads(ctx context.Context, fileID string) ([]*ThumbnailUpload, error)
+	GetThumbnailUploadBySHA256(ctx context.Context, sha256 string) (*ThumbnailUpload, error)
+
+	EnqueueOutbox(ctx context.Context, entry *OutboxEntry) (bool, error)
+	ListDueOutbox(ctx context.Context, now time.Time, limit int) ([]*OutboxEntry, error)
+	UpdateOutboxEntry(ctx context.Context, entry *OutboxEntry) error
+	CountOutbox(ctx context.Context, status string) (int, error)
+	SupersedeOutbox(ctx context.Context, kind, fileID string) error
+	SupersedeSourceOutbox(ctx context.Context, sourceID string) (int, error)
+
+	GetCloudSyncState(ctx context.Context, fileID, kind string) (*CloudSyncState, error)
+	UpsertCloudSyncState(ctx context.Context, state *CloudSyncState) error
+	ClearCloudSyncState(ctx context.Context, kind string) (int, error)
+
+	InsertCloudAudit(ctx context.Context, a *CloudAudit) error
+	ListCloudAudit(ctx context.Context, fileID string) ([]*CloudAudit, error)
+
+	UpsertFileProxy(ctx context.Context, p *FileProxy) error
+	GetFileProxy(ctx context.Context, fileID string) (*FileProxy, error)
+	ListFileProxies(ctx context.Context) ([]*FileProxy, error)
+	TouchFileProxy(ctx context.Context, fileID string, at time.Time) error
+
+	UpsertSceneAnnotation(ctx context.Context, a *SceneAnnotation) (bool, error)
+	ListSceneAnnotations(ctx context.Context, fileID string) ([]*SceneAnnotation, error)
+	UpsertPersonName(ctx context.Context, p *PersonName) (bool, error)
+	ListPersonNames(ctx context.Context, fileID string) ([]*PersonName, error)
 }
 
 type SQLiteRepository struct {
@@ -44,15 +78,17 @@ func NewRepository(db *sql.DB) *SQLiteRepository {
 
 func (r *SQLiteRepository) CreateSource(ctx context.Context, s *Source) error {
 	_, err := r.db.ExecContext(ctx, `
-		INSERT INTO sources (id, type, path, display_name, drive_nickname, cloud_library_id, present, created_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
-	`, s.ID, s.Type, s.Path, s.DisplayName, nullString(s.DriveNickname), nullString(s.CloudLibraryID), boolToInt(s.Present), s.CreatedAt.Format(time.RFC3339))
+		INSERT INTO sources (id, type, path, display_name, drive_nickname, cloud_library_id, cloud_sync_disabled, privacy_transcript, privacy_ocr, privacy_faces, present, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, s.ID, s.Type, s.Path, s.DisplayName, nullString(s.DriveNickname), nullString(s.CloudLibraryID), boolToInt(s.CloudSyncDisabled),
+		privacyAction(s.Privacy.Transcript), privacyAction(s.Privacy.OCR), privacyAction(s.Privacy.Faces),
+		boolToInt(s.Present), s.CreatedAt.Format(time.RFC3339))
 	return err
 }
 
 func (r *SQLiteRepository) GetSource(ctx context.Context, id string) (*Source, error) {
 	row := r.db.QueryRowContext(ctx, `
-		SELECT id, type, path, display_name, drive_nickname, cloud_library_id, present, created_at
+		SELECT id, type, path, display_name, drive_nickname, cloud_library_id, cloud_sync_disabled, privacy_transcript, privacy_ocr, privacy_faces, present, created_at
 		FROM sources WHERE id = ?
 	`, id)
 	return r.scanSource(row)
@@ -60,7 +96,7 @@ func (r *SQLiteRepository) GetSource(ctx context.Context, id string) (*Source, e
 
 func (r *SQLiteRepository) GetSourceByPath(ctx context.Context, path string) (*Source, error) {
 	row := r.db.QueryRowContext(ctx, `
-		SELECT id, type, path, display_name, drive_nickname, cloud_library_id, present, created_at
+		SELECT id, type, path, display_name, drive_nickname, cloud_library_id, cloud_sync_disabled, privacy_transcript, privacy_ocr, privacy_faces, present, created_at
 		FROM sources WHERE path = ?
 	`, path)
 	return r.scanSource(row)
@@ -68,12 +104,12 @@ func (r *SQLiteRepository) GetSourceByPath(ctx context.Context, path string) (*S
 
 func (r *SQLiteRepository) scanSource(row *sql.Row) (*Source, error) {
 	var s Source
-	var present int
+	var present, syncDisabled int
 	var createdAt string
 	var driveNickname sql.NullString
 	var cloudLibraryID sql.NullString
 
-	err := row.Scan(&s.ID, &s.Type, &s.Path, &s.DisplayName, &driveNickname, &cloudLibraryID, &present, &createdAt)
+	err := row.Scan(&s.ID, &s.Type, &s.Path, &s.DisplayName, &driveNickname, &cloudLibraryID, &syncDisabled, &s.Privacy.Transcript, &s.Privacy.OCR, &s.Privacy.Faces, &present, &createdAt)
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
@@ -82,6 +118,7 @@ func (r *SQLiteRepository) scanSource(row *sql.Row) (*Source, error) {
 	}
 
 	s.Present = present == 1
+	s.CloudSyncDisabled = syncDisabled == 1
 	s.DriveNickname = driveNickname.String
 	s.CloudLibraryID = cloudLibraryID.String
 	s.CreatedAt, _ = time.Parse(time.RFC3339, createdAt)
@@ -90,7 +127,7 @@ func (r *SQLiteRepository) scanSource(row *sql.Row) (*Source, error) {
 
 func (r *SQLiteRepository) ListSources(ctx context.Context) ([]*Source, error) {
 	rows, err := r.db.QueryContext(ctx, `
-		SELECT id, type, path, display_name, drive_nickname, cloud_library_id, present, created_at
+		SELECT id, type, path, display_name, drive_nickname, cloud_library_id, cloud_sync_disabled, privacy_transcript, privacy_ocr, privacy_faces, present, created_at
 		FROM sources ORDER BY created_at DESC
 	`)
 	if err != nil {
@@ -101,15 +138,16 @@ func (r *SQLiteRepository) ListSources(ctx context.Context) ([]*Source, error) {
 	var sources []*Source
 	for rows.Next() {
 		var s Source
-		var present int
+		var present, syncDisabled int
 		var createdAt string
 		var driveNickname sql.NullString
 		var cloudLibraryID sql.NullString
 
-		if err := rows.Scan(&s.ID, &s.Type, &s.Path, &s.DisplayName, &driveNickname, &cloudLibraryID, &present, &createdAt); err != nil {
+		if err := rows.Scan(&s.ID, &s.Type, &s.Path, &s.DisplayName, &driveNickname, &cloudLibraryID, &syncDisabled, &s.Privacy.Transcript, &s.Privacy.OCR, &s.Privacy.Faces, &present, &createdAt); err != nil {
 			return nil, err
 		}
 		s.Present = present == 1
+		s.CloudSyncDisabled = syncDisabled == 1
 		s.DriveNickname = driveNickname.String
 		s.CloudLibraryID = cloudLibraryID.String
 		s.CreatedAt, _ = time.Parse(time.RFC3339, createdAt)
@@ -133,6 +171,29 @@ func (r *SQLiteRepository) UpdateSourceCloudLibraryID(ctx context.Context, id, c
 	return err
 }
 
+// UpdateSourceCloudSync sets the source's library mapping and sync opt-out
+// together. An empty cloudLibraryID clears the mapping.
+func (r *SQLiteRepository) UpdateSourceCloudSync(ctx context.Context, id, cloudLibraryID string, disabled bool) error {
+	_, err := r.db.ExecContext(ctx, "UPDATE sources SET cloud_library_id = ?, cloud_sync_disabled = ? WHERE id = ?",
+		nullString(cloudLibraryID), boolToInt(disabled), id)
+	return err
+}
+
+// UpdateSourcePrivacy replaces the source's privacy policy. Empty actions
+// are stored as send.
+func (r *SQLiteRepository) UpdateSourcePrivacy(ctx context.Context, id string, policy PrivacyPolicy) error {
+	_, err := r.db.ExecContext(ctx, "UPDATE sources SET privacy_transcript = ?, privacy_ocr = ?, privacy_faces = ? WHERE id = ?",
+		privacyAction(policy.Transcript), privacyAction(policy.OCR), privacyAction(policy.Faces), id)
+	return err
+}
+
+func privacyAction(action string) string {
+	if action == "" {
+		return PrivacySend
+	}
+	return action
+}
+
 func (r *SQLiteRepository) CreateFile(ctx context.Context, f *File) error {
 	_, err := r.db.ExecContext(ctx, `
 		INSERT INTO files (id, source_id, path, filename, size, mtime, fingerprint, created_at)
@@ -232,6 +293,27 @@ func (r *SQLiteRepository) CountFiles(ctx context.Context) (int, error) {
 	return count, err
 }
 
+// CountFilesBySource returns the number of files in each source that has
+// any.
+func (r *SQLiteRepository) CountFilesBySource(ctx context.Context) (map[string]int, error) {
+	rows, err := r.db.QueryContext(ctx, "SELECT source_id, COUNT(*) FROM files GROUP BY source_id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := make(map[string]int)
+	for rows.Next() {
+		var sourceID string
+		var n int
+		if err := rows.Scan(&sourceID, &n); err != nil {
+			return nil, err
+		}
+		counts[sourceID] = n
+	}
+	return counts, rows.Err()
+}
+
 func (r *SQLiteRepository) CreateJob(ctx context.Context, j *Job) error {
 	_, err := r.db.ExecContext(ctx, `
 		INSERT INTO jobs (id, type, status, source_id, file_id, progress, error, created_at, updated_at)
@@ -287,6 +369,34 @@ func (r *SQLiteRepository) ListJobs(ctx context.Context, limit int) ([]*Job, err
 	return r.scanJobs(rows)
 }
 
+// ListJobFileIDs returns the distinct files that have a job of jobType in
+// one of statuses, or in any status when none are given.
+func (r *SQLiteRepository) ListJobFileIDs(ctx context.Context, jobType string, statuses ...string) ([]string, error) {
+	query := "SELECT DISTINCT file_id FROM jobs WHERE type = ? AND file_id IS NOT NULL"
+	args := []interface{}{jobType}
+	if len(statuses) > 0 {
+		query += " AND status IN (?" + strings.Repeat(", ?", len(statuses)-1) + ")"
+		for _, s := range statuses {
+			args = append(args, s)
+		}
+	}
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
 func (r *SQLiteRepository) ListPendingJobs(ctx context.Context) ([]*Job, error) {
 	rows, err := r.db.QueryContext(ctx, `
 		SELECT id, type, status, source_id, file_id, progress, error, created_at, updated_at
@@ -351,6 +461,452 @@ func (r *SQLiteRepository) SetConfig(ctx context.Context, key, value string) err
 	return err
 }
 
+func (r *SQLiteRepository) UpsertThumbnailUpload(ctx context.Context, upload *ThumbnailUpload) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO thumbnail_uploads (scene_id, file_id, sha256, object_key, url, uploaded_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT(scene_id) DO UPDATE SET
+			file_id = excluded.file_id,
+			sha256 = excluded.sha256,
+			object_key = excluded.object_key,
+			url = excluded.url,
+			uploaded_at = excluded.uploaded_at
+	`, upload.SceneID, upload.FileID, upload.SHA256, upload.ObjectKey, nullString(upload.URL), upload.UploadedAt.Format(time.RFC3339))
+	return err
+}
+
+func (r *SQLiteRepository) ListThumbnailUploads(ctx context.Context, fileID string) ([]*ThumbnailUpload, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT scene_id, file_id, sha256, object_key, url, uploaded_at
+		FROM thumbnail_uploads WHERE file_id = ? ORDER BY scene_id
+	`, fileID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var uploads []*ThumbnailUpload
+	for rows.Next() {
+		u, err := scanThumbnailUpload(rows)
+		if err != nil {
+			return nil, err
+		}
+		uploads = append(uploads, u)
+	}
+	return uploads, rows.Err()
+}
+
+func (r *SQLiteRepository) GetThumbnailUploadBySHA256(ctx context.Context, sha256 string) (*ThumbnailUpload, error) {
+	row := r.db.QueryRowContext(ctx, `
+		SELECT scene_id, file_id, sha256, object_key, url, uploaded_at
+		FROM thumbnail_uploads WHERE sha256 = ? LIMIT 1
+	`, sha256)
+	u, err := scanThumbnailUpload(row)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return u, err
+}
+
+// EnqueueOutbox inserts entry, or revives a superseded or failed entry with
+// the same idempotency key. Sent entries tied to a file are revived too: the
+// caller checks cloud_sync_state first, so reaching here means the cloud no
+// longer holds that payload. Pending entries are left alone. Older pending
+// entries of the same kind for the same file are superseded since only the
+// latest state matters. It reports whether anything was queued.
+func (r *SQLiteRepository) EnqueueOutbox(ctx context.Context, entry *OutboxEntry) (bool, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	now := time.Now().UTC().Format(time.RFC3339)
+	res, err := tx.ExecContext(ctx, `
+		INSERT INTO cloud_outbox (id, kind, file_id, payload, idempotency_key, status, attempts, next_attempt_at, last_error, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(idempotency_key) DO UPDATE SET
+			status = excluded.status,
+			attempts = 0,
+			next_attempt_at = excluded.next_attempt_at,
+			last_error = NULL,
+			updated_at = excluded.updated_at
+		WHERE cloud_outbox.status IN ('superseded', 'failed')
+			OR (cloud_outbox.status = 'sent' AND cloud_outbox.file_id IS NOT NULL)
+	`, entry.ID, entry.Kind, nullString(entry.FileID), entry.Payload, entry.IdempotencyKey, entry.Status,
+		entry.Attempts, entry.NextAttemptAt.UTC().Format(time.RFC3339), nullString(entry.LastError), now, now)
+	if err != nil {
+		return false, err
+	}
+	inserted, _ := res.RowsAffected()
+	if inserted == 0 {
+		return false, tx.Commit()
+	}
+
+	if entry.FileID != "" {
+		if _, err := tx.ExecContext(ctx, `
+			UPDATE cloud_outbox SET status = ?, updated_at = ?
+			WHERE kind = ? AND file_id = ? AND status = ? AND idempotency_key != ?
+		`, OutboxStatusSuperseded, now, entry.Kind, entry.FileID, OutboxStatusPending, entry.IdempotencyKey); err != nil {
+			return false, err
+		}
+	}
+	return true, tx.Commit()
+}
+
+func (r *SQLiteRepository) ListDueOutbox(ctx context.Context, now time.Time, limit int) ([]*OutboxEntry, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, kind, file_id, payload, idempotency_key, status, attempts, next_attempt_at, last_error, created_at, updated_at
+		FROM cloud_outbox
+		WHERE status = ? AND next_attempt_at <= ?
+		ORDER BY created_at ASC
+		LIMIT ?
+	`, OutboxStatusPending, now.UTC().Format(time.RFC3339), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []*OutboxEntry
+	for rows.Next() {
+		var e OutboxEntry
+		var fileID, lastError sql.NullString
+		var nextAttemptAt, createdAt, updatedAt string
+		if err := rows.Scan(&e.ID, &e.Kind, &fileID, &e.Payload, &e.IdempotencyKey, &e.Status, &e.Attempts,
+			&nextAttemptAt, &lastError, &createdAt, &updatedAt); err != nil {
+			return nil, err
+		}
+		e.FileID = fileID.String
+		e.LastError = lastError.String
+		e.NextAttemptAt, _ = time.Parse(time.RFC3339, nextAttemptAt)
+		e.CreatedAt, _ = time.Parse(time.RFC3339, createdAt)
+		e.UpdatedAt, _ = time.Parse(time.RFC3339, updatedAt)
+		entries = append(entries, &e)
+	}
+	return entries, rows.Err()
+}
+
+// UpdateOutboxEntry persists delivery state: status, attempts, next attempt
+// and last error.
+func (r *SQLiteRepository) UpdateOutboxEntry(ctx context.Context, entry *OutboxEntry) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE cloud_outbox
+		SET status = ?, attempts = ?, next_attempt_at = ?, last_error = ?, updated_at = ?
+		WHERE id = ?
+	`, entry.Status, entry.Attempts, entry.NextAttemptAt.UTC().Format(time.RFC3339), nullString(entry.LastError),
+		time.Now().UTC().Format(time.RFC3339), entry.ID)
+	return err
+}
+
+func (r *SQLiteRepository) CountOutbox(ctx context.Context, status string) (int, error) {
+	var count int
+	err := r.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM cloud_outbox WHERE status = ?", status).Scan(&count)
+	return count, err
+}
+
+// SupersedeOutbox drops pending entries of kind for fileID, used when the
+// cloud already holds the current payload.
+func (r *SQLiteRepository) SupersedeOutbox(ctx context.Context, kind, fileID string) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE cloud_outbox SET status = ?, updated_at = ?
+		WHERE kind = ? AND file_id = ? AND status = ?
+	`, OutboxStatusSuperseded, time.Now().UTC().Format(time.RFC3339), kind, fileID, OutboxStatusPending)
+	return err
+}
+
+// SupersedeSourceOutbox drops the pending and failed entries of every file
+// in a source, used when the source stops syncing or its privacy policy is
+// tightened. It returns how many entries were dropped.
+func (r *SQLiteRepository) SupersedeSourceOutbox(ctx context.Context, sourceID string) (int, error) {
+	res, err := r.db.ExecContext(ctx, `
+		UPDATE cloud_outbox SET status = ?, updated_at = ?
+		WHERE status IN (?, ?) AND file_id IN (SELECT id FROM files WHERE source_id = ?)
+	`, OutboxStatusSuperseded, time.Now().UTC().Format(time.RFC3339), OutboxStatusPending, OutboxStatusFailed, sourceID)
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	return int(n), err
+}
+
+func (r *SQLiteRepository) GetCloudSyncState(ctx context.Context, fileID, kind string) (*CloudSyncState, error) {
+	var st CloudSyncState
+	var syncedAt string
+	err := r.db.QueryRowContext(ctx, `
+		SELECT file_id, kind, payload_hash, synced_at
+		FROM cloud_sync_state WHERE file_id = ? AND kind = ?
+	`, fileID, kind).Scan(&st.FileID, &st.Kind, &st.PayloadHash, &syncedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	st.SyncedAt, _ = time.Parse(time.RFC3339, syncedAt)
+	return &st, nil
+}
+
+func (r *SQLiteRepository) UpsertCloudSyncState(ctx context.Context, state *CloudSyncState) error {
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO cloud_sync_state (file_id, kind, payload_hash, synced_at)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT(file_id, kind) DO UPDATE SET
+			payload_hash = excluded.payload_hash,
+			synced_at = excluded.synced_at
+	`, state.FileID, state.Kind, state.PayloadHash, state.SyncedAt.UTC().Format(time.RFC3339))
+	return err
+}
+
+// ClearCloudSyncState forgets what was sent for kind so the next enqueue
+// uploads again. It returns the number of files affected.
+func (r *SQLiteRepository) ClearCloudSyncState(ctx context.Context, kind string) (int, error) {
+	res, err := r.db.ExecContext(ctx, "DELETE FROM cloud_sync_state WHERE kind = ?", kind)
+	if err != nil {
+		return 0, err
+	}
+	n, _ := res.RowsAffected()
+	return int(n), nil
+}
+
+func (r *SQLiteRepository) InsertCloudAudit(ctx context.Context, a *CloudAudit) error {
+	fields, err := json.Marshal(nonNilStrings(a.Fields))
+	if err != nil {
+		return err
+	}
+	_, err = r.db.ExecContext(ctx, `
+		INSERT INTO cloud_audit (id, file_id, kind, payload_hash, fields, sent_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, a.ID, a.FileID, a.Kind, a.PayloadHash, string(fields), a.SentAt.UTC().Format(time.RFC3339))
+	return err
+}
+
+// ListCloudAudit returns the file's audit records, newest first.
+func (r *SQLiteRepository) ListCloudAudit(ctx context.Context, fileID string) ([]*CloudAudit, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT id, file_id, kind, payload_hash, fields, sent_at
+		FROM cloud_audit WHERE file_id = ? ORDER BY sent_at DESC, rowid DESC
+	`, fileID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var records []*CloudAudit
+	for rows.Next() {
+		var a CloudAudit
+		var fields, sentAt string
+		if err := rows.Scan(&a.ID, &a.FileID, &a.Kind, &a.PayloadHash, &fields, &sentAt); err != nil {
+			return nil, err
+		}
+		json.Unmarshal([]byte(fields), &a.Fields)
+		a.SentAt, _ = time.Parse(time.RFC3339, sentAt)
+		records = append(records, &a)
+	}
+	return records, rows.Err()
+}
+
+func (r *SQLiteRepository) UpsertFileProxy(ctx context.Context, p *FileProxy) error {
+	var accessed sql.NullString
+	if !p.LastAccessedAt.IsZero() {
+		accessed = nullString(p.LastAccessedAt.UTC().Format(time.RFC3339))
+	}
+	_, err := r.db.ExecContext(ctx, `
+		INSERT INTO file_proxies (file_id, status, path, height, size_bytes, fingerprint, error, last_accessed_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(file_id) DO UPDATE SET
+			status = excluded.status,
+			path = excluded.path,
+			height = excluded.height,
+			size_bytes = excluded.size_bytes,
+			fingerprint = excluded.fingerprint,
+			error = excluded.error,
+			last_accessed_at = COALESCE(excluded.last_accessed_at, file_proxies.last_accessed_at),
+			updated_at = excluded.updated_at
+	`, p.FileID, p.Status, nullString(p.Path), p.Height, p.SizeBytes, nullString(p.Fingerprint), nullString(p.Error),
+		accessed, p.UpdatedAt.UTC().Format(time.RFC3339))
+	return err
+}
+
+func (r *SQLiteRepository) GetFileProxy(ctx context.Context, fileID string) (*FileProxy, error) {
+	row := r.db.QueryRowContext(ctx, `
+		SELECT file_id, status, path, height, size_bytes, fingerprint, error, last_accessed_at, updated_at
+		FROM file_proxies WHERE file_id = ?
+	`, fileID)
+	p, err := scanFileProxy(row)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return p, err
+}
+
+// ListFileProxies returns all proxies, least recently used first. Proxies
+// never played count from when they were made.
+func (r *SQLiteRepository) ListFileProxies(ctx context.Context) ([]*FileProxy, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT file_id, status, path, height, size_bytes, fingerprint, error, last_accessed_at, updated_at
+		FROM file_proxies ORDER BY COALESCE(last_accessed_at, updated_at), file_id
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var proxies []*FileProxy
+	for rows.Next() {
+		p, err := scanFileProxy(rows)
+		if err != nil {
+			return nil, err
+		}
+		proxies = append(proxies, p)
+	}
+	return proxies, rows.Err()
+}
+
+func (r *SQLiteRepository) TouchFileProxy(ctx context.Context, fileID string, at time.Time) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE file_proxies SET last_accessed_at = ? WHERE file_id = ?
+	`, at.UTC().Format(time.RFC3339), fileID)
+	return err
+}
+
+// UpsertSceneAnnotation stores a, unless the stored annotation carries a
+// later edit time. It reports whether a was applied, so replayed or
+// out-of-order pages never roll back a newer edit.
+func (r *SQLiteRepository) UpsertSceneAnnotation(ctx context.Context, a *SceneAnnotation) (bool, error) {
+	added, err := json.Marshal(nonNilStrings(a.TagsAdded))
+	if err != nil {
+		return false, err
+	}
+	removed, err := json.Marshal(nonNilStrings(a.TagsRemoved))
+	if err != nil {
+		return false, err
+	}
+	res, err := r.db.ExecContext(ctx, `
+		INSERT INTO scene_annotations (scene_id, file_id, tags_added, tags_removed, note, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT(scene_id) DO UPDATE SET
+			file_id = excluded.file_id,
+			tags_added = excluded.tags_added,
+			tags_removed = excluded.tags_removed,
+			note = excluded.note,
+			updated_at = excluded.updated_at
+		WHERE excluded.updated_at >= scene_annotations.updated_at
+	`, a.SceneID, a.FileID, string(added), string(removed), nullString(a.Note), a.UpdatedAt.UTC().Format(time.RFC3339))
+	if err != nil {
+		return false, err
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
+
+func (r *SQLiteRepository) ListSceneAnnotations(ctx context.Context, fileID string) ([]*SceneAnnotation, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT scene_id, file_id, tags_added, tags_removed, note, updated_at
+		FROM scene_annotations WHERE file_id = ? ORDER BY scene_id
+	`, fileID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var annotations []*SceneAnnotation
+	for rows.Next() {
+		var a SceneAnnotation
+		var added, removed, updatedAt string
+		var note sql.NullString
+		if err := rows.Scan(&a.SceneID, &a.FileID, &added, &removed, &note, &updatedAt); err != nil {
+			return nil, err
+		}
+		json.Unmarshal([]byte(added), &a.TagsAdded)
+		json.Unmarshal([]byte(removed), &a.TagsRemoved)
+		a.Note = note.String
+		a.UpdatedAt, _ = time.Parse(time.RFC3339, updatedAt)
+		annotations = append(annotations, &a)
+	}
+	return annotations, rows.Err()
+}
+
+// UpsertPersonName stores p under the same last-edit-wins rule as
+// UpsertSceneAnnotation.
+func (r *SQLiteRepository) UpsertPersonName(ctx context.Context, p *PersonName) (bool, error) {
+	res, err := r.db.ExecContext(ctx, `
+		INSERT INTO person_names (file_id, cluster_id, name, updated_at)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT(file_id, cluster_id) DO UPDATE SET
+			name = excluded.name,
+			updated_at = excluded.updated_at
+		WHERE excluded.updated_at >= person_names.updated_at
+	`, p.FileID, p.ClusterID, p.Name, p.UpdatedAt.UTC().Format(time.RFC3339))
+	if err != nil {
+		return false, err
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
+
+func (r *SQLiteRepository) ListPersonNames(ctx context.Context, fileID string) ([]*PersonName, error) {
+	rows, err := r.db.QueryContext(ctx, `
+		SELECT file_id, cluster_id, name, updated_at
+		FROM person_names WHERE file_id = ? ORDER BY cluster_id
+	`, fileID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var names []*PersonName
+	for rows.Next() {
+		var p PersonName
+		var updatedAt string
+		if err := rows.Scan(&p.FileID, &p.ClusterID, &p.Name, &updatedAt); err != nil {
+			return nil, err
+		}
+		p.UpdatedAt, _ = time.Parse(time.RFC3339, updatedAt)
+		names = append(names, &p)
+	}
+	return names, rows.Err()
+}
+
+func nonNilStrings(s []string) []string {
+	if s == nil {
+		return []string{}
+	}
+	return s
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanThumbnailUpload(row rowScanner) (*ThumbnailUpload, error) {
+	var u ThumbnailUpload
+	var url sql.NullString
+	var uploadedAt string
+	if err := row.Scan(&u.SceneID, &u.FileID, &u.SHA256, &u.ObjectKey, &url, &uploadedAt); err != nil {
+		return nil, err
+	}
+	u.URL = url.String
+	u.UploadedAt, _ = time.Parse(time.RFC3339, uploadedAt)
+	return &u, nil
+}
+
+func scanFileProxy(row rowScanner) (*FileProxy, error) {
+	var p FileProxy
+	var path, fingerprint, errMsg, accessed sql.NullString
+	var updatedAt string
+	if err := row.Scan(&p.FileID, &p.Status, &path, &p.Height, &p.SizeBytes, &fingerprint, &errMsg, &accessed, &updatedAt); err != nil {
+		return nil, err
+	}
+	p.Path = path.String
+	p.Fingerprint = fingerprint.String
+	p.Error = errMsg.String
+	if accessed.Valid {
+		p.LastAccessedAt, _ = time.Parse(time.RFC3339, accessed.String)
+	}
+	p.UpdatedAt, _ = time.Parse(time.RFC3339, updatedAt)
+	return &p, nil
+}
+
 func boolToInt(b bool) int {
 	if b {
 		return 1
@@ -16,6 +16,7 @@ import (
 	"github.com/heimdex/heimdex-agent/internal/cloud"
 	"github.com/heimdex/heimdex-agent/internal/pipeline"
 	"github.com/heimdex/heimdex-agent/internal/pipelines"
+	"github.com/heimdex/heimdex-agent/internal/playback"
 )
 
 type Runner struct {
@@ -26,7 +27,11 @@ type Runner struct {
 	ffmpeg                  pipeline.FFmpeg
 	doctor                  *pipelines.CachedDoctor
 	cloudClient             cloud.Client
+	outbox                  *Outbox
 	fallbackLibraryID       string
+	libraryPolicy           string
+	proxy                   ProxyConfig
+	cacheBudget             *playback.CacheBudget
 	logger                  *slog.Logger
 	pollInterval            time.Duration
 	running                 atomic.Bool
@@ -34,6 +39,10 @@ type Runner struct {
 	parallelFacesWithSpeech bool
 }
 
+// ErrNoLibraryMapping is returned under LibraryPolicyExplicit when a source
+// has not been assigned a cloud library.
+var ErrNoLibraryMapping = errors.New("source has no cloud library; assign one with PUT /sources/{id}/cloud-library")
+
 type OCRConfig interface {
 	OCREnabled() bool
 	OCRRedactPII() bool
@@ -79,6 +88,22 @@ func (r *Runner) SetParallelFacesWithSpeech(enabled bool) {
 func (r *Runner) SetCloudClient(client cloud.Client, libraryID string) {
 	r.cloudClient = client
 	r.fallbackLibraryID = libraryID
+	r.outbox = nil
+	if client != nil {
+		r.outbox = NewOutbox(r.repo, client, r.logger)
+	}
+}
+
+// Outbox returns the cloud outbox, or nil when cloud sync is off.
+func (r *Runner) Outbox() *Outbox {
+	return r.outbox
+}
+
+// SetLibraryPolicy controls what happens when a source has no library
+// mapping: LibraryPolicyAuto creates one named after the source,
+// LibraryPolicyExplicit refuses to upload until a mapping is set.
+func (r *Runner) SetLibraryPolicy(policy string) {
+	r.libraryPolicy = policy
 }
 
 func (r *Runner) resolveLibraryID(ctx context.Context, source *Source) (string, error) {
@@ -86,7 +111,7 @@ func (r *Runner) resolveLibraryID(ctx context.Context, source *Source) (string,
 		return source.CloudLibraryID, nil
 	}
 
-	if r.cloudClient != nil && source != nil {
+	if r.cloudClient != nil && source != nil && r.libraryPolicy != LibraryPolicyExplicit {
 		result, err := r.cloudClient.Libraries().GetOrCreate(ctx, source.DisplayName)
 		if err != nil {
 			r.logger.Warn("library auto-create failed, using fallback",
@@ -113,57 +138,181 @@ func (r *Runner) resolveLibraryID(ctx context.Context, source *Source) (string,
 		return r.fallbackLibraryID, nil
 	}
 
+	if r.libraryPolicy == LibraryPolicyExplicit {
+		return "", ErrNoLibraryMapping
+	}
 	return "", fmt.Errorf("no library ID available: source has no mapping and no fallback configured")
 }
 
-func (r *Runner) backfillCloudUploads(ctx context.Context) {
-	jobs, err := r.repo.ListJobs(ctx, 10000)
+// cloudSyncDisabled reports whether the file's source has opted out of
+// cloud sync.
+func (r *Runner) cloudSyncDisabled(ctx context.Context, file *File) bool {
+	source, err := r.repo.GetSource(ctx, file.SourceID)
+	return err == nil && source != nil && source.CloudSyncDisabled
+}
+
+// QueueSourceUploads queues upload_scenes jobs for every indexed file in a
+// source, used after its library mapping changes. Unchanged payloads are
+// skipped by the outbox, so only files whose library moved are re-sent.
+// Files with an upload still pending are left alone, since that job reads
+// the new mapping when it runs. It returns the number of jobs created.
+func (r *Runner) QueueSourceUploads(ctx context.Context, sourceID string) (int, error) {
+	if r.outbox == nil || r.pipeRunner == nil {
+		return 0, cloud.ErrCloudDisabled
+	}
+	files, err := r.repo.GetFilesBySource(ctx, sourceID)
 	if err != nil {
-		r.logger.Warn("backfill: cannot list jobs", "error", err)
-		return
+		return 0, err
 	}
-
-	completedIndex := make(map[string]bool)
-	hasUpload := make(map[string]bool)
-	for _, j := range jobs {
-		if j.Type == JobTypeIndex && j.Status == JobStatusCompleted && j.FileID != "" {
-			completedIndex[j.FileID] = true
+	pending, err := r.filesWithJob(ctx, JobTypeUploadScenes, JobStatusPending)
+	if err != nil {
+		return 0, err
+	}
+	queued := 0
+	for _, f := range files {
+		if pending[f.ID] {
+			continue
 		}
-		if j.Type == JobTypeUploadScenes && j.FileID != "" {
-			hasUpload[j.FileID] = true
+		scenePath := filepath.Join(r.pipeRunner.ArtifactsDir(), f.ID, "scenes", "result.json")
+		if _, err := os.Stat(scenePath); err != nil {
+			continue
 		}
+		r.enqueueFileJob(ctx, JobTypeUploadScenes, f.ID)
+		queued++
+	}
+	return queued, nil
+}
+
+// backfillCloudUploads queues upload_scenes jobs for indexed files the cloud
+// has no sync record for, such as files indexed before cloud was enabled. It
+// returns the number of jobs created.
+func (r *Runner) backfillCloudUploads(ctx context.Context) int {
+	files, err := r.indexedFiles(ctx, "scenes")
+	if err != nil {
+		r.logger.Warn("backfill: cannot list files", "error", err)
+		return 0
+	}
+	queued, err := r.filesWithJob(ctx, JobTypeUploadScenes, JobStatusPending, JobStatusRunning)
+	if err != nil {
+		r.logger.Warn("backfill: cannot list jobs", "error", err)
+		return 0
 	}
 
-	created := 0
-	for fileID := range completedIndex {
-		if hasUpload[fileID] {
+	created, synced := 0, 0
+	for _, file := range files {
+		if queued[file.ID] {
 			continue
 		}
-		scenePath := filepath.Join(r.pipeRunner.ArtifactsDir(), fileID, "scenes", "result.json")
-		if _, err := os.Stat(scenePath); err != nil {
+		state, err := r.repo.GetCloudSyncState(ctx, file.ID, OutboxKindScenes)
+		if err != nil {
+			r.logger.Warn("backfill: cannot read sync state", "file_id", file.ID, "error", err)
 			continue
 		}
-		now := time.Now()
-		job := &Job{
-			ID:        NewID(),
-			Type:      JobTypeUploadScenes,
-			Status:    JobStatusPending,
-			FileID:    fileID,
-			CreatedAt: now,
-			UpdatedAt: now,
+		if state != nil {
+			synced++
+			continue
 		}
-		if err := r.repo.CreateJob(ctx, job); err != nil {
-			r.logger.Warn("backfill: cannot create upload job", "file_id", fileID, "error", err)
+		if r.cloudSyncDisabled(ctx, file) {
 			continue
 		}
+		r.enqueueFileJob(ctx, JobTypeUploadScenes, file.ID)
 		created++
 	}
 
 	r.logger.Info("backfill: scan complete",
-		"completed_index_jobs", len(completedIndex),
-		"already_uploaded", len(hasUpload),
+		"indexed_files", len(files),
+		"already_synced", synced,
 		"created", created,
 	)
+	return created
+}
+
+// indexedFiles lists catalog files with pipeline output of one of kinds on
+// disk, which is what a completed index job leaves behind. With no kinds,
+// speech, faces or scenes output all count. The file table is walked rather
+// than job history, which only goes back so far.
+func (r *Runner) indexedFiles(ctx context.Context, kinds ...string) ([]*File, error) {
+	if len(kinds) == 0 {
+		kinds = []string{"speech", "faces", "scenes"}
+	}
+	files, err := r.repo.ListFiles(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var indexed []*File
+	for _, file := range files {
+		for _, kind := range kinds {
+			if _, err := os.Stat(filepath.Join(r.pipeRunner.ArtifactsDir(), file.ID, kind, "result.json")); err == nil {
+				indexed = append(indexed, file)
+				break
+			}
+		}
+	}
+	return indexed, nil
+}
+
+// backfillFileJobs queues a jobType job for every indexed file that never
+// had one and for which needed reports true. Files whose job failed are
+// left alone, or they would be retried on every start.
+func (r *Runner) backfillFileJobs(ctx context.Context, jobType string, needed func(file *File) bool) {
+	files, err := r.indexedFiles(ctx)
+	if err != nil {
+		r.logger.Warn("backfill: cannot list files", "type", jobType, "error", err)
+		return
+	}
+	hasJob, err := r.filesWithJob(ctx, jobType)
+	if err != nil {
+		r.logger.Warn("backfill: cannot list jobs", "type", jobType, "error", err)
+		return
+	}
+
+	queued := 0
+	for _, file := range files {
+		if hasJob[file.ID] || !needed(file) {
+			continue
+		}
+		r.enqueueFileJob(ctx, jobType, file.ID)
+		queued++
+	}
+	if queued > 0 {
+		r.logger.Info("backfill queued", "type", jobType, "count", queued)
+	}
+}
+
+// filesWithJob returns the files that have a job of jobType in one of
+// statuses, or in any status when none are given.
+func (r *Runner) filesWithJob(ctx context.Context, jobType string, statuses ...string) (map[string]bool, error) {
+	ids, err := r.repo.ListJobFileIDs(ctx, jobType, statuses...)
+	if err != nil {
+		return nil, err
+	}
+	set := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		set[id] = true
+	}
+	return set, nil
+}
+
+// Resync forces a full push: it forgets which scene payloads the cloud has
+// accepted and queues an upload for every indexed file under a new resync
+// generation. The generation changes every Idempotency-Key, so the SaaS
+// ingests the docs again instead of dropping them as replays, which repairs
+// cloud data that was lost or damaged.
+func (r *Runner) Resync(ctx context.Context) (int, error) {
+	if r.outbox == nil || r.pipeRunner == nil {
+		return 0, cloud.ErrCloudDisabled
+	}
+	generation, err := r.outbox.nextGeneration(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("start resync generation: %w", err)
+	}
+	cleared, err := r.repo.ClearCloudSyncState(ctx, OutboxKindScenes)
+	if err != nil {
+		return 0, fmt.Errorf("clear sync state: %w", err)
+	}
+	created := r.backfillCloudUploads(ctx)
+	r.logger.Info("cloud resync requested", "generation", generation, "cleared", cleared, "queued", created)
+	return created, nil
 }
 
 func (r *Runner) Start(ctx context.Context) {
@@ -181,6 +330,16 @@ func (r *Runner) Start(ctx context.Context) {
 	if r.pipeRunner != nil && r.ffmpeg != nil {
 		r.backfillThumbnails(ctx)
 	}
+	if r.pipeRunner != nil && r.ffmpeg != nil {
+		r.backfillSprites(ctx)
+		r.backfillWaveforms(ctx)
+	}
+	if r.pipeRunner != nil && r.proxy.enabled() && r.ffmpeg != nil {
+		r.backfillProxies(ctx)
+	}
+	if r.outbox != nil {
+		go r.outbox.Run(ctx)
+	}
 
 	ticker := time.NewTicker(r.pollInterval)
 	defer ticker.Stop()
@@ -217,6 +376,29 @@ func (r *Runner) IsRunning() bool {
 	return r.running.Load()
 }
 
+// nextRunnableJob returns the oldest job that may run now. Bulk cloud
+// uploads stay pending while sync is paused, offline or metered, without
+// holding up indexing behind them. Proxy, sprite and waveform generation
+// decode whole files; they are background work and only run when nothing
+// else is waiting.
+func (r *Runner) nextRunnableJob(jobs []*Job) *Job {
+	bulkAllowed := r.outbox == nil || r.outbox.AllowBulk()
+	var backgroundJob *Job
+	for _, job := range jobs {
+		if !bulkAllowed && (job.Type == JobTypeUploadArtifacts || job.Type == JobTypeUploadThumbnails) {
+			continue
+		}
+		if job.Type == JobTypeGenerateProxy || job.Type == JobTypeGenerateSprites || job.Type == JobTypeGenerateWaveform {
+			if backgroundJob == nil {
+				backgroundJob = job
+			}
+			continue
+		}
+		return job
+	}
+	return backgroundJob
+}
+
 func (r *Runner) processNextJob(ctx context.Context) {
 	jobs, err := r.repo.ListPendingJobs(ctx)
 	if err != nil {
@@ -224,11 +406,10 @@ func (r *Runner) processNextJob(ctx context.Context) {
 		return
 	}
 
-	if len(jobs) == 0 {
+	job := r.nextRunnableJob(jobs)
+	if job == nil {
 		return
 	}
-
-	job := jobs[0]
 	r.logger.Info("processing job", "job_id", job.ID, "type", job.Type)
 
 	switch job.Type {
@@ -252,6 +433,21 @@ func (r *Runner) processNextJob(ctx context.Context) {
 	case JobTypeGenerateThumbnails:
 		r.processGenerateThumbnailsJob(ctx, job)
 
+	case JobTypeUploadArtifacts:
+		r.processUploadArtifactsJob(ctx, job)
+
+	case JobTypeUploadThumbnails:
+		r.processUploadThumbnailsJob(ctx, job)
+
+	case JobTypeGenerateProxy:
+		r.processGenerateProxyJob(ctx, job)
+
+	case JobTypeGenerateSprites:
+		r.processGenerateSpritesJob(ctx, job)
+
+	case JobTypeGenerateWaveform:
+		r.processGenerateWaveformJob(ctx, job)
+
 	default:
 		r.logger.Warn("unknown job type", "type", job.Type)
 		r.repo.UpdateJobStatus(ctx, job.ID, JobStatusFailed, "unknown job type")
@@ -494,6 +690,32 @@ func (r *Runner) processIndexJob(ctx context.Context, job *Job) {
 	if r.cloudClient != nil && caps.HasScenes && speechOK {
 		r.uploadScenesToCloud(ctx, job, file, artifactsBase)
 	}
+	if r.cloudClient != nil {
+		r.enqueueFileJob(ctx, JobTypeUploadArtifacts, file.ID)
+	}
+	if r.ffmpeg != nil {
+		r.enqueueFileJob(ctx, JobTypeGenerateSprites, file.ID)
+		r.enqueueFileJob(ctx, JobTypeGenerateWaveform, file.ID)
+	}
+	if _, err := r.EnsureProxy(ctx, file.ID); err != nil {
+		r.logger.Warn("failed to queue proxy", "file_id", file.ID, "error", err)
+	}
+}
+
+// enqueueFileJob creates a pending job of the given type for a file.
+func (r *Runner) enqueueFileJob(ctx context.Context, jobType, fileID string) {
+	now := time.Now()
+	job := &Job{
+		ID:        NewID(),
+		Type:      jobType,
+		Status:    JobStatusPending,
+		FileID:    fileID,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := r.repo.CreateJob(ctx, job); err != nil {
+		r.logger.Warn("failed to enqueue job", "type", jobType, "file_id", fileID, "error", err)
+	}
 }
 
 // buildSceneIngestDocs converts pipeline SceneBoundary output into the cloud
@@ -540,36 +762,42 @@ func resolveSourceType(source *Source) string {
 	}
 }
 
-func (r *Runner) uploadScenesToCloud(ctx context.Context, job *Job, file *File, artifactsBase string) {
+// buildScenePayload reads a file's scene output and maps it to the cloud
+// ingest payload. It returns nil when the file has no scenes.
+func (r *Runner) buildScenePayload(ctx context.Context, file *File, artifactsBase string) (*cloud.SceneIngestPayload, error) {
 	scenePath := filepath.Join(artifactsBase, "scenes", "result.json")
 
 	data, err := os.ReadFile(scenePath)
 	if err != nil {
-		r.logger.Warn("scene upload skipped: cannot read scene output", "job_id", job.ID, "error", err)
-		return
+		return nil, fmt.Errorf("cannot read scene output: %w", err)
 	}
 
 	var sceneOutput pipelines.SceneOutputPayload
 	if err := json.Unmarshal(data, &sceneOutput); err != nil {
-		r.logger.Warn("scene upload skipped: invalid scene JSON", "job_id", job.ID, "error", err)
-		return
+		return nil, fmt.Errorf("invalid scene JSON: %w", err)
 	}
 
 	if len(sceneOutput.Scenes) == 0 {
-		r.logger.Info("scene upload skipped: no scenes detected", "job_id", job.ID)
-		return
+		return nil, nil
 	}
 
 	source, _ := r.repo.GetSource(ctx, file.SourceID)
 	libraryID, err := r.resolveLibraryID(ctx, source)
 	if err != nil {
-		r.logger.Warn("scene upload skipped: no library available", "job_id", job.ID, "error", err)
-		return
+		return nil, fmt.Errorf("no library available: %w", err)
 	}
 	sourceType := resolveSourceType(source)
 	scenes := buildSceneIngestDocs(sceneOutput.Scenes, sourceType)
+	if source != nil {
+		secret, err := privacySecret(ctx, r.repo)
+		if err != nil {
+			return nil, err
+		}
+		applyPrivacy(scenes, source.Privacy, secret)
+	}
+	r.attachThumbnails(ctx, file.ID, scenes)
 
-	payload := cloud.SceneIngestPayload{
+	return &cloud.SceneIngestPayload{
 		VideoID:         sceneOutput.VideoID,
 		VideoTitle:      strings.TrimSuffix(file.Filename, filepath.Ext(file.Filename)),
 		LibraryID:       libraryID,
@@ -577,54 +805,43 @@ func (r *Runner) uploadScenesToCloud(ctx context.Context, job *Job, file *File,
 		ModelVersion:    sceneOutput.ModelVersion,
 		TotalDurationMs: sceneOutput.TotalDurationMs,
 		Scenes:          scenes,
-	}
-
-	uploadCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
-	defer cancel()
-
-	if err := r.cloudClient.Scenes().UploadScenes(uploadCtx, payload); err != nil {
-		r.logger.Warn("scene upload failed (non-blocking)", "job_id", job.ID, "video_id", sceneOutput.VideoID, "error", err)
-
-		var uploadErr *cloud.UploadError
-		if errors.As(err, &uploadErr) && !uploadErr.IsRetryable() {
-			r.logger.Warn("scene upload permanent failure, no retry",
-				"job_id", job.ID, "status_code", uploadErr.StatusCode, "error", err)
-			return
-		}
+	}, nil
+}
 
-		retryJob := &Job{
-			ID:        NewID(),
-			Type:      JobTypeUploadScenes,
-			Status:    JobStatusPending,
-			FileID:    file.ID,
-			Progress:  0,
-			Error:     err.Error(),
-			CreatedAt: time.Now(),
-			UpdatedAt: time.Now(),
-		}
-		if createErr := r.repo.CreateJob(ctx, retryJob); createErr != nil {
-			r.logger.Error("failed to create upload retry job", "error", createErr)
-		} else {
-			r.logger.Info("created upload retry job", "retry_job_id", retryJob.ID, "file_id", file.ID)
-		}
-		return
+// enqueueScenes queues a file's scene docs in the outbox and attempts
+// delivery right away. Failed deliveries stay in the outbox for retry.
+func (r *Runner) enqueueScenes(ctx context.Context, file *File, artifactsBase string) error {
+	if r.cloudSyncDisabled(ctx, file) {
+		r.logger.Info("scene upload skipped: cloud sync disabled for source", "file_id", file.ID)
+		return nil
 	}
 
-	r.logger.Info("scene upload succeeded", "job_id", job.ID, "video_id", sceneOutput.VideoID, "scene_count", len(scenes))
+	payload, err := r.buildScenePayload(ctx, file, artifactsBase)
+	if err != nil {
+		return err
+	}
+	if payload == nil {
+		r.logger.Info("scene upload skipped: no scenes detected", "file_id", file.ID)
+		return nil
+	}
 
-	// Record a completed upload_scenes job so backfillCloudUploads won't
-	// create a duplicate upload for this file on next restart.
-	now := time.Now()
-	uploadJob := &Job{
-		ID:        NewID(),
-		Type:      JobTypeUploadScenes,
-		Status:    JobStatusCompleted,
-		FileID:    file.ID,
-		CreatedAt: now,
-		UpdatedAt: now,
+	queued, err := r.outbox.Enqueue(ctx, OutboxKindScenes, file.ID, payload)
+	if err != nil {
+		return err
+	}
+	if !queued {
+		r.logger.Info("scene upload skipped: unchanged since last sync", "file_id", file.ID)
+		return nil
 	}
-	if createErr := r.repo.CreateJob(ctx, uploadJob); createErr != nil {
-		r.logger.Warn("failed to record upload job (non-critical)", "file_id", file.ID, "error", createErr)
+	if _, err := r.outbox.Flush(ctx); err != nil {
+		r.logger.Warn("outbox flush failed", "file_id", file.ID, "error", err)
+	}
+	return nil
+}
+
+func (r *Runner) uploadScenesToCloud(ctx context.Context, job *Job, file *File, artifactsBase string) {
+	if err := r.enqueueScenes(ctx, file, artifactsBase); err != nil {
+		r.logger.Warn("scene upload skipped", "job_id", job.ID, "file_id", file.ID, "error", err)
 	}
 }
 
@@ -643,41 +860,32 @@ func uploadBackoff(attempt int) time.Duration {
 	return backoff
 }
 
+// processUploadScenesJob hands a file's scene docs to the outbox. Delivery
+// and retries happen there, so the job completes once the docs are queued.
 func (r *Runner) processUploadScenesJob(ctx context.Context, job *Job) {
-	const maxRetries = 5
-
-	delay := uploadBackoff(job.Progress)
-	if time.Since(job.UpdatedAt) < delay {
-		return
-	}
-
-	attempt := job.Progress + 1
-	if attempt > maxRetries {
-		r.repo.UpdateJobStatus(ctx, job.ID, JobStatusFailed, fmt.Sprintf("max retries (%d) exceeded: %s", maxRetries, job.Error))
-		r.logger.Warn("upload retry abandoned", "job_id", job.ID, "attempts", attempt)
-		return
-	}
-
 	if r.pipeRunner == nil {
 		r.repo.UpdateJobStatus(ctx, job.ID, JobStatusFailed, "pipeline runner not configured")
 		return
 	}
-	if r.cloudClient == nil {
+	if r.outbox == nil {
 		r.repo.UpdateJobStatus(ctx, job.ID, JobStatusFailed, "cloud client not configured")
 		return
 	}
 
 	file, err := r.repo.GetFile(ctx, job.FileID)
 	if err != nil || file == nil {
-		r.repo.UpdateJobStatus(ctx, job.ID, JobStatusFailed, "file not found for retry")
+		r.repo.UpdateJobStatus(ctx, job.ID, JobStatusFailed, "file not found")
 		return
 	}
 
 	r.repo.UpdateJobStatus(ctx, job.ID, JobStatusRunning, "")
-	r.repo.UpdateJobProgress(ctx, job.ID, attempt)
 
 	artifactsBase := filepath.Join(r.pipeRunner.ArtifactsDir(), file.ID)
-	r.uploadScenesToCloudRetry(ctx, job, file, artifactsBase, attempt)
+	if err := r.enqueueScenes(ctx, file, artifactsBase); err != nil {
+		r.repo.UpdateJobStatus(ctx, job.ID, JobStatusFailed, err.Error())
+		return
+	}
+	r.repo.UpdateJobStatus(ctx, job.ID, JobStatusCompleted, "")
 }
 
 func (r *Runner) processGenerateThumbnailsJob(ctx context.Context, job *Job) {
@@ -727,64 +935,10 @@ func (r *Runner) processGenerateThumbnailsJob(ctx context.Context, job *Job) {
 
 	r.logger.Info("thumbnails generated", "file_id", file.ID, "count", generated, "total", len(sceneOutput.Scenes))
 	r.repo.UpdateJobStatus(ctx, job.ID, JobStatusCompleted, "")
-}
-
-func (r *Runner) uploadScenesToCloudRetry(ctx context.Context, job *Job, file *File, artifactsBase string, attempt int) {
-	scenePath := filepath.Join(artifactsBase, "scenes", "result.json")
-
-	data, err := os.ReadFile(scenePath)
-	if err != nil {
-		r.repo.UpdateJobStatus(ctx, job.ID, JobStatusFailed, fmt.Sprintf("cannot read scene output: %v", err))
-		return
-	}
-
-	var sceneOutput pipelines.SceneOutputPayload
-	if err := json.Unmarshal(data, &sceneOutput); err != nil {
-		r.repo.UpdateJobStatus(ctx, job.ID, JobStatusFailed, fmt.Sprintf("invalid scene JSON: %v", err))
-		return
-	}
 
-	if len(sceneOutput.Scenes) == 0 {
-		r.repo.UpdateJobStatus(ctx, job.ID, JobStatusCompleted, "")
-		return
+	if r.cloudClient != nil && generated > 0 {
+		r.enqueueFileJob(ctx, JobTypeUploadThumbnails, file.ID)
 	}
-
-	source, _ := r.repo.GetSource(ctx, file.SourceID)
-	libraryID, err := r.resolveLibraryID(ctx, source)
-	if err != nil {
-		r.repo.UpdateJobStatus(ctx, job.ID, JobStatusFailed, fmt.Sprintf("no library available: %v", err))
-		return
-	}
-	sourceType := resolveSourceType(source)
-	scenes := buildSceneIngestDocs(sceneOutput.Scenes, sourceType)
-
-	payload := cloud.SceneIngestPayload{
-		VideoID:         sceneOutput.VideoID,
-		VideoTitle:      strings.TrimSuffix(file.Filename, filepath.Ext(file.Filename)),
-		LibraryID:       libraryID,
-		PipelineVersion: sceneOutput.PipelineVersion,
-		ModelVersion:    sceneOutput.ModelVersion,
-		TotalDurationMs: sceneOutput.TotalDurationMs,
-		Scenes:          scenes,
-	}
-
-	uploadCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
-	defer cancel()
-
-	if err := r.cloudClient.Scenes().UploadScenes(uploadCtx, payload); err != nil {
-		var uploadErr *cloud.UploadError
-		if errors.As(err, &uploadErr) && !uploadErr.IsRetryable() {
-			r.repo.UpdateJobStatus(ctx, job.ID, JobStatusFailed, fmt.Sprintf("permanent error (HTTP %d): %s", uploadErr.StatusCode, uploadErr.Body))
-			return
-		}
-
-		r.logger.Warn("upload retry failed", "job_id", job.ID, "attempt", attempt, "error", err)
-		r.repo.UpdateJobStatus(ctx, job.ID, JobStatusPending, err.Error())
-		return
-	}
-
-	r.repo.UpdateJobStatus(ctx, job.ID, JobStatusCompleted, "")
-	r.logger.Info("upload retry succeeded", "job_id", job.ID, "attempt", attempt, "video_id", sceneOutput.VideoID)
 }
 
 func truncateStr(s string, maxLen int) string {
@@ -3,8 +3,10 @@ package catalog
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
+	"net"
 	"os"
 	"path/filepath"
 	"strings"
@@ -496,6 +498,7 @@ func TestProcessIndexJob_FacesFailsScenesStillDrains(t *testing.T) {
 
 type fakeSceneUploader struct {
 	uploadFn func(ctx context.Context, payload cloud.SceneIngestPayload) error
+	batchFn  func(ctx context.Context, items []cloud.SceneBatchItem) ([]cloud.SceneBatchResult, error)
 }
 
 func (f *fakeSceneUploader) UploadScenes(ctx context.Context, payload cloud.SceneIngestPayload) error {
@@ -505,19 +508,58 @@ func (f *fakeSceneUploader) UploadScenes(ctx context.Context, payload cloud.Scen
 	return nil
 }
 
+// UploadScenesBatch behaves like a server without the batch endpoint unless
+// batchFn is set.
+func (f *fakeSceneUploader) UploadScenesBatch(ctx context.Context, items []cloud.SceneBatchItem) ([]cloud.SceneBatchResult, error) {
+	if f.batchFn != nil {
+		return f.batchFn(ctx, items)
+	}
+	return nil, cloud.ErrBatchUnsupported
+}
+
 type fakeCloudClient struct {
 	scenes *fakeSceneUploader
+	upload cloud.UploadService
+
+	registered  []cloud.DeviceInfo
+	heartbeats  []cloud.HeartbeatPayload
+	heartbeatFn func(ctx context.Context, payload cloud.HeartbeatPayload) error
+	annotations cloud.AnnotationService
+	libraries   *fakeLibraryService
+}
+
+func (f *fakeCloudClient) Auth() cloud.AuthService     { return nil }
+func (f *fakeCloudClient) Upload() cloud.UploadService { return f.upload }
+func (f *fakeCloudClient) Scenes() cloud.SceneUploader { return f.scenes }
+func (f *fakeCloudClient) Libraries() cloud.LibraryService {
+	if f.libraries != nil {
+		return f.libraries
+	}
+	return &fakeLibraryService{}
+}
+func (f *fakeCloudClient) Annotations() cloud.AnnotationService {
+	return f.annotations
+}
+
+func (f *fakeCloudClient) RegisterDevice(ctx context.Context, info cloud.DeviceInfo) error {
+	f.registered = append(f.registered, info)
+	return nil
 }
 
-func (f *fakeCloudClient) Auth() cloud.AuthService              { return nil }
-func (f *fakeCloudClient) Upload() cloud.UploadService          { return nil }
-func (f *fakeCloudClient) Scenes() cloud.SceneUploader          { return f.scenes }
-func (f *fakeCloudClient) Libraries() cloud.LibraryService      { return &fakeLibraryService{} }
-func (f *fakeCloudClient) RegisterDevice(deviceID string) error { return nil }
+func (f *fakeCloudClient) Heartbeat(ctx context.Context, payload cloud.HeartbeatPayload) error {
+	f.heartbeats = append(f.heartbeats, payload)
+	if f.heartbeatFn != nil {
+		return f.heartbeatFn(ctx, payload)
+	}
+	return nil
+}
 
-type fakeLibraryService struct{}
+type fakeLibraryService struct {
+	created []string
+}
 
 func (f *fakeLibraryService) GetOrCreate(ctx context.Context, name string) (*cloud.LibraryResult, error) {
+	f.created = append(f.created, name)
 	return &cloud.LibraryResult{ID: "auto-lib-" + name, Name: name, Created: true}, nil
 }
 
@@ -565,7 +607,7 @@ func writeSceneResultWithPayload(t *testing.T, artifactsDir, fileID string, payl
 	}
 }
 
-func TestUploadScenesToCloud_PermanentError_NoRetryJob(t *testing.T) {
+func TestUploadScenesToCloud_PermanentError_FailsOutboxEntry(t *testing.T) {
 	fake := &fakePipeRunner{}
 	caps := &pipelines.Capabilities{HasSpeech: true, HasFaces: true, HasScenes: true, ProbedAt: time.Now()}
 
@@ -574,39 +616,32 @@ func TestUploadScenesToCloud_PermanentError_NoRetryJob(t *testing.T) {
 	tmpDir := t.TempDir()
 	fake.artifacts = tmpDir
 
+	calls := 0
 	cc := &fakeCloudClient{scenes: &fakeSceneUploader{
 		uploadFn: func(_ context.Context, _ cloud.SceneIngestPayload) error {
+			calls++
 			return &cloud.UploadError{StatusCode: 422, Body: "unprocessable"}
 		},
 	}}
 	runner.SetCloudClient(cc, "lib-1")
 
-	_, file := createTestJobAndFile(t, repo)
+	job, file := createTestJobAndFile(t, repo)
 	writeSceneResult(t, tmpDir, file.ID)
 
-	job := &Job{
-		ID:        NewID(),
-		Type:      JobTypeIndex,
-		Status:    JobStatusRunning,
-		FileID:    file.ID,
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
-	}
-	if err := repo.CreateJob(context.Background(), job); err != nil {
-		t.Fatalf("create job: %v", err)
-	}
-
 	runner.uploadScenesToCloud(context.Background(), job, file, filepath.Join(tmpDir, file.ID))
 
-	jobs, _ := repo.ListJobs(context.Background(), 100)
-	for _, j := range jobs {
-		if j.Type == JobTypeUploadScenes {
-			t.Error("expected NO upload_scenes retry job for permanent 422 error, but found one")
-		}
+	if calls != 1 {
+		t.Fatalf("upload calls = %d, want 1", calls)
+	}
+	if n, _ := repo.CountOutbox(context.Background(), OutboxStatusFailed); n != 1 {
+		t.Errorf("failed outbox entries = %d, want 1 for permanent 422 error", n)
+	}
+	if n, _ := repo.CountOutbox(context.Background(), OutboxStatusPending); n != 0 {
+		t.Errorf("pending outbox entries = %d, want 0 (no retry for permanent error)", n)
 	}
 }
 
-func TestUploadScenesToCloud_RetryableError_CreatesRetryJob(t *testing.T) {
+func TestUploadScenesToCloud_RetryableError_SchedulesRetry(t *testing.T) {
 	fake := &fakePipeRunner{}
 	caps := &pipelines.Capabilities{HasSpeech: true, HasFaces: true, HasScenes: true, ProbedAt: time.Now()}
 
@@ -615,43 +650,39 @@ func TestUploadScenesToCloud_RetryableError_CreatesRetryJob(t *testing.T) {
 	tmpDir := t.TempDir()
 	fake.artifacts = tmpDir
 
+	calls := 0
 	cc := &fakeCloudClient{scenes: &fakeSceneUploader{
 		uploadFn: func(_ context.Context, _ cloud.SceneIngestPayload) error {
+			calls++
 			return &cloud.UploadError{StatusCode: 500, Body: "internal server error"}
 		},
 	}}
 	runner.SetCloudClient(cc, "lib-1")
 
-	_, file := createTestJobAndFile(t, repo)
+	job, file := createTestJobAndFile(t, repo)
 	writeSceneResult(t, tmpDir, file.ID)
 
-	job := &Job{
-		ID:        NewID(),
-		Type:      JobTypeIndex,
-		Status:    JobStatusRunning,
-		FileID:    file.ID,
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
-	}
-	if err := repo.CreateJob(context.Background(), job); err != nil {
-		t.Fatalf("create job: %v", err)
-	}
-
 	runner.uploadScenesToCloud(context.Background(), job, file, filepath.Join(tmpDir, file.ID))
 
-	found := false
-	jobs, _ := repo.ListJobs(context.Background(), 100)
-	for _, j := range jobs {
-		if j.Type == JobTypeUploadScenes {
-			found = true
-		}
+	entries, _ := repo.ListDueOutbox(context.Background(), time.Now().Add(time.Hour), 10)
+	if len(entries) != 1 {
+		t.Fatalf("pending outbox entries = %d, want 1", len(entries))
 	}
-	if !found {
-		t.Error("expected upload_scenes retry job for 500 error, but none found")
+	if entries[0].Attempts != 1 {
+		t.Errorf("attempts = %d, want 1", entries[0].Attempts)
+	}
+	if !entries[0].NextAttemptAt.After(time.Now()) {
+		t.Errorf("next_attempt_at = %v, want in the future", entries[0].NextAttemptAt)
+	}
+
+	// A flush before the backoff elapses must not resend.
+	runner.Outbox().Flush(context.Background())
+	if calls != 1 {
+		t.Errorf("upload calls = %d, want 1 during backoff", calls)
 	}
 }
 
-func TestUploadScenesToCloud_NetworkError_CreatesRetryJob(t *testing.T) {
+func TestUploadScenesToCloud_NetworkError_PausesWithoutAttempt(t *testing.T) {
 	fake := &fakePipeRunner{}
 	caps := &pipelines.Capabilities{HasSpeech: true, HasFaces: true, HasScenes: true, ProbedAt: time.Now()}
 
@@ -662,37 +693,25 @@ func TestUploadScenesToCloud_NetworkError_CreatesRetryJob(t *testing.T) {
 
 	cc := &fakeCloudClient{scenes: &fakeSceneUploader{
 		uploadFn: func(_ context.Context, _ cloud.SceneIngestPayload) error {
-			return fmt.Errorf("connection refused")
+			return fmt.Errorf("http request failed: %w", &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")})
 		},
 	}}
 	runner.SetCloudClient(cc, "lib-1")
 
-	_, file := createTestJobAndFile(t, repo)
+	job, file := createTestJobAndFile(t, repo)
 	writeSceneResult(t, tmpDir, file.ID)
 
-	job := &Job{
-		ID:        NewID(),
-		Type:      JobTypeIndex,
-		Status:    JobStatusRunning,
-		FileID:    file.ID,
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
-	}
-	if err := repo.CreateJob(context.Background(), job); err != nil {
-		t.Fatalf("create job: %v", err)
-	}
-
 	runner.uploadScenesToCloud(context.Background(), job, file, filepath.Join(tmpDir, file.ID))
 
-	found := false
-	jobs, _ := repo.ListJobs(context.Background(), 100)
-	for _, j := range jobs {
-		if j.Type == JobTypeUploadScenes {
-			found = true
-		}
+	if !runner.Outbox().Offline() {
+		t.Error("expected outbox to pause after network error")
 	}
-	if !found {
-		t.Error("expected upload_scenes retry job for network error, but none found")
+	entries, _ := repo.ListDueOutbox(context.Background(), time.Now(), 10)
+	if len(entries) != 1 {
+		t.Fatalf("due outbox entries = %d, want 1", len(entries))
+	}
+	if entries[0].Attempts != 0 {
+		t.Errorf("attempts = %d, want 0 (offline must not burn retries)", entries[0].Attempts)
 	}
 }
 
@@ -1048,7 +1067,7 @@ func TestUploadScenesToCloud_OlderPipelineOutput_StillWorks(t *testing.T) {
 	}
 }
 
-func TestProcessUploadScenesJob_RespectsBackoff(t *testing.T) {
+func TestProcessUploadScenesJob_QueuesInOutbox(t *testing.T) {
 	fake := &fakePipeRunner{}
 	caps := &pipelines.Capabilities{HasSpeech: true, HasFaces: true, HasScenes: true, ProbedAt: time.Now()}
 
@@ -1057,10 +1076,10 @@ func TestProcessUploadScenesJob_RespectsBackoff(t *testing.T) {
 	tmpDir := t.TempDir()
 	fake.artifacts = tmpDir
 
-	uploadCalled := false
+	var uploaded []string
 	cc := &fakeCloudClient{scenes: &fakeSceneUploader{
-		uploadFn: func(_ context.Context, _ cloud.SceneIngestPayload) error {
-			uploadCalled = true
+		uploadFn: func(_ context.Context, payload cloud.SceneIngestPayload) error {
+			uploaded = append(uploaded, payload.VideoID)
 			return nil
 		},
 	}}
@@ -1085,13 +1104,21 @@ func TestProcessUploadScenesJob_RespectsBackoff(t *testing.T) {
 
 	runner.processUploadScenesJob(context.Background(), job)
 
-	if uploadCalled {
-		t.Error("expected upload to be skipped due to backoff, but it was called")
+	updatedJob, _ := repo.GetJob(context.Background(), job.ID)
+	if updatedJob.Status != JobStatusCompleted {
+		t.Errorf("job status = %s, want completed once queued", updatedJob.Status)
+	}
+	if len(uploaded) != 1 {
+		t.Fatalf("uploads = %d, want 1", len(uploaded))
+	}
+	if n, _ := repo.CountOutbox(context.Background(), OutboxStatusSent); n != 1 {
+		t.Errorf("sent outbox entries = %d, want 1", n)
 	}
 
-	updatedJob, _ := repo.GetJob(context.Background(), job.ID)
-	if updatedJob.Status != JobStatusPending {
-		t.Errorf("job should remain pending during backoff, got %s", updatedJob.Status)
+	// Re-running with unchanged scenes is deduplicated by idempotency key.
+	runner.processUploadScenesJob(context.Background(), job)
+	if len(uploaded) != 1 {
+		t.Errorf("uploads = %d, want 1 for unchanged payload", len(uploaded))
 	}
 }
 
@@ -1294,7 +1321,7 @@ func TestBackfillCloudUploads_SkipsAlreadyUploaded(t *testing.T) {
 	ctx := context.Background()
 
 	repo.CreateJob(ctx, &Job{ID: NewID(), Type: JobTypeIndex, Status: JobStatusCompleted, FileID: file.ID, CreatedAt: time.Now(), UpdatedAt: time.Now()})
-	repo.CreateJob(ctx, &Job{ID: NewID(), Type: JobTypeUploadScenes, Status: JobStatusCompleted, FileID: file.ID, CreatedAt: time.Now(), UpdatedAt: time.Now()})
+	repo.UpsertCloudSyncState(ctx, &CloudSyncState{FileID: file.ID, Kind: OutboxKindScenes, PayloadHash: "abc", SyncedAt: time.Now()})
 
 	writeSceneResult(t, tmpDir, file.ID)
 
@@ -1307,8 +1334,57 @@ func TestBackfillCloudUploads_SkipsAlreadyUploaded(t *testing.T) {
 			uploadCount++
 		}
 	}
-	if uploadCount != 1 {
-		t.Errorf("expected 1 upload_scenes job (pre-existing), got %d", uploadCount)
+	if uploadCount != 0 {
+		t.Errorf("expected 0 upload_scenes jobs (already synced), got %d", uploadCount)
+	}
+}
+
+func TestBackfillCloudUploads_IgnoresJobHistory(t *testing.T) {
+	fake := &fakePipeRunner{}
+	caps := &pipelines.Capabilities{HasSpeech: true, HasScenes: true, ProbedAt: time.Now()}
+
+	runner, repo := setupRunnerTest(t, fake, caps)
+
+	tmpDir := t.TempDir()
+	fake.artifacts = tmpDir
+
+	cc := &fakeCloudClient{scenes: &fakeSceneUploader{}}
+	runner.SetCloudClient(cc, "lib-1")
+
+	_, file := createTestJobAndFile(t, repo)
+
+	ctx := context.Background()
+
+	// A completed upload job without a sync record (e.g. the upload was
+	// never acknowledged) must not stop the file from being queued.
+	repo.CreateJob(ctx, &Job{ID: NewID(), Type: JobTypeIndex, Status: JobStatusCompleted, FileID: file.ID, CreatedAt: time.Now(), UpdatedAt: time.Now()})
+	repo.CreateJob(ctx, &Job{ID: NewID(), Type: JobTypeUploadScenes, Status: JobStatusCompleted, FileID: file.ID, CreatedAt: time.Now(), UpdatedAt: time.Now()})
+
+	writeSceneResult(t, tmpDir, file.ID)
+
+	if created := runner.backfillCloudUploads(ctx); created != 1 {
+		t.Errorf("created = %d, want 1", created)
+	}
+}
+
+func TestBackfillCloudUploads_FindsFilesWithoutIndexJobs(t *testing.T) {
+	fake := &fakePipeRunner{artifacts: t.TempDir()}
+	runner, repo := setupRunnerTest(t, fake, &pipelines.Capabilities{ProbedAt: time.Now()})
+	runner.SetCloudClient(&fakeCloudClient{scenes: &fakeSceneUploader{}}, "lib-1")
+
+	ctx := context.Background()
+	_, indexed := createTestJobAndFile(t, repo)
+	writeSceneResult(t, fake.artifacts, indexed.ID)
+	unindexed := &File{ID: NewID(), SourceID: indexed.SourceID, Path: "/test/videos/new.mp4", Filename: "new.mp4", Mtime: time.Now(), CreatedAt: time.Now()}
+	repo.CreateFile(ctx, unindexed)
+
+	// Scene output on disk is what counts; the index job may have been
+	// pruned from history long ago.
+	if created := runner.backfillCloudUploads(ctx); created != 1 {
+		t.Fatalf("created = %d, want 1", created)
+	}
+	if created := runner.backfillCloudUploads(ctx); created != 0 {
+		t.Errorf("second pass created = %d, want 0 while the upload is queued", created)
 	}
 }
 
@@ -1495,3 +1571,126 @@ func TestBackfillCloudUploads_SkipsMissingArtifacts(t *testing.T) {
 		t.Errorf("expected 0 upload_scenes jobs (no artifacts), got %d", uploadCount)
 	}
 }
+
+func TestResolveLibraryID_ExplicitPolicySkipsAutoCreate(t *testing.T) {
+	runner, repo := setupRunnerTest(t, &fakePipeRunner{artifacts: t.TempDir()}, &pipelines.Capabilities{HasScenes: true, ProbedAt: time.Now()})
+
+	libs := &fakeLibraryService{}
+	runner.SetCloudClient(&fakeCloudClient{scenes: &fakeSceneUploader{}, libraries: libs}, "")
+	runner.SetLibraryPolicy(LibraryPolicyExplicit)
+
+	_, file := createTestJobAndFile(t, repo)
+	source, _ := repo.GetSource(context.Background(), file.SourceID)
+
+	if _, err := runner.resolveLibraryID(context.Background(), source); !errors.Is(err, ErrNoLibraryMapping) {
+		t.Fatalf("err = %v, want ErrNoLibraryMapping", err)
+	}
+	if len(libs.created) != 0 {
+		t.Fatalf("GetOrCreate called %d times under explicit policy", len(libs.created))
+	}
+
+	runner.SetLibraryPolicy(LibraryPolicyAuto)
+	id, err := runner.resolveLibraryID(context.Background(), source)
+	if err != nil || id != "auto-lib-Test" {
+		t.Fatalf("auto policy resolved %q, %v", id, err)
+	}
+}
+
+func TestProcessUploadScenesJob_SkipsSyncDisabledSource(t *testing.T) {
+	fake := &fakePipeRunner{artifacts: t.TempDir()}
+	runner, repo := setupRunnerTest(t, fake, &pipelines.Capabilities{HasScenes: true, ProbedAt: time.Now()})
+
+	uploads := 0
+	runner.SetCloudClient(&fakeCloudClient{scenes: &fakeSceneUploader{
+		uploadFn: func(context.Context, cloud.SceneIngestPayload) error {
+			uploads++
+			return nil
+		},
+	}}, "lib-1")
+
+	ctx := context.Background()
+	_, file := createTestJobAndFile(t, repo)
+	writeSceneResult(t, fake.artifacts, file.ID)
+	if err := repo.UpdateSourceCloudSync(ctx, file.SourceID, "", true); err != nil {
+		t.Fatalf("disable sync: %v", err)
+	}
+
+	now := time.Now()
+	job := &Job{ID: NewID(), Type: JobTypeUploadScenes, Status: JobStatusPending, FileID: file.ID, CreatedAt: now, UpdatedAt: now}
+	if err := repo.CreateJob(ctx, job); err != nil {
+		t.Fatalf("create job: %v", err)
+	}
+
+	runner.processUploadScenesJob(ctx, job)
+
+	if uploads != 0 {
+		t.Fatalf("uploads = %d, want 0 for a source with sync disabled", uploads)
+	}
+	if n, _ := repo.CountOutbox(ctx, OutboxStatusPending); n != 0 {
+		t.Errorf("pending outbox entries = %d, want 0", n)
+	}
+	updated, _ := repo.GetJob(ctx, job.ID)
+	if updated.Status != JobStatusCompleted {
+		t.Errorf("job status = %s, want completed", updated.Status)
+	}
+}
+
+func TestQueueSourceUploads(t *testing.T) {
+	fake := &fakePipeRunner{artifacts: t.TempDir()}
+	runner, repo := setupRunnerTest(t, fake, &pipelines.Capabilities{HasScenes: true, ProbedAt: time.Now()})
+	runner.SetCloudClient(&fakeCloudClient{scenes: &fakeSceneUploader{}}, "lib-1")
+
+	ctx := context.Background()
+	_, file := createTestJobAndFile(t, repo)
+	writeSceneResult(t, fake.artifacts, file.ID)
+
+	queued, err := runner.QueueSourceUploads(ctx, file.SourceID)
+	if err != nil {
+		t.Fatalf("QueueSourceUploads: %v", err)
+	}
+	if queued != 1 {
+		t.Fatalf("queued = %d, want 1", queued)
+	}
+
+	jobs, _ := repo.ListJobs(ctx, 100)
+	found := false
+	for _, j := range jobs {
+		if j.Type == JobTypeUploadScenes && j.FileID == file.ID {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatal("upload_scenes job not created")
+	}
+}
+
+func TestQueueSourceUploads_SkipsFilesWithPendingUpload(t *testing.T) {
+	fake := &fakePipeRunner{artifacts: t.TempDir()}
+	runner, repo := setupRunnerTest(t, fake, &pipelines.Capabilities{HasScenes: true, ProbedAt: time.Now()})
+	runner.SetCloudClient(&fakeCloudClient{scenes: &fakeSceneUploader{}}, "lib-1")
+
+	ctx := context.Background()
+	_, file := createTestJobAndFile(t, repo)
+	writeSceneResult(t, fake.artifacts, file.ID)
+
+	for i, want := range []int{1, 0} {
+		queued, err := runner.QueueSourceUploads(ctx, file.SourceID)
+		if err != nil {
+			t.Fatalf("QueueSourceUploads #%d: %v", i+1, err)
+		}
+		if queued != want {
+			t.Errorf("QueueSourceUploads #%d queued = %d, want %d", i+1, queued, want)
+		}
+	}
+
+	jobs, _ := repo.ListJobs(ctx, 100)
+	uploads := 0
+	for _, j := range jobs {
+		if j.Type == JobTypeUploadScenes && j.FileID == file.ID {
+			uploads++
+		}
+	}
+	if uploads != 1 {
+		t.Errorf("upload_scenes jobs = %d, want 1", uploads)
+	}
+}
@@ -20,6 +20,8 @@ type CatalogService interface {
 	RemoveSource(ctx context.Context, id string) error
 	GetSources(ctx context.Context) ([]*Source, error)
 	GetSource(ctx context.Context, id string) (*Source, error)
+	SetSourceCloudLibrary(ctx context.Context, id, libraryID string, syncDisabled bool) (*Source, error)
+	SetSourcePrivacy(ctx context.Context, id string, policy PrivacyPolicy) (*Source, error)
 	GetFiles(ctx context.Context, sourceID string) ([]*File, error)
 	GetFile(ctx context.Context, id string) (*File, error)
 	CountFiles(ctx context.Context) (int, error)
@@ -96,6 +98,98 @@ func (s *Service) GetSource(ctx context.Context, id string) (*Source, error) {
 	return s.repo.GetSource(ctx, id)
 }
 
+// SetSourceCloudLibrary maps a source to a cloud library, or opts it out of
+// cloud sync. It returns nil when the source does not exist.
+func (s *Service) SetSourceCloudLibrary(ctx context.Context, id, libraryID string, syncDisabled bool) (*Source, error) {
+	source, err := s.repo.GetSource(ctx, id)
+	if err != nil || source == nil {
+		return nil, err
+	}
+	if err := s.repo.UpdateSourceCloudSync(ctx, id, libraryID, syncDisabled); err != nil {
+		return nil, err
+	}
+	previous := source.CloudLibraryID
+	source.CloudLibraryID = libraryID
+	source.CloudSyncDisabled = syncDisabled
+
+	if syncDisabled {
+		if err := s.supersedeOutbox(ctx, id); err != nil {
+			return nil, err
+		}
+	}
+
+	if s.logger != nil {
+		s.logger.Info("source cloud library updated",
+			"source_id", id,
+			"library_id", libraryID,
+			"sync_disabled", syncDisabled,
+		)
+		// The SaaS has no delete endpoint for scene docs, so whatever
+		// reached the old library stays there.
+		if previous != "" && (previous != libraryID || syncDisabled) {
+			s.logger.Warn("scene docs already uploaded remain in the previous cloud library",
+				"source_id", id,
+				"previous_library_id", previous,
+			)
+		}
+	}
+	return source, nil
+}
+
+// SetSourcePrivacy replaces the privacy policy applied to the source's
+// uploads. It returns nil when the source does not exist.
+func (s *Service) SetSourcePrivacy(ctx context.Context, id string, policy PrivacyPolicy) (*Source, error) {
+	for _, action := range []string{policy.Transcript, policy.OCR, policy.Faces} {
+		if action != "" && !ValidPrivacyAction(action) {
+			return nil, fmt.Errorf("invalid privacy action %q (want send, hash or drop)", action)
+		}
+	}
+
+	source, err := s.repo.GetSource(ctx, id)
+	if err != nil || source == nil {
+		return nil, err
+	}
+	if err := s.repo.UpdateSourcePrivacy(ctx, id, policy); err != nil {
+		return nil, err
+	}
+	previous := source.Privacy
+	source.Privacy = PrivacyPolicy{
+		Transcript: privacyAction(policy.Transcript),
+		OCR:        privacyAction(policy.OCR),
+		Faces:      privacyAction(policy.Faces),
+	}
+
+	// Queued payloads were built under the old policy and may carry what
+	// the new one withholds.
+	if source.Privacy.stricterThan(previous) {
+		if err := s.supersedeOutbox(ctx, id); err != nil {
+			return nil, err
+		}
+	}
+
+	if s.logger != nil {
+		s.logger.Info("source privacy policy updated",
+			"source_id", id,
+			"transcript", source.Privacy.Transcript,
+			"ocr", source.Privacy.OCR,
+			"faces", source.Privacy.Faces,
+		)
+	}
+	return source, nil
+}
+
+// supersedeOutbox drops the source's undelivered cloud mutations.
+func (s *Service) supersedeOutbox(ctx context.Context, sourceID string) error {
+	n, err := s.repo.SupersedeSourceOutbox(ctx, sourceID)
+	if err != nil {
+		return fmt.Errorf("supersede outbox: %w", err)
+	}
+	if n > 0 && s.logger != nil {
+		s.logger.Info("dropped queued cloud uploads", "source_id", sourceID, "entries", n)
+	}
+	return nil
+}
+
 func (s *Service) GetFiles(ctx context.Context, sourceID string) ([]*File, error) {
 	return s.repo.GetFilesBySource(ctx, sourceID)
 }
@@ -5,6 +5,7 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/heimdex/heimdex-agent/internal/db"
 )
@@ -147,6 +148,111 @@ func TestService_ExecuteScan_SkipsHiddenDirs(t *testing.T) {
 	}
 }
 
+func TestService_SetSourceCloudLibrary(t *testing.T) {
+	database, repo := setupTestDB(t)
+	defer database.Close()
+
+	svc := NewService(repo, nil)
+	ctx := context.Background()
+
+	source, err := svc.AddFolder(ctx, t.TempDir(), "Footage")
+	if err != nil {
+		t.Fatalf("AddFolder() error = %v", err)
+	}
+
+	updated, err := svc.SetSourceCloudLibrary(ctx, source.ID, "lib-9", false)
+	if err != nil {
+		t.Fatalf("SetSourceCloudLibrary() error = %v", err)
+	}
+	if updated.CloudLibraryID != "lib-9" || updated.CloudSyncDisabled {
+		t.Errorf("source = %+v, want library lib-9 with sync enabled", updated)
+	}
+
+	updated, err = svc.SetSourceCloudLibrary(ctx, source.ID, "", true)
+	if err != nil {
+		t.Fatalf("SetSourceCloudLibrary() error = %v", err)
+	}
+	if !updated.CloudSyncDisabled {
+		t.Error("CloudSyncDisabled = false, want true")
+	}
+
+	missing, err := svc.SetSourceCloudLibrary(ctx, "nope", "lib-9", false)
+	if err != nil || missing != nil {
+		t.Errorf("SetSourceCloudLibrary(missing) = %v, %v, want nil, nil", missing, err)
+	}
+}
+
+func TestService_SetSourcePrivacy(t *testing.T) {
+	database, repo := setupTestDB(t)
+	defer database.Close()
+
+	svc := NewService(repo, nil)
+	ctx := context.Background()
+
+	source, err := svc.AddFolder(ctx, t.TempDir(), "NDA Shoot")
+	if err != nil {
+		t.Fatalf("AddFolder() error = %v", err)
+	}
+	if source.Privacy.Transcript != "" && source.Privacy.Transcript != PrivacySend {
+		t.Errorf("default transcript action = %q, want send", source.Privacy.Transcript)
+	}
+
+	if _, err := svc.SetSourcePrivacy(ctx, source.ID, PrivacyPolicy{Transcript: "encrypt"}); err == nil {
+		t.Error("SetSourcePrivacy() accepted an unknown action")
+	}
+
+	if _, err := svc.SetSourcePrivacy(ctx, source.ID, PrivacyPolicy{Transcript: PrivacyDrop, Faces: PrivacyHash}); err != nil {
+		t.Fatalf("SetSourcePrivacy() error = %v", err)
+	}
+	stored, _ := repo.GetSource(ctx, source.ID)
+	want := PrivacyPolicy{Transcript: PrivacyDrop, OCR: PrivacySend, Faces: PrivacyHash}
+	if stored.Privacy != want {
+		t.Errorf("stored privacy = %+v, want %+v", stored.Privacy, want)
+	}
+}
+
+func TestService_SupersedesQueuedUploads(t *testing.T) {
+	database, repo := setupTestDB(t)
+	defer database.Close()
+
+	svc := NewService(repo, nil)
+	ctx := context.Background()
+
+	source, _ := svc.AddFolder(ctx, t.TempDir(), "Footage")
+	file := &File{ID: NewID(), SourceID: source.ID, Path: filepath.Join(source.Path, "a.mp4"), Filename: "a.mp4", Mtime: time.Now(), CreatedAt: time.Now()}
+	if err := repo.CreateFile(ctx, file); err != nil {
+		t.Fatalf("CreateFile() error = %v", err)
+	}
+	enqueue := func(key string) {
+		t.Helper()
+		entry := &OutboxEntry{ID: NewID(), Kind: OutboxKindScenes, FileID: file.ID, Payload: "{}", IdempotencyKey: key, Status: OutboxStatusPending, NextAttemptAt: time.Now()}
+		if _, err := repo.EnqueueOutbox(ctx, entry); err != nil {
+			t.Fatalf("EnqueueOutbox() error = %v", err)
+		}
+	}
+	pending := func() int {
+		n, _ := repo.CountOutbox(ctx, OutboxStatusPending)
+		return n
+	}
+
+	enqueue("k1")
+	svc.SetSourcePrivacy(ctx, source.ID, PrivacyPolicy{Transcript: PrivacyHash})
+	if pending() != 0 {
+		t.Error("tightening the policy left the queued upload pending")
+	}
+
+	enqueue("k2")
+	svc.SetSourcePrivacy(ctx, source.ID, PrivacyPolicy{})
+	if pending() != 1 {
+		t.Error("loosening the policy dropped the queued upload")
+	}
+
+	svc.SetSourceCloudLibrary(ctx, source.ID, "", true)
+	if pending() != 0 {
+		t.Error("switching to local-only left the queued upload pending")
+	}
+}
+
 func TestIsVideoFile(t *testing.T) {
 	tests := []struct {
 		filename string
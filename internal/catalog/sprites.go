@@ -0,0 +1,157 @@
+package catalog
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/heimdex/heimdex-agent/internal/pipeline"
+)
+
+// Sprite sheets are written to <artifacts>/<file_id>/sprites/ as
+// sprite_000.jpg, sprite_001.jpg, ... with a WebVTT track, sprites.vtt,
+// mapping each interval to a tile. The track is written last, so its
+// presence means the set is complete.
+const (
+	SpriteTrackName = "sprites.vtt"
+
+	spriteInterval  = 5.0
+	spriteTileWidth = 160
+	spriteColumns   = 10
+	spriteRows      = 10
+)
+
+// SpriteDir returns where a file's sprite sheets and track are kept.
+func SpriteDir(artifactsDir, fileID string) string {
+	return filepath.Join(artifactsDir, fileID, "sprites")
+}
+
+// SpriteSheetName returns the file name of sprite sheet n.
+func SpriteSheetName(n int) string {
+	return fmt.Sprintf("sprite_%03d.jpg", n)
+}
+
+// spriteLayout sizes tiles to the video's aspect ratio, assuming 16:9 when
+// the probe has no dimensions.
+func spriteLayout(probe *pipeline.ProbeResult) pipeline.SpriteLayout {
+	height := spriteTileWidth * 9 / 16
+	if probe != nil && probe.Width > 0 && probe.Height > 0 {
+		height = int(math.Round(float64(spriteTileWidth)*float64(probe.Height)/float64(probe.Width)/2)) * 2
+		height = max(height, 2)
+	}
+	return pipeline.SpriteLayout{
+		Interval:   spriteInterval,
+		TileWidth:  spriteTileWidth,
+		TileHeight: height,
+		Columns:    spriteColumns,
+		Rows:       spriteRows,
+	}
+}
+
+// spriteVTT builds a WebVTT thumbnail track. Each cue's payload is the
+// sheet URL, relative to the track and prefixed with prefix, plus a
+// #xywh media fragment selecting the tile.
+func spriteVTT(prefix string, duration float64, layout pipeline.SpriteLayout) string {
+	var b strings.Builder
+	b.WriteString("WEBVTT\n")
+	tiles := int(math.Ceil(duration / layout.Interval))
+	perSheet := layout.TilesPerSheet()
+	for i := 0; i < tiles; i++ {
+		start := float64(i) * layout.Interval
+		end := math.Min(start+layout.Interval, duration)
+		pos := i % perSheet
+		fmt.Fprintf(&b, "\n%s --> %s\n%s%s#xywh=%d,%d,%d,%d\n",
+			vttTimestamp(start), vttTimestamp(end),
+			prefix, SpriteSheetName(i/perSheet),
+			(pos%layout.Columns)*layout.TileWidth, (pos/layout.Columns)*layout.TileHeight,
+			layout.TileWidth, layout.TileHeight,
+		)
+	}
+	return b.String()
+}
+
+// vttTimestamp formats seconds as HH:MM:SS.mmm.
+func vttTimestamp(seconds float64) string {
+	ms := int64(math.Round(seconds * 1000))
+	return fmt.Sprintf("%02d:%02d:%02d.%03d", ms/3600000, ms/60000%60, ms/1000%60, ms%1000)
+}
+
+func (r *Runner) processGenerateSpritesJob(ctx context.Context, job *Job) {
+	if r.pipeRunner == nil || r.ffmpeg == nil {
+		r.repo.UpdateJobStatus(ctx, job.ID, JobStatusFailed, "sprite generation not configured")
+		return
+	}
+
+	r.repo.UpdateJobStatus(ctx, job.ID, JobStatusRunning, "")
+
+	file, err := r.repo.GetFile(ctx, job.FileID)
+	if err != nil || file == nil {
+		r.repo.UpdateJobStatus(ctx, job.ID, JobStatusFailed, "file not found")
+		return
+	}
+
+	count, err := r.generateSprites(ctx, file)
+	if err != nil {
+		r.logger.Warn("sprite generation failed", "job_id", job.ID, "file_id", file.ID, "error", err)
+		r.repo.UpdateJobStatus(ctx, job.ID, JobStatusFailed, err.Error())
+		return
+	}
+
+	r.logger.Info("sprites generated", "file_id", file.ID, "sheets", count)
+	r.repo.UpdateJobStatus(ctx, job.ID, JobStatusCompleted, "")
+}
+
+// generateSprites replaces the file's sprite sheets and track and returns
+// the number of sheets.
+func (r *Runner) generateSprites(ctx context.Context, file *File) (int, error) {
+	probe, err := r.ffmpeg.Probe(file.Path)
+	if err != nil {
+		return 0, fmt.Errorf("probe: %w", err)
+	}
+	if probe.Duration <= 0 {
+		return 0, fmt.Errorf("unknown media duration")
+	}
+	layout := spriteLayout(probe)
+
+	dir := SpriteDir(r.pipeRunner.ArtifactsDir(), file.ID)
+	if err := os.RemoveAll(dir); err != nil {
+		return 0, fmt.Errorf("clear sprite dir: %w", err)
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return 0, fmt.Errorf("create sprite dir: %w", err)
+	}
+	if err := r.ffmpeg.GenerateSprites(ctx, file.Path, filepath.Join(dir, "sprite_%03d.jpg"), layout); err != nil {
+		return 0, err
+	}
+
+	sheets := int(math.Ceil(math.Ceil(probe.Duration/layout.Interval) / float64(layout.TilesPerSheet())))
+	for n := 0; n < sheets; n++ {
+		if _, err := os.Stat(filepath.Join(dir, SpriteSheetName(n))); err != nil {
+			return 0, fmt.Errorf("sprite sheet %d missing", n)
+		}
+	}
+
+	track := filepath.Join(dir, SpriteTrackName)
+	vtt := spriteVTT(file.ID+"/", probe.Duration, layout)
+	if err := os.WriteFile(track+".tmp", []byte(vtt), 0o644); err != nil {
+		return 0, fmt.Errorf("write sprite track: %w", err)
+	}
+	if err := os.Rename(track+".tmp", track); err != nil {
+		os.Remove(track + ".tmp")
+		return 0, fmt.Errorf("store sprite track: %w", err)
+	}
+	return sheets, nil
+}
+
+// backfillSprites queues sprites for indexed files that have none and have
+// never had a sprite job.
+func (r *Runner) backfillSprites(ctx context.Context) {
+	r.backfillFileJobs(ctx, JobTypeGenerateSprites, func(file *File) bool {
+		track := filepath.Join(SpriteDir(r.pipeRunner.ArtifactsDir(), file.ID), SpriteTrackName)
+		_, err := os.Stat(track)
+		return err != nil
+	})
+}
@@ -0,0 +1,144 @@
+package catalog
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/heimdex/heimdex-agent/internal/pipeline"
+	"github.com/heimdex/heimdex-agent/internal/pipelines"
+)
+
+// fakeSpriteFFmpeg writes empty sprite sheets for a fixed-length video.
+type fakeSpriteFFmpeg struct {
+	*pipeline.StubFFmpeg
+	probe  *pipeline.ProbeResult
+	sheets int
+}
+
+func (f *fakeSpriteFFmpeg) Probe(string) (*pipeline.ProbeResult, error) {
+	return f.probe, nil
+}
+
+func (f *fakeSpriteFFmpeg) GenerateSprites(ctx context.Context, filePath, outputPattern string, layout pipeline.SpriteLayout) error {
+	for n := 0; n < f.sheets; n++ {
+		path := filepath.Join(filepath.Dir(outputPattern), SpriteSheetName(n))
+		if err := os.WriteFile(path, []byte("jpg"), 0o644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestSpriteVTT(t *testing.T) {
+	layout := pipeline.SpriteLayout{Interval: 5, TileWidth: 160, TileHeight: 90, Columns: 2, Rows: 2}
+	got := spriteVTT("f1/", 23.5, layout)
+
+	want := "WEBVTT\n" +
+		"\n00:00:00.000 --> 00:00:05.000\nf1/sprite_000.jpg#xywh=0,0,160,90\n" +
+		"\n00:00:05.000 --> 00:00:10.000\nf1/sprite_000.jpg#xywh=160,0,160,90\n" +
+		"\n00:00:10.000 --> 00:00:15.000\nf1/sprite_000.jpg#xywh=0,90,160,90\n" +
+		"\n00:00:15.000 --> 00:00:20.000\nf1/sprite_000.jpg#xywh=160,90,160,90\n" +
+		"\n00:00:20.000 --> 00:00:23.500\nf1/sprite_001.jpg#xywh=0,0,160,90\n"
+	if got != want {
+		t.Errorf("spriteVTT() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestVTTTimestamp(t *testing.T) {
+	tests := map[float64]string{0: "00:00:00.000", 65.25: "00:01:05.250", 3725.5: "01:02:05.500"}
+	for in, want := range tests {
+		if got := vttTimestamp(in); got != want {
+			t.Errorf("vttTimestamp(%v) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestSpriteLayout_FollowsAspectRatio(t *testing.T) {
+	if l := spriteLayout(&pipeline.ProbeResult{Width: 1920, Height: 1080}); l.TileHeight != 90 {
+		t.Errorf("16:9 TileHeight = %d, want 90", l.TileHeight)
+	}
+	if l := spriteLayout(&pipeline.ProbeResult{Width: 1080, Height: 1920}); l.TileHeight != 284 {
+		t.Errorf("9:16 TileHeight = %d, want 284", l.TileHeight)
+	}
+	if l := spriteLayout(&pipeline.ProbeResult{}); l.TileHeight != 90 {
+		t.Errorf("unknown size TileHeight = %d, want 90", l.TileHeight)
+	}
+}
+
+func TestRunner_GenerateSprites(t *testing.T) {
+	artifacts := t.TempDir()
+	runner, repo := setupRunnerTest(t, &fakePipeRunner{artifacts: artifacts}, &pipelines.Capabilities{})
+	ff := &fakeSpriteFFmpeg{
+		StubFFmpeg: pipeline.NewStubFFmpeg(slog.New(slog.NewTextHandler(io.Discard, nil))),
+		probe:      &pipeline.ProbeResult{Duration: 600, Width: 1920, Height: 1080},
+		sheets:     2,
+	}
+	runner.ffmpeg = ff
+	ctx := context.Background()
+	_, file := createTestJobAndFile(t, repo)
+
+	job := &Job{ID: NewID(), Type: JobTypeGenerateSprites, Status: JobStatusPending, FileID: file.ID}
+	if err := repo.CreateJob(ctx, job); err != nil {
+		t.Fatal(err)
+	}
+	runner.processGenerateSpritesJob(ctx, job)
+
+	if got, _ := repo.GetJob(ctx, job.ID); got.Status != JobStatusCompleted {
+		t.Fatalf("job = %+v, want completed", got)
+	}
+	data, err := os.ReadFile(filepath.Join(SpriteDir(artifacts, file.ID), SpriteTrackName))
+	if err != nil {
+		t.Fatalf("track missing: %v", err)
+	}
+	vtt := string(data)
+	if strings.Count(vtt, " --> ") != 120 {
+		t.Errorf("track has %d cues, want 120", strings.Count(vtt, " --> "))
+	}
+	if !strings.Contains(vtt, file.ID+"/sprite_001.jpg#xywh=") {
+		t.Error("track does not reference the second sheet")
+	}
+
+	// A missing sheet fails the job rather than publishing a broken track.
+	ff.sheets = 1
+	job2 := &Job{ID: NewID(), Type: JobTypeGenerateSprites, Status: JobStatusPending, FileID: file.ID}
+	repo.CreateJob(ctx, job2)
+	runner.processGenerateSpritesJob(ctx, job2)
+	if got, _ := repo.GetJob(ctx, job2.ID); got.Status != JobStatusFailed {
+		t.Errorf("job = %+v, want failed", got)
+	}
+	if _, err := os.Stat(filepath.Join(SpriteDir(artifacts, file.ID), SpriteTrackName)); !os.IsNotExist(err) {
+		t.Errorf("stale track left behind: %v", err)
+	}
+}
+
+func TestRunner_BackfillSprites(t *testing.T) {
+	artifacts := t.TempDir()
+	runner, repo := setupRunnerTest(t, &fakePipeRunner{artifacts: artifacts}, &pipelines.Capabilities{})
+	ctx := context.Background()
+
+	_, file := createTestJobAndFile(t, repo)
+	writeSidecarResult(t, artifacts, file.ID, "speech")
+	failed := &File{ID: NewID(), SourceID: file.SourceID, Path: "/test/videos/b.mp4", Filename: "b.mp4", Mtime: time.Now(), CreatedAt: time.Now()}
+	repo.CreateFile(ctx, failed)
+	writeSidecarResult(t, artifacts, failed.ID, "speech")
+	repo.CreateJob(ctx, &Job{ID: NewID(), Type: JobTypeGenerateSprites, Status: JobStatusFailed, FileID: failed.ID, CreatedAt: time.Now(), UpdatedAt: time.Now()})
+
+	runner.backfillSprites(ctx)
+
+	pending, _ := repo.ListPendingJobs(ctx)
+	var queued []string
+	for _, j := range pending {
+		if j.Type == JobTypeGenerateSprites {
+			queued = append(queued, j.FileID)
+		}
+	}
+	if len(queued) != 1 || queued[0] != file.ID {
+		t.Errorf("queued = %v, want only %s", queued, file.ID)
+	}
+}
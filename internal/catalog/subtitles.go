@@ -0,0 +1,53 @@
+package catalog
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/heimdex/heimdex-agent/internal/pipelines"
+	"github.com/heimdex/heimdex-agent/internal/subtitles"
+)
+
+// LoadCaptions returns a file's transcript as caption cues. Speech segments
+// are used when the speech pipeline ran; otherwise each scene's transcript
+// becomes cues spread over the scene. Long text is split to caption length.
+// It returns nil when the file has no transcript.
+func LoadCaptions(ctx context.Context, repo Repository, artifactsDir, fileID string) ([]subtitles.Cue, error) {
+	var cues []subtitles.Cue
+
+	data, err := os.ReadFile(filepath.Join(artifactsDir, fileID, "speech", "result.json"))
+	switch {
+	case err == nil:
+		var output pipelines.SpeechOutputPayload
+		if err := json.Unmarshal(data, &output); err != nil {
+			return nil, fmt.Errorf("invalid speech JSON: %w", err)
+		}
+		for _, seg := range output.Segments {
+			if seg.EndMs > seg.StartMs {
+				cues = append(cues, subtitles.Cue{StartMs: seg.StartMs, EndMs: seg.EndMs, Text: seg.Text})
+			}
+		}
+	case !os.IsNotExist(err):
+		return nil, fmt.Errorf("read speech output: %w", err)
+	}
+
+	if len(cues) == 0 {
+		scenes, err := LoadScenes(ctx, repo, artifactsDir, fileID)
+		if err != nil {
+			return nil, err
+		}
+		for _, s := range scenes {
+			if s.Transcript != "" && s.EndMs > s.StartMs {
+				cues = append(cues, subtitles.Cue{StartMs: s.StartMs, EndMs: s.EndMs, Text: s.Transcript})
+			}
+		}
+	}
+
+	if len(cues) == 0 {
+		return nil, nil
+	}
+	return subtitles.Split(cues, subtitles.MaxCueChars), nil
+}
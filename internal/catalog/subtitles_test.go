@@ -0,0 +1,67 @@
+package catalog
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/heimdex/heimdex-agent/internal/pipelines"
+	"github.com/heimdex/heimdex-agent/internal/subtitles"
+)
+
+func writeArtifact(t *testing.T, artifacts, fileID, kind string, v any) {
+	t.Helper()
+	dir := filepath.Join(artifacts, fileID, kind)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := json.Marshal(v)
+	if err := os.WriteFile(filepath.Join(dir, "result.json"), data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadCaptions(t *testing.T) {
+	_, repo := setupRunnerTest(t, &fakePipeRunner{}, &pipelines.Capabilities{})
+	artifacts := t.TempDir()
+	ctx := context.Background()
+
+	scenes := pipelines.SceneOutputPayload{Scenes: []pipelines.SceneBoundary{
+		{SceneID: "f_scene_0", StartMs: 0, EndMs: 4000, TranscriptRaw: strings.Repeat("word ", 30)},
+		{SceneID: "f_scene_1", StartMs: 4000, EndMs: 6000},
+	}}
+	writeArtifact(t, artifacts, "f", "scenes", scenes)
+
+	cues, err := LoadCaptions(ctx, repo, artifacts, "f")
+	if err != nil {
+		t.Fatalf("LoadCaptions: %v", err)
+	}
+	if len(cues) != 2 || cues[0].StartMs != 0 || cues[1].EndMs != 4000 {
+		t.Fatalf("scene fallback cues = %+v, want the scene transcript split in two", cues)
+	}
+	for _, c := range cues {
+		if len(c.Text) > subtitles.MaxCueChars {
+			t.Errorf("cue %q longer than %d", c.Text, subtitles.MaxCueChars)
+		}
+	}
+
+	writeArtifact(t, artifacts, "f", "speech", map[string]any{"segments": []map[string]any{
+		{"start": 0.5, "end": 1.25, "text": "hello"},
+		{"start": 2.0, "end": 2.0, "text": "empty span"},
+	}})
+	cues, err = LoadCaptions(ctx, repo, artifacts, "f")
+	if err != nil {
+		t.Fatalf("LoadCaptions: %v", err)
+	}
+	if len(cues) != 1 || cues[0] != (subtitles.Cue{StartMs: 500, EndMs: 1250, Text: "hello"}) {
+		t.Errorf("speech cues = %+v, want speech segments over scene transcripts", cues)
+	}
+
+	cues, err = LoadCaptions(ctx, repo, artifacts, "missing")
+	if err != nil || cues != nil {
+		t.Errorf("no transcript = %+v, %v; want nil, nil", cues, err)
+	}
+}
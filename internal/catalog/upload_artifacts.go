@@ -0,0 +1,160 @@
+package catalog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/heimdex/heimdex-agent/internal/cloud"
+)
+
+// artifactSidecarKinds are the pipeline outputs pushed to the cloud after
+// indexing, in upload order. Scene documents are sent separately through
+// the ingest endpoint.
+var artifactSidecarKinds = []string{"speech", "faces"}
+
+// processUploadArtifactsJob pushes a file's metadata and pipeline sidecars
+// to the cloud.
+func (r *Runner) processUploadArtifactsJob(ctx context.Context, job *Job) {
+	r.runCloudUploadJob(ctx, job, func(ctx context.Context, file *File) error {
+		uploaded, err := r.uploadArtifacts(ctx, file)
+		if err == nil {
+			r.logger.Info("artifacts uploaded", "job_id", job.ID, "file_id", file.ID, "sidecars", uploaded)
+		}
+		return err
+	})
+}
+
+// runCloudUploadJob drives a per-file cloud upload job. Retries reuse the
+// upload_scenes backoff, with Progress counting attempts; permanent HTTP
+// errors fail the job immediately. Like the outbox, the job waits without
+// spending an attempt while the SaaS is unreachable.
+func (r *Runner) runCloudUploadJob(ctx context.Context, job *Job, upload func(ctx context.Context, file *File) error) {
+	const maxRetries = 5
+
+	if r.outbox != nil && r.outbox.Offline() {
+		return
+	}
+
+	delay := uploadBackoff(job.Progress)
+	if job.Progress > 0 && time.Since(job.UpdatedAt) < delay {
+		return
+	}
+
+	attempt := job.Progress + 1
+	if attempt > maxRetries {
+		r.repo.UpdateJobStatus(ctx, job.ID, JobStatusFailed, fmt.Sprintf("max retries (%d) exceeded: %s", maxRetries, job.Error))
+		return
+	}
+
+	if r.pipeRunner == nil {
+		r.repo.UpdateJobStatus(ctx, job.ID, JobStatusFailed, "pipeline runner not configured")
+		return
+	}
+	if r.cloudClient == nil {
+		r.repo.UpdateJobStatus(ctx, job.ID, JobStatusFailed, "cloud client not configured")
+		return
+	}
+
+	file, err := r.repo.GetFile(ctx, job.FileID)
+	if err != nil || file == nil {
+		r.repo.UpdateJobStatus(ctx, job.ID, JobStatusFailed, "file not found")
+		return
+	}
+
+	if r.cloudSyncDisabled(ctx, file) {
+		r.repo.UpdateJobStatus(ctx, job.ID, JobStatusCompleted, "")
+		return
+	}
+
+	r.repo.UpdateJobStatus(ctx, job.ID, JobStatusRunning, "")
+	r.repo.UpdateJobProgress(ctx, job.ID, attempt)
+
+	uploadCtx, cancel := context.WithTimeout(ctx, 10*time.Minute)
+	defer cancel()
+
+	if err := upload(uploadCtx, file); err != nil {
+		if cloud.IsNetworkError(err) {
+			if r.outbox != nil {
+				r.outbox.setOffline(err)
+			}
+			r.repo.UpdateJobProgress(ctx, job.ID, job.Progress)
+			r.repo.UpdateJobStatus(ctx, job.ID, JobStatusPending, err.Error())
+			return
+		}
+
+		var uploadErr *cloud.UploadError
+		if errors.As(err, &uploadErr) && !uploadErr.IsRetryable() {
+			r.repo.UpdateJobStatus(ctx, job.ID, JobStatusFailed, fmt.Sprintf("permanent error (HTTP %d): %s", uploadErr.StatusCode, uploadErr.Body))
+			return
+		}
+
+		r.logger.Warn("cloud upload job failed", "job_id", job.ID, "type", job.Type, "attempt", attempt, "error", err)
+		r.repo.UpdateJobStatus(ctx, job.ID, JobStatusPending, err.Error())
+		return
+	}
+
+	r.repo.UpdateJobStatus(ctx, job.ID, JobStatusCompleted, "")
+}
+
+// uploadArtifacts queues file metadata in the outbox and uploads every
+// sidecar present on disk.
+// It returns the number of sidecars transferred.
+func (r *Runner) uploadArtifacts(ctx context.Context, file *File) (int, error) {
+	source, _ := r.repo.GetSource(ctx, file.SourceID)
+
+	metadata := map[string]interface{}{
+		"video_id":    file.ID,
+		"filename":    file.Filename,
+		"size":        file.Size,
+		"mtime":       file.Mtime.UTC().Format(time.RFC3339),
+		"fingerprint": file.Fingerprint,
+		"source_type": resolveSourceType(source),
+	}
+	if source != nil && source.CloudLibraryID != "" {
+		metadata["library_id"] = source.CloudLibraryID
+	}
+
+	if r.outbox != nil {
+		if _, err := r.outbox.Enqueue(ctx, OutboxKindMetadata, file.ID, metadata); err != nil {
+			return 0, err
+		}
+	}
+
+	var privacy PrivacyPolicy
+	if source != nil {
+		privacy = source.Privacy
+	}
+
+	upload := r.cloudClient.Upload()
+	uploaded := 0
+	for _, kind := range artifactSidecarKinds {
+		if !sidecarAllowed(kind, privacy) {
+			r.logger.Info("sidecar withheld by privacy policy", "file_id", file.ID, "kind", kind)
+			continue
+		}
+		path := filepath.Join(r.pipeRunner.ArtifactsDir(), file.ID, kind, "result.json")
+		if _, err := os.Stat(path); err != nil {
+			continue
+		}
+		result, err := upload.UploadSidecar(ctx, file.ID, kind, path)
+		if err != nil {
+			return uploaded, fmt.Errorf("%s sidecar: %w", kind, err)
+		}
+		if !result.Skipped {
+			uploaded++
+			hash, _ := fileSHA256(path)
+			recordCloudAudit(ctx, r.repo, r.logger, &CloudAudit{
+				FileID:      file.ID,
+				Kind:        "sidecar:" + kind,
+				PayloadHash: hash,
+				Fields:      []string{kind + "_sidecar"},
+				SentAt:      time.Now(),
+			})
+		}
+	}
+	return uploaded, nil
+}
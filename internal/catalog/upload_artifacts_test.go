@@ -0,0 +1,162 @@
+package catalog
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/heimdex/heimdex-agent/internal/cloud"
+	"github.com/heimdex/heimdex-agent/internal/pipelines"
+)
+
+type fakeUploadService struct {
+	metadata  map[string]map[string]interface{}
+	sidecars  []string
+	sidecarFn func(kind string) error
+}
+
+func (f *fakeUploadService) UploadMetadata(ctx context.Context, fileID string, metadata map[string]interface{}) error {
+	if f.metadata == nil {
+		f.metadata = map[string]map[string]interface{}{}
+	}
+	f.metadata[fileID] = metadata
+	return nil
+}
+
+func (f *fakeUploadService) UploadSidecar(ctx context.Context, fileID, kind, sidecarPath string) (*cloud.SidecarUploadResult, error) {
+	if f.sidecarFn != nil {
+		if err := f.sidecarFn(kind); err != nil {
+			return nil, err
+		}
+	}
+	f.sidecars = append(f.sidecars, kind)
+	return &cloud.SidecarUploadResult{ObjectKey: fileID + "/" + kind}, nil
+}
+
+func (f *fakeUploadService) GetPresignedURL(ctx context.Context, req cloud.PresignRequest) (*cloud.PresignedUpload, error) {
+	return &cloud.PresignedUpload{AlreadyExists: true}, nil
+}
+
+func writeSidecarResult(t *testing.T, artifactsDir, fileID, kind string) {
+	t.Helper()
+	dir := filepath.Join(artifactsDir, fileID, kind)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "result.json"), []byte(`{"schema_version":"1.0"}`), 0o644); err != nil {
+		t.Fatalf("write sidecar: %v", err)
+	}
+}
+
+func TestProcessIndexJob_EnqueuesUploadArtifacts(t *testing.T) {
+	fake := &fakePipeRunner{artifacts: t.TempDir()}
+	caps := &pipelines.Capabilities{HasSpeech: true, HasFaces: true, ProbedAt: time.Now()}
+
+	runner, repo := setupRunnerTest(t, fake, caps)
+	runner.SetCloudClient(&fakeCloudClient{scenes: &fakeSceneUploader{}}, "lib-1")
+	job, file := createTestJobAndFile(t, repo)
+
+	runner.processIndexJob(context.Background(), job)
+
+	jobs, _ := repo.ListPendingJobs(context.Background())
+	found := false
+	for _, j := range jobs {
+		if j.Type == JobTypeUploadArtifacts && j.FileID == file.ID {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatal("expected pending upload_artifacts job after indexing")
+	}
+}
+
+func TestProcessUploadArtifactsJob_UploadsPresentSidecars(t *testing.T) {
+	fake := &fakePipeRunner{artifacts: t.TempDir()}
+	runner, repo := setupRunnerTest(t, fake, &pipelines.Capabilities{ProbedAt: time.Now()})
+
+	upload := &fakeUploadService{}
+	runner.SetCloudClient(&fakeCloudClient{upload: upload}, "lib-1")
+
+	_, file := createTestJobAndFile(t, repo)
+	writeSidecarResult(t, fake.artifacts, file.ID, "speech")
+
+	job := &Job{ID: NewID(), Type: JobTypeUploadArtifacts, Status: JobStatusPending, FileID: file.ID, CreatedAt: time.Now(), UpdatedAt: time.Now()}
+	repo.CreateJob(context.Background(), job)
+
+	runner.processUploadArtifactsJob(context.Background(), job)
+	runner.Outbox().Flush(context.Background())
+
+	updated, _ := repo.GetJob(context.Background(), job.ID)
+	if updated.Status != JobStatusCompleted {
+		t.Fatalf("job status = %s (%s), want completed", updated.Status, updated.Error)
+	}
+	if len(upload.sidecars) != 1 || upload.sidecars[0] != "speech" {
+		t.Fatalf("sidecars = %v, want [speech]", upload.sidecars)
+	}
+	if upload.metadata[file.ID]["filename"] != "clip.mp4" {
+		t.Fatalf("metadata = %+v", upload.metadata[file.ID])
+	}
+}
+
+func TestProcessUploadArtifactsJob_RetryableErrorStaysPending(t *testing.T) {
+	fake := &fakePipeRunner{artifacts: t.TempDir()}
+	runner, repo := setupRunnerTest(t, fake, &pipelines.Capabilities{ProbedAt: time.Now()})
+
+	upload := &fakeUploadService{sidecarFn: func(kind string) error {
+		return &cloud.UploadError{StatusCode: 503, Body: "unavailable"}
+	}}
+	runner.SetCloudClient(&fakeCloudClient{upload: upload}, "lib-1")
+
+	_, file := createTestJobAndFile(t, repo)
+	writeSidecarResult(t, fake.artifacts, file.ID, "faces")
+
+	job := &Job{ID: NewID(), Type: JobTypeUploadArtifacts, Status: JobStatusPending, FileID: file.ID, CreatedAt: time.Now(), UpdatedAt: time.Now()}
+	repo.CreateJob(context.Background(), job)
+
+	runner.processUploadArtifactsJob(context.Background(), job)
+
+	updated, _ := repo.GetJob(context.Background(), job.ID)
+	if updated.Status != JobStatusPending {
+		t.Fatalf("job status = %s, want pending for retry", updated.Status)
+	}
+	if updated.Progress != 1 {
+		t.Fatalf("attempt counter = %d, want 1", updated.Progress)
+	}
+}
+
+func TestProcessUploadArtifactsJob_NetworkErrorKeepsAttempts(t *testing.T) {
+	fake := &fakePipeRunner{artifacts: t.TempDir()}
+	runner, repo := setupRunnerTest(t, fake, &pipelines.Capabilities{ProbedAt: time.Now()})
+
+	calls := 0
+	upload := &fakeUploadService{sidecarFn: func(kind string) error {
+		calls++
+		return &net.OpError{Op: "dial", Err: errors.New("connection refused")}
+	}}
+	runner.SetCloudClient(&fakeCloudClient{upload: upload}, "lib-1")
+
+	_, file := createTestJobAndFile(t, repo)
+	writeSidecarResult(t, fake.artifacts, file.ID, "faces")
+
+	job := &Job{ID: NewID(), Type: JobTypeUploadArtifacts, Status: JobStatusPending, FileID: file.ID, CreatedAt: time.Now(), UpdatedAt: time.Now()}
+	repo.CreateJob(context.Background(), job)
+
+	runner.processUploadArtifactsJob(context.Background(), job)
+
+	updated, _ := repo.GetJob(context.Background(), job.ID)
+	if updated.Status != JobStatusPending || updated.Progress != 0 {
+		t.Fatalf("job = %s attempt %d, want pending with no attempt spent", updated.Status, updated.Progress)
+	}
+	if !runner.Outbox().Offline() {
+		t.Fatal("outbox not marked offline after a network error")
+	}
+
+	runner.processUploadArtifactsJob(context.Background(), updated)
+	if calls != 1 {
+		t.Errorf("uploads = %d, want no retry while offline", calls)
+	}
+}
@@ -0,0 +1,149 @@
+package catalog
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/heimdex/heimdex-agent/internal/cloud"
+	"github.com/heimdex/heimdex-agent/internal/pipelines"
+)
+
+// processUploadThumbnailsJob pushes each scene's keyframe JPEG to the cloud
+// and, when any scene gained a thumbnail, re-sends the file's scene docs so
+// the SaaS learns the new keys.
+func (r *Runner) processUploadThumbnailsJob(ctx context.Context, job *Job) {
+	r.runCloudUploadJob(ctx, job, func(ctx context.Context, file *File) error {
+		changed, err := r.uploadThumbnails(ctx, file)
+		if err != nil {
+			return err
+		}
+		r.logger.Info("thumbnails synced to cloud", "job_id", job.ID, "file_id", file.ID, "changed", changed)
+		if changed > 0 {
+			r.enqueueFileJob(ctx, JobTypeUploadScenes, file.ID)
+		}
+		return nil
+	})
+}
+
+// uploadThumbnails uploads thumbnails whose content is not yet in the cloud
+// and records the object key per scene. A JPEG whose hash was already
+// uploaded for any scene reuses that object without a transfer. Each
+// transfer is audited. It returns the number of scenes whose thumbnail
+// record changed.
+func (r *Runner) uploadThumbnails(ctx context.Context, file *File) (int, error) {
+	scenePath := filepath.Join(r.pipeRunner.ArtifactsDir(), file.ID, "scenes", "result.json")
+	data, err := os.ReadFile(scenePath)
+	if err != nil {
+		return 0, fmt.Errorf("cannot read scene result: %w", err)
+	}
+
+	var sceneOutput pipelines.SceneOutputPayload
+	if err := json.Unmarshal(data, &sceneOutput); err != nil {
+		return 0, fmt.Errorf("invalid scene JSON: %w", err)
+	}
+
+	existing, err := r.repo.ListThumbnailUploads(ctx, file.ID)
+	if err != nil {
+		return 0, err
+	}
+	uploadedScenes := make(map[string]*ThumbnailUpload, len(existing))
+	for _, u := range existing {
+		uploadedScenes[u.SceneID] = u
+	}
+
+	thumbDir := filepath.Join(r.pipeRunner.ArtifactsDir(), file.ID, "thumbnails")
+	changed := 0
+	for _, scene := range sceneOutput.Scenes {
+		path := filepath.Join(thumbDir, scene.SceneID+".jpg")
+		if _, err := os.Stat(path); err != nil {
+			continue
+		}
+
+		sum, err := fileSHA256(path)
+		if err != nil {
+			return changed, err
+		}
+		if prev := uploadedScenes[scene.SceneID]; prev != nil && prev.SHA256 == sum {
+			continue
+		}
+
+		record := &ThumbnailUpload{
+			SceneID:    scene.SceneID,
+			FileID:     file.ID,
+			SHA256:     sum,
+			UploadedAt: time.Now(),
+		}
+
+		dup, err := r.repo.GetThumbnailUploadBySHA256(ctx, sum)
+		if err != nil {
+			return changed, err
+		}
+		if dup != nil {
+			record.ObjectKey = dup.ObjectKey
+			record.URL = dup.URL
+		} else {
+			result, err := r.cloudClient.Upload().UploadSidecar(ctx, file.ID, "thumbnail", path)
+			if err != nil {
+				return changed, fmt.Errorf("thumbnail %s: %w", scene.SceneID, err)
+			}
+			record.ObjectKey = result.ObjectKey
+			record.URL = result.URL
+			if !result.Skipped {
+				recordCloudAudit(ctx, r.repo, r.logger, &CloudAudit{
+					FileID:      file.ID,
+					Kind:        "thumbnail",
+					PayloadHash: sum,
+					Fields:      []string{"thumbnail"},
+					SentAt:      time.Now(),
+				})
+			}
+		}
+
+		if err := r.repo.UpsertThumbnailUpload(ctx, record); err != nil {
+			return changed, err
+		}
+		changed++
+	}
+	return changed, nil
+}
+
+// attachThumbnails fills in thumbnail keys for scenes whose keyframe has
+// been uploaded. Scenes without an upload are left untouched.
+func (r *Runner) attachThumbnails(ctx context.Context, fileID string, docs []cloud.SceneIngestDoc) {
+	uploads, err := r.repo.ListThumbnailUploads(ctx, fileID)
+	if err != nil || len(uploads) == 0 {
+		return
+	}
+
+	bySceneID := make(map[string]*ThumbnailUpload, len(uploads))
+	for _, u := range uploads {
+		bySceneID[u.SceneID] = u
+	}
+	for i := range docs {
+		if u, ok := bySceneID[docs[i].SceneID]; ok {
+			docs[i].ThumbnailKey = u.ObjectKey
+			docs[i].ThumbnailURL = u.URL
+		}
+	}
+}
+
+func fileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,168 @@
+package catalog
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/heimdex/heimdex-agent/internal/cloud"
+	"github.com/heimdex/heimdex-agent/internal/pipelines"
+)
+
+func writeThumbnail(t *testing.T, artifactsDir, fileID, sceneID, content string) {
+	t.Helper()
+	dir := filepath.Join(artifactsDir, fileID, "thumbnails")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatalf("mkdir thumbnails: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, sceneID+".jpg"), []byte(content), 0o644); err != nil {
+		t.Fatalf("write thumbnail: %v", err)
+	}
+}
+
+func TestUploadThumbnails_DedupesByHash(t *testing.T) {
+	fake := &fakePipeRunner{artifacts: t.TempDir()}
+	runner, repo := setupRunnerTest(t, fake, &pipelines.Capabilities{ProbedAt: time.Now()})
+
+	upload := &fakeUploadService{}
+	runner.SetCloudClient(&fakeCloudClient{upload: upload}, "lib-1")
+
+	_, file := createTestJobAndFile(t, repo)
+	writeSceneResultWithPayload(t, fake.artifacts, file.ID, pipelines.SceneOutputPayload{
+		VideoID: file.ID,
+		Scenes: []pipelines.SceneBoundary{
+			{SceneID: file.ID + "_scene_0", Index: 0, StartMs: 0, EndMs: 1000},
+			{SceneID: file.ID + "_scene_1", Index: 1, StartMs: 1000, EndMs: 2000},
+			{SceneID: file.ID + "_scene_2", Index: 2, StartMs: 2000, EndMs: 3000},
+		},
+	})
+	// Scenes 0 and 1 share identical keyframes; scene 2 has no thumbnail.
+	writeThumbnail(t, fake.artifacts, file.ID, file.ID+"_scene_0", "jpeg-a")
+	writeThumbnail(t, fake.artifacts, file.ID, file.ID+"_scene_1", "jpeg-a")
+
+	changed, err := runner.uploadThumbnails(context.Background(), file)
+	if err != nil {
+		t.Fatalf("uploadThumbnails: %v", err)
+	}
+	if changed != 2 {
+		t.Fatalf("changed = %d, want 2", changed)
+	}
+	if len(upload.sidecars) != 1 {
+		t.Fatalf("uploads = %d, want 1 for identical content", len(upload.sidecars))
+	}
+
+	records, _ := repo.ListThumbnailUploads(context.Background(), file.ID)
+	if len(records) != 2 || records[0].ObjectKey != records[1].ObjectKey {
+		t.Fatalf("records = %+v, want two scenes sharing one object", records)
+	}
+
+	changed, err = runner.uploadThumbnails(context.Background(), file)
+	if err != nil {
+		t.Fatalf("second uploadThumbnails: %v", err)
+	}
+	if changed != 0 || len(upload.sidecars) != 1 {
+		t.Fatalf("second run changed=%d uploads=%d, want nothing new", changed, len(upload.sidecars))
+	}
+}
+
+func TestUploadThumbnails_RecordsAudit(t *testing.T) {
+	fake := &fakePipeRunner{artifacts: t.TempDir()}
+	runner, repo := setupRunnerTest(t, fake, &pipelines.Capabilities{ProbedAt: time.Now()})
+	runner.SetCloudClient(&fakeCloudClient{upload: &fakeUploadService{}}, "lib-1")
+	ctx := context.Background()
+
+	_, file := createTestJobAndFile(t, repo)
+	writeSceneResultWithPayload(t, fake.artifacts, file.ID, pipelines.SceneOutputPayload{
+		VideoID: file.ID,
+		Scenes: []pipelines.SceneBoundary{
+			{SceneID: file.ID + "_scene_0", Index: 0, StartMs: 0, EndMs: 1000},
+			{SceneID: file.ID + "_scene_1", Index: 1, StartMs: 1000, EndMs: 2000},
+		},
+	})
+	// The second scene reuses the first one's object, so only one JPEG
+	// leaves the machine.
+	writeThumbnail(t, fake.artifacts, file.ID, file.ID+"_scene_0", "jpeg-a")
+	writeThumbnail(t, fake.artifacts, file.ID, file.ID+"_scene_1", "jpeg-a")
+
+	if _, err := runner.uploadThumbnails(ctx, file); err != nil {
+		t.Fatalf("uploadThumbnails: %v", err)
+	}
+
+	records, err := repo.ListCloudAudit(ctx, file.ID)
+	if err != nil {
+		t.Fatalf("ListCloudAudit: %v", err)
+	}
+	if len(records) != 1 {
+		t.Fatalf("audit records = %d, want 1", len(records))
+	}
+	want, _ := fileSHA256(filepath.Join(fake.artifacts, file.ID, "thumbnails", file.ID+"_scene_0.jpg"))
+	if a := records[0]; a.Kind != "thumbnail" || a.PayloadHash != want || len(a.Fields) != 1 || a.Fields[0] != "thumbnail" {
+		t.Errorf("audit = %+v, want a thumbnail record with the JPEG's hash", a)
+	}
+}
+
+func TestProcessUploadThumbnailsJob_EnqueuesSceneReupload(t *testing.T) {
+	fake := &fakePipeRunner{artifacts: t.TempDir()}
+	runner, repo := setupRunnerTest(t, fake, &pipelines.Capabilities{ProbedAt: time.Now()})
+	runner.SetCloudClient(&fakeCloudClient{upload: &fakeUploadService{}}, "lib-1")
+
+	_, file := createTestJobAndFile(t, repo)
+	writeSceneResult(t, fake.artifacts, file.ID)
+	writeThumbnail(t, fake.artifacts, file.ID, "video-1_scene_0", "jpeg-a")
+
+	job := &Job{ID: NewID(), Type: JobTypeUploadThumbnails, Status: JobStatusPending, FileID: file.ID, CreatedAt: time.Now(), UpdatedAt: time.Now()}
+	repo.CreateJob(context.Background(), job)
+
+	runner.processUploadThumbnailsJob(context.Background(), job)
+
+	updated, _ := repo.GetJob(context.Background(), job.ID)
+	if updated.Status != JobStatusCompleted {
+		t.Fatalf("job status = %s (%s), want completed", updated.Status, updated.Error)
+	}
+
+	pending, _ := repo.ListPendingJobs(context.Background())
+	found := false
+	for _, j := range pending {
+		if j.Type == JobTypeUploadScenes && j.FileID == file.ID {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatal("expected upload_scenes job to re-send scene docs with thumbnail keys")
+	}
+}
+
+func TestUploadScenesToCloud_AttachesThumbnailKeys(t *testing.T) {
+	fake := &fakePipeRunner{artifacts: t.TempDir()}
+	runner, repo := setupRunnerTest(t, fake, &pipelines.Capabilities{ProbedAt: time.Now()})
+
+	var got cloud.SceneIngestPayload
+	runner.SetCloudClient(&fakeCloudClient{scenes: &fakeSceneUploader{
+		uploadFn: func(_ context.Context, payload cloud.SceneIngestPayload) error {
+			got = payload
+			return nil
+		},
+	}}, "lib-1")
+
+	job, file := createTestJobAndFile(t, repo)
+	writeSceneResult(t, fake.artifacts, file.ID)
+	repo.UpsertThumbnailUpload(context.Background(), &ThumbnailUpload{
+		SceneID:    "video-1_scene_0",
+		FileID:     file.ID,
+		SHA256:     "abc",
+		ObjectKey:  "thumbs/abc.jpg",
+		URL:        "https://cdn.example/thumbs/abc.jpg",
+		UploadedAt: time.Now(),
+	})
+
+	runner.uploadScenesToCloud(context.Background(), job, file, filepath.Join(fake.artifacts, file.ID))
+
+	if len(got.Scenes) != 1 {
+		t.Fatalf("scenes = %d, want 1", len(got.Scenes))
+	}
+	if got.Scenes[0].ThumbnailKey != "thumbs/abc.jpg" || got.Scenes[0].ThumbnailURL != "https://cdn.example/thumbs/abc.jpg" {
+		t.Fatalf("thumbnail = %q %q", got.Scenes[0].ThumbnailKey, got.Scenes[0].ThumbnailURL)
+	}
+}
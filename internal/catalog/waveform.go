@@ -0,0 +1,107 @@
+package catalog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/heimdex/heimdex-agent/internal/pipeline"
+	"github.com/heimdex/heimdex-agent/internal/waveform"
+)
+
+// WaveformSamplesPerPixel is the resolution peaks are stored at: 100 pairs
+// per second at pipeline.AudioSampleRate.
+const WaveformSamplesPerPixel = pipeline.AudioSampleRate / 100
+
+// WaveformPath returns where a file's peaks are kept, in audiowaveform's
+// binary format.
+func WaveformPath(artifactsDir, fileID string) string {
+	return filepath.Join(artifactsDir, fileID, "waveform", "peaks.dat")
+}
+
+func (r *Runner) processGenerateWaveformJob(ctx context.Context, job *Job) {
+	if r.pipeRunner == nil || r.ffmpeg == nil {
+		r.repo.UpdateJobStatus(ctx, job.ID, JobStatusFailed, "waveform generation not configured")
+		return
+	}
+
+	r.repo.UpdateJobStatus(ctx, job.ID, JobStatusRunning, "")
+
+	file, err := r.repo.GetFile(ctx, job.FileID)
+	if err != nil || file == nil {
+		r.repo.UpdateJobStatus(ctx, job.ID, JobStatusFailed, "file not found")
+		return
+	}
+
+	peaks, err := r.generateWaveform(file)
+	if err != nil {
+		r.logger.Warn("waveform generation failed", "job_id", job.ID, "file_id", file.ID, "error", err)
+		r.repo.UpdateJobStatus(ctx, job.ID, JobStatusFailed, err.Error())
+		return
+	}
+
+	r.logger.Info("waveform generated", "file_id", file.ID, "pixels", peaks.Length())
+	r.repo.UpdateJobStatus(ctx, job.ID, JobStatusCompleted, "")
+}
+
+// generateWaveform extracts the file's audio to a temporary WAV next to
+// the peaks and reduces it. A file without audio gets empty peaks, so the
+// UI can tell "silent" from "not generated yet".
+func (r *Runner) generateWaveform(file *File) (*waveform.Peaks, error) {
+	outPath := WaveformPath(r.pipeRunner.ArtifactsDir(), file.ID)
+	if err := os.MkdirAll(filepath.Dir(outPath), 0o755); err != nil {
+		return nil, fmt.Errorf("create waveform dir: %w", err)
+	}
+
+	wavPath := filepath.Join(filepath.Dir(outPath), "audio.wav")
+	defer os.Remove(wavPath)
+
+	peaks := &waveform.Peaks{SampleRate: pipeline.AudioSampleRate, SamplesPerPixel: WaveformSamplesPerPixel}
+	err := r.ffmpeg.ExtractAudio(file.Path, wavPath)
+	switch {
+	case errors.Is(err, pipeline.ErrNoAudio):
+	case err != nil:
+		return nil, err
+	default:
+		f, err := os.Open(wavPath)
+		if err != nil {
+			return nil, fmt.Errorf("audio output missing: %w", err)
+		}
+		peaks, err = waveform.Compute(f, WaveformSamplesPerPixel)
+		f.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	tmpPath := outPath + ".tmp"
+	out, err := os.Create(tmpPath)
+	if err != nil {
+		return nil, fmt.Errorf("write waveform: %w", err)
+	}
+	if err := peaks.WriteBinary(out); err != nil {
+		out.Close()
+		os.Remove(tmpPath)
+		return nil, fmt.Errorf("write waveform: %w", err)
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(tmpPath)
+		return nil, fmt.Errorf("write waveform: %w", err)
+	}
+	if err := os.Rename(tmpPath, outPath); err != nil {
+		os.Remove(tmpPath)
+		return nil, fmt.Errorf("store waveform: %w", err)
+	}
+	return peaks, nil
+}
+
+// backfillWaveforms queues waveforms for indexed files that have none and
+// have never had a waveform job.
+func (r *Runner) backfillWaveforms(ctx context.Context) {
+	r.backfillFileJobs(ctx, JobTypeGenerateWaveform, func(file *File) bool {
+		_, err := os.Stat(WaveformPath(r.pipeRunner.ArtifactsDir(), file.ID))
+		return err != nil
+	})
+}
@@ -0,0 +1,114 @@
+package catalog
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io"
+	"log/slog"
+	"os"
+	"testing"
+
+	"github.com/heimdex/heimdex-agent/internal/pipeline"
+	"github.com/heimdex/heimdex-agent/internal/pipelines"
+	"github.com/heimdex/heimdex-agent/internal/waveform"
+)
+
+// fakeAudioFFmpeg writes a mono WAV of the given samples, or reports no
+// audio stream when samples is nil.
+type fakeAudioFFmpeg struct {
+	*pipeline.StubFFmpeg
+	samples []int16
+}
+
+func (f *fakeAudioFFmpeg) ExtractAudio(filePath, outputPath string) error {
+	if f.samples == nil {
+		return pipeline.ErrNoAudio
+	}
+	var b bytes.Buffer
+	b.WriteString("RIFF\xff\xff\xff\xffWAVEfmt ")
+	binary.Write(&b, binary.LittleEndian, []uint32{16})
+	binary.Write(&b, binary.LittleEndian, []uint16{1, 1})
+	binary.Write(&b, binary.LittleEndian, []uint32{pipeline.AudioSampleRate, pipeline.AudioSampleRate * 2})
+	binary.Write(&b, binary.LittleEndian, []uint16{2, 16})
+	b.WriteString("data")
+	binary.Write(&b, binary.LittleEndian, uint32(2*len(f.samples)))
+	binary.Write(&b, binary.LittleEndian, f.samples)
+	return os.WriteFile(outputPath, b.Bytes(), 0o644)
+}
+
+func runWaveformJob(t *testing.T, samples []int16) (*waveform.Peaks, string) {
+	t.Helper()
+	artifacts := t.TempDir()
+	runner, repo := setupRunnerTest(t, &fakePipeRunner{artifacts: artifacts}, &pipelines.Capabilities{})
+	runner.ffmpeg = &fakeAudioFFmpeg{
+		StubFFmpeg: pipeline.NewStubFFmpeg(slog.New(slog.NewTextHandler(io.Discard, nil))),
+		samples:    samples,
+	}
+	ctx := context.Background()
+	_, file := createTestJobAndFile(t, repo)
+
+	job := &Job{ID: NewID(), Type: JobTypeGenerateWaveform, Status: JobStatusPending, FileID: file.ID}
+	if err := repo.CreateJob(ctx, job); err != nil {
+		t.Fatal(err)
+	}
+	runner.processGenerateWaveformJob(ctx, job)
+	if got, _ := repo.GetJob(ctx, job.ID); got.Status != JobStatusCompleted {
+		t.Fatalf("job = %+v, want completed", got)
+	}
+
+	f, err := os.Open(WaveformPath(artifacts, file.ID))
+	if err != nil {
+		t.Fatalf("peaks missing: %v", err)
+	}
+	defer f.Close()
+	peaks, err := waveform.ReadBinary(f)
+	if err != nil {
+		t.Fatalf("ReadBinary: %v", err)
+	}
+	return peaks, artifacts
+}
+
+func TestRunner_GenerateWaveform(t *testing.T) {
+	samples := make([]int16, 2*WaveformSamplesPerPixel+10)
+	samples[5] = 1200
+	samples[WaveformSamplesPerPixel+7] = -800
+
+	peaks, _ := runWaveformJob(t, samples)
+	if peaks.SamplesPerPixel != WaveformSamplesPerPixel || peaks.Length() != 3 {
+		t.Fatalf("peaks = %d pairs at %d, want 3 at %d", peaks.Length(), peaks.SamplesPerPixel, WaveformSamplesPerPixel)
+	}
+	if peaks.Data[1] != 1200 || peaks.Data[2] != -800 {
+		t.Errorf("data = %v", peaks.Data)
+	}
+}
+
+func TestRunner_GenerateWaveform_NoAudio(t *testing.T) {
+	peaks, _ := runWaveformJob(t, nil)
+	if peaks.Length() != 0 || peaks.SampleRate != pipeline.AudioSampleRate {
+		t.Errorf("peaks = %+v, want empty", peaks)
+	}
+}
+
+func TestRunner_BackfillWaveforms(t *testing.T) {
+	artifacts := t.TempDir()
+	runner, repo := setupRunnerTest(t, &fakePipeRunner{artifacts: artifacts}, &pipelines.Capabilities{})
+	ctx := context.Background()
+
+	_, file := createTestJobAndFile(t, repo)
+	writeSceneResult(t, artifacts, file.ID)
+
+	runner.backfillWaveforms(ctx)
+	runner.backfillWaveforms(ctx)
+
+	pending, _ := repo.ListPendingJobs(ctx)
+	queued := 0
+	for _, j := range pending {
+		if j.Type == JobTypeGenerateWaveform && j.FileID == file.ID {
+			queued++
+		}
+	}
+	if queued != 1 {
+		t.Errorf("waveform jobs = %d, want 1", queued)
+	}
+}
@@ -0,0 +1,103 @@
+package cloud
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// AnnotationService pulls edits made in the Heimdex web app (renamed people
+// clusters, tag changes, scene notes) back down to the agent.
+type AnnotationService interface {
+	// Pull returns annotations changed after cursor. An empty cursor starts
+	// from the beginning. Callers keep pulling while HasMore is set.
+	Pull(ctx context.Context, cursor string, limit int) (*AnnotationPage, error)
+}
+
+// AnnotationPage is one page of GET /api/sync/annotations.
+type AnnotationPage struct {
+	Videos  []VideoAnnotations `json:"videos"`
+	Cursor  string             `json:"cursor"`
+	HasMore bool               `json:"has_more"`
+}
+
+// VideoAnnotations holds the edits for one video. VideoID is the agent's
+// file ID, as sent in SceneIngestPayload.
+type VideoAnnotations struct {
+	VideoID string             `json:"video_id"`
+	People  []PersonAnnotation `json:"people,omitempty"`
+	Scenes  []SceneAnnotation  `json:"scenes,omitempty"`
+}
+
+// PersonAnnotation names a people cluster. An empty Name clears it.
+type PersonAnnotation struct {
+	ClusterID string    `json:"cluster_id"`
+	Name      string    `json:"name"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// SceneAnnotation is the full set of cloud-side edits for a scene, relative
+// to the pipeline output.
+type SceneAnnotation struct {
+	SceneID     string    `json:"scene_id"`
+	TagsAdded   []string  `json:"tags_added,omitempty"`
+	TagsRemoved []string  `json:"tags_removed,omitempty"`
+	Note        string    `json:"note,omitempty"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+type HTTPAnnotationService struct {
+	client *HTTPClient
+}
+
+func (s *HTTPAnnotationService) Pull(ctx context.Context, cursor string, limit int) (*AnnotationPage, error) {
+	q := url.Values{}
+	if cursor != "" {
+		q.Set("since", cursor)
+	}
+	if limit > 0 {
+		q.Set("limit", strconv.Itoa(limit))
+	}
+	path := "/api/sync/annotations"
+	if len(q) > 0 {
+		path += "?" + q.Encode()
+	}
+
+	req, err := s.client.newRequest(ctx, http.MethodGet, path, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := s.client.do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 8<<20))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, &UploadError{StatusCode: resp.StatusCode, Body: string(respBody)}
+	}
+
+	var page AnnotationPage
+	if err := json.Unmarshal(respBody, &page); err != nil {
+		return nil, fmt.Errorf("unmarshal annotations response: %w", err)
+	}
+	return &page, nil
+}
+
+type StubAnnotationService struct {
+	logger *slog.Logger
+}
+
+func (s *StubAnnotationService) Pull(ctx context.Context, cursor string, limit int) (*AnnotationPage, error) {
+	s.logger.Debug("cloud stub: annotation pull requested", "cursor", cursor)
+	return &AnnotationPage{Cursor: cursor}, nil
+}
@@ -1,14 +1,474 @@
 package cloud
 
-import "log/slog"
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Config keys used to persist cloud credentials in the agent's config table.
+const (
+	ConfigKeyAccessToken    = "cloud_access_token"
+	ConfigKeyRefreshToken   = "cloud_refresh_token"
+	ConfigKeyTokenExpiresAt = "cloud_token_expires_at"
+)
+
+var (
+	ErrCloudDisabled        = errors.New("cloud sync is not configured")
+	ErrNotAuthenticated     = errors.New("not signed in to heimdex cloud")
+	ErrAccessDenied         = errors.New("device login was denied")
+	ErrDeviceCodeExpired    = errors.New("device login code expired")
+	errAuthorizationPending = errors.New("authorization pending")
+	errSlowDown             = errors.New("slow down")
+)
 
 type AuthService interface {
-	Login(email, password string) error
-	Logout() error
+	// StartDeviceLogin requests a device code the user approves in a browser.
+	StartDeviceLogin(ctx context.Context) (*DeviceCode, error)
+	// PollDeviceLogin blocks until the code is approved, denied or expired.
+	PollDeviceLogin(ctx context.Context, code *DeviceCode) error
+	// PendingLogin returns the device code awaiting approval, if any.
+	PendingLogin() *DeviceCode
+	// ResumeOrLogin returns the device code already awaiting approval or,
+	// when there is none, starts a login with LoginInBackground. started
+	// reports whether a new login was started; onDone is only used for a
+	// new one.
+	ResumeOrLogin(logger *slog.Logger, onDone func(error)) (code *DeviceCode, started bool, err error)
+	Refresh(ctx context.Context) error
+	Logout(ctx context.Context) error
 	IsAuthenticated() bool
 	GetAccessToken() string
 }
 
+// ConfigStore persists credentials between restarts. catalog.Repository
+// satisfies it.
+type ConfigStore interface {
+	GetConfig(ctx context.Context, key string) (string, error)
+	SetConfig(ctx context.Context, key, value string) error
+}
+
+// DeviceCode is the response from POST /api/auth/device/code.
+type DeviceCode struct {
+	DeviceCode              string `json:"device_code"`
+	UserCode                string `json:"user_code"`
+	VerificationURI         string `json:"verification_uri"`
+	VerificationURIComplete string `json:"verification_uri_complete,omitempty"`
+	ExpiresIn               int    `json:"expires_in"`
+	Interval                int    `json:"interval"`
+}
+
+// TokenSet is the response from the device token and refresh endpoints.
+type TokenSet struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int    `json:"expires_in"`
+	TokenType    string `json:"token_type,omitempty"`
+}
+
+// SignedIn reports whether c holds any credential: device-login tokens, or
+// a static API token for clients configured with one.
+func SignedIn(c Client) bool {
+	if c.Auth().IsAuthenticated() {
+		return true
+	}
+	s, ok := c.(interface{ HasStaticToken() bool })
+	return ok && s.HasStaticToken()
+}
+
+// LoginInBackground starts the device-code flow and polls for approval in a
+// goroutine bounded by the code's lifetime. onDone, if set, receives the
+// final result.
+func LoginInBackground(auth AuthService, logger *slog.Logger, onDone func(error)) (*DeviceCode, error) {
+	startCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	code, err := auth.StartDeviceLogin(startCtx)
+	cancel()
+	if err != nil {
+		return nil, err
+	}
+
+	expiresIn := time.Duration(code.ExpiresIn) * time.Second
+	if expiresIn <= 0 {
+		expiresIn = 15 * time.Minute
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), expiresIn)
+		defer cancel()
+
+		err := auth.PollDeviceLogin(ctx, code)
+		if err != nil {
+			logger.Warn("cloud device login did not complete", "error", err)
+		} else {
+			logger.Info("cloud device login approved")
+		}
+		if onDone != nil {
+			onDone(err)
+		}
+	}()
+
+	return code, nil
+}
+
+// HTTPAuth implements the OAuth-style device authorization flow against the
+// Heimdex SaaS and keeps the resulting tokens in a ConfigStore.
+type HTTPAuth struct {
+	client *HTTPClient
+	store  ConfigStore
+
+	mu           sync.RWMutex
+	accessToken  string
+	refreshToken string
+	expiresAt    time.Time
+	pending      *DeviceCode
+
+	// loginMu serialises the pending-login check with starting a new
+	// login, so two sign-in clicks never request two device codes.
+	loginMu   sync.Mutex
+	refreshMu sync.Mutex
+}
+
+func newHTTPAuth(client *HTTPClient) *HTTPAuth {
+	return &HTTPAuth{client: client}
+}
+
+// load restores persisted tokens from the store.
+func (a *HTTPAuth) load(ctx context.Context) error {
+	if a.store == nil {
+		return nil
+	}
+
+	access, err := a.store.GetConfig(ctx, ConfigKeyAccessToken)
+	if err != nil {
+		return err
+	}
+	refresh, err := a.store.GetConfig(ctx, ConfigKeyRefreshToken)
+	if err != nil {
+		return err
+	}
+	expires, _ := a.store.GetConfig(ctx, ConfigKeyTokenExpiresAt)
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.accessToken = access
+	a.refreshToken = refresh
+	a.expiresAt = time.Time{}
+	if unix, err := strconv.ParseInt(expires, 10, 64); err == nil && unix > 0 {
+		a.expiresAt = time.Unix(unix, 0)
+	}
+	return nil
+}
+
+func (a *HTTPAuth) save(ctx context.Context, tokens TokenSet) error {
+	expiresAt := time.Time{}
+	if tokens.ExpiresIn > 0 {
+		expiresAt = time.Now().Add(time.Duration(tokens.ExpiresIn) * time.Second)
+	}
+
+	a.mu.Lock()
+	a.accessToken = tokens.AccessToken
+	if tokens.RefreshToken != "" {
+		a.refreshToken = tokens.RefreshToken
+	}
+	a.expiresAt = expiresAt
+	refresh := a.refreshToken
+	a.mu.Unlock()
+
+	if a.store == nil {
+		return nil
+	}
+
+	expires := ""
+	if !expiresAt.IsZero() {
+		expires = strconv.FormatInt(expiresAt.Unix(), 10)
+	}
+	for key, value := range map[string]string{
+		ConfigKeyAccessToken:    tokens.AccessToken,
+		ConfigKeyRefreshToken:   refresh,
+		ConfigKeyTokenExpiresAt: expires,
+	} {
+		if err := a.store.SetConfig(ctx, key, value); err != nil {
+			return fmt.Errorf("persist %s: %w", key, err)
+		}
+	}
+	return nil
+}
+
+func (a *HTTPAuth) StartDeviceLogin(ctx context.Context) (*DeviceCode, error) {
+	respBody, err := a.post(ctx, "/api/auth/device/code", map[string]string{
+		"device_id": a.client.getDeviceID(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("start device login: %w", err)
+	}
+
+	var code DeviceCode
+	if err := json.Unmarshal(respBody, &code); err != nil {
+		return nil, fmt.Errorf("unmarshal device code: %w", err)
+	}
+	if code.DeviceCode == "" || code.UserCode == "" {
+		return nil, fmt.Errorf("device code response missing device_code or user_code")
+	}
+
+	a.mu.Lock()
+	a.pending = &code
+	a.mu.Unlock()
+
+	a.client.logger.Info("cloud device login started",
+		"user_code", code.UserCode,
+		"verification_uri", code.VerificationURI,
+	)
+	return &code, nil
+}
+
+func (a *HTTPAuth) PollDeviceLogin(ctx context.Context, code *DeviceCode) error {
+	defer a.clearPending(code)
+
+	interval := time.Duration(code.Interval) * time.Second
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(interval):
+		}
+
+		tokens, err := a.exchangeDeviceCode(ctx, code.DeviceCode)
+		switch {
+		case err == nil:
+			return a.save(ctx, *tokens)
+		case errors.Is(err, errAuthorizationPending):
+			continue
+		case errors.Is(err, errSlowDown):
+			interval += 5 * time.Second
+			continue
+		default:
+			return err
+		}
+	}
+}
+
+func (a *HTTPAuth) exchangeDeviceCode(ctx context.Context, deviceCode string) (*TokenSet, error) {
+	respBody, err := a.post(ctx, "/api/auth/device/token", map[string]string{
+		"grant_type":  "urn:ietf:params:oauth:grant-type:device_code",
+		"device_code": deviceCode,
+	})
+	if err != nil {
+		var uploadErr *UploadError
+		if errors.As(err, &uploadErr) {
+			if oauthErr := parseOAuthError(uploadErr.Body); oauthErr != nil {
+				return nil, oauthErr
+			}
+		}
+		return nil, fmt.Errorf("poll device login: %w", err)
+	}
+
+	var tokens TokenSet
+	if err := json.Unmarshal(respBody, &tokens); err != nil {
+		return nil, fmt.Errorf("unmarshal token response: %w", err)
+	}
+	if tokens.AccessToken == "" {
+		return nil, fmt.Errorf("token response missing access_token")
+	}
+	return &tokens, nil
+}
+
+// parseOAuthError maps RFC 8628 error codes to sentinel errors.
+func parseOAuthError(body string) error {
+	var payload struct {
+		Error string `json:"error"`
+	}
+	if err := json.Unmarshal([]byte(body), &payload); err != nil {
+		return nil
+	}
+	switch payload.Error {
+	case "authorization_pending":
+		return errAuthorizationPending
+	case "slow_down":
+		return errSlowDown
+	case "access_denied":
+		return ErrAccessDenied
+	case "expired_token":
+		return ErrDeviceCodeExpired
+	default:
+		return nil
+	}
+}
+
+func (a *HTTPAuth) PendingLogin() *DeviceCode {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.pending
+}
+
+func (a *HTTPAuth) ResumeOrLogin(logger *slog.Logger, onDone func(error)) (*DeviceCode, bool, error) {
+	a.loginMu.Lock()
+	defer a.loginMu.Unlock()
+	if code := a.PendingLogin(); code != nil {
+		return code, false, nil
+	}
+	code, err := LoginInBackground(a, logger, onDone)
+	return code, err == nil, err
+}
+
+func (a *HTTPAuth) clearPending(code *DeviceCode) {
+	a.mu.Lock()
+	if a.pending == code {
+		a.pending = nil
+	}
+	a.mu.Unlock()
+}
+
+// Refresh exchanges the refresh token for a new access token. Concurrent
+// callers share one exchange.
+func (a *HTTPAuth) Refresh(ctx context.Context) error {
+	a.mu.RLock()
+	before := a.accessToken
+	a.mu.RUnlock()
+
+	a.refreshMu.Lock()
+	defer a.refreshMu.Unlock()
+
+	a.mu.RLock()
+	current := a.accessToken
+	refresh := a.refreshToken
+	a.mu.RUnlock()
+
+	// Another caller refreshed while we waited for the lock.
+	if current != before {
+		return nil
+	}
+	if refresh == "" {
+		return ErrNotAuthenticated
+	}
+
+	respBody, err := a.post(ctx, "/api/auth/token/refresh", map[string]string{
+		"grant_type":    "refresh_token",
+		"refresh_token": refresh,
+	})
+	if err != nil {
+		var uploadErr *UploadError
+		if errors.As(err, &uploadErr) &&
+			(uploadErr.StatusCode == http.StatusBadRequest || uploadErr.StatusCode == http.StatusUnauthorized) {
+			// The refresh token was revoked or expired; keeping it would
+			// report a sign-in that can no longer work.
+			a.client.logger.Warn("cloud refresh token rejected, signing out", "status", uploadErr.StatusCode)
+			if clearErr := a.clearTokens(ctx); clearErr != nil {
+				a.client.logger.Warn("failed to clear cloud credentials", "error", clearErr)
+			}
+			return fmt.Errorf("refresh token rejected: %w", ErrNotAuthenticated)
+		}
+		return fmt.Errorf("refresh token: %w", err)
+	}
+
+	var tokens TokenSet
+	if err := json.Unmarshal(respBody, &tokens); err != nil {
+		return fmt.Errorf("unmarshal refresh response: %w", err)
+	}
+	if tokens.AccessToken == "" {
+		return fmt.Errorf("refresh response missing access_token")
+	}
+
+	a.client.logger.Info("cloud access token refreshed")
+	return a.save(ctx, tokens)
+}
+
+func (a *HTTPAuth) Logout(ctx context.Context) error {
+	a.mu.RLock()
+	refresh := a.refreshToken
+	a.mu.RUnlock()
+
+	if refresh != "" {
+		if _, err := a.post(ctx, "/api/auth/logout", map[string]string{"refresh_token": refresh}); err != nil {
+			// Revocation is best effort; local credentials are dropped regardless.
+			a.client.logger.Warn("cloud token revocation failed", "error", err)
+		}
+	}
+
+	a.mu.Lock()
+	a.pending = nil
+	a.mu.Unlock()
+	if err := a.clearTokens(ctx); err != nil {
+		return err
+	}
+	a.client.logger.Info("signed out of heimdex cloud")
+	return nil
+}
+
+// clearTokens forgets the device-login tokens in memory and in the store.
+func (a *HTTPAuth) clearTokens(ctx context.Context) error {
+	a.mu.Lock()
+	a.accessToken = ""
+	a.refreshToken = ""
+	a.expiresAt = time.Time{}
+	a.mu.Unlock()
+
+	if a.store == nil {
+		return nil
+	}
+	for _, key := range []string{ConfigKeyAccessToken, ConfigKeyRefreshToken, ConfigKeyTokenExpiresAt} {
+		if err := a.store.SetConfig(ctx, key, ""); err != nil {
+			return fmt.Errorf("clear %s: %w", key, err)
+		}
+	}
+	return nil
+}
+
+// IsAuthenticated reports whether device-login tokens are held. A static
+// API token does not count; see SignedIn.
+func (a *HTTPAuth) IsAuthenticated() bool {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.accessToken != "" || a.refreshToken != ""
+}
+
+func (a *HTTPAuth) GetAccessToken() string {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.accessToken
+}
+
+func (a *HTTPAuth) canRefresh() bool {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.refreshToken != ""
+}
+
+// post sends an unauthenticated JSON request to an auth endpoint.
+func (a *HTTPAuth) post(ctx context.Context, path string, v interface{}) ([]byte, error) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("marshal request: %w", err)
+	}
+
+	req, err := a.client.newRequest(ctx, http.MethodPost, path, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Del("Authorization")
+
+	resp, err := a.client.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("http request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 65536))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, &UploadError{StatusCode: resp.StatusCode, Body: string(respBody)}
+	}
+	return respBody, nil
+}
+
 type StubAuth struct {
 	logger *slog.Logger
 }
@@ -17,12 +477,29 @@ func NewStubAuth(logger *slog.Logger) *StubAuth {
 	return &StubAuth{logger: logger}
 }
 
-func (s *StubAuth) Login(email, password string) error {
-	s.logger.Info("cloud auth stub: login requested", "email", email)
+func (s *StubAuth) StartDeviceLogin(ctx context.Context) (*DeviceCode, error) {
+	s.logger.Info("cloud auth stub: device login requested")
+	return nil, ErrCloudDisabled
+}
+
+func (s *StubAuth) PollDeviceLogin(ctx context.Context, code *DeviceCode) error {
+	return ErrCloudDisabled
+}
+
+func (s *StubAuth) PendingLogin() *DeviceCode {
 	return nil
 }
 
-func (s *StubAuth) Logout() error {
+func (s *StubAuth) ResumeOrLogin(logger *slog.Logger, onDone func(error)) (*DeviceCode, bool, error) {
+	s.logger.Info("cloud auth stub: device login requested")
+	return nil, false, ErrCloudDisabled
+}
+
+func (s *StubAuth) Refresh(ctx context.Context) error {
+	return ErrCloudDisabled
+}
+
+func (s *StubAuth) Logout(ctx context.Context) error {
 	s.logger.Info("cloud auth stub: logout requested")
 	return nil
 }
@@ -0,0 +1,270 @@
+package cloud
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+type memoryConfigStore struct {
+	mu     sync.Mutex
+	values map[string]string
+}
+
+func newMemoryConfigStore() *memoryConfigStore {
+	return &memoryConfigStore{values: map[string]string{}}
+}
+
+func (m *memoryConfigStore) GetConfig(ctx context.Context, key string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.values[key], nil
+}
+
+func (m *memoryConfigStore) SetConfig(ctx context.Context, key, value string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.values[key] = value
+	return nil
+}
+
+func TestHTTPAuth_DeviceLogin(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "" {
+			t.Errorf("auth endpoint %s should not carry a bearer token", r.URL.Path)
+		}
+		switch r.URL.Path {
+		case "/api/auth/device/code":
+			json.NewEncoder(w).Encode(DeviceCode{
+				DeviceCode:      "dev-code",
+				UserCode:        "ABCD-EFGH",
+				VerificationURI: "https://heimdex.example/device",
+				ExpiresIn:       600,
+				Interval:        1,
+			})
+		case "/api/auth/device/token":
+			var req map[string]string
+			json.NewDecoder(r.Body).Decode(&req)
+			if req["device_code"] != "dev-code" {
+				t.Errorf("device_code = %q, want dev-code", req["device_code"])
+			}
+			json.NewEncoder(w).Encode(TokenSet{AccessToken: "access-1", RefreshToken: "refresh-1", ExpiresIn: 3600})
+		default:
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	store := newMemoryConfigStore()
+	client := NewHTTPClient(server.URL, "", "", testLogger())
+	if err := client.SetTokenStore(context.Background(), store); err != nil {
+		t.Fatalf("SetTokenStore: %v", err)
+	}
+	auth := client.Auth()
+
+	code, err := auth.StartDeviceLogin(context.Background())
+	if err != nil {
+		t.Fatalf("StartDeviceLogin: %v", err)
+	}
+	if code.UserCode != "ABCD-EFGH" {
+		t.Fatalf("user_code = %q, want ABCD-EFGH", code.UserCode)
+	}
+	if auth.PendingLogin() == nil {
+		t.Fatal("expected pending login after start")
+	}
+
+	if err := auth.PollDeviceLogin(context.Background(), code); err != nil {
+		t.Fatalf("PollDeviceLogin: %v", err)
+	}
+	if !auth.IsAuthenticated() || auth.GetAccessToken() != "access-1" {
+		t.Fatalf("access token = %q, want access-1", auth.GetAccessToken())
+	}
+	if auth.PendingLogin() != nil {
+		t.Fatal("pending login should be cleared after approval")
+	}
+	if store.values[ConfigKeyRefreshToken] != "refresh-1" {
+		t.Fatalf("stored refresh token = %q, want refresh-1", store.values[ConfigKeyRefreshToken])
+	}
+}
+
+func TestHTTPAuth_PollDenied(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error":"access_denied"}`))
+	}))
+	defer server.Close()
+
+	client := NewHTTPClient(server.URL, "", "", testLogger())
+	err := client.Auth().PollDeviceLogin(context.Background(), &DeviceCode{DeviceCode: "dev-code", Interval: 1})
+	if !errors.Is(err, ErrAccessDenied) {
+		t.Fatalf("err = %v, want ErrAccessDenied", err)
+	}
+}
+
+func TestHTTPClient_RefreshesOn401(t *testing.T) {
+	var ingestCalls int
+	var lastAuth string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/auth/token/refresh":
+			var req map[string]string
+			json.NewDecoder(r.Body).Decode(&req)
+			if req["refresh_token"] != "refresh-1" {
+				t.Errorf("refresh_token = %q, want refresh-1", req["refresh_token"])
+			}
+			json.NewEncoder(w).Encode(TokenSet{AccessToken: "access-2", ExpiresIn: 3600})
+		case "/api/ingest/scenes":
+			ingestCalls++
+			lastAuth = r.Header.Get("Authorization")
+			if lastAuth != "Bearer access-2" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			var payload SceneIngestPayload
+			if err := json.NewDecoder(r.Body).Decode(&payload); err != nil || payload.VideoID != "vid1" {
+				t.Errorf("replayed body not intact: %v %+v", err, payload)
+			}
+			json.NewEncoder(w).Encode(SceneIngestResponse{IndexedCount: 1, VideoID: "vid1"})
+		}
+	}))
+	defer server.Close()
+
+	store := newMemoryConfigStore()
+	store.values[ConfigKeyAccessToken] = "access-1"
+	store.values[ConfigKeyRefreshToken] = "refresh-1"
+
+	client := NewHTTPClient(server.URL, "", "", testLogger())
+	if err := client.SetTokenStore(context.Background(), store); err != nil {
+		t.Fatalf("SetTokenStore: %v", err)
+	}
+
+	err := client.UploadScenes(context.Background(), SceneIngestPayload{
+		VideoID: "vid1",
+		Scenes:  []SceneIngestDoc{{SceneID: "vid1_scene_0", Index: 0, StartMs: 0, EndMs: 1000}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ingestCalls != 2 {
+		t.Fatalf("ingest calls = %d, want 2", ingestCalls)
+	}
+	if store.values[ConfigKeyAccessToken] != "access-2" {
+		t.Fatalf("stored access token = %q, want access-2", store.values[ConfigKeyAccessToken])
+	}
+	// The refresh response omitted a new refresh token; the old one is kept.
+	if store.values[ConfigKeyRefreshToken] != "refresh-1" {
+		t.Fatalf("stored refresh token = %q, want refresh-1", store.values[ConfigKeyRefreshToken])
+	}
+}
+
+func TestHTTPAuth_RejectedRefreshClearsTokens(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/auth/token/refresh" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error":"invalid_grant"}`))
+			return
+		}
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	store := newMemoryConfigStore()
+	store.values[ConfigKeyAccessToken] = "access-1"
+	store.values[ConfigKeyRefreshToken] = "refresh-1"
+
+	client := NewHTTPClient(server.URL, "", "", testLogger())
+	client.SetTokenStore(context.Background(), store)
+
+	err := client.Auth().Refresh(context.Background())
+	if !errors.Is(err, ErrNotAuthenticated) {
+		t.Fatalf("Refresh = %v, want ErrNotAuthenticated", err)
+	}
+	if client.Auth().IsAuthenticated() {
+		t.Fatal("still signed in after the refresh token was rejected")
+	}
+	if store.values[ConfigKeyAccessToken] != "" || store.values[ConfigKeyRefreshToken] != "" {
+		t.Fatalf("tokens not cleared: %+v", store.values)
+	}
+}
+
+func TestHTTPAuth_ResumeOrLogin_OneCodeForConcurrentCalls(t *testing.T) {
+	var mu sync.Mutex
+	requested := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/auth/device/code" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error":"authorization_pending"}`))
+			return
+		}
+		mu.Lock()
+		requested++
+		mu.Unlock()
+		json.NewEncoder(w).Encode(DeviceCode{DeviceCode: "dev-code", UserCode: "ABCD-EFGH", ExpiresIn: 1, Interval: 5})
+	}))
+	defer server.Close()
+
+	auth := NewHTTPClient(server.URL, "", "", testLogger()).Auth()
+	var wg sync.WaitGroup
+	started := make([]bool, 4)
+	for i := range started {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			code, ok, err := auth.ResumeOrLogin(testLogger(), nil)
+			if err != nil || code.UserCode != "ABCD-EFGH" {
+				t.Errorf("ResumeOrLogin = %+v, %v", code, err)
+			}
+			started[i] = ok
+		}(i)
+	}
+	wg.Wait()
+
+	if requested != 1 {
+		t.Errorf("device codes requested = %d, want 1", requested)
+	}
+	n := 0
+	for _, ok := range started {
+		if ok {
+			n++
+		}
+	}
+	if n != 1 {
+		t.Errorf("logins started = %d, want 1", n)
+	}
+}
+
+func TestHTTPAuth_LogoutClearsTokens(t *testing.T) {
+	var revoked bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/auth/logout" {
+			revoked = true
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	store := newMemoryConfigStore()
+	store.values[ConfigKeyAccessToken] = "access-1"
+	store.values[ConfigKeyRefreshToken] = "refresh-1"
+
+	client := NewHTTPClient(server.URL, "", "", testLogger())
+	client.SetTokenStore(context.Background(), store)
+
+	if err := client.Auth().Logout(context.Background()); err != nil {
+		t.Fatalf("Logout: %v", err)
+	}
+	if !revoked {
+		t.Fatal("expected refresh token revocation")
+	}
+	if client.Auth().IsAuthenticated() {
+		t.Fatal("expected signed out after logout")
+	}
+	if store.values[ConfigKeyAccessToken] != "" || store.values[ConfigKeyRefreshToken] != "" {
+		t.Fatalf("tokens not cleared: %+v", store.values)
+	}
+}
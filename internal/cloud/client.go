@@ -2,19 +2,27 @@ package cloud
 
 import (
 	"context"
+	"errors"
 	"log/slog"
 )
 
+// ErrBatchUnsupported is returned by UploadScenesBatch when the SaaS does not
+// expose the batch endpoint; callers fall back to UploadScenes per video.
+var ErrBatchUnsupported = errors.New("batch scene ingest not supported by server")
+
 type Client interface {
 	Auth() AuthService
 	Upload() UploadService
 	Scenes() SceneUploader
 	Libraries() LibraryService
-	RegisterDevice(deviceID string) error
+	Annotations() AnnotationService
+	RegisterDevice(ctx context.Context, info DeviceInfo) error
+	Heartbeat(ctx context.Context, payload HeartbeatPayload) error
 }
 
 type SceneUploader interface {
 	UploadScenes(ctx context.Context, payload SceneIngestPayload) error
+	UploadScenesBatch(ctx context.Context, items []SceneBatchItem) ([]SceneBatchResult, error)
 }
 
 type StubClient struct {
@@ -22,6 +30,7 @@ type StubClient struct {
 	upload    *StubUpload
 	scenes    *StubSceneUploader
 	libraries *StubLibraryService
+	notes     *StubAnnotationService
 	logger    *slog.Logger
 }
 
@@ -31,6 +40,7 @@ func NewStubClient(logger *slog.Logger) *StubClient {
 		upload:    NewStubUpload(logger),
 		scenes:    &StubSceneUploader{logger: logger},
 		libraries: &StubLibraryService{logger: logger},
+		notes:     &StubAnnotationService{logger: logger},
 		logger:    logger,
 	}
 }
@@ -51,8 +61,20 @@ func (c *StubClient) Libraries() LibraryService {
 	return c.libraries
 }
 
-func (c *StubClient) RegisterDevice(deviceID string) error {
-	c.logger.Info("cloud stub: device registration requested", "device_id", deviceID)
+func (c *StubClient) Annotations() AnnotationService {
+	return c.notes
+}
+
+func (c *StubClient) RegisterDevice(ctx context.Context, info DeviceInfo) error {
+	c.logger.Info("cloud stub: device registration requested", "device_id", info.DeviceID)
+	return nil
+}
+
+func (c *StubClient) Heartbeat(ctx context.Context, payload HeartbeatPayload) error {
+	c.logger.Debug("cloud stub: heartbeat requested",
+		"device_id", payload.DeviceID,
+		"queue_depth", payload.QueueDepth,
+	)
 	return nil
 }
 
@@ -67,3 +89,16 @@ func (s *StubSceneUploader) UploadScenes(ctx context.Context, payload SceneInges
 	)
 	return nil
 }
+
+func (s *StubSceneUploader) UploadScenesBatch(ctx context.Context, items []SceneBatchItem) ([]SceneBatchResult, error) {
+	s.logger.Info("cloud stub: batch scene upload requested", "videos", len(items))
+	results := make([]SceneBatchResult, 0, len(items))
+	for _, item := range items {
+		results = append(results, SceneBatchResult{
+			IdempotencyKey: item.IdempotencyKey,
+			VideoID:        item.Payload.VideoID,
+			StatusCode:     200,
+		})
+	}
+	return results, nil
+}
@@ -0,0 +1,74 @@
+package cloud
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"net/http"
+)
+
+const (
+	CompressionNone = "none"
+	CompressionGzip = "gzip"
+
+	// compressMinBytes skips compression for bodies too small to benefit.
+	compressMinBytes = 8 << 10
+)
+
+// SetCompression selects the Content-Encoding for scene ingest requests.
+func (c *HTTPClient) SetCompression(mode string) error {
+	switch mode {
+	case CompressionNone, CompressionGzip:
+		c.compression = mode
+		return nil
+	default:
+		return fmt.Errorf("unsupported compression %q", mode)
+	}
+}
+
+// sendIngest sends body to an ingest endpoint, gzip-compressing it when
+// enabled and large enough. A server that answers 415 to a compressed body
+// does not understand Content-Encoding; compression is then turned off for
+// the rest of the process and the request is replayed uncompressed.
+func (c *HTTPClient) sendIngest(ctx context.Context, method, path string, body []byte) (*http.Response, error) {
+	compress := c.compression == CompressionGzip && len(body) >= compressMinBytes && !c.compressionRejected.Load()
+
+	payload := body
+	if compress {
+		var err error
+		if payload, err = gzipBytes(body); err != nil {
+			return nil, fmt.Errorf("compress request: %w", err)
+		}
+	}
+
+	req, err := c.newRequest(ctx, method, path, payload)
+	if err != nil {
+		return nil, err
+	}
+	if compress {
+		req.Header.Set("Content-Encoding", CompressionGzip)
+	}
+
+	resp, err := c.do(req)
+	if err != nil || !compress || resp.StatusCode != http.StatusUnsupportedMediaType {
+		return resp, err
+	}
+	resp.Body.Close()
+
+	c.compressionRejected.Store(true)
+	c.logger.Info("server rejected compressed request, sending uncompressed", "path", path)
+	return c.sendIngest(ctx, method, path, body)
+}
+
+func gzipBytes(body []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(body); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
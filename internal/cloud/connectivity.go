@@ -0,0 +1,119 @@
+package cloud
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	monitorInterval     = 15 * time.Second
+	monitorProbeTimeout = 10 * time.Second
+)
+
+// Ping checks that the SaaS answers at all. Any HTTP response counts as
+// reachable; only transport failures are returned.
+func (c *HTTPClient) Ping(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, monitorProbeTimeout)
+	defer cancel()
+
+	req, err := c.newRequest(ctx, http.MethodGet, "/api/health", nil)
+	if err != nil {
+		return err
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+	resp.Body.Close()
+	return nil
+}
+
+// Monitor tracks whether the SaaS is reachable by probing it periodically
+// and notifies listeners when that changes. It starts out assuming the
+// network is up so nothing is held back before the first probe.
+type Monitor struct {
+	probe    func(ctx context.Context) error
+	logger   *slog.Logger
+	interval time.Duration
+
+	mu        sync.Mutex
+	online    bool
+	listeners []func(online bool)
+}
+
+func NewMonitor(probe func(ctx context.Context) error, logger *slog.Logger) *Monitor {
+	return &Monitor{
+		probe:    probe,
+		logger:   logger,
+		interval: monitorInterval,
+		online:   true,
+	}
+}
+
+func (m *Monitor) SetInterval(d time.Duration) {
+	m.interval = d
+}
+
+// OnChange registers fn to be called with the new state whenever
+// reachability flips. Callbacks run on the monitor goroutine.
+func (m *Monitor) OnChange(fn func(online bool)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.listeners = append(m.listeners, fn)
+}
+
+func (m *Monitor) Online() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.online
+}
+
+// Run probes immediately and then every interval until ctx is cancelled.
+func (m *Monitor) Run(ctx context.Context) {
+	m.Check(ctx)
+
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			m.Check(ctx)
+		}
+	}
+}
+
+// Check probes once and returns the resulting state.
+func (m *Monitor) Check(ctx context.Context) bool {
+	err := m.probe(ctx)
+	if ctx.Err() != nil {
+		return m.Online()
+	}
+	online := err == nil
+
+	m.mu.Lock()
+	changed := online != m.online
+	m.online = online
+	listeners := append([]func(bool){}, m.listeners...)
+	m.mu.Unlock()
+
+	if !changed {
+		return online
+	}
+	if online {
+		m.logger.Info("cloud reachable again")
+	} else {
+		m.logger.Warn("cloud unreachable", "error", err)
+	}
+	for _, fn := range listeners {
+		fn(online)
+	}
+	return online
+}
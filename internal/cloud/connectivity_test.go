@@ -0,0 +1,54 @@
+package cloud
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHTTPClient_Ping(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	client := NewHTTPClient(server.URL, "", "", testLogger())
+
+	if err := client.Ping(context.Background()); err != nil {
+		t.Fatalf("Ping with any HTTP response = %v, want nil", err)
+	}
+
+	server.Close()
+	if err := client.Ping(context.Background()); err == nil {
+		t.Fatal("Ping to a closed server succeeded")
+	}
+}
+
+func TestMonitor_NotifiesOnChange(t *testing.T) {
+	probeErr := errors.New("dial tcp: connection refused")
+	var fail bool
+	monitor := NewMonitor(func(context.Context) error {
+		if fail {
+			return probeErr
+		}
+		return nil
+	}, testLogger())
+
+	var changes []bool
+	monitor.OnChange(func(online bool) { changes = append(changes, online) })
+
+	ctx := context.Background()
+	monitor.Check(ctx)
+	fail = true
+	monitor.Check(ctx)
+	monitor.Check(ctx)
+	fail = false
+	monitor.Check(ctx)
+
+	if len(changes) != 2 || changes[0] || !changes[1] {
+		t.Fatalf("changes = %v, want [false true]", changes)
+	}
+	if !monitor.Online() {
+		t.Error("Online = false after recovery")
+	}
+}
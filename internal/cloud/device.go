@@ -0,0 +1,90 @@
+package cloud
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// DeviceInfo is the request body sent to POST /api/devices/register.
+// It tells the SaaS which machine holds which libraries so uploads tagged
+// with X-Heimdex-Device-Id can be attributed to a known device.
+type DeviceInfo struct {
+	DeviceID     string             `json:"device_id"`
+	Hostname     string             `json:"hostname"`
+	OS           string             `json:"os"`
+	Arch         string             `json:"arch"`
+	AgentVersion string             `json:"agent_version"`
+	Capabilities DeviceCapabilities `json:"capabilities"`
+}
+
+// DeviceCapabilities mirrors the pipeline capabilities reported by doctor.
+type DeviceCapabilities struct {
+	Faces            bool   `json:"faces"`
+	Speech           bool   `json:"speech"`
+	Scenes           bool   `json:"scenes"`
+	OCR              bool   `json:"ocr"`
+	PipelinesVersion string `json:"pipelines_version,omitempty"`
+}
+
+// DeviceRegistrationResponse is the response from POST /api/devices/register.
+type DeviceRegistrationResponse struct {
+	DeviceID   string `json:"device_id"`
+	Registered bool   `json:"registered"`
+}
+
+// HeartbeatPayload is the request body sent to POST /api/devices/heartbeat.
+type HeartbeatPayload struct {
+	DeviceID     string            `json:"device_id"`
+	AgentVersion string            `json:"agent_version"`
+	Sources      []HeartbeatSource `json:"sources"`
+	QueueDepth   int               `json:"queue_depth"`
+	JobsRunning  int               `json:"jobs_running"`
+	Paused       bool              `json:"paused"`
+	SentAt       time.Time         `json:"sent_at"`
+}
+
+// HeartbeatSource reports one local source and whether its drive is attached.
+type HeartbeatSource struct {
+	SourceID       string `json:"source_id"`
+	DisplayName    string `json:"display_name"`
+	SourceType     string `json:"source_type"`
+	CloudLibraryID string `json:"cloud_library_id,omitempty"`
+	DriveNickname  string `json:"drive_nickname,omitempty"`
+	Present        bool   `json:"present"`
+	FileCount      int    `json:"file_count"`
+}
+
+func (c *HTTPClient) RegisterDevice(ctx context.Context, info DeviceInfo) error {
+	if info.DeviceID != "" {
+		c.SetDeviceID(info.DeviceID)
+	}
+
+	respBody, err := c.postJSON(ctx, "/api/devices/register", info)
+	if err != nil {
+		return fmt.Errorf("device registration: %w", err)
+	}
+
+	var result DeviceRegistrationResponse
+	if err := json.Unmarshal(respBody, &result); err == nil {
+		c.logger.Info("device registered with cloud",
+			"device_id", info.DeviceID,
+			"hostname", info.Hostname,
+			"agent_version", info.AgentVersion,
+		)
+	}
+	return nil
+}
+
+func (c *HTTPClient) Heartbeat(ctx context.Context, payload HeartbeatPayload) error {
+	if _, err := c.postJSON(ctx, "/api/devices/heartbeat", payload); err != nil {
+		return fmt.Errorf("device heartbeat: %w", err)
+	}
+	c.logger.Debug("heartbeat sent",
+		"device_id", payload.DeviceID,
+		"sources", len(payload.Sources),
+		"queue_depth", payload.QueueDepth,
+	)
+	return nil
+}
@@ -0,0 +1,111 @@
+package cloud
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestHTTPClient_RegisterDevice(t *testing.T) {
+	var received DeviceInfo
+	var receivedDeviceHeader string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/devices/register" {
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+		if r.Method != http.MethodPost {
+			t.Errorf("unexpected method: %s", r.Method)
+		}
+		receivedDeviceHeader = r.Header.Get("X-Heimdex-Device-Id")
+		body, _ := io.ReadAll(r.Body)
+		json.Unmarshal(body, &received)
+		json.NewEncoder(w).Encode(DeviceRegistrationResponse{DeviceID: received.DeviceID, Registered: true})
+	}))
+	defer server.Close()
+
+	client := NewHTTPClient(server.URL, "test-token", "devorg", testLogger())
+
+	err := client.RegisterDevice(context.Background(), DeviceInfo{
+		DeviceID:     "device-abc",
+		Hostname:     "edit-bay-1",
+		OS:           "darwin",
+		Arch:         "arm64",
+		AgentVersion: "0.1.0",
+		Capabilities: DeviceCapabilities{Speech: true, Scenes: true},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if received.DeviceID != "device-abc" || received.Hostname != "edit-bay-1" {
+		t.Fatalf("payload = %+v, want device-abc on edit-bay-1", received)
+	}
+	if !received.Capabilities.Speech || !received.Capabilities.Scenes || received.Capabilities.Faces {
+		t.Fatalf("capabilities = %+v, want speech+scenes only", received.Capabilities)
+	}
+	if receivedDeviceHeader != "device-abc" {
+		t.Fatalf("device_id_header = %q, want %q", receivedDeviceHeader, "device-abc")
+	}
+}
+
+func TestHTTPClient_Heartbeat(t *testing.T) {
+	var received HeartbeatPayload
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/devices/heartbeat" {
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+		body, _ := io.ReadAll(r.Body)
+		json.Unmarshal(body, &received)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	client := NewHTTPClient(server.URL, "test-token", "devorg", testLogger())
+	client.SetDeviceID("device-abc")
+
+	err := client.Heartbeat(context.Background(), HeartbeatPayload{
+		DeviceID:   "device-abc",
+		QueueDepth: 3,
+		Sources: []HeartbeatSource{
+			{SourceID: "src-1", DisplayName: "Shoot A", Present: false, FileCount: 12},
+		},
+		SentAt: time.Now(),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if received.QueueDepth != 3 {
+		t.Errorf("queue_depth = %d, want 3", received.QueueDepth)
+	}
+	if len(received.Sources) != 1 || received.Sources[0].Present || received.Sources[0].FileCount != 12 {
+		t.Errorf("sources = %+v, want one absent source with 12 files", received.Sources)
+	}
+}
+
+func TestHTTPClient_Heartbeat_UnknownDevice(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"detail":"unknown device"}`))
+	}))
+	defer server.Close()
+
+	client := NewHTTPClient(server.URL, "test-token", "devorg", testLogger())
+
+	err := client.Heartbeat(context.Background(), HeartbeatPayload{DeviceID: "device-abc"})
+	if err == nil {
+		t.Fatal("expected error for 404 response")
+	}
+
+	var uploadErr *UploadError
+	if !errors.As(err, &uploadErr) || uploadErr.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected UploadError with 404, got %v", err)
+	}
+}
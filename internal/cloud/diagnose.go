@@ -0,0 +1,255 @@
+package cloud
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const diagnoseStepTimeout = 10 * time.Second
+
+// Diagnostic step states.
+const (
+	StepOK      = "ok"
+	StepFailed  = "failed"
+	StepSkipped = "skipped"
+)
+
+// DiagnosticStep is the outcome of one stage of a connectivity check.
+type DiagnosticStep struct {
+	Name     string
+	Status   string
+	Detail   string
+	Duration time.Duration
+}
+
+// Diagnosis walks the path to the SaaS one layer at a time so a failure
+// points at the exact stage: configuration, proxy, DNS, TCP, TLS, HTTP or
+// credentials. Steps after the first failure are skipped.
+type Diagnosis struct {
+	OK    bool
+	Steps []DiagnosticStep
+}
+
+// Diagnoser is implemented by clients that can explain connectivity
+// failures.
+type Diagnoser interface {
+	Diagnose(ctx context.Context) *Diagnosis
+}
+
+type diagnoseState struct {
+	base   *url.URL
+	proxy  *url.URL
+	target string // host:port dialled first: the proxy, or the SaaS itself
+}
+
+// Diagnose runs the connectivity check against the configured base URL.
+func (c *HTTPClient) Diagnose(ctx context.Context) *Diagnosis {
+	st := &diagnoseState{}
+	steps := []struct {
+		name string
+		run  func(ctx context.Context, st *diagnoseState) (string, error)
+	}{
+		{"config", c.diagnoseConfig},
+		{"proxy", c.diagnoseProxy},
+		{"dns", diagnoseDNS},
+		{"connect", diagnoseConnect},
+		{"tls", c.diagnoseTLS},
+		{"http", c.diagnoseHTTP},
+		{"auth", c.diagnoseAuth},
+	}
+
+	d := &Diagnosis{OK: true}
+	for _, step := range steps {
+		if !d.OK {
+			d.Steps = append(d.Steps, DiagnosticStep{Name: step.name, Status: StepSkipped, Detail: "earlier step failed"})
+			continue
+		}
+
+		stepCtx, cancel := context.WithTimeout(ctx, diagnoseStepTimeout)
+		start := time.Now()
+		detail, err := step.run(stepCtx, st)
+		cancel()
+
+		result := DiagnosticStep{Name: step.name, Status: StepOK, Detail: detail, Duration: time.Since(start)}
+		switch {
+		case errors.Is(err, errStepSkipped):
+			result.Status = StepSkipped
+		case err != nil:
+			result.Status = StepFailed
+			result.Detail = err.Error()
+			d.OK = false
+		}
+		d.Steps = append(d.Steps, result)
+	}
+	return d
+}
+
+var errStepSkipped = errors.New("skipped")
+
+func (c *HTTPClient) diagnoseConfig(_ context.Context, st *diagnoseState) (string, error) {
+	if c.transportErr != nil {
+		return "", fmt.Errorf("invalid transport settings: %w", c.transportErr)
+	}
+	base, err := url.Parse(c.baseURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid base URL: %w", err)
+	}
+	if (base.Scheme != "http" && base.Scheme != "https") || base.Host == "" {
+		return "", fmt.Errorf("base URL %q must be an absolute http or https URL", c.baseURL)
+	}
+	st.base = base
+	return base.String(), nil
+}
+
+func (c *HTTPClient) diagnoseProxy(_ context.Context, st *diagnoseState) (string, error) {
+	st.target = hostPort(st.base)
+	if c.transport == nil || c.transport.Proxy == nil {
+		return "direct connection", nil
+	}
+
+	proxy, err := c.transport.Proxy(&http.Request{URL: st.base, Header: http.Header{}})
+	if err != nil {
+		return "", fmt.Errorf("resolve proxy: %w", err)
+	}
+	if proxy == nil {
+		return "direct connection", nil
+	}
+	st.proxy = proxy
+	st.target = hostPort(proxy)
+	return "via " + redactURL(proxy), nil
+}
+
+func diagnoseDNS(ctx context.Context, st *diagnoseState) (string, error) {
+	host, _, _ := net.SplitHostPort(st.target)
+	if net.ParseIP(host) != nil {
+		return host + " is an IP address", nil
+	}
+	addrs, err := net.DefaultResolver.LookupHost(ctx, host)
+	if err != nil {
+		return "", fmt.Errorf("resolve %s: %w", host, err)
+	}
+	return host + " -> " + strings.Join(addrs, ", "), nil
+}
+
+func diagnoseConnect(ctx context.Context, st *diagnoseState) (string, error) {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", st.target)
+	if err != nil {
+		return "", fmt.Errorf("connect to %s: %w", st.target, err)
+	}
+	conn.Close()
+	return "connected to " + st.target, nil
+}
+
+// diagnoseTLS handshakes directly with the SaaS. Through a proxy the TLS
+// session is tunnelled, so it is exercised by the http step instead.
+func (c *HTTPClient) diagnoseTLS(ctx context.Context, st *diagnoseState) (string, error) {
+	if st.base.Scheme != "https" {
+		return "plain http", errStepSkipped
+	}
+	if st.proxy != nil {
+		return "tunnelled through proxy; checked by the http step", errStepSkipped
+	}
+
+	config := &tls.Config{}
+	if c.transport != nil && c.transport.TLSClientConfig != nil {
+		config = c.transport.TLSClientConfig.Clone()
+	}
+	config.ServerName = st.base.Hostname()
+
+	dialer := &tls.Dialer{Config: config}
+	conn, err := dialer.DialContext(ctx, "tcp", st.target)
+	if err != nil {
+		return "", describeTLSError(err)
+	}
+	defer conn.Close()
+
+	state := conn.(*tls.Conn).ConnectionState()
+	detail := tls.VersionName(state.Version)
+	if len(state.PeerCertificates) > 0 {
+		detail += ", issued by " + state.PeerCertificates[0].Issuer.CommonName
+	}
+	return detail, nil
+}
+
+func describeTLSError(err error) error {
+	var unknownAuthority x509.UnknownAuthorityError
+	if errors.As(err, &unknownAuthority) {
+		issuer := ""
+		if unknownAuthority.Cert != nil {
+			issuer = unknownAuthority.Cert.Issuer.CommonName
+		}
+		return fmt.Errorf("certificate signed by unknown authority %q; add its CA to the CA bundle", issuer)
+	}
+	var hostnameErr x509.HostnameError
+	if errors.As(err, &hostnameErr) {
+		return fmt.Errorf("certificate is not valid for this host: %w", err)
+	}
+	return fmt.Errorf("TLS handshake failed: %w", err)
+}
+
+func (c *HTTPClient) diagnoseHTTP(ctx context.Context, st *diagnoseState) (string, error) {
+	req, err := c.newRequest(ctx, http.MethodGet, "/api/health", nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		var unknownAuthority x509.UnknownAuthorityError
+		if errors.As(err, &unknownAuthority) {
+			return "", describeTLSError(err)
+		}
+		return "", fmt.Errorf("request failed: %w", err)
+	}
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+	resp.Body.Close()
+
+	if resp.StatusCode == http.StatusProxyAuthRequired {
+		return "", fmt.Errorf("proxy requires authentication (HTTP 407); put credentials in the proxy URL")
+	}
+	return fmt.Sprintf("HTTP %d", resp.StatusCode), nil
+}
+
+func (c *HTTPClient) diagnoseAuth(ctx context.Context, _ *diagnoseState) (string, error) {
+	if c.bearerToken() == "" {
+		return "not signed in", errStepSkipped
+	}
+	req, err := c.newRequest(ctx, http.MethodGet, "/api/libraries", nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := c.do(req)
+	if err != nil {
+		return "", err
+	}
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+	resp.Body.Close()
+
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		return "", fmt.Errorf("credentials rejected (HTTP %d)", resp.StatusCode)
+	}
+	return fmt.Sprintf("authenticated (HTTP %d)", resp.StatusCode), nil
+}
+
+func hostPort(u *url.URL) string {
+	if u.Port() != "" {
+		return u.Host
+	}
+	port := "80"
+	switch u.Scheme {
+	case "https":
+		port = "443"
+	case "socks5":
+		port = "1080"
+	}
+	return net.JoinHostPort(u.Hostname(), port)
+}
@@ -4,12 +4,17 @@ import (
 	"bytes"
 	"context"
 	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
+	"net"
 	"net/http"
-	"time"
+	"sync"
+	"sync/atomic"
 )
 
 // UploadError represents an error from the scene upload endpoint.
@@ -28,32 +33,79 @@ func (e *UploadError) IsRetryable() bool {
 	return e.StatusCode >= 500
 }
 
+// IsNetworkError reports whether err means the SaaS could not be reached at
+// all (DNS failure, refused connection, no route), as opposed to an HTTP
+// error response or a timeout on an established connection.
+func IsNetworkError(err error) bool {
+	var uploadErr *UploadError
+	if err == nil || errors.As(err, &uploadErr) {
+		return false
+	}
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return true
+	}
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+type idempotencyKeyCtx struct{}
+
+// WithIdempotencyKey attaches an Idempotency-Key header value to requests
+// made with the returned context, letting the SaaS drop replays.
+func WithIdempotencyKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, idempotencyKeyCtx{}, key)
+}
+
+// IdempotencyKey returns the key attached by WithIdempotencyKey, or "".
+func IdempotencyKey(ctx context.Context) string {
+	key, _ := ctx.Value(idempotencyKeyCtx{}).(string)
+	return key
+}
+
 // HTTPClient is a real cloud client that communicates with the Heimdex SaaS.
 // It sends scene ingestion payloads via HTTP to the SaaS ingest endpoint.
 type HTTPClient struct {
 	baseURL    string
 	token      string
 	orgSlug    string
+	deviceMu   sync.RWMutex
 	deviceID   string
 	httpClient *http.Client
 	logger     *slog.Logger
 
-	auth   *StubAuth
-	upload *StubUpload
+	compression         string
+	batchUnsupported    atomic.Bool
+	chunkedUnsupported  atomic.Bool
+	compressionRejected atomic.Bool
+	limiter             *uploadLimiter
+	transport           *http.Transport
+	transportErr        error
+
+	auth   *HTTPAuth
+	upload *HTTPUpload
 }
 
 func NewHTTPClient(baseURL, token, orgSlug string, logger *slog.Logger) *HTTPClient {
-	return &HTTPClient{
+	limiter := newUploadLimiter()
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	c := &HTTPClient{
 		baseURL: baseURL,
 		token:   token,
 		orgSlug: orgSlug,
+		// No client-wide Timeout: throttledTransport gives each request a
+		// deadline that grows with its body under an upload cap.
 		httpClient: &http.Client{
-			Timeout: 60 * time.Second,
+			Transport: &throttledTransport{base: transport, limiter: limiter},
 		},
-		logger: logger,
-		auth:   NewStubAuth(logger),
-		upload: NewStubUpload(logger),
+		logger:      logger,
+		compression: CompressionGzip,
+		limiter:     limiter,
+		transport:   transport,
 	}
+	c.auth = newHTTPAuth(c)
+	c.upload = &HTTPUpload{client: c}
+	return c
 }
 
 func (c *HTTPClient) Auth() AuthService {
@@ -72,53 +124,73 @@ func (c *HTTPClient) Libraries() LibraryService {
 	return &HTTPLibraryService{client: c}
 }
 
-func (c *HTTPClient) RegisterDevice(deviceID string) error {
-	c.logger.Info("cloud http: device registration requested", "device_id", deviceID)
-	return nil
+func (c *HTTPClient) Annotations() AnnotationService {
+	return &HTTPAnnotationService{client: c}
 }
 
 func (c *HTTPClient) SetDeviceID(id string) {
+	c.deviceMu.Lock()
+	defer c.deviceMu.Unlock()
 	c.deviceID = id
 }
 
+func (c *HTTPClient) getDeviceID() string {
+	c.deviceMu.RLock()
+	defer c.deviceMu.RUnlock()
+	return c.deviceID
+}
+
+// SetTokenStore persists device-login tokens in store and restores any
+// tokens saved by a previous run.
+func (c *HTTPClient) SetTokenStore(ctx context.Context, store ConfigStore) error {
+	c.auth.store = store
+	return c.auth.load(ctx)
+}
+
+// HasStaticToken reports whether the client was configured with a static
+// API token, used when no device-login token is held.
+func (c *HTTPClient) HasStaticToken() bool {
+	return c.token != ""
+}
+
+// bearerToken prefers the device-login access token and falls back to the
+// static HEIMDEX_CLOUD_TOKEN.
+func (c *HTTPClient) bearerToken() string {
+	if token := c.auth.GetAccessToken(); token != "" {
+		return token
+	}
+	return c.token
+}
+
 func (c *HTTPClient) UploadScenes(ctx context.Context, payload SceneIngestPayload) error {
 	body, err := json.Marshal(payload)
 	if err != nil {
 		return fmt.Errorf("marshal scene payload: %w", err)
 	}
-
-	// Build URL with org slug as subdomain: {org}.app.heimdex.local -> baseURL
-	url := fmt.Sprintf("%s/api/ingest/scenes", c.baseURL)
-
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
-	if err != nil {
-		return fmt.Errorf("create request: %w", err)
-	}
-
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+c.token)
-	req.Header.Set("X-Heimdex-Request-Id", generateRequestID())
-	if c.deviceID != "" {
-		req.Header.Set("X-Heimdex-Device-Id", c.deviceID)
+	if _, ok := ctx.Value(idempotencyKeyCtx{}).(string); !ok {
+		// Callers outside the outbox still get replay protection: the key is
+		// derived from content, so an identical payload maps to one ingest.
+		sum := sha256.Sum256(body)
+		ctx = WithIdempotencyKey(ctx, "scenes:"+payload.VideoID+":"+hex.EncodeToString(sum[:16]))
 	}
 
-	// Set Host header for tenancy resolution
-	// The SaaS resolves org from the Host header subdomain
-	if c.orgSlug != "" {
-		req.Host = c.orgSlug + ".app.heimdex.local"
+	if len(body) > SceneChunkThreshold && !c.chunkedUnsupported.Load() {
+		err := c.uploadScenesChunked(ctx, payload)
+		if !errors.Is(err, errChunkedUnsupported) {
+			return err
+		}
 	}
 
 	c.logger.Info("uploading scenes to cloud",
-		"url", url,
-		"host", req.Host,
+		"url", c.baseURL+"/api/ingest/scenes",
 		"video_id", payload.VideoID,
 		"scene_count", len(payload.Scenes),
 		"body_bytes", len(body),
 	)
 
-	resp, err := c.httpClient.Do(req)
+	resp, err := c.sendIngest(ctx, http.MethodPost, "/api/ingest/scenes", body)
 	if err != nil {
-		return fmt.Errorf("http request failed: %w", err)
+		return err
 	}
 	defer resp.Body.Close()
 
@@ -138,6 +210,135 @@ func (c *HTTPClient) UploadScenes(ctx context.Context, payload SceneIngestPayloa
 	return &UploadError{StatusCode: resp.StatusCode, Body: string(respBody)}
 }
 
+// UploadScenesBatch sends several videos in one request. A 404 or 405 means
+// the server predates the batch endpoint; the client remembers that and
+// returns ErrBatchUnsupported without further round trips.
+func (c *HTTPClient) UploadScenesBatch(ctx context.Context, items []SceneBatchItem) ([]SceneBatchResult, error) {
+	if c.batchUnsupported.Load() {
+		return nil, ErrBatchUnsupported
+	}
+
+	body, err := json.Marshal(SceneBatchRequest{Items: items})
+	if err != nil {
+		return nil, fmt.Errorf("marshal batch request: %w", err)
+	}
+
+	respBody, err := c.ingestCall(ctx, http.MethodPost, "/api/ingest/scenes/batch", body)
+	if err != nil {
+		var uploadErr *UploadError
+		if errors.As(err, &uploadErr) &&
+			(uploadErr.StatusCode == http.StatusNotFound || uploadErr.StatusCode == http.StatusMethodNotAllowed) {
+			c.batchUnsupported.Store(true)
+			c.logger.Info("batch scene ingest unavailable, falling back to per-video uploads")
+			return nil, ErrBatchUnsupported
+		}
+		return nil, err
+	}
+
+	var result SceneBatchResponse
+	if err := json.Unmarshal(respBody, &result); err != nil {
+		return nil, fmt.Errorf("unmarshal batch response: %w", err)
+	}
+
+	c.logger.Info("batch scene upload completed", "videos", len(items), "results", len(result.Results))
+	return result.Results, nil
+}
+
+// newRequest builds a SaaS request carrying the auth, correlation and
+// tenancy headers every endpoint expects. A nil body sends no payload.
+func (c *HTTPClient) newRequest(ctx context.Context, method, path string, body []byte) (*http.Request, error) {
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reader)
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Authorization", "Bearer "+c.bearerToken())
+	req.Header.Set("X-Heimdex-Request-Id", generateRequestID())
+	if key := IdempotencyKey(ctx); key != "" {
+		req.Header.Set("Idempotency-Key", key)
+	}
+	if id := c.getDeviceID(); id != "" {
+		req.Header.Set("X-Heimdex-Device-Id", id)
+	}
+
+	// The SaaS resolves org from the Host header subdomain:
+	// {org}.app.heimdex.local -> baseURL
+	if c.orgSlug != "" {
+		req.Host = c.orgSlug + ".app.heimdex.local"
+	}
+
+	return req, nil
+}
+
+// postJSON marshals v, POSTs it to path and returns the response body.
+// Non-2xx responses are returned as *UploadError.
+func (c *HTTPClient) postJSON(ctx context.Context, path string, v interface{}) ([]byte, error) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("marshal request: %w", err)
+	}
+
+	req, err := c.newRequest(ctx, http.MethodPost, path, body)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 65536))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, &UploadError{StatusCode: resp.StatusCode, Body: string(respBody)}
+	}
+
+	return respBody, nil
+}
+
+// do sends req. When the SaaS rejects an expired access token it refreshes
+// the token once and replays the request.
+func (c *HTTPClient) do(req *http.Request) (*http.Response, error) {
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("http request failed: %w", err)
+	}
+	if resp.StatusCode != http.StatusUnauthorized || !c.auth.canRefresh() {
+		return resp, nil
+	}
+	resp.Body.Close()
+
+	if err := c.auth.Refresh(req.Context()); err != nil {
+		return nil, err
+	}
+
+	retry := req.Clone(req.Context())
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, fmt.Errorf("rewind request body: %w", err)
+		}
+		retry.Body = body
+	}
+	retry.Header.Set("Authorization", "Bearer "+c.bearerToken())
+
+	resp, err = c.httpClient.Do(retry)
+	if err != nil {
+		return nil, fmt.Errorf("http request failed: %w", err)
+	}
+	return resp, nil
+}
+
 func generateRequestID() string {
 	b := make([]byte, 16)
 	_, _ = rand.Read(b)
@@ -278,3 +278,138 @@ func TestHTTPClient_EmptyOrgSlug(t *testing.T) {
 		t.Error("host should not have empty slug prefix")
 	}
 }
+
+func TestHTTPClient_UploadScenesBatch_SendsItems(t *testing.T) {
+	var req SceneBatchRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/ingest/scenes/batch" {
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		var resp SceneBatchResponse
+		for _, item := range req.Items {
+			resp.Results = append(resp.Results, SceneBatchResult{IdempotencyKey: item.IdempotencyKey, StatusCode: 200})
+		}
+		json.NewEncoder(w).Encode(resp)
+	}))
+	defer server.Close()
+
+	client := NewHTTPClient(server.URL, "test-token", "devorg", testLogger())
+	results, err := client.UploadScenesBatch(context.Background(), []SceneBatchItem{
+		{IdempotencyKey: "k1", Payload: SceneIngestPayload{VideoID: "v1"}},
+		{IdempotencyKey: "k2", Payload: SceneIngestPayload{VideoID: "v2"}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(req.Items) != 2 || len(results) != 2 || results[1].IdempotencyKey != "k2" {
+		t.Errorf("items = %d results = %+v", len(req.Items), results)
+	}
+}
+
+func TestHTTPClient_UploadScenesBatch_Unsupported(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	client := NewHTTPClient(server.URL, "test-token", "devorg", testLogger())
+	items := []SceneBatchItem{{IdempotencyKey: "k1", Payload: SceneIngestPayload{VideoID: "v1"}}}
+
+	for i := 0; i < 2; i++ {
+		if _, err := client.UploadScenesBatch(context.Background(), items); !errors.Is(err, ErrBatchUnsupported) {
+			t.Fatalf("attempt %d: err = %v, want ErrBatchUnsupported", i, err)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("server calls = %d, want 1 (unsupported is remembered)", calls)
+	}
+}
+
+func TestHTTPClient_SendsIdempotencyKey(t *testing.T) {
+	var key string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key = r.Header.Get("Idempotency-Key")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	client := NewHTTPClient(server.URL, "test-token", "devorg", testLogger())
+	ctx := WithIdempotencyKey(context.Background(), "scenes:f1:abc")
+	if err := client.UploadScenes(ctx, SceneIngestPayload{VideoID: "v1"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if key != "scenes:f1:abc" {
+		t.Errorf("Idempotency-Key = %q", key)
+	}
+}
+
+func TestIsNetworkError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	url := server.URL
+	server.Close()
+
+	client := NewHTTPClient(url, "test-token", "devorg", testLogger())
+	err := client.UploadScenes(context.Background(), SceneIngestPayload{VideoID: "v1"})
+	if !IsNetworkError(err) {
+		t.Errorf("IsNetworkError(%v) = false, want true for refused connection", err)
+	}
+	if IsNetworkError(&UploadError{StatusCode: 503}) {
+		t.Error("IsNetworkError(503) = true, want false")
+	}
+}
+
+func TestHTTPClient_UploadScenes_DerivesIdempotencyKey(t *testing.T) {
+	var keys []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		keys = append(keys, r.Header.Get("Idempotency-Key"))
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	client := NewHTTPClient(server.URL, "test-token", "devorg", testLogger())
+	payload := SceneIngestPayload{VideoID: "v1", Scenes: []SceneIngestDoc{{SceneID: "v1_scene_0"}}}
+	client.UploadScenes(context.Background(), payload)
+	client.UploadScenes(context.Background(), payload)
+	payload.VideoTitle = "changed"
+	client.UploadScenes(context.Background(), payload)
+
+	if len(keys) != 3 || keys[0] == "" {
+		t.Fatalf("keys = %v", keys)
+	}
+	if keys[0] != keys[1] {
+		t.Errorf("identical payloads got different keys: %q vs %q", keys[0], keys[1])
+	}
+	if keys[0] == keys[2] {
+		t.Errorf("changed payload reused key %q", keys[0])
+	}
+}
+
+func TestHTTPAnnotationService_Pull(t *testing.T) {
+	var query string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/sync/annotations" {
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+		query = r.URL.RawQuery
+		json.NewEncoder(w).Encode(AnnotationPage{
+			Videos: []VideoAnnotations{{VideoID: "v1", Scenes: []SceneAnnotation{{SceneID: "v1_scene_0", Note: "n"}}}},
+			Cursor: "next",
+		})
+	}))
+	defer server.Close()
+
+	client := NewHTTPClient(server.URL, "test-token", "devorg", testLogger())
+	page, err := client.Annotations().Pull(context.Background(), "abc", 50)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if query != "limit=50&since=abc" {
+		t.Errorf("query = %q", query)
+	}
+	if page.Cursor != "next" || len(page.Videos) != 1 || page.Videos[0].Scenes[0].Note != "n" {
+		t.Errorf("page = %+v", page)
+	}
+}
@@ -1,7 +1,6 @@
 package cloud
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
@@ -31,21 +30,14 @@ func (s *HTTPLibraryService) GetOrCreate(ctx context.Context, name string) (*Lib
 		return nil, fmt.Errorf("marshal library request: %w", err)
 	}
 
-	url := fmt.Sprintf("%s/api/libraries", s.client.baseURL)
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	req, err := s.client.newRequest(ctx, http.MethodPost, "/api/libraries", body)
 	if err != nil {
-		return nil, fmt.Errorf("create request: %w", err)
+		return nil, err
 	}
 
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+s.client.token)
-	if s.client.orgSlug != "" {
-		req.Host = s.client.orgSlug + ".app.heimdex.local"
-	}
-
-	resp, err := s.client.httpClient.Do(req)
+	resp, err := s.client.do(req)
 	if err != nil {
-		return nil, fmt.Errorf("http request failed: %w", err)
+		return nil, err
 	}
 	defer resp.Body.Close()
 
@@ -64,20 +56,14 @@ func (s *HTTPLibraryService) GetOrCreate(ctx context.Context, name string) (*Lib
 }
 
 func (s *HTTPLibraryService) List(ctx context.Context) ([]LibraryResult, error) {
-	url := fmt.Sprintf("%s/api/libraries", s.client.baseURL)
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	req, err := s.client.newRequest(ctx, http.MethodGet, "/api/libraries", nil)
 	if err != nil {
-		return nil, fmt.Errorf("create request: %w", err)
-	}
-
-	req.Header.Set("Authorization", "Bearer "+s.client.token)
-	if s.client.orgSlug != "" {
-		req.Host = s.client.orgSlug + ".app.heimdex.local"
+		return nil, err
 	}
 
-	resp, err := s.client.httpClient.Do(req)
+	resp, err := s.client.do(req)
 	if err != nil {
-		return nil, fmt.Errorf("http request failed: %w", err)
+		return nil, err
 	}
 	defer resp.Body.Close()
 
@@ -28,6 +28,8 @@ type SceneIngestDoc struct {
 	OCRCharCount          int      `json:"ocr_char_count,omitempty"`
 	SourceType            string   `json:"source_type,omitempty"`
 	RequiredDriveNickname string   `json:"required_drive_nickname,omitempty"`
+	ThumbnailKey          string   `json:"thumbnail_key,omitempty"`
+	ThumbnailURL          string   `json:"thumbnail_url,omitempty"`
 }
 
 // SceneIngestResponse is the response from POST /api/ingest/scenes.
@@ -36,3 +38,28 @@ type SceneIngestResponse struct {
 	VideoID      string `json:"video_id"`
 	SkippedCount int    `json:"skipped_count"`
 }
+
+// SceneBatchItem is one video in a POST /api/ingest/scenes/batch request.
+// The SaaS deduplicates items by IdempotencyKey.
+type SceneBatchItem struct {
+	IdempotencyKey string             `json:"idempotency_key"`
+	Payload        SceneIngestPayload `json:"payload"`
+}
+
+type SceneBatchRequest struct {
+	Items []SceneBatchItem `json:"items"`
+}
+
+// SceneBatchResult is the per-item outcome of a batch ingest. StatusCode
+// follows HTTP semantics so callers can reuse UploadError retry rules.
+type SceneBatchResult struct {
+	IdempotencyKey string `json:"idempotency_key"`
+	VideoID        string `json:"video_id"`
+	StatusCode     int    `json:"status_code"`
+	IndexedCount   int    `json:"indexed_count"`
+	Error          string `json:"error,omitempty"`
+}
+
+type SceneBatchResponse struct {
+	Results []SceneBatchResult `json:"results"`
+}
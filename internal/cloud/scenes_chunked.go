@@ -0,0 +1,209 @@
+package cloud
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+const (
+	// SceneChunkThreshold is the encoded payload size above which scenes are
+	// sent through a chunked ingest session instead of one request.
+	SceneChunkThreshold = 2 << 20
+
+	sceneChunkMaxBytes  = 512 << 10
+	sceneChunkMaxScenes = 200
+	maxChunkAttempts    = 3
+)
+
+// errChunkedUnsupported means the SaaS has no session endpoints and the
+// payload must go in a single request.
+var errChunkedUnsupported = errors.New("chunked scene ingest not supported by server")
+
+// sceneSessionRequest opens a chunked ingest session. It carries the video
+// fields of SceneIngestPayload; scenes follow in chunks.
+type sceneSessionRequest struct {
+	VideoID         string `json:"video_id"`
+	VideoTitle      string `json:"video_title,omitempty"`
+	LibraryID       string `json:"library_id"`
+	PipelineVersion string `json:"pipeline_version,omitempty"`
+	ModelVersion    string `json:"model_version,omitempty"`
+	TotalDurationMs int    `json:"total_duration_ms,omitempty"`
+	SceneCount      int    `json:"scene_count"`
+	ChunkCount      int    `json:"chunk_count"`
+}
+
+// sceneSessionResponse lists chunks the SaaS already holds for the session,
+// so a retried upload with the same Idempotency-Key resumes.
+type sceneSessionResponse struct {
+	SessionID      string `json:"session_id"`
+	ReceivedChunks []int  `json:"received_chunks,omitempty"`
+}
+
+type sceneChunkRequest struct {
+	Index  int              `json:"index"`
+	Scenes []SceneIngestDoc `json:"scenes"`
+}
+
+type sceneFinalizeRequest struct {
+	ChunkCount int `json:"chunk_count"`
+	SceneCount int `json:"scene_count"`
+}
+
+// uploadScenesChunked sends payload as ordered scene chunks: open a session,
+// PUT each chunk (retrying only the chunk that failed), then finalize. The
+// upload's Idempotency-Key goes on the session open; chunks and finalize
+// carry keys derived from it.
+func (c *HTTPClient) uploadScenesChunked(ctx context.Context, payload SceneIngestPayload) error {
+	chunks := splitSceneChunks(payload.Scenes, sceneChunkMaxBytes, sceneChunkMaxScenes)
+
+	sessionBody, err := json.Marshal(sceneSessionRequest{
+		VideoID:         payload.VideoID,
+		VideoTitle:      payload.VideoTitle,
+		LibraryID:       payload.LibraryID,
+		PipelineVersion: payload.PipelineVersion,
+		ModelVersion:    payload.ModelVersion,
+		TotalDurationMs: payload.TotalDurationMs,
+		SceneCount:      len(payload.Scenes),
+		ChunkCount:      len(chunks),
+	})
+	if err != nil {
+		return fmt.Errorf("marshal session request: %w", err)
+	}
+
+	respBody, err := c.ingestCall(ctx, http.MethodPost, "/api/ingest/scenes/sessions", sessionBody)
+	if err != nil {
+		var uploadErr *UploadError
+		if errors.As(err, &uploadErr) &&
+			(uploadErr.StatusCode == http.StatusNotFound || uploadErr.StatusCode == http.StatusMethodNotAllowed) {
+			c.chunkedUnsupported.Store(true)
+			c.logger.Info("chunked scene ingest unavailable, sending large payloads whole")
+			return errChunkedUnsupported
+		}
+		return fmt.Errorf("open ingest session: %w", err)
+	}
+
+	var session sceneSessionResponse
+	if err := json.Unmarshal(respBody, &session); err != nil {
+		return fmt.Errorf("unmarshal session response: %w", err)
+	}
+
+	received := make(map[int]bool, len(session.ReceivedChunks))
+	for _, i := range session.ReceivedChunks {
+		received[i] = true
+	}
+
+	sessionPath := "/api/ingest/scenes/sessions/" + url.PathEscape(session.SessionID)
+	for i, scenes := range chunks {
+		if received[i] {
+			continue
+		}
+		body, err := json.Marshal(sceneChunkRequest{Index: i, Scenes: scenes})
+		if err != nil {
+			return fmt.Errorf("marshal chunk %d: %w", i, err)
+		}
+		if err := c.putSceneChunk(stepContext(ctx, "chunk:"+strconv.Itoa(i)), sessionPath+"/chunks/"+strconv.Itoa(i), body); err != nil {
+			return fmt.Errorf("upload chunk %d/%d: %w", i+1, len(chunks), err)
+		}
+	}
+
+	finalizeBody, _ := json.Marshal(sceneFinalizeRequest{ChunkCount: len(chunks), SceneCount: len(payload.Scenes)})
+	respBody, err = c.ingestCall(stepContext(ctx, "finalize"), http.MethodPost, sessionPath+"/finalize", finalizeBody)
+	if err != nil {
+		return fmt.Errorf("finalize ingest session: %w", err)
+	}
+
+	var result SceneIngestResponse
+	if err := json.Unmarshal(respBody, &result); err == nil {
+		c.logger.Info("chunked scene upload succeeded",
+			"video_id", payload.VideoID,
+			"chunks", len(chunks),
+			"resumed_chunks", len(received),
+			"indexed_count", result.IndexedCount,
+		)
+	}
+	return nil
+}
+
+// stepContext gives one step of a chunked upload its own Idempotency-Key,
+// derived from the upload's, so a retried step is deduplicated without
+// being mistaken for the session open or another step.
+func stepContext(ctx context.Context, step string) context.Context {
+	key := IdempotencyKey(ctx)
+	if key == "" {
+		return ctx
+	}
+	return WithIdempotencyKey(ctx, key+":"+step)
+}
+
+// putSceneChunk uploads one chunk, retrying server and network errors.
+func (c *HTTPClient) putSceneChunk(ctx context.Context, path string, body []byte) error {
+	var lastErr error
+	for attempt := 1; attempt <= maxChunkAttempts; attempt++ {
+		if attempt > 1 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(time.Duration(attempt-1) * partRetryBaseDelay):
+			}
+		}
+
+		_, err := c.ingestCall(ctx, http.MethodPut, path, body)
+		if err == nil {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		var uploadErr *UploadError
+		if errors.As(err, &uploadErr) && !uploadErr.IsRetryable() {
+			return err
+		}
+		lastErr = err
+	}
+	return lastErr
+}
+
+// ingestCall sends body through sendIngest and returns the response body.
+// Non-2xx responses are returned as *UploadError.
+func (c *HTTPClient) ingestCall(ctx context.Context, method, path string, body []byte) ([]byte, error) {
+	resp, err := c.sendIngest(ctx, method, path, body)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 65536))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, &UploadError{StatusCode: resp.StatusCode, Body: string(respBody)}
+	}
+	return respBody, nil
+}
+
+// splitSceneChunks groups scenes in order so each chunk stays under maxBytes
+// of encoded JSON and maxScenes entries. A single scene larger than maxBytes
+// gets a chunk of its own.
+func splitSceneChunks(scenes []SceneIngestDoc, maxBytes, maxScenes int) [][]SceneIngestDoc {
+	var chunks [][]SceneIngestDoc
+	var current []SceneIngestDoc
+	size := 0
+	for _, s := range scenes {
+		encoded, _ := json.Marshal(s)
+		if len(current) > 0 && (size+len(encoded) > maxBytes || len(current) >= maxScenes) {
+			chunks = append(chunks, current)
+			current, size = nil, 0
+		}
+		current = append(current, s)
+		size += len(encoded) + 1
+	}
+	if len(current) > 0 {
+		chunks = append(chunks, current)
+	}
+	return chunks
+}
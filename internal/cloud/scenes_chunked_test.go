@@ -0,0 +1,224 @@
+package cloud
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// largeScenePayload builds a payload above SceneChunkThreshold.
+func largeScenePayload(n int) SceneIngestPayload {
+	transcript := strings.Repeat("word ", 2000)
+	payload := SceneIngestPayload{VideoID: "vid-long", LibraryID: "lib-1"}
+	for i := 0; i < n; i++ {
+		payload.Scenes = append(payload.Scenes, SceneIngestDoc{SceneID: "s" + string(rune('a'+i%26)), Index: i, TranscriptRaw: transcript})
+	}
+	return payload
+}
+
+func readBody(t *testing.T, r *http.Request) []byte {
+	t.Helper()
+	var reader io.Reader = r.Body
+	if r.Header.Get("Content-Encoding") == "gzip" {
+		zr, err := gzip.NewReader(r.Body)
+		if err != nil {
+			t.Fatalf("gzip reader: %v", err)
+		}
+		reader = zr
+	}
+	body, _ := io.ReadAll(reader)
+	return body
+}
+
+func TestSplitSceneChunks_RespectsLimits(t *testing.T) {
+	scenes := largeScenePayload(30).Scenes
+	chunks := splitSceneChunks(scenes, 40<<10, 200)
+
+	total := 0
+	for i, c := range chunks {
+		encoded, _ := json.Marshal(c)
+		if len(c) > 1 && len(encoded) > 40<<10 {
+			t.Errorf("chunk %d is %d bytes", i, len(encoded))
+		}
+		for _, s := range c {
+			if s.Index != total {
+				t.Fatalf("chunk %d out of order: index %d, want %d", i, s.Index, total)
+			}
+			total++
+		}
+	}
+	if total != 30 {
+		t.Errorf("scenes across chunks = %d, want 30", total)
+	}
+	if got := splitSceneChunks(scenes, 1<<30, 7); len(got) != 5 {
+		t.Errorf("chunks capped by count = %d, want 5", len(got))
+	}
+}
+
+func TestHTTPClient_UploadScenes_CompressesLargeBody(t *testing.T) {
+	var encoding string
+	var payload SceneIngestPayload
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		encoding = r.Header.Get("Content-Encoding")
+		json.Unmarshal(readBody(t, r), &payload)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	client := NewHTTPClient(server.URL, "test-token", "", testLogger())
+	if err := client.UploadScenes(context.Background(), largeScenePayload(3)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if encoding != "gzip" {
+		t.Errorf("Content-Encoding = %q, want gzip", encoding)
+	}
+	if len(payload.Scenes) != 3 {
+		t.Errorf("decoded scenes = %d, want 3", len(payload.Scenes))
+	}
+}
+
+func TestHTTPClient_UploadScenes_UncompressedOn415(t *testing.T) {
+	var encodings []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		encodings = append(encodings, r.Header.Get("Content-Encoding"))
+		if r.Header.Get("Content-Encoding") != "" {
+			w.WriteHeader(http.StatusUnsupportedMediaType)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	client := NewHTTPClient(server.URL, "test-token", "", testLogger())
+	for i := 0; i < 2; i++ {
+		if err := client.UploadScenes(context.Background(), largeScenePayload(3)); err != nil {
+			t.Fatalf("upload %d: %v", i, err)
+		}
+	}
+	if len(encodings) != 3 || encodings[0] != "gzip" || encodings[1] != "" || encodings[2] != "" {
+		t.Errorf("encodings = %q, want [gzip \"\" \"\"]", encodings)
+	}
+}
+
+func TestHTTPClient_UploadScenes_CompressionDisabled(t *testing.T) {
+	var encoding string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		encoding = r.Header.Get("Content-Encoding")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	client := NewHTTPClient(server.URL, "test-token", "", testLogger())
+	client.SetCompression(CompressionNone)
+	client.UploadScenes(context.Background(), largeScenePayload(3))
+	if encoding != "" {
+		t.Errorf("Content-Encoding = %q, want none", encoding)
+	}
+}
+
+func TestHTTPClient_UploadScenes_ChunkedSession(t *testing.T) {
+	var mu sync.Mutex
+	chunkCalls := map[string]int{}
+	keys := map[string]string{}
+	var scenes int
+	finalized := false
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		keys[r.URL.Path] = r.Header.Get("Idempotency-Key")
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/api/ingest/scenes/sessions":
+			var req sceneSessionRequest
+			json.Unmarshal(readBody(t, r), &req)
+			if req.ChunkCount < 2 {
+				t.Errorf("chunk_count = %d, want several", req.ChunkCount)
+			}
+			json.NewEncoder(w).Encode(sceneSessionResponse{SessionID: "sess-1", ReceivedChunks: []int{0}})
+		case r.Method == http.MethodPut && strings.HasPrefix(r.URL.Path, "/api/ingest/scenes/sessions/sess-1/chunks/"):
+			chunkCalls[r.URL.Path]++
+			if strings.HasSuffix(r.URL.Path, "/1") && chunkCalls[r.URL.Path] == 1 {
+				w.WriteHeader(http.StatusBadGateway)
+				return
+			}
+			var req sceneChunkRequest
+			json.Unmarshal(readBody(t, r), &req)
+			scenes += len(req.Scenes)
+			w.WriteHeader(http.StatusOK)
+		case r.URL.Path == "/api/ingest/scenes/sessions/sess-1/finalize":
+			finalized = true
+			json.NewEncoder(w).Encode(SceneIngestResponse{VideoID: "vid-long", IndexedCount: 300})
+		default:
+			t.Errorf("unexpected %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	client := NewHTTPClient(server.URL, "test-token", "", testLogger())
+	payload := largeScenePayload(300)
+	ctx := WithIdempotencyKey(context.Background(), "scenes:vid-long:abc")
+	if err := client.UploadScenes(ctx, payload); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for path, want := range map[string]string{
+		"/api/ingest/scenes/sessions":                 "scenes:vid-long:abc",
+		"/api/ingest/scenes/sessions/sess-1/chunks/1": "scenes:vid-long:abc:chunk:1",
+		"/api/ingest/scenes/sessions/sess-1/chunks/2": "scenes:vid-long:abc:chunk:2",
+		"/api/ingest/scenes/sessions/sess-1/finalize": "scenes:vid-long:abc:finalize",
+	} {
+		if keys[path] != want {
+			t.Errorf("Idempotency-Key for %s = %q, want %q", path, keys[path], want)
+		}
+	}
+	if !finalized {
+		t.Fatal("session was not finalized")
+	}
+	if _, ok := chunkCalls["/api/ingest/scenes/sessions/sess-1/chunks/0"]; ok {
+		t.Error("chunk 0 was re-sent although the server already had it")
+	}
+	if chunkCalls["/api/ingest/scenes/sessions/sess-1/chunks/1"] != 2 {
+		t.Errorf("chunk 1 calls = %d, want 2 (one retry)", chunkCalls["/api/ingest/scenes/sessions/sess-1/chunks/1"])
+	}
+	for path, n := range chunkCalls {
+		if !strings.HasSuffix(path, "/1") && n != 1 {
+			t.Errorf("%s sent %d times, want 1", path, n)
+		}
+	}
+	chunks := splitSceneChunks(payload.Scenes, sceneChunkMaxBytes, sceneChunkMaxScenes)
+	if want := len(payload.Scenes) - len(chunks[0]); scenes != want {
+		t.Errorf("scenes received in chunks = %d, want %d", scenes, want)
+	}
+}
+
+func TestHTTPClient_UploadScenes_ChunkedUnsupportedFallsBack(t *testing.T) {
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		if r.URL.Path != "/api/ingest/scenes" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	client := NewHTTPClient(server.URL, "test-token", "", testLogger())
+	payload := largeScenePayload(300)
+	for i := 0; i < 2; i++ {
+		if err := client.UploadScenes(context.Background(), payload); err != nil {
+			t.Fatalf("upload %d: %v", i, err)
+		}
+	}
+	want := []string{"/api/ingest/scenes/sessions", "/api/ingest/scenes", "/api/ingest/scenes"}
+	if strings.Join(paths, ",") != strings.Join(want, ",") {
+		t.Errorf("paths = %v, want %v", paths, want)
+	}
+}
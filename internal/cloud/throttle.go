@@ -0,0 +1,161 @@
+package cloud
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// throttleChunk bounds how much of a request body is read between limiter
+// reservations, keeping the send rate smooth instead of bursty.
+const throttleChunk = 16 << 10
+
+// requestTimeout is the deadline of a request sent at full speed. Under an
+// upload cap the time the body needs at the capped rate is added on top.
+const requestTimeout = 60 * time.Second
+
+// SetUploadLimit caps the combined rate at which request bodies are sent to
+// the SaaS, in kilobits per second. Zero or less removes the cap.
+func (c *HTTPClient) SetUploadLimit(kbps int) {
+	c.limiter.setRate(float64(kbps) * 1000 / 8)
+}
+
+// uploadLimiter is a token bucket shared by every request the client sends,
+// so concurrent uploads together stay under the cap.
+type uploadLimiter struct {
+	mu     sync.Mutex
+	rate   float64 // bytes per second; 0 means unlimited
+	tokens float64
+	last   time.Time
+	now    func() time.Time
+}
+
+func newUploadLimiter() *uploadLimiter {
+	return &uploadLimiter{now: time.Now}
+}
+
+func (l *uploadLimiter) setRate(bytesPerSec float64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if bytesPerSec < 0 {
+		bytesPerSec = 0
+	}
+	l.rate = bytesPerSec
+	l.tokens = bytesPerSec
+	l.last = l.now()
+}
+
+func (l *uploadLimiter) enabled() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.rate > 0
+}
+
+// timeout returns the deadline for a request with a body of size bytes. The
+// capped transfer time is doubled, since concurrent uploads share the cap.
+func (l *uploadLimiter) timeout(size int64) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.rate <= 0 || size <= 0 {
+		return requestTimeout
+	}
+	return requestTimeout + time.Duration(2*float64(size)/l.rate*float64(time.Second))
+}
+
+// reserve takes n bytes from the bucket and returns how long the caller must
+// wait before sending them. The bucket holds at most one second of traffic.
+func (l *uploadLimiter) reserve(n int) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.rate <= 0 {
+		return 0
+	}
+
+	now := l.now()
+	l.tokens += now.Sub(l.last).Seconds() * l.rate
+	if l.tokens > l.rate {
+		l.tokens = l.rate
+	}
+	l.last = now
+
+	l.tokens -= float64(n)
+	if l.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-l.tokens / l.rate * float64(time.Second))
+}
+
+func (l *uploadLimiter) wait(ctx context.Context, n int) error {
+	delay := l.reserve(n)
+	if delay <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// throttledTransport paces request bodies through the client's limiter and
+// bounds each request, including reading its response, by limiter.timeout.
+type throttledTransport struct {
+	base    http.RoundTripper
+	limiter *uploadLimiter
+}
+
+func (t *throttledTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	size := req.ContentLength
+	if req.Body == nil || req.Body == http.NoBody {
+		size = 0
+	}
+	ctx, cancel := context.WithTimeout(req.Context(), t.limiter.timeout(size))
+	bounded := req.Clone(ctx)
+	if size != 0 && t.limiter.enabled() {
+		bounded.Body = &throttledBody{ReadCloser: req.Body, ctx: ctx, limiter: t.limiter}
+	}
+
+	resp, err := t.base.RoundTrip(bounded)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	resp.Body = &cancelOnClose{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+// cancelOnClose releases a request's deadline once its response is read.
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelOnClose) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
+type throttledBody struct {
+	io.ReadCloser
+	ctx     context.Context
+	limiter *uploadLimiter
+}
+
+func (b *throttledBody) Read(p []byte) (int, error) {
+	if len(p) > throttleChunk {
+		p = p[:throttleChunk]
+	}
+	n, err := b.ReadCloser.Read(p)
+	if n > 0 {
+		if waitErr := b.limiter.wait(b.ctx, n); waitErr != nil {
+			return n, waitErr
+		}
+	}
+	return n, err
+}
@@ -0,0 +1,86 @@
+package cloud
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestUploadLimiter_Reserve(t *testing.T) {
+	now := time.Unix(0, 0)
+	l := newUploadLimiter()
+	l.now = func() time.Time { return now }
+	l.setRate(1000) // bytes per second
+
+	if d := l.reserve(1000); d != 0 {
+		t.Fatalf("first second of traffic delayed by %v, want 0", d)
+	}
+	if d := l.reserve(500); d != 500*time.Millisecond {
+		t.Fatalf("delay = %v, want 500ms", d)
+	}
+
+	now = now.Add(2 * time.Second)
+	if d := l.reserve(100); d != 0 {
+		t.Fatalf("delay after refill = %v, want 0", d)
+	}
+}
+
+func TestUploadLimiter_Unlimited(t *testing.T) {
+	l := newUploadLimiter()
+	if d := l.reserve(1 << 30); d != 0 {
+		t.Fatalf("unlimited limiter delayed by %v", d)
+	}
+}
+
+func TestUploadLimiter_TimeoutScalesWithCap(t *testing.T) {
+	l := newUploadLimiter()
+	if d := l.timeout(8 << 20); d != requestTimeout {
+		t.Errorf("uncapped timeout = %v, want %v", d, requestTimeout)
+	}
+
+	l.setRate(500_000 / 8) // 500 kbit/s
+	part := int64(8 << 20)
+	if d := l.timeout(part); d < time.Duration(float64(part)/l.rate*float64(time.Second)) {
+		t.Errorf("capped timeout = %v, shorter than sending an 8 MiB part at the cap", d)
+	}
+	if d := l.timeout(0); d != requestTimeout {
+		t.Errorf("bodyless timeout = %v, want %v", d, requestTimeout)
+	}
+}
+
+func TestHTTPClient_SetUploadLimit_PacesBody(t *testing.T) {
+	var received int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received = len(body)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	client := NewHTTPClient(server.URL, "", "", testLogger())
+	client.SetUploadLimit(800) // 100 KB/s
+
+	// The bucket starts full with one second of traffic; the rest must wait.
+	body := bytes.Repeat([]byte("x"), 150_000)
+	req, err := client.newRequest(t.Context(), http.MethodPost, "/api/ingest/scenes", body)
+	if err != nil {
+		t.Fatalf("newRequest: %v", err)
+	}
+
+	start := time.Now()
+	resp, err := client.httpClient.Do(req)
+	if err != nil {
+		t.Fatalf("Do: %v", err)
+	}
+	resp.Body.Close()
+
+	if received != len(body) {
+		t.Fatalf("server received %d bytes, want %d", received, len(body))
+	}
+	if elapsed := time.Since(start); elapsed < 400*time.Millisecond {
+		t.Errorf("upload took %v, want it paced to roughly 500ms", elapsed)
+	}
+}
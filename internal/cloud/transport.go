@@ -0,0 +1,109 @@
+package cloud
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+)
+
+// TransportConfig describes how the agent reaches the SaaS on networks with
+// proxies or TLS inspection. Zero values keep Go's defaults: proxies from
+// HTTPS_PROXY/HTTP_PROXY/NO_PROXY and the system trust store.
+type TransportConfig struct {
+	// ProxyURL overrides the proxy environment variables. Credentials in
+	// the URL are sent as Proxy-Authorization.
+	ProxyURL string
+	// CABundle is a PEM file of CAs trusted in addition to the system
+	// roots, for TLS-inspecting middleboxes.
+	CABundle string
+	// ClientCert and ClientKey are PEM files presented for mutual TLS.
+	ClientCert string
+	ClientKey  string
+}
+
+// ConfigureTransport replaces the client's transport according to tc. The
+// upload limiter keeps wrapping the new transport.
+//
+// When tc is invalid the client is left degraded rather than falling back
+// to a direct connection that would bypass the proxy or trust settings:
+// every request fails with the configuration error, as if the SaaS were
+// unreachable, and the doctor's config step reports it.
+func (c *HTTPClient) ConfigureTransport(tc TransportConfig) error {
+	transport, err := newTransport(tc)
+	if err != nil {
+		c.transportErr = err
+		c.httpClient.Transport = &throttledTransport{base: misconfiguredTransport{err}, limiter: c.limiter}
+		return err
+	}
+	c.transportErr = nil
+	c.transport = transport
+	c.httpClient.Transport = &throttledTransport{base: transport, limiter: c.limiter}
+	return nil
+}
+
+// misconfiguredTransport fails every request with the transport
+// configuration error. It is reported as a dial failure so uploads wait
+// offline instead of using up their attempts.
+type misconfiguredTransport struct {
+	err error
+}
+
+func (t misconfiguredTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+	return nil, &net.OpError{Op: "dial", Net: "tcp", Err: fmt.Errorf("cloud transport misconfigured: %w", t.err)}
+}
+
+func newTransport(tc TransportConfig) (*http.Transport, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	if tc.ProxyURL != "" {
+		proxyURL, err := url.Parse(tc.ProxyURL)
+		if err != nil {
+			return nil, fmt.Errorf("parse proxy URL: %w", err)
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+
+	if tc.CABundle == "" && tc.ClientCert == "" {
+		return transport, nil
+	}
+
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if tc.CABundle != "" {
+		pem, err := os.ReadFile(tc.CABundle)
+		if err != nil {
+			return nil, fmt.Errorf("read CA bundle: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("CA bundle %s contains no PEM certificates", tc.CABundle)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	if tc.ClientCert != "" {
+		cert, err := tls.LoadX509KeyPair(tc.ClientCert, tc.ClientKey)
+		if err != nil {
+			return nil, fmt.Errorf("load client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	transport.TLSClientConfig = tlsConfig
+	return transport, nil
+}
+
+// redactURL hides proxy credentials in log and diagnostic output.
+func redactURL(u *url.URL) string {
+	if u == nil {
+		return ""
+	}
+	return u.Redacted()
+}
@@ -0,0 +1,132 @@
+package cloud
+
+import (
+	"context"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeServerCA(t *testing.T, server *httptest.Server) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "ca.pem")
+	block := &pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}
+	if err := os.WriteFile(path, pem.EncodeToMemory(block), 0o600); err != nil {
+		t.Fatalf("write CA bundle: %v", err)
+	}
+	return path
+}
+
+func TestConfigureTransport_CABundle(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	client := NewHTTPClient(server.URL, "", "", testLogger())
+	if err := client.Ping(context.Background()); err == nil {
+		t.Fatal("Ping succeeded without trusting the test CA")
+	}
+
+	if err := client.ConfigureTransport(TransportConfig{CABundle: writeServerCA(t, server)}); err != nil {
+		t.Fatalf("ConfigureTransport: %v", err)
+	}
+	if err := client.Ping(context.Background()); err != nil {
+		t.Fatalf("Ping with CA bundle: %v", err)
+	}
+}
+
+func TestConfigureTransport_InvalidBundle(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "empty.pem")
+	os.WriteFile(path, []byte("not a certificate"), 0o600)
+
+	client := NewHTTPClient("https://example.invalid", "", "", testLogger())
+	if err := client.ConfigureTransport(TransportConfig{CABundle: path}); err == nil {
+		t.Fatal("expected error for bundle without certificates")
+	}
+	if err := client.ConfigureTransport(TransportConfig{ClientCert: path, ClientKey: path}); err == nil {
+		t.Fatal("expected error for unreadable client key pair")
+	}
+
+	// The client stays usable but degraded: requests fail as if offline
+	// and the doctor's config step names the problem.
+	if err := client.Ping(context.Background()); !IsNetworkError(err) {
+		t.Errorf("Ping = %v, want a network error", err)
+	}
+	d := client.Diagnose(context.Background())
+	if d.OK || stepStatus(d, "config") != StepFailed || !strings.Contains(d.Steps[0].Detail, "client") {
+		t.Errorf("diagnosis = %+v, want the config step to fail", d.Steps)
+	}
+}
+
+func TestConfigureTransport_Proxy(t *testing.T) {
+	var proxied string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = r.URL.String()
+		if r.Header.Get("Proxy-Authorization") == "" {
+			w.WriteHeader(http.StatusProxyAuthRequired)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer proxy.Close()
+
+	client := NewHTTPClient("http://saas.example", "", "", testLogger())
+	proxyURL := strings.Replace(proxy.URL, "http://", "http://user:secret@", 1)
+	if err := client.ConfigureTransport(TransportConfig{ProxyURL: proxyURL}); err != nil {
+		t.Fatalf("ConfigureTransport: %v", err)
+	}
+	if err := client.Ping(context.Background()); err != nil {
+		t.Fatalf("Ping via proxy: %v", err)
+	}
+	if proxied != "http://saas.example/api/health" {
+		t.Errorf("proxy saw %q, want absolute SaaS URL", proxied)
+	}
+}
+
+func TestDiagnose_ReportsFailingStep(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	client := NewHTTPClient(server.URL, "", "", testLogger())
+
+	d := client.Diagnose(context.Background())
+	if d.OK {
+		t.Fatal("diagnosis OK without trusting the test CA")
+	}
+	if got := stepStatus(d, "connect"); got != StepOK {
+		t.Errorf("connect = %s, want ok", got)
+	}
+	if got := stepStatus(d, "tls"); got != StepFailed {
+		t.Fatalf("tls = %s, want failed", got)
+	}
+	if got := stepStatus(d, "http"); got != StepSkipped {
+		t.Errorf("http = %s, want skipped after failure", got)
+	}
+
+	if err := client.ConfigureTransport(TransportConfig{CABundle: writeServerCA(t, server)}); err != nil {
+		t.Fatalf("ConfigureTransport: %v", err)
+	}
+	d = client.Diagnose(context.Background())
+	if !d.OK {
+		t.Fatalf("diagnosis failed with CA bundle: %+v", d.Steps)
+	}
+	if got := stepStatus(d, "auth"); got != StepSkipped {
+		t.Errorf("auth = %s, want skipped without credentials", got)
+	}
+}
+
+func stepStatus(d *Diagnosis, name string) string {
+	for _, s := range d.Steps {
+		if s.Name == name {
+			return s.Status
+		}
+	}
+	return ""
+}
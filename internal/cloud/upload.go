@@ -1,11 +1,286 @@
 package cloud
 
-import "log/slog"
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	defaultPartSize    = 8 << 20
+	maxPartAttempts    = 3
+	partRetryBaseDelay = time.Second
+)
+
+// ErrChecksumMismatch is returned when the SaaS reports a different SHA-256
+// for a completed upload than the one computed locally.
+var ErrChecksumMismatch = errors.New("uploaded object checksum mismatch")
 
 type UploadService interface {
-	UploadMetadata(fileID string, metadata map[string]interface{}) error
-	UploadSidecar(fileID, sidecarPath string) error
-	GetPresignedURL(fileID string) (string, error)
+	UploadMetadata(ctx context.Context, fileID string, metadata map[string]interface{}) error
+	// UploadSidecar uploads a local artifact (faces/speech JSON, thumbnails)
+	// via presigned multipart URLs, resuming parts the SaaS already holds.
+	UploadSidecar(ctx context.Context, fileID, kind, sidecarPath string) (*SidecarUploadResult, error)
+	GetPresignedURL(ctx context.Context, req PresignRequest) (*PresignedUpload, error)
+}
+
+// PresignRequest is the request body sent to POST /api/uploads/presign.
+type PresignRequest struct {
+	FileID      string `json:"file_id"`
+	Kind        string `json:"kind"`
+	Filename    string `json:"filename"`
+	ContentType string `json:"content_type"`
+	Size        int64  `json:"size"`
+	SHA256      string `json:"sha256"`
+	PartSize    int64  `json:"part_size"`
+}
+
+// PresignedUpload describes a multipart upload session. CompletedParts lists
+// parts received by an earlier attempt with the same checksum, so an
+// interrupted upload resumes instead of starting over. AlreadyExists means
+// the object is stored and nothing needs to be sent.
+type PresignedUpload struct {
+	UploadID       string          `json:"upload_id"`
+	ObjectKey      string          `json:"object_key"`
+	URL            string          `json:"url,omitempty"`
+	AlreadyExists  bool            `json:"already_exists"`
+	PartSize       int64           `json:"part_size"`
+	Parts          []PresignedPart `json:"parts"`
+	CompletedParts []CompletedPart `json:"completed_parts,omitempty"`
+}
+
+type PresignedPart struct {
+	Number int    `json:"number"`
+	URL    string `json:"url"`
+}
+
+type CompletedPart struct {
+	Number int    `json:"number"`
+	ETag   string `json:"etag"`
+}
+
+type completeUploadRequest struct {
+	Parts  []CompletedPart `json:"parts"`
+	SHA256 string          `json:"sha256"`
+}
+
+type completeUploadResponse struct {
+	ObjectKey string `json:"object_key"`
+	URL       string `json:"url,omitempty"`
+	SHA256    string `json:"sha256"`
+}
+
+// SidecarUploadResult reports where an artifact ended up. Skipped is true
+// when the SaaS already had an identical object.
+type SidecarUploadResult struct {
+	ObjectKey string
+	URL       string
+	SHA256    string
+	Size      int64
+	Skipped   bool
+}
+
+// HTTPUpload implements UploadService against the Heimdex SaaS upload API.
+type HTTPUpload struct {
+	client *HTTPClient
+}
+
+func (u *HTTPUpload) UploadMetadata(ctx context.Context, fileID string, metadata map[string]interface{}) error {
+	path := "/api/files/" + url.PathEscape(fileID) + "/metadata"
+	if _, err := u.client.postJSON(ctx, path, metadata); err != nil {
+		return fmt.Errorf("upload metadata: %w", err)
+	}
+	u.client.logger.Debug("file metadata uploaded", "file_id", fileID)
+	return nil
+}
+
+func (u *HTTPUpload) GetPresignedURL(ctx context.Context, req PresignRequest) (*PresignedUpload, error) {
+	respBody, err := u.client.postJSON(ctx, "/api/uploads/presign", req)
+	if err != nil {
+		return nil, fmt.Errorf("presign upload: %w", err)
+	}
+
+	var upload PresignedUpload
+	if err := json.Unmarshal(respBody, &upload); err != nil {
+		return nil, fmt.Errorf("unmarshal presign response: %w", err)
+	}
+	return &upload, nil
+}
+
+func (u *HTTPUpload) UploadSidecar(ctx context.Context, fileID, kind, sidecarPath string) (*SidecarUploadResult, error) {
+	f, err := os.Open(sidecarPath)
+	if err != nil {
+		return nil, fmt.Errorf("open sidecar: %w", err)
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("stat sidecar: %w", err)
+	}
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return nil, fmt.Errorf("hash sidecar: %w", err)
+	}
+	sum := hex.EncodeToString(h.Sum(nil))
+
+	upload, err := u.GetPresignedURL(ctx, PresignRequest{
+		FileID:      fileID,
+		Kind:        kind,
+		Filename:    filepath.Base(sidecarPath),
+		ContentType: contentTypeFor(sidecarPath),
+		Size:        info.Size(),
+		SHA256:      sum,
+		PartSize:    defaultPartSize,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := &SidecarUploadResult{ObjectKey: upload.ObjectKey, URL: upload.URL, SHA256: sum, Size: info.Size()}
+	if upload.AlreadyExists {
+		result.Skipped = true
+		return result, nil
+	}
+
+	partSize := upload.PartSize
+	if partSize <= 0 {
+		partSize = defaultPartSize
+	}
+
+	completed := make(map[int]CompletedPart, len(upload.CompletedParts))
+	for _, p := range upload.CompletedParts {
+		completed[p.Number] = p
+	}
+
+	resumed := len(completed)
+	for _, part := range upload.Parts {
+		if _, ok := completed[part.Number]; ok {
+			continue
+		}
+
+		offset := int64(part.Number-1) * partSize
+		length := partSize
+		if offset+length > info.Size() {
+			length = info.Size() - offset
+		}
+		if length < 0 {
+			return nil, fmt.Errorf("part %d starts beyond end of %d-byte file", part.Number, info.Size())
+		}
+
+		etag, err := u.putPart(ctx, part.URL, io.NewSectionReader(f, offset, length), length)
+		if err != nil {
+			return nil, fmt.Errorf("upload part %d: %w", part.Number, err)
+		}
+		completed[part.Number] = CompletedPart{Number: part.Number, ETag: etag}
+	}
+
+	parts := make([]CompletedPart, 0, len(completed))
+	for _, p := range completed {
+		parts = append(parts, p)
+	}
+	sort.Slice(parts, func(i, j int) bool { return parts[i].Number < parts[j].Number })
+
+	respBody, err := u.client.postJSON(ctx, "/api/uploads/"+url.PathEscape(upload.UploadID)+"/complete", completeUploadRequest{
+		Parts:  parts,
+		SHA256: sum,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("complete upload: %w", err)
+	}
+
+	var done completeUploadResponse
+	if err := json.Unmarshal(respBody, &done); err != nil {
+		return nil, fmt.Errorf("unmarshal complete response: %w", err)
+	}
+	if done.SHA256 != "" && !strings.EqualFold(done.SHA256, sum) {
+		return nil, fmt.Errorf("%w: local %s, remote %s", ErrChecksumMismatch, sum, done.SHA256)
+	}
+	if done.ObjectKey != "" {
+		result.ObjectKey = done.ObjectKey
+	}
+	if done.URL != "" {
+		result.URL = done.URL
+	}
+
+	u.client.logger.Info("sidecar uploaded",
+		"file_id", fileID,
+		"kind", kind,
+		"object_key", result.ObjectKey,
+		"bytes", info.Size(),
+		"parts", len(parts),
+		"resumed_parts", resumed,
+	)
+	return result, nil
+}
+
+// putPart PUTs one part to its presigned URL, retrying server and network
+// errors. Presigned URLs carry their own authorization, so none of the SaaS
+// headers are attached.
+func (u *HTTPUpload) putPart(ctx context.Context, partURL string, body *io.SectionReader, length int64) (string, error) {
+	var lastErr error
+	for attempt := 1; attempt <= maxPartAttempts; attempt++ {
+		if attempt > 1 {
+			select {
+			case <-ctx.Done():
+				return "", ctx.Err()
+			case <-time.After(time.Duration(attempt-1) * partRetryBaseDelay):
+			}
+		}
+
+		if _, err := body.Seek(0, io.SeekStart); err != nil {
+			return "", err
+		}
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodPut, partURL, io.NopCloser(body))
+		if err != nil {
+			return "", fmt.Errorf("create request: %w", err)
+		}
+		req.ContentLength = length
+
+		resp, err := u.client.httpClient.Do(req)
+		if err != nil {
+			if ctx.Err() != nil {
+				return "", ctx.Err()
+			}
+			lastErr = fmt.Errorf("http request failed: %w", err)
+			continue
+		}
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		resp.Body.Close()
+
+		if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+			return strings.Trim(resp.Header.Get("ETag"), `"`), nil
+		}
+
+		uploadErr := &UploadError{StatusCode: resp.StatusCode, Body: string(respBody)}
+		if !uploadErr.IsRetryable() {
+			return "", uploadErr
+		}
+		lastErr = uploadErr
+	}
+	return "", lastErr
+}
+
+func contentTypeFor(path string) string {
+	if ct := mime.TypeByExtension(strings.ToLower(filepath.Ext(path))); ct != "" {
+		return ct
+	}
+	return "application/octet-stream"
 }
 
 type StubUpload struct {
@@ -16,17 +291,17 @@ func NewStubUpload(logger *slog.Logger) *StubUpload {
 	return &StubUpload{logger: logger}
 }
 
-func (s *StubUpload) UploadMetadata(fileID string, metadata map[string]interface{}) error {
+func (s *StubUpload) UploadMetadata(ctx context.Context, fileID string, metadata map[string]interface{}) error {
 	s.logger.Info("cloud upload stub: metadata upload requested", "file_id", fileID)
 	return nil
 }
 
-func (s *StubUpload) UploadSidecar(fileID, sidecarPath string) error {
-	s.logger.Info("cloud upload stub: sidecar upload requested", "file_id", fileID, "path", sidecarPath)
-	return nil
+func (s *StubUpload) UploadSidecar(ctx context.Context, fileID, kind, sidecarPath string) (*SidecarUploadResult, error) {
+	s.logger.Info("cloud upload stub: sidecar upload requested", "file_id", fileID, "kind", kind, "path", sidecarPath)
+	return &SidecarUploadResult{Skipped: true}, nil
 }
 
-func (s *StubUpload) GetPresignedURL(fileID string) (string, error) {
-	s.logger.Info("cloud upload stub: presigned URL requested", "file_id", fileID)
-	return "", nil
+func (s *StubUpload) GetPresignedURL(ctx context.Context, req PresignRequest) (*PresignedUpload, error) {
+	s.logger.Info("cloud upload stub: presigned URL requested", "file_id", req.FileID, "kind", req.Kind)
+	return &PresignedUpload{AlreadyExists: true}, nil
 }
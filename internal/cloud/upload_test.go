@@ -0,0 +1,161 @@
+package cloud
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func writeSidecar(t *testing.T, content string) (string, string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "result.json")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("write sidecar: %v", err)
+	}
+	sum := sha256.Sum256([]byte(content))
+	return path, hex.EncodeToString(sum[:])
+}
+
+func TestHTTPUpload_UploadSidecar_ResumesMultipart(t *testing.T) {
+	content := `{"segments":[1,2,3]}`
+	path, sum := writeSidecar(t, content)
+
+	var mu sync.Mutex
+	received := map[string]string{}
+	var completeReq completeUploadRequest
+
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/api/uploads/presign":
+			var req PresignRequest
+			json.NewDecoder(r.Body).Decode(&req)
+			if req.SHA256 != sum || req.Kind != "speech" || req.Size != int64(len(content)) {
+				t.Errorf("unexpected presign request: %+v", req)
+			}
+			// 8-byte parts: 3 parts, the first already received.
+			json.NewEncoder(w).Encode(PresignedUpload{
+				UploadID:  "up-1",
+				ObjectKey: "files/vid1/speech.json",
+				PartSize:  8,
+				Parts: []PresignedPart{
+					{Number: 1, URL: server.URL + "/s3/part1"},
+					{Number: 2, URL: server.URL + "/s3/part2"},
+					{Number: 3, URL: server.URL + "/s3/part3"},
+				},
+				CompletedParts: []CompletedPart{{Number: 1, ETag: "etag-1"}},
+			})
+		case r.Method == http.MethodPut:
+			if r.Header.Get("Authorization") != "" {
+				t.Error("presigned PUT must not carry SaaS credentials")
+			}
+			body, _ := io.ReadAll(r.Body)
+			mu.Lock()
+			received[r.URL.Path] = string(body)
+			mu.Unlock()
+			w.Header().Set("ETag", fmt.Sprintf(`"etag-%s"`, r.URL.Path[len("/s3/part"):]))
+		case r.URL.Path == "/api/uploads/up-1/complete":
+			json.NewDecoder(r.Body).Decode(&completeReq)
+			json.NewEncoder(w).Encode(completeUploadResponse{ObjectKey: "files/vid1/speech.json", SHA256: sum})
+		default:
+			t.Errorf("unexpected request: %s %s", r.Method, r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	client := NewHTTPClient(server.URL, "test-token", "", testLogger())
+	result, err := client.Upload().UploadSidecar(context.Background(), "vid1", "speech", path)
+	if err != nil {
+		t.Fatalf("UploadSidecar: %v", err)
+	}
+
+	if _, ok := received["/s3/part1"]; ok {
+		t.Fatal("part 1 was already uploaded and should be skipped")
+	}
+	if received["/s3/part2"] != content[8:16] || received["/s3/part3"] != content[16:] {
+		t.Fatalf("parts = %+v", received)
+	}
+	if len(completeReq.Parts) != 3 || completeReq.Parts[0].ETag != "etag-1" || completeReq.Parts[2].ETag != "etag-3" {
+		t.Fatalf("complete parts = %+v", completeReq.Parts)
+	}
+	if result.SHA256 != sum || result.Skipped {
+		t.Fatalf("result = %+v", result)
+	}
+}
+
+func TestHTTPUpload_UploadSidecar_AlreadyExists(t *testing.T) {
+	path, _ := writeSidecar(t, `{}`)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/uploads/presign" {
+			t.Errorf("unexpected request: %s", r.URL.Path)
+		}
+		json.NewEncoder(w).Encode(PresignedUpload{ObjectKey: "k", AlreadyExists: true})
+	}))
+	defer server.Close()
+
+	client := NewHTTPClient(server.URL, "test-token", "", testLogger())
+	result, err := client.Upload().UploadSidecar(context.Background(), "vid1", "faces", path)
+	if err != nil {
+		t.Fatalf("UploadSidecar: %v", err)
+	}
+	if !result.Skipped {
+		t.Fatal("expected upload to be skipped")
+	}
+}
+
+func TestHTTPUpload_UploadSidecar_ChecksumMismatch(t *testing.T) {
+	path, _ := writeSidecar(t, `{"a":1}`)
+
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/api/uploads/presign":
+			json.NewEncoder(w).Encode(PresignedUpload{
+				UploadID: "up-2",
+				Parts:    []PresignedPart{{Number: 1, URL: server.URL + "/s3/part1"}},
+			})
+		case r.Method == http.MethodPut:
+			w.Header().Set("ETag", `"e1"`)
+		default:
+			json.NewEncoder(w).Encode(completeUploadResponse{SHA256: "deadbeef"})
+		}
+	}))
+	defer server.Close()
+
+	client := NewHTTPClient(server.URL, "test-token", "", testLogger())
+	_, err := client.Upload().UploadSidecar(context.Background(), "vid1", "faces", path)
+	if !errors.Is(err, ErrChecksumMismatch) {
+		t.Fatalf("err = %v, want ErrChecksumMismatch", err)
+	}
+}
+
+func TestHTTPUpload_UploadMetadata(t *testing.T) {
+	var got map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/files/vid1/metadata" {
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+		json.NewDecoder(r.Body).Decode(&got)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	client := NewHTTPClient(server.URL, "test-token", "", testLogger())
+	if err := client.Upload().UploadMetadata(context.Background(), "vid1", map[string]interface{}{"filename": "a.mp4"}); err != nil {
+		t.Fatalf("UploadMetadata: %v", err)
+	}
+	if got["filename"] != "a.mp4" {
+		t.Fatalf("metadata = %+v", got)
+	}
+}
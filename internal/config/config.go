@@ -4,6 +4,7 @@ package config
 
 import (
 	"fmt"
+	"net/url"
 	"os"
 	"path/filepath"
 	"strconv"
@@ -29,23 +30,47 @@ const (
 	EnvHeadless = "HEIMDEX_HEADLESS"
 
 	// Cloud sync environment variable names
-	EnvCloudEnabled   = "HEIMDEX_CLOUD_ENABLED"
-	EnvCloudBaseURL   = "HEIMDEX_CLOUD_BASE_URL"
-	EnvCloudToken     = "HEIMDEX_CLOUD_TOKEN"
-	EnvCloudOrgSlug   = "HEIMDEX_CLOUD_ORG_SLUG"
-	EnvCloudLibraryID = "HEIMDEX_CLOUD_LIBRARY_ID"
-	EnvOCREnabled     = "HEIMDEX_OCR_ENABLED"
-	EnvOCRRedactPII   = "HEIMDEX_OCR_REDACT_PII"
+	EnvCloudEnabled    = "HEIMDEX_CLOUD_ENABLED"
+	EnvCloudBaseURL    = "HEIMDEX_CLOUD_BASE_URL"
+	EnvCloudToken      = "HEIMDEX_CLOUD_TOKEN"
+	EnvCloudOrgSlug    = "HEIMDEX_CLOUD_ORG_SLUG"
+	EnvCloudLibraryID  = "HEIMDEX_CLOUD_LIBRARY_ID"
+	EnvCloudCompress   = "HEIMDEX_CLOUD_COMPRESSION"
+	EnvCloudLibPolicy  = "HEIMDEX_CLOUD_LIBRARY_POLICY"
+	EnvCloudUploadKbps = "HEIMDEX_CLOUD_UPLOAD_KBPS"
+	EnvCloudProxy      = "HEIMDEX_CLOUD_PROXY"
+	EnvCloudCABundle   = "HEIMDEX_CLOUD_CA_BUNDLE"
+	EnvCloudClientCert = "HEIMDEX_CLOUD_CLIENT_CERT"
+	EnvCloudClientKey  = "HEIMDEX_CLOUD_CLIENT_KEY"
+	EnvOCREnabled      = "HEIMDEX_OCR_ENABLED"
+	EnvOCRRedactPII    = "HEIMDEX_OCR_REDACT_PII"
 
 	// Performance tuning environment variable names
 	EnvParallelFacesWithSpeech = "HEIMDEX_PARALLEL_FACES_WITH_SPEECH"
 
+	// Playback environment variable names
+	EnvProxyHeight = "HEIMDEX_PROXY_HEIGHT"
+
+	// DefaultCloudCompression is the Content-Encoding used for large ingest
+	// requests. "none" disables compression.
+	DefaultCloudCompression = "gzip"
+
+	// DefaultCloudLibraryPolicy auto-creates a cloud library per source.
+	// "explicit" requires every source to be mapped before it uploads.
+	DefaultCloudLibraryPolicy = "auto"
+
 	// Database filename
 	DBFilename = "heimdex.db"
 
 	// Cache settings
 	DefaultCacheMaxBytes = 10 * 1024 * 1024 * 1024 // 10GB
 
+	// DefaultProxyHeight is the height of playback proxies in lines. 0
+	// disables proxy generation.
+	DefaultProxyHeight = 540
+	minProxyHeight     = 144
+	maxProxyHeight     = 2160
+
 	// Pipeline defaults
 	DefaultPipelinesModule        = "heimdex_media_pipelines"
 	DefaultPipelinesTimeoutDoctor = 30   // seconds
@@ -76,9 +101,17 @@ type Config interface {
 	CloudToken() string
 	CloudOrgSlug() string
 	CloudLibraryID() string
+	CloudCompression() string
+	CloudLibraryPolicy() string
+	CloudUploadKbps() int
+	CloudProxy() string
+	CloudCABundle() string
+	CloudClientCert() string
+	CloudClientKey() string
 	OCREnabled() bool
 	OCRRedactPII() bool
 	ParallelFacesWithSpeech() bool
+	ProxyHeight() int
 }
 
 // EnvConfig reads configuration from environment variables
@@ -93,15 +126,24 @@ type EnvConfig struct {
 
 	headless bool
 
-	cloudEnabled   bool
-	cloudBaseURL   string
-	cloudToken     string
-	cloudOrgSlug   string
-	cloudLibraryID string
-	ocrEnabled     bool
-	ocrRedactPII   bool
+	cloudEnabled    bool
+	cloudBaseURL    string
+	cloudToken      string
+	cloudOrgSlug    string
+	cloudLibraryID  string
+	cloudCompress   string
+	cloudLibPolicy  string
+	cloudUploadKbps int
+	cloudProxy      string
+	cloudCABundle   string
+	cloudClientCert string
+	cloudClientKey  string
+	ocrEnabled      bool
+	ocrRedactPII    bool
 
 	parallelFacesWithSpeech bool
+
+	proxyHeight int
 }
 
 // New creates a new EnvConfig with defaults and environment variable overrides
@@ -111,6 +153,7 @@ func New() (*EnvConfig, error) {
 		logLevel:      DefaultLogLevel,
 		dataDir:       defaultDataDir(),
 		cacheMaxBytes: DefaultCacheMaxBytes,
+		proxyHeight:   DefaultProxyHeight,
 	}
 
 	// Override port from environment
@@ -159,10 +202,75 @@ func New() (*EnvConfig, error) {
 	cfg.cloudOrgSlug = os.Getenv(EnvCloudOrgSlug)
 	cfg.cloudLibraryID = os.Getenv(EnvCloudLibraryID)
 
+	cfg.cloudCompress = DefaultCloudCompression
+	if cc := os.Getenv(EnvCloudCompress); cc != "" {
+		switch cc {
+		case "gzip", "none":
+			cfg.cloudCompress = cc
+		default:
+			return nil, fmt.Errorf("invalid %s: %q (want gzip or none)", EnvCloudCompress, cc)
+		}
+	}
+
+	cfg.cloudLibPolicy = DefaultCloudLibraryPolicy
+	if lp := os.Getenv(EnvCloudLibPolicy); lp != "" {
+		switch lp {
+		case "auto", "explicit":
+			cfg.cloudLibPolicy = lp
+		default:
+			return nil, fmt.Errorf("invalid %s: %q (want auto or explicit)", EnvCloudLibPolicy, lp)
+		}
+	}
+
+	if ub := os.Getenv(EnvCloudUploadKbps); ub != "" {
+		kbps, err := strconv.Atoi(ub)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", EnvCloudUploadKbps, err)
+		}
+		if kbps < 0 {
+			return nil, fmt.Errorf("invalid %s: must not be negative", EnvCloudUploadKbps)
+		}
+		cfg.cloudUploadKbps = kbps
+	}
+
+	if px := os.Getenv(EnvCloudProxy); px != "" {
+		u, err := url.Parse(px)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", EnvCloudProxy, err)
+		}
+		switch u.Scheme {
+		case "http", "https", "socks5":
+		default:
+			return nil, fmt.Errorf("invalid %s: scheme must be http, https or socks5", EnvCloudProxy)
+		}
+		if u.Host == "" {
+			return nil, fmt.Errorf("invalid %s: missing host", EnvCloudProxy)
+		}
+		cfg.cloudProxy = px
+	}
+
+	cfg.cloudCABundle = os.Getenv(EnvCloudCABundle)
+	cfg.cloudClientCert = os.Getenv(EnvCloudClientCert)
+	cfg.cloudClientKey = os.Getenv(EnvCloudClientKey)
+	if (cfg.cloudClientCert == "") != (cfg.cloudClientKey == "") {
+		return nil, fmt.Errorf("%s and %s must be set together", EnvCloudClientCert, EnvCloudClientKey)
+	}
+
 	if pf := os.Getenv(EnvParallelFacesWithSpeech); pf == "true" || pf == "1" {
 		cfg.parallelFacesWithSpeech = true
 	}
 
+	if ph := os.Getenv(EnvProxyHeight); ph != "" {
+		height, err := strconv.Atoi(ph)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", EnvProxyHeight, err)
+		}
+		if height != 0 && (height < minProxyHeight || height > maxProxyHeight) {
+			return nil, fmt.Errorf("invalid %s: must be 0 or between %d and %d", EnvProxyHeight, minProxyHeight, maxProxyHeight)
+		}
+		cfg.proxyHeight = height
+	}
+
 	return cfg, nil
 }
 
@@ -251,6 +359,42 @@ func (c *EnvConfig) CloudLibraryID() string {
 	return c.cloudLibraryID
 }
 
+// CloudCompression returns the Content-Encoding for large cloud uploads
+func (c *EnvConfig) CloudCompression() string {
+	return c.cloudCompress
+}
+
+// CloudLibraryPolicy returns how unmapped sources get a cloud library
+func (c *EnvConfig) CloudLibraryPolicy() string {
+	return c.cloudLibPolicy
+}
+
+// CloudUploadKbps returns the cloud upload cap in kilobits per second (0 = unlimited)
+func (c *EnvConfig) CloudUploadKbps() int {
+	return c.cloudUploadKbps
+}
+
+// CloudProxy returns the proxy URL for cloud requests, overriding
+// HTTPS_PROXY and friends when set
+func (c *EnvConfig) CloudProxy() string {
+	return c.cloudProxy
+}
+
+// CloudCABundle returns a PEM file of extra trusted CAs for cloud requests
+func (c *EnvConfig) CloudCABundle() string {
+	return c.cloudCABundle
+}
+
+// CloudClientCert returns the PEM client certificate for mutual TLS
+func (c *EnvConfig) CloudClientCert() string {
+	return c.cloudClientCert
+}
+
+// CloudClientKey returns the PEM private key for CloudClientCert
+func (c *EnvConfig) CloudClientKey() string {
+	return c.cloudClientKey
+}
+
 func (c *EnvConfig) OCREnabled() bool {
 	return c.ocrEnabled
 }
@@ -263,6 +407,11 @@ func (c *EnvConfig) ParallelFacesWithSpeech() bool {
 	return c.parallelFacesWithSpeech
 }
 
+// ProxyHeight returns the playback proxy height in lines (0 = proxies disabled)
+func (c *EnvConfig) ProxyHeight() int {
+	return c.proxyHeight
+}
+
 // defaultDataDir returns the default data directory path
 func defaultDataDir() string {
 	home, err := os.UserHomeDir()
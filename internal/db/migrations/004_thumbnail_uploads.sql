@@ -0,0 +1,14 @@
+-- Migration 004: Track per-scene thumbnail uploads to the cloud
+-- Thumbnails are content-addressed: sha256 lets identical keyframes share
+-- one stored object across scenes.
+CREATE TABLE IF NOT EXISTS thumbnail_uploads (
+    scene_id TEXT PRIMARY KEY,
+    file_id TEXT NOT NULL REFERENCES files(id) ON DELETE CASCADE,
+    sha256 TEXT NOT NULL,
+    object_key TEXT NOT NULL,
+    url TEXT,
+    uploaded_at TEXT NOT NULL DEFAULT (datetime('now'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_thumbnail_uploads_file ON thumbnail_uploads(file_id);
+CREATE INDEX IF NOT EXISTS idx_thumbnail_uploads_sha256 ON thumbnail_uploads(sha256);
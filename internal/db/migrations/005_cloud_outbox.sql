@@ -0,0 +1,19 @@
+-- Migration 005: Durable outbox for cloud mutations
+-- Each row is one request the agent still owes the SaaS. Rows survive
+-- restarts and network outages; idempotency_key lets the SaaS drop replays.
+CREATE TABLE IF NOT EXISTS cloud_outbox (
+    id TEXT PRIMARY KEY,
+    kind TEXT NOT NULL,
+    file_id TEXT REFERENCES files(id) ON DELETE CASCADE,
+    payload TEXT NOT NULL,
+    idempotency_key TEXT NOT NULL UNIQUE,
+    status TEXT NOT NULL DEFAULT 'pending',
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TEXT NOT NULL,
+    last_error TEXT,
+    created_at TEXT NOT NULL,
+    updated_at TEXT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_cloud_outbox_due ON cloud_outbox(status, next_attempt_at);
+CREATE INDEX IF NOT EXISTS idx_cloud_outbox_file ON cloud_outbox(file_id, kind);
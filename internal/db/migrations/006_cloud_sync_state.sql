@@ -0,0 +1,10 @@
+-- Migration 006: Remember what the cloud last accepted for each file
+-- payload_hash is the SHA-256 of the last delivered payload, so re-indexing
+-- that produces identical scene docs does not upload them again.
+CREATE TABLE IF NOT EXISTS cloud_sync_state (
+    file_id TEXT NOT NULL REFERENCES files(id) ON DELETE CASCADE,
+    kind TEXT NOT NULL,
+    payload_hash TEXT NOT NULL,
+    synced_at TEXT NOT NULL DEFAULT (datetime('now')),
+    PRIMARY KEY (file_id, kind)
+);
@@ -0,0 +1,22 @@
+-- Migration 007: Edits pulled down from the Heimdex web app
+-- Annotations are stored as deltas against the pipeline output so they
+-- survive re-indexing. updated_at is the cloud edit time and decides which
+-- of two conflicting edits wins.
+CREATE TABLE IF NOT EXISTS scene_annotations (
+    scene_id TEXT PRIMARY KEY,
+    file_id TEXT NOT NULL REFERENCES files(id) ON DELETE CASCADE,
+    tags_added TEXT NOT NULL DEFAULT '[]',
+    tags_removed TEXT NOT NULL DEFAULT '[]',
+    note TEXT,
+    updated_at TEXT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_scene_annotations_file ON scene_annotations(file_id);
+
+CREATE TABLE IF NOT EXISTS person_names (
+    file_id TEXT NOT NULL REFERENCES files(id) ON DELETE CASCADE,
+    cluster_id TEXT NOT NULL,
+    name TEXT NOT NULL,
+    updated_at TEXT NOT NULL,
+    PRIMARY KEY (file_id, cluster_id)
+);
@@ -0,0 +1,2 @@
+-- Migration 008: Let users opt a source out of cloud sync
+ALTER TABLE sources ADD COLUMN cloud_sync_disabled INTEGER NOT NULL DEFAULT 0;
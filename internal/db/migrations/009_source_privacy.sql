@@ -0,0 +1,19 @@
+-- Migration 009: Per-source privacy policy and an audit of cloud uploads
+-- Each privacy column is 'send', 'hash' or 'drop' and applies to scene docs
+-- and sidecars before they leave the machine.
+ALTER TABLE sources ADD COLUMN privacy_transcript TEXT NOT NULL DEFAULT 'send';
+ALTER TABLE sources ADD COLUMN privacy_ocr TEXT NOT NULL DEFAULT 'send';
+ALTER TABLE sources ADD COLUMN privacy_faces TEXT NOT NULL DEFAULT 'send';
+
+-- cloud_audit records every payload the cloud accepted, with the fields it
+-- carried, so users can verify what left the machine for each video.
+CREATE TABLE IF NOT EXISTS cloud_audit (
+    id TEXT PRIMARY KEY,
+    file_id TEXT NOT NULL REFERENCES files(id) ON DELETE CASCADE,
+    kind TEXT NOT NULL,
+    payload_hash TEXT NOT NULL,
+    fields TEXT NOT NULL,
+    sent_at TEXT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_cloud_audit_file ON cloud_audit(file_id, sent_at);
@@ -0,0 +1,14 @@
+-- Migration 010: Track low-resolution playback proxies per file
+-- last_accessed_at drives least-recently-used eviction under the cache
+-- budget.
+CREATE TABLE IF NOT EXISTS file_proxies (
+    file_id TEXT PRIMARY KEY REFERENCES files(id) ON DELETE CASCADE,
+    status TEXT NOT NULL,
+    path TEXT,
+    height INTEGER NOT NULL DEFAULT 0,
+    size_bytes INTEGER NOT NULL DEFAULT 0,
+    fingerprint TEXT,
+    error TEXT,
+    last_accessed_at TEXT,
+    updated_at TEXT NOT NULL DEFAULT (datetime('now'))
+);
@@ -2,17 +2,20 @@ package export
 
 import (
 	"fmt"
-	"math"
 	"strings"
 )
 
+// GenerateEDL writes a CMX 3600 EDL. Clip times are counted in frames at the
+// exact frameRate, so 23.976 and 29.97 do not drift, and labelled with
+// drop-frame timecode at 29.97 and 59.94. Source timecode is offset by the
+// file's embedded start timecode and the reel field carries its reel name.
 func GenerateEDL(clips []ResolvedClip, title string, frameRate float64) string {
-	fps := int(math.Round(frameRate))
-	if fps <= 0 {
-		fps = 30
+	if frameRate <= 0 {
+		frameRate = 30
 	}
-
-	isDropFrame := math.Abs(frameRate-29.97) < 0.01 || math.Abs(frameRate-59.94) < 0.01
+	fd := FrameDuration(frameRate)
+	fps := nominalFPS(frameRate)
+	isDropFrame := isDropFrameRate(frameRate)
 
 	lines := []string{fmt.Sprintf("TITLE: %s", title)}
 	if isDropFrame {
@@ -22,34 +25,48 @@ func GenerateEDL(clips []ResolvedClip, title string, frameRate float64) string {
 	}
 	lines = append(lines, "")
 
-	recordOffsetMs := 0
+	var record int64
 	for i, clip := range clips {
-		srcIn := msToTimecode(clip.StartMs, fps)
-		srcOut := msToTimecode(clip.EndMs, fps)
-		recIn := msToTimecode(recordOffsetMs, fps)
-		durationMs := clip.EndMs - clip.StartMs
-		recOut := msToTimecode(recordOffsetMs+durationMs, fps)
+		var sourceStart int64
+		if clip.StartTimecode != "" {
+			if n, err := parseTimecode(clip.StartTimecode, fps); err == nil {
+				sourceStart = n
+			}
+		}
+		srcIn := sourceStart + frames(clip.StartMs, fd)
+		duration := clipFrames(clip, fd)
+		srcOut := srcIn + duration
 
 		lines = append(lines,
-			fmt.Sprintf("%03d  %-8s %-5s C        %s %s %s %s", i+1, "AX", "V", srcIn, srcOut, recIn, recOut),
+			fmt.Sprintf("%03d  %-8s %-5s C        %s %s %s %s", i+1, edlReel(clip.ReelName), "V",
+				framesToTimecode(srcIn, fps, isDropFrame), framesToTimecode(srcOut, fps, isDropFrame),
+				framesToTimecode(record, fps, isDropFrame), framesToTimecode(record+duration, fps, isDropFrame)),
 			fmt.Sprintf("* FROM CLIP NAME:  %s", clip.ClipName),
 			fmt.Sprintf("* MEDIA PATH:  %s", clip.MediaPath),
 		)
 
-		recordOffsetMs += durationMs
+		record += duration
 	}
 
 	lines = append(lines, "")
 	return strings.Join(lines, "\n")
 }
 
-func msToTimecode(ms int, fps int) string {
-	totalFrames := int(math.Round(float64(ms) * float64(fps) / 1000.0))
-	frames := totalFrames % fps
-	totalSeconds := totalFrames / fps
-	seconds := totalSeconds % 60
-	totalMinutes := totalSeconds / 60
-	minutes := totalMinutes % 60
-	hours := totalMinutes / 60
-	return fmt.Sprintf("%02d:%02d:%02d:%02d", hours, minutes, seconds, frames)
+// edlReel fits a reel name into the 8-character CMX reel field, keeping
+// letters, digits and underscores. Media without a reel name is "AX", the
+// auxiliary source.
+func edlReel(name string) string {
+	var b strings.Builder
+	for _, r := range strings.ToUpper(name) {
+		if b.Len() == 8 {
+			break
+		}
+		if (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || r == '_' {
+			b.WriteRune(r)
+		}
+	}
+	if b.Len() == 0 {
+		return "AX"
+	}
+	return b.String()
 }
@@ -48,6 +48,29 @@ func TestGenerateEDL_MultipleClips(t *testing.T) {
 	}
 }
 
+func TestGenerateEDL_SourceOutMatchesClipLength(t *testing.T) {
+	// 1020ms rounds up to frame 31 and 2040ms down to frame 61, but the
+	// clip is 1020ms long, 31 frames. Source and record durations agree.
+	clips := []ResolvedClip{
+		{ClipName: "Rounded", MediaPath: "/a.mp4", StartMs: 1020, EndMs: 2040},
+		{ClipName: "Empty", MediaPath: "/b.mp4", StartMs: 5000, EndMs: 5000},
+		{ClipName: "Next", MediaPath: "/c.mp4", StartMs: 0, EndMs: 1000},
+	}
+
+	edl := GenerateEDL(clips, "Lengths", 30.0)
+
+	for _, want := range []string{
+		"001  AX       V     C        00:00:01:01 00:00:02:02 00:00:00:00 00:00:01:01",
+		// A zero-length clip still takes one frame.
+		"002  AX       V     C        00:00:05:00 00:00:05:01 00:00:01:01 00:00:01:02",
+		"003  AX       V     C        00:00:00:00 00:00:01:00 00:00:01:02 00:00:02:02",
+	} {
+		if !strings.Contains(edl, want) {
+			t.Errorf("missing event line %q in:\n%s", want, edl)
+		}
+	}
+}
+
 func TestGenerateEDL_DropFrame(t *testing.T) {
 	clips := []ResolvedClip{{ClipName: "Clip", MediaPath: "/x.mp4", StartMs: 0, EndMs: 1000}}
 	edl := GenerateEDL(clips, "Drop", 29.97)
@@ -57,26 +80,36 @@ func TestGenerateEDL_DropFrame(t *testing.T) {
 	}
 }
 
-func TestMsToTimecode(t *testing.T) {
-	tests := []struct {
-		name string
-		ms   int
-		fps  int
-		want string
-	}{
-		{name: "zero", ms: 0, fps: 30, want: "00:00:00:00"},
-		{name: "one second", ms: 1000, fps: 30, want: "00:00:01:00"},
-		{name: "fractional second", ms: 500, fps: 30, want: "00:00:00:15"},
-		{name: "one minute", ms: 60000, fps: 30, want: "00:01:00:00"},
-		{name: "one hour", ms: 3600000, fps: 30, want: "01:00:00:00"},
-	}
-
-	for _, tc := range tests {
-		t.Run(tc.name, func(t *testing.T) {
-			got := msToTimecode(tc.ms, tc.fps)
-			if got != tc.want {
-				t.Fatalf("msToTimecode(%d, %d) = %q, want %q", tc.ms, tc.fps, got, tc.want)
-			}
-		})
+func TestGenerateEDL_DropFrameTimecode(t *testing.T) {
+	clips := []ResolvedClip{{ClipName: "Clip", MediaPath: "/x.mp4", StartMs: 60060, EndMs: 600000}}
+	edl := GenerateEDL(clips, "Drop", 29.97)
+
+	if !strings.Contains(edl, "001  AX       V     C        00:01:00;02 00:10:00;00 00:00:00;00 00:08:59;28") {
+		t.Fatalf("drop-frame event line mismatch: %q", edl)
+	}
+}
+
+func TestGenerateEDL_FractionalRate(t *testing.T) {
+	// An hour of 23.976 footage is 86314 frames, not 86400.
+	clips := []ResolvedClip{{ClipName: "Long", MediaPath: "/x.mov", StartMs: 0, EndMs: 3600000}}
+	edl := GenerateEDL(clips, "Film", 23.976)
+
+	if !strings.Contains(edl, "00:00:00:00 00:59:56:10 00:00:00:00 00:59:56:10") {
+		t.Fatalf("23.976 event line mismatch: %q", edl)
+	}
+}
+
+func TestGenerateEDL_SourceTimecodeAndReel(t *testing.T) {
+	clips := []ResolvedClip{
+		{ClipName: "A", MediaPath: "/a.mov", StartMs: 2000, EndMs: 3000, StartTimecode: "01:00:00:00", ReelName: "a001-c002 long"},
+		{ClipName: "B", MediaPath: "/b.mov", StartMs: 0, EndMs: 1000, StartTimecode: "garbage"},
+	}
+	edl := GenerateEDL(clips, "Reels", 25)
+
+	if !strings.Contains(edl, "001  A001C002 V     C        01:00:02:00 01:00:03:00 00:00:00:00 00:00:01:00") {
+		t.Fatalf("source timecode or reel mismatch: %q", edl)
+	}
+	if !strings.Contains(edl, "002  AX       V     C        00:00:00:00 00:00:01:00 00:00:01:00 00:00:02:00") {
+		t.Fatalf("unparseable timecode should start at zero: %q", edl)
 	}
 }
@@ -0,0 +1,305 @@
+package export
+
+import (
+	"encoding/xml"
+	"fmt"
+	"math"
+	"net/url"
+	"path/filepath"
+	"strings"
+)
+
+// FCPXMLVersion is the FCPXML version written. 1.9 is the first with
+// media-rep, and is read by Final Cut Pro 10.4.9 and later, DaVinci Resolve
+// and Premiere Pro through their FCPXML importers.
+const FCPXMLVersion = "1.9"
+
+// Rational is a time in seconds as Num/Den, the form FCPXML requires.
+type Rational struct {
+	Num int64
+	Den int64
+}
+
+func (r Rational) String() string {
+	if r.Num == 0 {
+		return "0s"
+	}
+	g := gcd(r.Num, r.Den)
+	if r.Den/g == 1 {
+		return fmt.Sprintf("%ds", r.Num/g)
+	}
+	return fmt.Sprintf("%d/%ds", r.Num/g, r.Den/g)
+}
+
+// FrameDuration returns the length of one frame at frameRate as FCP writes
+// it: 1001/30000s for 29.97, 100/2500s for 25. Rates within 0.01 of an
+// NTSC rate (N*1000/1001) are treated as that rate.
+func FrameDuration(frameRate float64) Rational {
+	if frameRate <= 0 {
+		frameRate = 30
+	}
+	nominal := math.Round(frameRate * 1001 / 1000)
+	if math.Abs(frameRate-nominal*1000/1001) < 0.01 && math.Abs(frameRate-nominal) > 0.01 {
+		return Rational{Num: 1001, Den: int64(nominal) * 1000}
+	}
+	return Rational{Num: 100, Den: int64(math.Round(frameRate * 100))}
+}
+
+// frames converts milliseconds to a whole number of frames of length fd.
+func frames(ms int, fd Rational) int64 {
+	return int64(math.Round(float64(ms) * float64(fd.Den) / (1000 * float64(fd.Num))))
+}
+
+// clipFrames is a clip's length on the timeline, at least one frame.
+func clipFrames(clip ResolvedClip, fd Rational) int64 {
+	return max(frames(clip.EndMs-clip.StartMs, fd), 1)
+}
+
+// TimelineOffsets returns where each clip starts on an export timeline at
+// frameRate, in milliseconds. The generators lay clips out on whole frames,
+// so this drifts from the sum of the requested lengths over many clips.
+func TimelineOffsets(clips []ResolvedClip, frameRate float64) []int {
+	if frameRate <= 0 {
+		frameRate = 30
+	}
+	fd := FrameDuration(frameRate)
+	offsets := make([]int, len(clips))
+	var n int64
+	for i, clip := range clips {
+		offsets[i] = int(math.Round(float64(n*fd.Num) * 1000 / float64(fd.Den)))
+		n += clipFrames(clip, fd)
+	}
+	return offsets
+}
+
+// frameTime is n frames of length fd.
+func frameTime(n int64, fd Rational) Rational {
+	return Rational{Num: n * fd.Num, Den: fd.Den}
+}
+
+// msTime snaps milliseconds to the frame grid of fd.
+func msTime(ms int, fd Rational) Rational {
+	return frameTime(frames(ms, fd), fd)
+}
+
+type fcpxmlDoc struct {
+	XMLName   xml.Name        `xml:"fcpxml"`
+	Version   string          `xml:"version,attr"`
+	Resources fcpxmlResources `xml:"resources"`
+	Library   fcpxmlLibrary   `xml:"library"`
+}
+
+type fcpxmlResources struct {
+	Formats []fcpxmlFormat `xml:"format"`
+	Assets  []fcpxmlAsset  `xml:"asset"`
+}
+
+type fcpxmlFormat struct {
+	ID            string `xml:"id,attr"`
+	FrameDuration string `xml:"frameDuration,attr"`
+	Width         int    `xml:"width,attr"`
+	Height        int    `xml:"height,attr"`
+}
+
+type fcpxmlAsset struct {
+	ID            string         `xml:"id,attr"`
+	Name          string         `xml:"name,attr"`
+	Start         string         `xml:"start,attr"`
+	Duration      string         `xml:"duration,attr"`
+	HasVideo      int            `xml:"hasVideo,attr"`
+	HasAudio      int            `xml:"hasAudio,attr,omitempty"`
+	AudioSources  int            `xml:"audioSources,attr,omitempty"`
+	AudioChannels int            `xml:"audioChannels,attr,omitempty"`
+	Format        string         `xml:"format,attr"`
+	MediaRep      fcpxmlMediaRep `xml:"media-rep"`
+}
+
+type fcpxmlMediaRep struct {
+	Kind string `xml:"kind,attr"`
+	Src  string `xml:"src,attr"`
+}
+
+type fcpxmlLibrary struct {
+	Event fcpxmlEvent `xml:"event"`
+}
+
+type fcpxmlEvent struct {
+	Name    string        `xml:"name,attr"`
+	Project fcpxmlProject `xml:"project"`
+}
+
+type fcpxmlProject struct {
+	Name     string         `xml:"name,attr"`
+	Sequence fcpxmlSequence `xml:"sequence"`
+}
+
+type fcpxmlSequence struct {
+	Format      string           `xml:"format,attr"`
+	Duration    string           `xml:"duration,attr"`
+	TCStart     string           `xml:"tcStart,attr"`
+	TCFormat    string           `xml:"tcFormat,attr"`
+	AudioLayout string           `xml:"audioLayout,attr"`
+	AudioRate   string           `xml:"audioRate,attr"`
+	Clips       []fcpxmlAssetRef `xml:"spine>asset-clip"`
+}
+
+type fcpxmlAssetRef struct {
+	Ref      string         `xml:"ref,attr"`
+	Name     string         `xml:"name,attr"`
+	Offset   string         `xml:"offset,attr"`
+	Start    string         `xml:"start,attr"`
+	Duration string         `xml:"duration,attr"`
+	Format   string         `xml:"format,attr"`
+	TCFormat string         `xml:"tcFormat,attr"`
+	Markers  []fcpxmlMarker `xml:"marker"`
+}
+
+type fcpxmlMarker struct {
+	Start    string `xml:"start,attr"`
+	Duration string `xml:"duration,attr"`
+	Value    string `xml:"value,attr"`
+	Note     string `xml:"note,attr,omitempty"`
+}
+
+// GenerateFCPXML builds an FCPXML project that lays the clips end to end on
+// one spine. Each source file becomes an asset with its own format at its
+// probed frame rate; the sequence runs at frameRate. Clip boundaries are
+// snapped to the frame grid, and scene markers are placed on the clips.
+func GenerateFCPXML(clips []ResolvedClip, title string, frameRate float64) (string, error) {
+	if frameRate <= 0 {
+		frameRate = 30
+	}
+	seqFD := FrameDuration(frameRate)
+
+	doc := fcpxmlDoc{Version: FCPXMLVersion}
+	ids := 0
+	nextID := func() string {
+		ids++
+		return fmt.Sprintf("r%d", ids)
+	}
+
+	width, height := 1920, 1080
+	if len(clips) > 0 && clips[0].Width > 0 && clips[0].Height > 0 {
+		width, height = clips[0].Width, clips[0].Height
+	}
+	seqFormat := fcpxmlFormat{ID: nextID(), FrameDuration: seqFD.String(), Width: width, Height: height}
+	doc.Resources.Formats = append(doc.Resources.Formats, seqFormat)
+
+	formatIDs := map[string]string{seqFormat.FrameDuration + fmt.Sprintf("@%dx%d", width, height): seqFormat.ID}
+	type assetInfo struct {
+		id     string
+		format string
+		fd     Rational
+	}
+	assets := make(map[string]assetInfo)
+
+	tcFormat := "NDF"
+	if isDropFrameRate(frameRate) {
+		tcFormat = "DF"
+	}
+
+	seq := fcpxmlSequence{
+		Format:      seqFormat.ID,
+		TCStart:     "0s",
+		TCFormat:    tcFormat,
+		AudioLayout: "stereo",
+		AudioRate:   "48k",
+	}
+
+	var offset int64
+	for _, clip := range clips {
+		asset, ok := assets[clip.MediaPath]
+		if !ok {
+			fd := seqFD
+			if clip.FrameRate > 0 {
+				fd = FrameDuration(clip.FrameRate)
+			}
+			w, h := clip.Width, clip.Height
+			if w <= 0 || h <= 0 {
+				w, h = width, height
+			}
+			key := fd.String() + fmt.Sprintf("@%dx%d", w, h)
+			formatID, ok := formatIDs[key]
+			if !ok {
+				formatID = nextID()
+				formatIDs[key] = formatID
+				doc.Resources.Formats = append(doc.Resources.Formats, fcpxmlFormat{ID: formatID, FrameDuration: fd.String(), Width: w, Height: h})
+			}
+
+			durationMs := max(clip.DurationMs, clip.EndMs)
+			a := fcpxmlAsset{
+				ID:       nextID(),
+				Name:     filepath.Base(filepath.FromSlash(strings.ReplaceAll(clip.MediaPath, `\`, "/"))),
+				Start:    "0s",
+				Duration: msTime(durationMs, fd).String(),
+				HasVideo: 1,
+				Format:   formatID,
+				MediaRep: fcpxmlMediaRep{Kind: "original-media", Src: fileURL(clip.MediaPath)},
+			}
+			if clip.HasAudio {
+				a.HasAudio, a.AudioSources, a.AudioChannels = 1, 1, 2
+				if clip.AudioChannels > 0 {
+					a.AudioChannels = clip.AudioChannels
+				}
+			}
+			doc.Resources.Assets = append(doc.Resources.Assets, a)
+			asset = assetInfo{id: a.ID, format: formatID, fd: fd}
+			assets[clip.MediaPath] = asset
+		}
+
+		length := clipFrames(clip, seqFD)
+		ref := fcpxmlAssetRef{
+			Ref:      asset.id,
+			Name:     clip.ClipName,
+			Offset:   frameTime(offset, seqFD).String(),
+			Start:    msTime(clip.StartMs, asset.fd).String(),
+			Duration: frameTime(length, seqFD).String(),
+			Format:   asset.format,
+			TCFormat: tcFormat,
+		}
+		for _, m := range clip.Markers {
+			if m.StartMs < clip.StartMs || m.StartMs >= clip.EndMs {
+				continue
+			}
+			ref.Markers = append(ref.Markers, fcpxmlMarker{
+				Start:    msTime(m.StartMs, asset.fd).String(),
+				Duration: asset.fd.String(),
+				Value:    m.Name,
+				Note:     strings.Join(m.Tags, ", "),
+			})
+		}
+		seq.Clips = append(seq.Clips, ref)
+		offset += length
+	}
+	seq.Duration = frameTime(offset, seqFD).String()
+
+	doc.Library.Event = fcpxmlEvent{
+		Name:    title,
+		Project: fcpxmlProject{Name: title, Sequence: seq},
+	}
+
+	out, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("encode fcpxml: %w", err)
+	}
+	return xml.Header + "<!DOCTYPE fcpxml>\n" + string(out) + "\n", nil
+}
+
+// fileURL turns a local path, including a Windows one, into a file:// URL.
+func fileURL(path string) string {
+	p := strings.ReplaceAll(path, `\`, "/")
+	if !strings.HasPrefix(p, "/") {
+		p = "/" + p
+	}
+	return (&url.URL{Scheme: "file", Path: p}).String()
+}
+
+func gcd(a, b int64) int64 {
+	for b != 0 {
+		a, b = b, a%b
+	}
+	if a < 0 {
+		return -a
+	}
+	return a
+}
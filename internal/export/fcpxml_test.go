@@ -0,0 +1,141 @@
+package export
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+)
+
+func TestFrameDuration(t *testing.T) {
+	tests := []struct {
+		rate float64
+		want string
+	}{
+		{23.976, "1001/24000s"},
+		{24, "1/24s"},
+		{25, "1/25s"},
+		{29.97, "1001/30000s"},
+		{30, "1/30s"},
+		{59.94, "1001/60000s"},
+		{0, "1/30s"},
+	}
+	for _, tt := range tests {
+		if got := FrameDuration(tt.rate).String(); got != tt.want {
+			t.Errorf("FrameDuration(%v) = %s, want %s", tt.rate, got, tt.want)
+		}
+	}
+}
+
+func TestGenerateFCPXML_Structure(t *testing.T) {
+	clips := []ResolvedClip{
+		{
+			ClipName: "Intro", MediaPath: "/media/a.mp4", StartMs: 1000, EndMs: 3000,
+			FrameRate: 25, Width: 1280, Height: 720, DurationMs: 10000, HasAudio: true,
+			Markers: []Marker{{StartMs: 2000, Name: "Scene 2", Tags: []string{"beach"}}},
+		},
+		{ClipName: "Again", MediaPath: "/media/a.mp4", StartMs: 5000, EndMs: 6000, FrameRate: 25, Width: 1280, Height: 720},
+		{ClipName: "Other", MediaPath: `C:\Footage\b roll.mov`, StartMs: 0, EndMs: 400, FrameRate: 29.97},
+	}
+
+	doc, err := GenerateFCPXML(clips, "Project <One>", 25)
+	if err != nil {
+		t.Fatalf("GenerateFCPXML error: %v", err)
+	}
+	if !strings.Contains(doc, "<!DOCTYPE fcpxml>") || !strings.Contains(doc, `<fcpxml version="1.9">`) {
+		t.Fatalf("missing FCPXML header:\n%s", doc)
+	}
+
+	var parsed fcpxmlDoc
+	if err := xml.Unmarshal([]byte(doc), &parsed); err != nil {
+		t.Fatalf("output is not valid XML: %v", err)
+	}
+	if len(parsed.Resources.Assets) != 2 {
+		t.Fatalf("assets = %d, want 2 (deduplicated by path)", len(parsed.Resources.Assets))
+	}
+	if src := parsed.Resources.Assets[1].MediaRep.Src; src != "file:///C:/Footage/b%20roll.mov" {
+		t.Errorf("windows src = %q", src)
+	}
+	if parsed.Resources.Assets[0].HasAudio != 1 || parsed.Resources.Assets[1].HasAudio != 0 {
+		t.Errorf("hasAudio = %d, %d", parsed.Resources.Assets[0].HasAudio, parsed.Resources.Assets[1].HasAudio)
+	}
+	if parsed.Library.Event.Project.Name != "Project <One>" {
+		t.Errorf("project name = %q", parsed.Library.Event.Project.Name)
+	}
+
+	seq := parsed.Library.Event.Project.Sequence
+	if seq.TCFormat != "NDF" || seq.Duration != "17/5s" {
+		t.Errorf("sequence tcFormat = %s duration = %s, want NDF 17/5s", seq.TCFormat, seq.Duration)
+	}
+	if len(seq.Clips) != 3 {
+		t.Fatalf("spine clips = %d, want 3", len(seq.Clips))
+	}
+	first, second, third := seq.Clips[0], seq.Clips[1], seq.Clips[2]
+	if first.Offset != "0s" || first.Start != "1s" || first.Duration != "2s" {
+		t.Errorf("first clip = %+v", first)
+	}
+	if second.Offset != "2s" || second.Start != "5s" || second.Ref != first.Ref {
+		t.Errorf("second clip = %+v", second)
+	}
+	if third.Offset != "3s" || third.Duration != "2/5s" || third.Format == first.Format {
+		t.Errorf("third clip = %+v", third)
+	}
+	if len(first.Markers) != 1 || first.Markers[0].Start != "2s" || first.Markers[0].Value != "Scene 2" || first.Markers[0].Note != "beach" {
+		t.Errorf("markers = %+v", first.Markers)
+	}
+}
+
+func TestGenerateFCPXML_ClipLengthsAndOffsets(t *testing.T) {
+	clips := []ResolvedClip{
+		{ClipName: "Empty", MediaPath: "/media/a.mp4", StartMs: 0, EndMs: 0, FrameRate: 25},
+		{ClipName: "Short", MediaPath: "/media/a.mp4", StartMs: 1000, EndMs: 1030, FrameRate: 25},
+		{ClipName: "Second", MediaPath: "/media/a.mp4", StartMs: 0, EndMs: 1000, FrameRate: 25},
+	}
+
+	doc, err := GenerateFCPXML(clips, "Lengths", 25)
+	if err != nil {
+		t.Fatalf("GenerateFCPXML error: %v", err)
+	}
+	var parsed fcpxmlDoc
+	if err := xml.Unmarshal([]byte(doc), &parsed); err != nil {
+		t.Fatalf("output is not valid XML: %v", err)
+	}
+	seq := parsed.Library.Event.Project.Sequence
+	if len(seq.Clips) != 3 {
+		t.Fatalf("spine clips = %d, want 3", len(seq.Clips))
+	}
+
+	// Clips shorter than a frame, even empty ones, last one frame; each
+	// clip starts where the previous one ends.
+	want := []struct{ offset, duration string }{
+		{"0s", "1/25s"},
+		{"1/25s", "1/25s"},
+		{"2/25s", "1s"},
+	}
+	for i, w := range want {
+		if c := seq.Clips[i]; c.Offset != w.offset || c.Duration != w.duration {
+			t.Errorf("clip %d offset = %s duration = %s, want %s %s", i, c.Offset, c.Duration, w.offset, w.duration)
+		}
+	}
+	if seq.Duration != "27/25s" {
+		t.Errorf("sequence duration = %s, want 27/25s", seq.Duration)
+	}
+
+	offsets := TimelineOffsets(clips, 25)
+	if len(offsets) != 3 || offsets[0] != 0 || offsets[1] != 40 || offsets[2] != 80 {
+		t.Errorf("TimelineOffsets = %v, want [0 40 80]", offsets)
+	}
+}
+
+func TestGenerateFCPXML_DropFrame(t *testing.T) {
+	clips := []ResolvedClip{{ClipName: "A", MediaPath: "/a.mp4", StartMs: 1001, EndMs: 2002}}
+
+	doc, err := GenerateFCPXML(clips, "NTSC", 29.97)
+	if err != nil {
+		t.Fatalf("GenerateFCPXML error: %v", err)
+	}
+	for _, want := range []string{`tcFormat="DF"`, `frameDuration="1001/30000s"`, `start="1001/1000s"`, `duration="1001/1000s"`} {
+		if !strings.Contains(doc, want) {
+			t.Errorf("missing %s:\n%s", want, doc)
+		}
+	}
+}
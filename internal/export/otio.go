@@ -0,0 +1,232 @@
+package export
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// OpenTimelineIO schema versions and keys written. The schema versions are
+// the ones OTIO 0.15 and later read and write by default.
+const (
+	otioTimeline      = "Timeline.1"
+	otioStack         = "Stack.1"
+	otioTrack         = "Track.1"
+	otioClip          = "Clip.2"
+	otioExternalRef   = "ExternalReference.1"
+	otioMarker        = "Marker.2"
+	otioTimeRange     = "TimeRange.1"
+	otioRationalTime  = "RationalTime.1"
+	otioDefaultMedia  = "DEFAULT_MEDIA"
+	otioMarkerColor   = "GREEN"
+	otioMetadataSpace = "heimdex"
+)
+
+type otioRational struct {
+	Schema string  `json:"OTIO_SCHEMA"`
+	Rate   float64 `json:"rate"`
+	Value  float64 `json:"value"`
+}
+
+type otioRange struct {
+	Schema    string       `json:"OTIO_SCHEMA"`
+	StartTime otioRational `json:"start_time"`
+	Duration  otioRational `json:"duration"`
+}
+
+type otioTimelineDoc struct {
+	Schema          string         `json:"OTIO_SCHEMA"`
+	Metadata        map[string]any `json:"metadata"`
+	Name            string         `json:"name"`
+	GlobalStartTime *otioRational  `json:"global_start_time"`
+	Tracks          otioStackDoc   `json:"tracks"`
+}
+
+type otioStackDoc struct {
+	Schema      string         `json:"OTIO_SCHEMA"`
+	Metadata    map[string]any `json:"metadata"`
+	Name        string         `json:"name"`
+	SourceRange *otioRange     `json:"source_range"`
+	Effects     []any          `json:"effects"`
+	Markers     []otioMarkerEl `json:"markers"`
+	Enabled     bool           `json:"enabled"`
+	Children    []otioTrackDoc `json:"children"`
+}
+
+type otioTrackDoc struct {
+	Schema      string         `json:"OTIO_SCHEMA"`
+	Metadata    map[string]any `json:"metadata"`
+	Name        string         `json:"name"`
+	SourceRange *otioRange     `json:"source_range"`
+	Effects     []any          `json:"effects"`
+	Markers     []otioMarkerEl `json:"markers"`
+	Enabled     bool           `json:"enabled"`
+	Children    []otioClipDoc  `json:"children"`
+	Kind        string         `json:"kind"`
+}
+
+type otioClipDoc struct {
+	Schema                  string                  `json:"OTIO_SCHEMA"`
+	Metadata                map[string]any          `json:"metadata"`
+	Name                    string                  `json:"name"`
+	SourceRange             *otioRange              `json:"source_range"`
+	Effects                 []any                   `json:"effects"`
+	Markers                 []otioMarkerEl          `json:"markers"`
+	Enabled                 bool                    `json:"enabled"`
+	MediaReferences         map[string]otioMediaRef `json:"media_references"`
+	ActiveMediaReferenceKey string                  `json:"active_media_reference_key"`
+}
+
+type otioMediaRef struct {
+	Schema         string         `json:"OTIO_SCHEMA"`
+	Metadata       map[string]any `json:"metadata"`
+	Name           string         `json:"name"`
+	AvailableRange *otioRange     `json:"available_range"`
+	TargetURL      string         `json:"target_url"`
+}
+
+type otioMarkerEl struct {
+	Schema      string         `json:"OTIO_SCHEMA"`
+	Metadata    map[string]any `json:"metadata"`
+	Name        string         `json:"name"`
+	Color       string         `json:"color"`
+	MarkedRange otioRange      `json:"marked_range"`
+	Comment     string         `json:"comment"`
+}
+
+// MarshalJSON writes rate and value as JSON floats, "24.0" rather than "24",
+// because OTIO's reader types numbers by their JSON spelling.
+func (t otioRational) MarshalJSON() ([]byte, error) {
+	return fmt.Appendf(nil, `{"OTIO_SCHEMA":%q,"rate":%s,"value":%s}`, t.Schema, otioFloat(t.Rate), otioFloat(t.Value)), nil
+}
+
+func otioFloat(f float64) string {
+	s := strconv.FormatFloat(f, 'f', -1, 64)
+	if !strings.ContainsAny(s, ".eE") {
+		s += ".0"
+	}
+	return s
+}
+
+// otioTime is ms snapped to the frame grid of fd, as an OTIO RationalTime
+// counting frames at the matching rate (24000/1001 for 23.976).
+func otioTime(ms int, fd Rational) otioRational {
+	return otioRational{
+		Schema: otioRationalTime,
+		Rate:   float64(fd.Den) / float64(fd.Num),
+		Value:  float64(frames(ms, fd)),
+	}
+}
+
+func otioTimeRangeOf(startMs, durationMs int, fd Rational) *otioRange {
+	return &otioRange{Schema: otioTimeRange, StartTime: otioTime(startMs, fd), Duration: otioTime(durationMs, fd)}
+}
+
+// GenerateOTIO builds an OpenTimelineIO timeline with the clips end to end on
+// one video track. Source ranges and markers are in each file's own probed
+// frame rate, falling back to frameRate. The clip's metadata carries the
+// catalog IDs and the scenes it covers under the "heimdex" key.
+func GenerateOTIO(clips []ResolvedClip, title string, frameRate float64) (string, error) {
+	if frameRate <= 0 {
+		frameRate = 30
+	}
+	seqFD := FrameDuration(frameRate)
+
+	track := otioTrackDoc{
+		Schema:   otioTrack,
+		Metadata: map[string]any{},
+		Name:     "V1",
+		Effects:  []any{},
+		Markers:  []otioMarkerEl{},
+		Enabled:  true,
+		Children: make([]otioClipDoc, 0, len(clips)),
+		Kind:     "Video",
+	}
+
+	for _, clip := range clips {
+		fd := seqFD
+		if clip.FrameRate > 0 {
+			fd = FrameDuration(clip.FrameRate)
+		}
+
+		ref := otioMediaRef{
+			Schema:    otioExternalRef,
+			Metadata:  map[string]any{},
+			Name:      filepath.Base(filepath.FromSlash(strings.ReplaceAll(clip.MediaPath, `\`, "/"))),
+			TargetURL: fileURL(clip.MediaPath),
+		}
+		if clip.DurationMs > 0 {
+			ref.AvailableRange = otioTimeRangeOf(0, clip.DurationMs, fd)
+		}
+
+		scenes := make([]map[string]any, 0, len(clip.Markers))
+		markers := make([]otioMarkerEl, 0, len(clip.Markers))
+		for _, m := range clip.Markers {
+			if m.StartMs < clip.StartMs || m.StartMs >= clip.EndMs {
+				continue
+			}
+			tags := m.Tags
+			if tags == nil {
+				tags = []string{}
+			}
+			scene := map[string]any{
+				"scene_id":   m.SceneID,
+				"start_ms":   m.StartMs,
+				"tags":       tags,
+				"transcript": m.Transcript,
+			}
+			scenes = append(scenes, scene)
+			markers = append(markers, otioMarkerEl{
+				Schema:      otioMarker,
+				Metadata:    map[string]any{otioMetadataSpace: scene},
+				Name:        m.Name,
+				Color:       otioMarkerColor,
+				MarkedRange: *otioTimeRangeOf(m.StartMs, 0, fd),
+				Comment:     markerComment(m),
+			})
+		}
+
+		track.Children = append(track.Children, otioClipDoc{
+			Schema: otioClip,
+			Metadata: map[string]any{otioMetadataSpace: map[string]any{
+				"video_id": clip.VideoID,
+				"scene_id": clip.SceneID,
+				"start_ms": clip.StartMs,
+				"end_ms":   clip.EndMs,
+				"scenes":   scenes,
+			}},
+			Name:                    clip.ClipName,
+			SourceRange:             otioTimeRangeOf(clip.StartMs, clip.EndMs-clip.StartMs, fd),
+			Effects:                 []any{},
+			Markers:                 markers,
+			Enabled:                 true,
+			MediaReferences:         map[string]otioMediaRef{otioDefaultMedia: ref},
+			ActiveMediaReferenceKey: otioDefaultMedia,
+		})
+	}
+
+	start := otioTime(0, seqFD)
+	doc := otioTimelineDoc{
+		Schema:          otioTimeline,
+		Metadata:        map[string]any{},
+		Name:            title,
+		GlobalStartTime: &start,
+		Tracks: otioStackDoc{
+			Schema:   otioStack,
+			Metadata: map[string]any{},
+			Name:     "tracks",
+			Effects:  []any{},
+			Markers:  []otioMarkerEl{},
+			Enabled:  true,
+			Children: []otioTrackDoc{track},
+		},
+	}
+
+	out, err := json.MarshalIndent(doc, "", "    ")
+	if err != nil {
+		return "", fmt.Errorf("encode otio: %w", err)
+	}
+	return string(out) + "\n", nil
+}
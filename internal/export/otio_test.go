@@ -0,0 +1,83 @@
+package export
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestGenerateOTIO_Structure(t *testing.T) {
+	clips := []ResolvedClip{
+		{
+			VideoID: "v1", SceneID: "v1_scene_1", ClipName: "Intro", MediaPath: "/media/a clip.mp4",
+			StartMs: 1000, EndMs: 3000, FrameRate: 25, DurationMs: 10000,
+			Markers: []Marker{
+				{SceneID: "v1_scene_1", StartMs: 2000, Name: "Scene 2", Tags: []string{"beach"}, Transcript: "hello"},
+				{SceneID: "v1_scene_2", StartMs: 3000, Name: "Scene 3"},
+			},
+		},
+		{VideoID: "v2", ClipName: "Other", MediaPath: `C:\Footage\b.mov`, StartMs: 0, EndMs: 1001, FrameRate: 29.97},
+	}
+
+	out, err := GenerateOTIO(clips, "Selects", 25)
+	if err != nil {
+		t.Fatalf("GenerateOTIO error: %v", err)
+	}
+	if !strings.Contains(out, `"rate": 25.0`) || !strings.Contains(out, `"value": 0.0`) {
+		t.Errorf("rational times should be written as floats:\n%s", out)
+	}
+
+	var doc map[string]any
+	if err := json.Unmarshal([]byte(out), &doc); err != nil {
+		t.Fatalf("output is not valid JSON: %v", err)
+	}
+	if doc["OTIO_SCHEMA"] != "Timeline.1" || doc["name"] != "Selects" {
+		t.Fatalf("timeline = %v %v", doc["OTIO_SCHEMA"], doc["name"])
+	}
+	track := doc["tracks"].(map[string]any)["children"].([]any)[0].(map[string]any)
+	if track["kind"] != "Video" {
+		t.Errorf("track kind = %v", track["kind"])
+	}
+	children := track["children"].([]any)
+	if len(children) != 2 {
+		t.Fatalf("clips = %d, want 2", len(children))
+	}
+
+	first := children[0].(map[string]any)
+	sr := first["source_range"].(map[string]any)
+	if start := sr["start_time"].(map[string]any); start["value"] != 25.0 || start["rate"] != 25.0 {
+		t.Errorf("start_time = %v", start)
+	}
+	if dur := sr["duration"].(map[string]any); dur["value"] != 50.0 {
+		t.Errorf("duration = %v", dur)
+	}
+	ref := first["media_references"].(map[string]any)["DEFAULT_MEDIA"].(map[string]any)
+	if ref["OTIO_SCHEMA"] != "ExternalReference.1" || ref["target_url"] != "file:///media/a%20clip.mp4" {
+		t.Errorf("media reference = %v", ref)
+	}
+	if first["active_media_reference_key"] != "DEFAULT_MEDIA" {
+		t.Errorf("active key = %v", first["active_media_reference_key"])
+	}
+
+	markers := first["markers"].([]any)
+	if len(markers) != 1 {
+		t.Fatalf("markers = %v, want only the scene inside the clip", markers)
+	}
+	marker := markers[0].(map[string]any)
+	if marker["name"] != "Scene 2" || marker["comment"] != "Tags: beach\nhello" {
+		t.Errorf("marker = %v", marker)
+	}
+	meta := first["metadata"].(map[string]any)["heimdex"].(map[string]any)
+	if meta["video_id"] != "v1" || meta["scene_id"] != "v1_scene_1" || len(meta["scenes"].([]any)) != 1 {
+		t.Errorf("clip metadata = %v", meta)
+	}
+
+	second := children[1].(map[string]any)
+	start := second["source_range"].(map[string]any)["duration"].(map[string]any)
+	if start["value"] != 30.0 || start["rate"].(float64) < 29.97 || start["rate"].(float64) > 29.98 {
+		t.Errorf("29.97 duration = %v", start)
+	}
+	if second["media_references"].(map[string]any)["DEFAULT_MEDIA"].(map[string]any)["available_range"] != nil {
+		t.Errorf("available_range should be null when the duration is unknown")
+	}
+}
@@ -0,0 +1,84 @@
+package export
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// isDropFrameRate reports whether frameRate is 29.97 or 59.94, the rates
+// SMPTE drop-frame timecode is defined for.
+func isDropFrameRate(frameRate float64) bool {
+	return math.Abs(frameRate-29.97) < 0.01 || math.Abs(frameRate-59.94) < 0.01
+}
+
+// nominalFPS is the whole frame count per timecode second: 30 for 29.97,
+// 24 for 23.976.
+func nominalFPS(frameRate float64) int {
+	fd := FrameDuration(frameRate)
+	if fd.Num == 1001 {
+		return int(fd.Den / 1000)
+	}
+	return int(math.Round(float64(fd.Den) / float64(fd.Num)))
+}
+
+// dropFrames is how many frame numbers drop-frame timecode skips at the start
+// of each minute not divisible by ten: 2 at 30 fps, 4 at 60.
+func dropFrames(fps int) int64 {
+	return int64(math.Round(float64(fps) / 15))
+}
+
+// framesToTimecode labels a frame count as HH:MM:SS:FF at fps frames per
+// timecode second. Drop-frame timecode skips frame numbers so the label keeps
+// pace with the clock at 29.97 and 59.94, and separates frames with ';'.
+func framesToTimecode(n int64, fps int, dropFrame bool) string {
+	if n < 0 {
+		n = 0
+	}
+	sep := ":"
+	if dropFrame {
+		sep = ";"
+		drop := dropFrames(fps)
+		perMinute := int64(fps)*60 - drop
+		perTenMinutes := int64(fps)*600 - drop*9
+		tens, rem := n/perTenMinutes, n%perTenMinutes
+		n += drop * 9 * tens
+		if rem > drop {
+			n += drop * ((rem - drop) / perMinute)
+		}
+	}
+
+	f := int64(fps)
+	return fmt.Sprintf("%02d:%02d:%02d%s%02d", n/(f*3600), n/(f*60)%60, n/f%60, sep, n%f)
+}
+
+// parseTimecode converts HH:MM:SS:FF to a frame count at fps. A ';' or '.'
+// before the frames marks drop-frame timecode, as ffprobe reports it.
+func parseTimecode(tc string, fps int) (int64, error) {
+	tc = strings.TrimSpace(tc)
+	dropFrame := strings.ContainsAny(tc, ";.")
+	parts := strings.FieldsFunc(tc, func(r rune) bool { return r == ':' || r == ';' || r == '.' })
+	if len(parts) != 4 {
+		return 0, fmt.Errorf("invalid timecode %q", tc)
+	}
+	var v [4]int64
+	for i, p := range parts {
+		n, err := strconv.ParseInt(p, 10, 64)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid timecode %q", tc)
+		}
+		v[i] = n
+	}
+	h, m, s, f := v[0], v[1], v[2], v[3]
+	if m >= 60 || s >= 60 || f >= int64(fps) {
+		return 0, fmt.Errorf("invalid timecode %q", tc)
+	}
+
+	n := ((h*60+m)*60+s)*int64(fps) + f
+	if dropFrame && fps%30 == 0 {
+		minutes := h*60 + m
+		n -= dropFrames(fps) * (minutes - minutes/10)
+	}
+	return n, nil
+}
@@ -0,0 +1,66 @@
+package export
+
+import "testing"
+
+func TestFramesToTimecode(t *testing.T) {
+	tests := []struct {
+		frames    int64
+		fps       int
+		dropFrame bool
+		want      string
+	}{
+		{0, 30, true, "00:00:00;00"},
+		{1799, 30, true, "00:00:59;29"},
+		{1800, 30, true, "00:01:00;02"},
+		{3597, 30, true, "00:01:59;29"},
+		{3598, 30, true, "00:02:00;02"},
+		{17982, 30, true, "00:10:00;00"},
+		{17983, 30, true, "00:10:00;01"},
+		{107892, 30, true, "01:00:00;00"},
+		{3600, 60, true, "00:01:00;04"},
+		{1800, 30, false, "00:01:00:00"},
+		{86400, 24, false, "01:00:00:00"},
+	}
+	for _, tt := range tests {
+		if got := framesToTimecode(tt.frames, tt.fps, tt.dropFrame); got != tt.want {
+			t.Errorf("framesToTimecode(%d, %d, %v) = %s, want %s", tt.frames, tt.fps, tt.dropFrame, got, tt.want)
+		}
+	}
+}
+
+func TestParseTimecode(t *testing.T) {
+	tests := []struct {
+		tc   string
+		fps  int
+		want int64
+	}{
+		{"00:01:00;02", 30, 1800},
+		{"00:10:00;00", 30, 17982},
+		{"01:00:00;00", 30, 107892},
+		{"01:00:00.00", 30, 107892},
+		{"01:00:00:00", 30, 108000},
+		{"00:00:10:12", 24, 252},
+	}
+	for _, tt := range tests {
+		got, err := parseTimecode(tt.tc, tt.fps)
+		if err != nil || got != tt.want {
+			t.Errorf("parseTimecode(%q, %d) = %d, %v; want %d", tt.tc, tt.fps, got, err, tt.want)
+		}
+	}
+
+	for _, bad := range []string{"", "01:00:00", "aa:00:00:00", "00:60:00:00", "00:00:00:30"} {
+		if _, err := parseTimecode(bad, 30); err == nil {
+			t.Errorf("parseTimecode(%q) accepted an invalid timecode", bad)
+		}
+	}
+}
+
+func TestDropFrameRoundTrip(t *testing.T) {
+	for n := int64(0); n < 40000; n += 7 {
+		tc := framesToTimecode(n, 30, true)
+		got, err := parseTimecode(tc, 30)
+		if err != nil || got != n {
+			t.Fatalf("frame %d -> %s -> %d, %v", n, tc, got, err)
+		}
+	}
+}
@@ -1,11 +1,17 @@
 package export
 
 type ExportRequest struct {
-	ProjectName string      `json:"project_name"`
-	Format      string      `json:"format"`
-	FrameRate   float64     `json:"frame_rate"`
-	OutputDir   string      `json:"output_dir"`
-	Clips       []ClipInput `json:"clips"`
+	ProjectName string  `json:"project_name"`
+	Format      string  `json:"format"`
+	FrameRate   float64 `json:"frame_rate"`
+	OutputDir   string  `json:"output_dir"`
+	// IncludeAudio adds the clips' audio on linked tracks. Only xmeml
+	// honours it; FCPXML asset clips always carry their audio.
+	IncludeAudio bool `json:"include_audio"`
+	// Subtitles, "srt" or "vtt", also writes the clips' transcripts as
+	// captions timed to the export timeline, next to the export file.
+	Subtitles string      `json:"subtitles"`
+	Clips     []ClipInput `json:"clips"`
 }
 
 type ClipInput struct {
@@ -17,11 +23,37 @@ type ClipInput struct {
 }
 
 type ResolvedClip struct {
+	VideoID   string
 	ClipName  string
 	MediaPath string
 	StartMs   int
 	EndMs     int
 	SceneID   string
+
+	// Media properties from ffprobe; zero when unknown.
+	FrameRate  float64
+	Width      int
+	Height     int
+	DurationMs int
+	HasAudio   bool
+	// AudioChannels is the channel count of the first audio stream.
+	AudioChannels int
+	SampleRate    int
+	// StartTimecode is the embedded timecode of the first frame, and
+	// ReelName the tape or reel name.
+	StartTimecode string
+	ReelName      string
+
+	// Markers are scene starts within StartMs..EndMs, in media time.
+	Markers []Marker
+}
+
+type Marker struct {
+	SceneID    string
+	StartMs    int
+	Name       string
+	Tags       []string
+	Transcript string
 }
 
 type ExportResponse struct {
@@ -30,4 +62,5 @@ type ExportResponse struct {
 	OutputPath      string   `json:"output_path"`
 	ClipCount       int      `json:"clip_count"`
 	UnresolvedClips []string `json:"unresolved_clips"`
+	SubtitlePath    string   `json:"subtitle_path,omitempty"`
 }
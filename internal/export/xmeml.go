@@ -0,0 +1,334 @@
+package export
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"strings"
+)
+
+// XMEMLVersion is the Final Cut Pro 7 interchange version written. Premiere
+// Pro imports version 4 and later natively and relinks media from pathurl.
+const XMEMLVersion = "4"
+
+type xmemlDoc struct {
+	XMLName  xml.Name      `xml:"xmeml"`
+	Version  string        `xml:"version,attr"`
+	Sequence xmemlSequence `xml:"sequence"`
+}
+
+type xmemlRate struct {
+	Timebase int    `xml:"timebase"`
+	NTSC     string `xml:"ntsc"`
+}
+
+type xmemlTimecode struct {
+	Rate          xmemlRate `xml:"rate"`
+	String        string    `xml:"string"`
+	Frame         int64     `xml:"frame"`
+	DisplayFormat string    `xml:"displayformat"`
+}
+
+type xmemlSequence struct {
+	ID       string        `xml:"id,attr"`
+	Name     string        `xml:"name"`
+	Duration int64         `xml:"duration"`
+	Rate     xmemlRate     `xml:"rate"`
+	Timecode xmemlTimecode `xml:"timecode"`
+	Media    xmemlSeqMedia `xml:"media"`
+}
+
+type xmemlSeqMedia struct {
+	Video xmemlVideo  `xml:"video"`
+	Audio *xmemlAudio `xml:"audio,omitempty"`
+}
+
+type xmemlVideo struct {
+	Format xmemlFormat  `xml:"format"`
+	Tracks []xmemlTrack `xml:"track"`
+}
+
+type xmemlAudio struct {
+	NumOutputChannels int          `xml:"numOutputChannels"`
+	Tracks            []xmemlTrack `xml:"track"`
+}
+
+type xmemlFormat struct {
+	SampleCharacteristics xmemlVideoSample `xml:"samplecharacteristics"`
+}
+
+type xmemlVideoSample struct {
+	Rate             *xmemlRate `xml:"rate,omitempty"`
+	Width            int        `xml:"width"`
+	Height           int        `xml:"height"`
+	PixelAspectRatio string     `xml:"pixelaspectratio"`
+}
+
+type xmemlAudioSample struct {
+	Depth      int `xml:"depth"`
+	SampleRate int `xml:"samplerate"`
+}
+
+type xmemlTrack struct {
+	ClipItems []xmemlClipItem `xml:"clipitem"`
+}
+
+type xmemlClipItem struct {
+	ID          string            `xml:"id,attr"`
+	Name        string            `xml:"name"`
+	Enabled     string            `xml:"enabled"`
+	Duration    int64             `xml:"duration"`
+	Rate        xmemlRate         `xml:"rate"`
+	Start       int64             `xml:"start"`
+	End         int64             `xml:"end"`
+	In          int64             `xml:"in"`
+	Out         int64             `xml:"out"`
+	File        xmemlFile         `xml:"file"`
+	SourceTrack *xmemlSourceTrack `xml:"sourcetrack,omitempty"`
+	Links       []xmemlLink       `xml:"link"`
+	Markers     []xmemlMarker     `xml:"marker"`
+}
+
+// xmemlFile is written in full on first use and as a bare id reference after.
+type xmemlFile struct {
+	ID       string          `xml:"id,attr"`
+	Name     string          `xml:"name,omitempty"`
+	PathURL  string          `xml:"pathurl,omitempty"`
+	Rate     *xmemlRate      `xml:"rate,omitempty"`
+	Duration int64           `xml:"duration,omitempty"`
+	Media    *xmemlFileMedia `xml:"media,omitempty"`
+}
+
+type xmemlFileMedia struct {
+	Video *xmemlFileVideo `xml:"video,omitempty"`
+	Audio *xmemlFileAudio `xml:"audio,omitempty"`
+}
+
+type xmemlFileVideo struct {
+	SampleCharacteristics xmemlVideoSample `xml:"samplecharacteristics"`
+}
+
+type xmemlFileAudio struct {
+	SampleCharacteristics xmemlAudioSample `xml:"samplecharacteristics"`
+	ChannelCount          int              `xml:"channelcount"`
+}
+
+type xmemlSourceTrack struct {
+	MediaType  string `xml:"mediatype"`
+	TrackIndex int    `xml:"trackindex"`
+}
+
+type xmemlLink struct {
+	LinkClipRef string `xml:"linkclipref"`
+	MediaType   string `xml:"mediatype"`
+	TrackIndex  int    `xml:"trackindex"`
+	ClipIndex   int    `xml:"clipindex"`
+}
+
+type xmemlMarker struct {
+	Name    string `xml:"name"`
+	Comment string `xml:"comment"`
+	In      int64  `xml:"in"`
+	Out     int64  `xml:"out"`
+}
+
+// xmemlTimebase returns the integer timebase and NTSC flag xmeml describes
+// frameRate with: 29.97 is timebase 30 with ntsc TRUE.
+func xmemlTimebase(frameRate float64) xmemlRate {
+	rate := xmemlRate{Timebase: nominalFPS(frameRate), NTSC: "FALSE"}
+	if FrameDuration(frameRate).Num == 1001 {
+		rate.NTSC = "TRUE"
+	}
+	return rate
+}
+
+// GenerateXMEML builds a Final Cut Pro 7 XML sequence that lays the clips end
+// to end on V1. Clip in/out points, sequence positions and markers are all
+// counted in frames of the sequence rate. With includeAudio, clips that have
+// audio also get one linked clip item per channel on A1..An.
+func GenerateXMEML(clips []ResolvedClip, title string, frameRate float64, includeAudio bool) (string, error) {
+	if frameRate <= 0 {
+		frameRate = 30
+	}
+	fd := FrameDuration(frameRate)
+	rate := xmemlTimebase(frameRate)
+
+	width, height := 1920, 1080
+	if len(clips) > 0 && clips[0].Width > 0 && clips[0].Height > 0 {
+		width, height = clips[0].Width, clips[0].Height
+	}
+
+	displayFormat, zeroTC := "NDF", "00:00:00:00"
+	if isDropFrameRate(frameRate) {
+		displayFormat, zeroTC = "DF", "00:00:00;00"
+	}
+
+	audioTracks := 0
+	if includeAudio {
+		for _, clip := range clips {
+			if clip.HasAudio {
+				audioTracks = max(audioTracks, audioChannelsOf(clip))
+			}
+		}
+	}
+
+	video := xmemlTrack{}
+	audio := make([]xmemlTrack, audioTracks)
+	fileIDs := make(map[string]string)
+
+	var offset int64
+	for i, clip := range clips {
+		in := frames(clip.StartMs, fd)
+		length := clipFrames(clip, fd)
+		mediaFrames := max(frames(max(clip.DurationMs, clip.EndMs), fd), in+length)
+
+		fileID, seen := fileIDs[clip.MediaPath]
+		if !seen {
+			fileID = fmt.Sprintf("file-%d", len(fileIDs)+1)
+			fileIDs[clip.MediaPath] = fileID
+		}
+		file := xmemlFile{ID: fileID}
+		if !seen {
+			file = xmemlFileDefinition(fileID, clip, rate, mediaFrames, width, height)
+		}
+
+		item := xmemlClipItem{
+			ID:       fmt.Sprintf("clipitem-v%d", i+1),
+			Name:     clip.ClipName,
+			Enabled:  "TRUE",
+			Duration: mediaFrames,
+			Rate:     rate,
+			Start:    offset,
+			End:      offset + length,
+			In:       in,
+			Out:      in + length,
+			File:     file,
+		}
+		for _, m := range clip.Markers {
+			if m.StartMs < clip.StartMs || m.StartMs >= clip.EndMs {
+				continue
+			}
+			item.Markers = append(item.Markers, xmemlMarker{
+				Name:    m.Name,
+				Comment: markerComment(m),
+				In:      frames(m.StartMs, fd),
+				Out:     -1,
+			})
+		}
+
+		channels := 0
+		if includeAudio && clip.HasAudio {
+			channels = audioChannelsOf(clip)
+		}
+		if channels > 0 {
+			item.Links = append(item.Links, xmemlLink{LinkClipRef: item.ID, MediaType: "video", TrackIndex: 1, ClipIndex: i + 1})
+			for ch := 1; ch <= channels; ch++ {
+				item.Links = append(item.Links, xmemlLink{
+					LinkClipRef: fmt.Sprintf("clipitem-a%d-%d", ch, i+1),
+					MediaType:   "audio",
+					TrackIndex:  ch,
+					ClipIndex:   len(audio[ch-1].ClipItems) + 1,
+				})
+			}
+		}
+		video.ClipItems = append(video.ClipItems, item)
+
+		for ch := 1; ch <= channels; ch++ {
+			a := item
+			a.ID = fmt.Sprintf("clipitem-a%d-%d", ch, i+1)
+			a.File = xmemlFile{ID: fileID}
+			a.SourceTrack = &xmemlSourceTrack{MediaType: "audio", TrackIndex: ch}
+			a.Markers = nil
+			audio[ch-1].ClipItems = append(audio[ch-1].ClipItems, a)
+		}
+
+		offset += length
+	}
+
+	seq := xmemlSequence{
+		ID:       "sequence-1",
+		Name:     title,
+		Duration: offset,
+		Rate:     rate,
+		Timecode: xmemlTimecode{Rate: rate, String: zeroTC, DisplayFormat: displayFormat},
+		Media: xmemlSeqMedia{
+			Video: xmemlVideo{
+				Format: xmemlFormat{SampleCharacteristics: xmemlVideoSample{
+					Rate: &rate, Width: width, Height: height, PixelAspectRatio: "square",
+				}},
+				Tracks: []xmemlTrack{video},
+			},
+		},
+	}
+	if audioTracks > 0 {
+		seq.Media.Audio = &xmemlAudio{NumOutputChannels: 2, Tracks: audio}
+	}
+
+	out, err := xml.MarshalIndent(xmemlDoc{Version: XMEMLVersion, Sequence: seq}, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("encode xmeml: %w", err)
+	}
+	return xml.Header + "<!DOCTYPE xmeml>\n" + string(out) + "\n", nil
+}
+
+func xmemlFileDefinition(id string, clip ResolvedClip, rate xmemlRate, duration int64, width, height int) xmemlFile {
+	w, h := clip.Width, clip.Height
+	if w <= 0 || h <= 0 {
+		w, h = width, height
+	}
+	fileRate := rate
+	if clip.FrameRate > 0 {
+		fileRate = xmemlTimebase(clip.FrameRate)
+	}
+	media := &xmemlFileMedia{Video: &xmemlFileVideo{SampleCharacteristics: xmemlVideoSample{
+		Width: w, Height: h, PixelAspectRatio: "square",
+	}}}
+	if clip.HasAudio {
+		sampleRate := clip.SampleRate
+		if sampleRate <= 0 {
+			sampleRate = 48000
+		}
+		media.Audio = &xmemlFileAudio{
+			SampleCharacteristics: xmemlAudioSample{Depth: 16, SampleRate: sampleRate},
+			ChannelCount:          audioChannelsOf(clip),
+		}
+	}
+	return xmemlFile{
+		ID:       id,
+		Name:     filepath.Base(filepath.FromSlash(strings.ReplaceAll(clip.MediaPath, `\`, "/"))),
+		PathURL:  xmemlPathURL(clip.MediaPath),
+		Rate:     &fileRate,
+		Duration: duration,
+		Media:    media,
+	}
+}
+
+// audioChannelsOf assumes stereo when the channel count was not probed.
+func audioChannelsOf(clip ResolvedClip) int {
+	if clip.AudioChannels > 0 {
+		return clip.AudioChannels
+	}
+	return 2
+}
+
+// markerComment puts a scene's tags and transcript in one marker comment.
+func markerComment(m Marker) string {
+	parts := make([]string, 0, 2)
+	if len(m.Tags) > 0 {
+		parts = append(parts, "Tags: "+strings.Join(m.Tags, ", "))
+	}
+	if m.Transcript != "" {
+		parts = append(parts, m.Transcript)
+	}
+	return strings.Join(parts, "\n")
+}
+
+// xmemlPathURL is the file://localhost/ form Premiere writes and relinks.
+func xmemlPathURL(path string) string {
+	p := strings.ReplaceAll(path, `\`, "/")
+	if !strings.HasPrefix(p, "/") {
+		p = "/" + p
+	}
+	return (&url.URL{Scheme: "file", Host: "localhost", Path: p}).String()
+}
@@ -0,0 +1,126 @@
+package export
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+)
+
+func TestXMEMLTimebase(t *testing.T) {
+	tests := []struct {
+		rate     float64
+		timebase int
+		ntsc     string
+	}{
+		{23.976, 24, "TRUE"},
+		{25, 25, "FALSE"},
+		{29.97, 30, "TRUE"},
+		{30, 30, "FALSE"},
+		{59.94, 60, "TRUE"},
+	}
+	for _, tt := range tests {
+		got := xmemlTimebase(tt.rate)
+		if got.Timebase != tt.timebase || got.NTSC != tt.ntsc {
+			t.Errorf("xmemlTimebase(%v) = %+v, want %d %s", tt.rate, got, tt.timebase, tt.ntsc)
+		}
+	}
+}
+
+func TestGenerateXMEML_VideoOnly(t *testing.T) {
+	clips := []ResolvedClip{
+		{
+			ClipName: "Intro", MediaPath: "/media/a clip.mp4", StartMs: 1000, EndMs: 3000,
+			FrameRate: 25, Width: 1280, Height: 720, DurationMs: 10000, HasAudio: true,
+			Markers: []Marker{
+				{StartMs: 2000, Name: "Scene 2", Tags: []string{"beach", "sunset"}, Transcript: "look at that"},
+				{StartMs: 4000, Name: "Scene 3"},
+			},
+		},
+		{ClipName: "Again", MediaPath: "/media/a clip.mp4", StartMs: 5000, EndMs: 6000},
+	}
+
+	doc, err := GenerateXMEML(clips, "Cut & Paste", 25, false)
+	if err != nil {
+		t.Fatalf("GenerateXMEML error: %v", err)
+	}
+	if !strings.Contains(doc, "<!DOCTYPE xmeml>") || !strings.Contains(doc, `<xmeml version="4">`) {
+		t.Fatalf("missing xmeml header:\n%s", doc)
+	}
+
+	var parsed xmemlDoc
+	if err := xml.Unmarshal([]byte(doc), &parsed); err != nil {
+		t.Fatalf("output is not valid XML: %v", err)
+	}
+	seq := parsed.Sequence
+	if seq.Name != "Cut & Paste" || seq.Duration != 75 || seq.Rate.Timebase != 25 {
+		t.Errorf("sequence = %q duration %d timebase %d", seq.Name, seq.Duration, seq.Rate.Timebase)
+	}
+	if seq.Media.Audio != nil {
+		t.Errorf("audio tracks written without include_audio")
+	}
+
+	items := seq.Media.Video.Tracks[0].ClipItems
+	if len(items) != 2 {
+		t.Fatalf("clip items = %d, want 2", len(items))
+	}
+	first, second := items[0], items[1]
+	if first.Start != 0 || first.End != 50 || first.In != 25 || first.Out != 75 {
+		t.Errorf("first clip = start %d end %d in %d out %d", first.Start, first.End, first.In, first.Out)
+	}
+	if first.File.PathURL != "file://localhost/media/a%20clip.mp4" || first.File.Media == nil {
+		t.Errorf("first file = %+v", first.File)
+	}
+	if second.Start != 50 || second.In != 125 || second.File.ID != first.File.ID || second.File.PathURL != "" {
+		t.Errorf("second clip should reference file %s: %+v", first.File.ID, second)
+	}
+	if len(first.Markers) != 1 {
+		t.Fatalf("markers = %+v, want only the scene inside the clip", first.Markers)
+	}
+	if m := first.Markers[0]; m.Name != "Scene 2" || m.In != 50 || m.Comment != "Tags: beach, sunset\nlook at that" {
+		t.Errorf("marker = %+v", m)
+	}
+}
+
+func TestGenerateXMEML_Audio(t *testing.T) {
+	clips := []ResolvedClip{
+		{ClipName: "Stereo", MediaPath: `C:\Footage\a.mov`, StartMs: 0, EndMs: 1001, FrameRate: 29.97, HasAudio: true, AudioChannels: 2},
+		{ClipName: "Silent", MediaPath: "/b.mov", StartMs: 0, EndMs: 1001, FrameRate: 29.97},
+	}
+
+	doc, err := GenerateXMEML(clips, "Audio", 29.97, true)
+	if err != nil {
+		t.Fatalf("GenerateXMEML error: %v", err)
+	}
+	if !strings.Contains(doc, "<displayformat>DF</displayformat>") || !strings.Contains(doc, "<string>00:00:00;00</string>") {
+		t.Errorf("missing drop-frame timecode:\n%s", doc)
+	}
+	if !strings.Contains(doc, "file://localhost/C:/Footage/a.mov") {
+		t.Errorf("missing windows pathurl:\n%s", doc)
+	}
+
+	var parsed xmemlDoc
+	if err := xml.Unmarshal([]byte(doc), &parsed); err != nil {
+		t.Fatalf("output is not valid XML: %v", err)
+	}
+	audio := parsed.Sequence.Media.Audio
+	if audio == nil || len(audio.Tracks) != 2 {
+		t.Fatalf("audio = %+v, want 2 tracks", audio)
+	}
+	for ch, track := range audio.Tracks {
+		if len(track.ClipItems) != 1 {
+			t.Fatalf("A%d clip items = %d, want 1 (silent clip has no audio)", ch+1, len(track.ClipItems))
+		}
+		item := track.ClipItems[0]
+		if item.SourceTrack == nil || item.SourceTrack.TrackIndex != ch+1 || item.End != 30 {
+			t.Errorf("A%d clip = %+v", ch+1, item)
+		}
+	}
+
+	video := parsed.Sequence.Media.Video.Tracks[0].ClipItems
+	if len(video[0].Links) != 3 || video[0].Links[2].LinkClipRef != audio.Tracks[1].ClipItems[0].ID {
+		t.Errorf("video links = %+v", video[0].Links)
+	}
+	if len(video[1].Links) != 0 {
+		t.Errorf("silent clip links = %+v", video[1].Links)
+	}
+}
@@ -2,11 +2,16 @@ package pipeline
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
+	"math"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -14,17 +19,42 @@ type FFmpeg interface {
 	Probe(filePath string) (*ProbeResult, error)
 	GenerateThumbnail(filePath, outputPath string, timeOffset float64) error
 	ExtractAudio(filePath, outputPath string) error
+	GenerateProxy(ctx context.Context, filePath, outputPath string, height int) error
+	GenerateSprites(ctx context.Context, filePath, outputPattern string, layout SpriteLayout) error
+}
+
+// SpriteLayout describes thumbnail sprite sheets: one tile every Interval
+// seconds, TileWidth x TileHeight pixels, Columns x Rows tiles per sheet.
+type SpriteLayout struct {
+	Interval   float64
+	TileWidth  int
+	TileHeight int
+	Columns    int
+	Rows       int
+}
+
+// TilesPerSheet is the number of tiles on one sheet.
+func (l SpriteLayout) TilesPerSheet() int {
+	return l.Columns * l.Rows
 }
 
 type ProbeResult struct {
-	Duration    float64
-	Width       int
-	Height      int
-	Codec       string
-	Bitrate     int64
-	FrameRate   float64
-	AudioCodec  string
-	AudioSample int
+	Duration      float64
+	Width         int
+	Height        int
+	Codec         string
+	PixelFormat   string
+	Bitrate       int64
+	FrameRate     float64
+	AudioCodec    string
+	AudioSample   int
+	AudioChannels int
+	// Timecode is the embedded start timecode, e.g. "01:00:00;00" for drop
+	// frame, and ReelName the tape or reel name; both empty when absent.
+	Timecode string
+	ReelName string
+	// Container is ffprobe's format name, e.g. "mov,mp4,m4a,3gp,3g2,mj2".
+	Container string
 }
 
 type StubFFmpeg struct {
@@ -32,8 +62,9 @@ type StubFFmpeg struct {
 }
 
 type RealFFmpeg struct {
-	ffmpegBin string
-	logger    *slog.Logger
+	ffmpegBin  string
+	ffprobeBin string
+	logger     *slog.Logger
 }
 
 func NewStubFFmpeg(logger *slog.Logger) *StubFFmpeg {
@@ -45,11 +76,130 @@ func NewRealFFmpeg(logger *slog.Logger) *RealFFmpeg {
 	if p, err := exec.LookPath("ffmpeg"); err == nil {
 		bin = p
 	}
-	return &RealFFmpeg{ffmpegBin: bin, logger: logger}
+	return &RealFFmpeg{ffmpegBin: bin, ffprobeBin: ffprobePath(bin), logger: logger}
+}
+
+// ffprobePath prefers ffprobe from PATH, then the one installed next to
+// ffmpeg.
+func ffprobePath(ffmpegBin string) string {
+	if p, err := exec.LookPath("ffprobe"); err == nil {
+		return p
+	}
+	if filepath.IsAbs(ffmpegBin) {
+		name := "ffprobe" + strings.TrimPrefix(filepath.Base(ffmpegBin), "ffmpeg")
+		candidate := filepath.Join(filepath.Dir(ffmpegBin), name)
+		if _, err := os.Stat(candidate); err == nil {
+			return candidate
+		}
+	}
+	return "ffprobe"
 }
 
 func (f *RealFFmpeg) Probe(filePath string) (*ProbeResult, error) {
-	return &ProbeResult{}, nil
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, f.ffprobeBin,
+		"-v", "error",
+		"-print_format", "json",
+		"-show_format",
+		"-show_streams",
+		filePath,
+	)
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("ffprobe failed: %w", err)
+	}
+	return parseProbeOutput(out)
+}
+
+type ffprobeOutput struct {
+	Streams []struct {
+		CodecType    string `json:"codec_type"`
+		CodecName    string `json:"codec_name"`
+		Width        int    `json:"width"`
+		Height       int    `json:"height"`
+		PixFmt       string `json:"pix_fmt"`
+		AvgFrameRate string `json:"avg_frame_rate"`
+		RFrameRate   string `json:"r_frame_rate"`
+		SampleRate   string `json:"sample_rate"`
+		Channels     int    `json:"channels"`
+		Duration     string `json:"duration"`
+		Disposition  struct {
+			AttachedPic int `json:"attached_pic"`
+		} `json:"disposition"`
+		Tags map[string]string `json:"tags"`
+	} `json:"streams"`
+	Format struct {
+		FormatName string            `json:"format_name"`
+		Duration   string            `json:"duration"`
+		BitRate    string            `json:"bit_rate"`
+		Tags       map[string]string `json:"tags"`
+	} `json:"format"`
+}
+
+// parseProbeOutput reads the first video and audio streams from ffprobe's
+// JSON output. Cover art is reported as a video stream and is skipped. The
+// start timecode and reel name come from the first stream tagged with them,
+// usually the QuickTime tmcd track, then from the container tags.
+func parseProbeOutput(data []byte) (*ProbeResult, error) {
+	var out ffprobeOutput
+	if err := json.Unmarshal(data, &out); err != nil {
+		return nil, fmt.Errorf("parse ffprobe output: %w", err)
+	}
+
+	result := &ProbeResult{Container: out.Format.FormatName}
+	result.Duration, _ = strconv.ParseFloat(out.Format.Duration, 64)
+	result.Bitrate, _ = strconv.ParseInt(out.Format.BitRate, 10, 64)
+
+	for _, s := range out.Streams {
+		switch {
+		case s.CodecType == "video" && result.Codec == "" && s.Disposition.AttachedPic == 0:
+			result.Codec = s.CodecName
+			result.Width = s.Width
+			result.Height = s.Height
+			result.PixelFormat = s.PixFmt
+			result.FrameRate = parseFrameRate(s.AvgFrameRate)
+			if result.FrameRate == 0 {
+				result.FrameRate = parseFrameRate(s.RFrameRate)
+			}
+			if result.Duration == 0 {
+				result.Duration, _ = strconv.ParseFloat(s.Duration, 64)
+			}
+		case s.CodecType == "audio" && result.AudioCodec == "":
+			result.AudioCodec = s.CodecName
+			result.AudioSample, _ = strconv.Atoi(s.SampleRate)
+			result.AudioChannels = s.Channels
+		}
+		if result.Timecode == "" {
+			result.Timecode = s.Tags["timecode"]
+		}
+		if result.ReelName == "" {
+			result.ReelName = s.Tags["reel_name"]
+		}
+	}
+	if result.Timecode == "" {
+		result.Timecode = out.Format.Tags["timecode"]
+	}
+	if result.ReelName == "" {
+		result.ReelName = out.Format.Tags["reel_name"]
+	}
+	return result, nil
+}
+
+// parseFrameRate parses ffprobe rates such as "30000/1001".
+func parseFrameRate(rate string) float64 {
+	num, den, ok := strings.Cut(rate, "/")
+	if !ok {
+		v, _ := strconv.ParseFloat(rate, 64)
+		return v
+	}
+	n, err1 := strconv.ParseFloat(num, 64)
+	d, err2 := strconv.ParseFloat(den, 64)
+	if err1 != nil || err2 != nil || d == 0 {
+		return 0
+	}
+	return n / d
 }
 
 func (f *RealFFmpeg) GenerateThumbnail(filePath, outputPath string, timeOffset float64) error {
@@ -79,8 +229,262 @@ func (f *RealFFmpeg) GenerateThumbnail(filePath, outputPath string, timeOffset f
 	return nil
 }
 
+// ExtractFrame writes the frame at `at` seconds as a single image, scaled
+// to width pixels wide when width > 0. The format follows outputPath's
+// extension: .webp or .jpg. Seeking past the end makes ffmpeg succeed
+// without writing anything, which is reported as an error.
+func (f *RealFFmpeg) ExtractFrame(ctx context.Context, filePath, outputPath string, at float64, width int) error {
+	if err := os.MkdirAll(filepath.Dir(outputPath), 0o755); err != nil {
+		return fmt.Errorf("create frame dir: %w", err)
+	}
+
+	args := []string{"-y",
+		"-ss", fmt.Sprintf("%.3f", at),
+		"-i", filePath,
+		"-frames:v", "1",
+		"-an",
+	}
+	if width > 0 {
+		args = append(args, "-vf", fmt.Sprintf("scale=%d:-2", width))
+	}
+	if strings.EqualFold(filepath.Ext(outputPath), ".webp") {
+		args = append(args, "-c:v", "libwebp", "-quality", "80")
+	} else {
+		args = append(args, "-q:v", "3")
+	}
+	args = append(args, "-f", "image2", "-update", "1", outputPath)
+
+	out, err := exec.CommandContext(ctx, f.ffmpegBin, args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("ffmpeg frame failed: %w: %s", err, truncateOutput(out))
+	}
+	info, err := os.Stat(outputPath)
+	if err != nil || info.Size() == 0 {
+		return fmt.Errorf("no frame at %.3fs", at)
+	}
+	return nil
+}
+
+// TranscodeSegment encodes duration seconds of filePath, starting at start,
+// as an H.264/AAC MPEG-TS segment no taller than 1080 lines. Timestamps are
+// offset by start so consecutive segments play as one stream.
+func (f *RealFFmpeg) TranscodeSegment(ctx context.Context, filePath, outputPath string, start, duration float64) error {
+	if err := os.MkdirAll(filepath.Dir(outputPath), 0o755); err != nil {
+		return fmt.Errorf("create segment dir: %w", err)
+	}
+
+	offset := fmt.Sprintf("%.3f", start)
+	cmd := exec.CommandContext(ctx, f.ffmpegBin, "-y",
+		"-ss", offset,
+		"-i", filePath,
+		"-t", fmt.Sprintf("%.3f", duration),
+		"-map", "0:v:0",
+		"-map", "0:a:0?",
+		"-vf", "scale=-2:'min(1080,ih)'",
+		"-c:v", "libx264",
+		"-preset", "veryfast",
+		"-pix_fmt", "yuv420p",
+		"-c:a", "aac",
+		"-ac", "2",
+		"-b:a", "128k",
+		"-output_ts_offset", offset,
+		"-muxdelay", "0",
+		"-f", "mpegts",
+		outputPath,
+	)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("ffmpeg transcode failed: %w: %s", err, truncateOutput(out))
+	}
+	return nil
+}
+
+// KeyframeBefore returns the time of the last video keyframe at or before
+// at, looking back at most ten seconds.
+func (f *RealFFmpeg) KeyframeBefore(ctx context.Context, filePath string, at float64) (float64, error) {
+	from := math.Max(0, at-10)
+	cmd := exec.CommandContext(ctx, f.ffprobeBin,
+		"-v", "error",
+		"-select_streams", "v:0",
+		"-skip_frame", "nokey",
+		"-read_intervals", fmt.Sprintf("%.3f%%%.3f", from, at+0.001),
+		"-show_entries", "frame=pts_time",
+		"-of", "csv=p=0",
+		filePath,
+	)
+	out, err := cmd.Output()
+	if err != nil {
+		return 0, fmt.Errorf("ffprobe keyframes failed: %w", err)
+	}
+	kf, ok := lastKeyframeBefore(string(out), at)
+	if !ok {
+		return 0, fmt.Errorf("no keyframe within 10s before %.3fs", at)
+	}
+	return kf, nil
+}
+
+// lastKeyframeBefore picks the latest timestamp not after at from ffprobe's
+// one-per-line CSV output.
+func lastKeyframeBefore(output string, at float64) (float64, bool) {
+	best, found := 0.0, false
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(line), ","))
+		if line == "" {
+			continue
+		}
+		t, err := strconv.ParseFloat(line, 64)
+		if err != nil || t > at+0.0005 {
+			continue
+		}
+		if !found || t > best {
+			best, found = t, true
+		}
+	}
+	return best, found
+}
+
+// CutClip writes duration seconds of filePath from start as a faststart
+// MP4. With copyStreams the packets are copied unchanged, which is only
+// frame-accurate when start is a keyframe; otherwise the clip is encoded
+// to H.264/AAC.
+func (f *RealFFmpeg) CutClip(ctx context.Context, filePath, outputPath string, start, duration float64, copyStreams bool) error {
+	if err := os.MkdirAll(filepath.Dir(outputPath), 0o755); err != nil {
+		return fmt.Errorf("create clip dir: %w", err)
+	}
+
+	args := []string{"-y",
+		"-ss", fmt.Sprintf("%.3f", start),
+		"-i", filePath,
+		"-t", fmt.Sprintf("%.3f", duration),
+		"-map", "0:v:0",
+		"-map", "0:a:0?",
+	}
+	if copyStreams {
+		args = append(args, "-c", "copy", "-avoid_negative_ts", "make_zero")
+	} else {
+		args = append(args,
+			"-c:v", "libx264",
+			"-preset", "veryfast",
+			"-crf", "20",
+			"-pix_fmt", "yuv420p",
+			"-c:a", "aac",
+			"-ac", "2",
+			"-b:a", "160k",
+		)
+	}
+	args = append(args, "-movflags", "+faststart", "-f", "mp4", outputPath)
+
+	out, err := exec.CommandContext(ctx, f.ffmpegBin, args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("ffmpeg clip failed: %w: %s", err, truncateOutput(out))
+	}
+	return nil
+}
+
+// GenerateProxy writes a H.264/AAC MP4 no taller than height lines, with a
+// keyframe every two seconds so players can seek it cheaply.
+func (f *RealFFmpeg) GenerateProxy(ctx context.Context, filePath, outputPath string, height int) error {
+	if err := os.MkdirAll(filepath.Dir(outputPath), 0o755); err != nil {
+		return fmt.Errorf("create proxy dir: %w", err)
+	}
+
+	cmd := exec.CommandContext(ctx, f.ffmpegBin, "-y",
+		"-i", filePath,
+		"-map", "0:v:0",
+		"-map", "0:a:0?",
+		"-vf", fmt.Sprintf("scale=-2:'min(%d,ih)'", height),
+		"-c:v", "libx264",
+		"-preset", "veryfast",
+		"-crf", "26",
+		"-pix_fmt", "yuv420p",
+		"-force_key_frames", "expr:gte(t,n_forced*2)",
+		"-c:a", "aac",
+		"-ac", "2",
+		"-b:a", "128k",
+		"-movflags", "+faststart",
+		"-f", "mp4",
+		outputPath,
+	)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("ffmpeg proxy failed: %w: %s", err, truncateOutput(out))
+	}
+	return nil
+}
+
+// GenerateSprites writes tiled JPEG sprite sheets numbered from 0 using
+// outputPattern, e.g. "sprite_%03d.jpg". Only keyframes are decoded, which
+// keeps long 4K files cheap; each tile shows the latest keyframe at or
+// before its time. Tiles are letterboxed to the layout's size.
+func (f *RealFFmpeg) GenerateSprites(ctx context.Context, filePath, outputPattern string, layout SpriteLayout) error {
+	if err := os.MkdirAll(filepath.Dir(outputPattern), 0o755); err != nil {
+		return fmt.Errorf("create sprite dir: %w", err)
+	}
+
+	w, h := layout.TileWidth, layout.TileHeight
+	filter := fmt.Sprintf(
+		"fps=1/%g,scale=%d:%d:force_original_aspect_ratio=decrease,pad=%d:%d:(ow-iw)/2:(oh-ih)/2,tile=%dx%d",
+		layout.Interval, w, h, w, h, layout.Columns, layout.Rows,
+	)
+	cmd := exec.CommandContext(ctx, f.ffmpegBin, "-y",
+		"-skip_frame", "nokey",
+		"-i", filePath,
+		"-map", "0:v:0",
+		"-vf", filter,
+		"-q:v", "5",
+		"-start_number", "0",
+		"-f", "image2",
+		outputPattern,
+	)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("ffmpeg sprites failed: %w: %s", err, truncateOutput(out))
+	}
+	return nil
+}
+
+// truncateOutput keeps the tail of ffmpeg's log, where the error is.
+func truncateOutput(out []byte) string {
+	const max = 2048
+	if len(out) > max {
+		out = out[len(out)-max:]
+	}
+	return string(out)
+}
+
+// AudioSampleRate is the rate ExtractAudio resamples to.
+const AudioSampleRate = 16000
+
+// ErrNoAudio is returned by ExtractAudio for files without an audio stream.
+var ErrNoAudio = errors.New("no audio stream")
+
+// ExtractAudio writes the first audio stream as mono 16-bit PCM WAV at
+// AudioSampleRate.
 func (f *RealFFmpeg) ExtractAudio(filePath, outputPath string) error {
-	f.logger.Info("ffmpeg: audio extraction not yet implemented", "input", filePath)
+	if err := os.MkdirAll(filepath.Dir(outputPath), 0o755); err != nil {
+		return fmt.Errorf("create audio dir: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, f.ffmpegBin, "-y",
+		"-i", filePath,
+		"-map", "0:a:0",
+		"-vn",
+		"-ac", "1",
+		"-ar", strconv.Itoa(AudioSampleRate),
+		"-c:a", "pcm_s16le",
+		"-f", "wav",
+		outputPath,
+	)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		if strings.Contains(string(out), "matches no streams") {
+			return ErrNoAudio
+		}
+		return fmt.Errorf("ffmpeg audio extraction failed: %w: %s", err, truncateOutput(out))
+	}
 	return nil
 }
 
@@ -101,3 +505,15 @@ func (f *StubFFmpeg) ExtractAudio(filePath, outputPath string) error {
 		"input", filePath, "output", outputPath)
 	return nil
 }
+
+func (f *StubFFmpeg) GenerateProxy(ctx context.Context, filePath, outputPath string, height int) error {
+	f.logger.Info("ffmpeg stub: proxy requested",
+		"input", filePath, "output", outputPath, "height", height)
+	return nil
+}
+
+func (f *StubFFmpeg) GenerateSprites(ctx context.Context, filePath, outputPattern string, layout SpriteLayout) error {
+	f.logger.Info("ffmpeg stub: sprites requested",
+		"input", filePath, "output", outputPattern, "interval", layout.Interval)
+	return nil
+}
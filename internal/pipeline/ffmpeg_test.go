@@ -0,0 +1,66 @@
+package pipeline
+
+import (
+	"math"
+	"testing"
+)
+
+func TestParseProbeOutput(t *testing.T) {
+	data := []byte(`{
+		"streams": [
+			{"codec_type": "video", "codec_name": "mjpeg", "disposition": {"attached_pic": 1}},
+			{"codec_type": "video", "codec_name": "prores", "width": 3840, "height": 2160,
+			 "pix_fmt": "yuv422p10le", "avg_frame_rate": "30000/1001", "r_frame_rate": "30000/1001"},
+			{"codec_type": "audio", "codec_name": "pcm_s24le", "sample_rate": "48000", "channels": 2},
+			{"codec_type": "audio", "codec_name": "aac", "sample_rate": "44100"},
+			{"codec_type": "data", "codec_name": "none", "tags": {"timecode": "01:00:00;00", "reel_name": "A001"}}
+		],
+		"format": {"format_name": "mov,mp4,m4a,3gp,3g2,mj2", "duration": "125.458", "bit_rate": "700000000",
+		           "tags": {"timecode": "00:00:00:00"}}
+	}`)
+
+	got, err := parseProbeOutput(data)
+	if err != nil {
+		t.Fatalf("parseProbeOutput: %v", err)
+	}
+	if got.Codec != "prores" || got.Width != 3840 || got.Height != 2160 || got.PixelFormat != "yuv422p10le" {
+		t.Errorf("video = %+v", got)
+	}
+	if math.Abs(got.FrameRate-29.97) > 0.01 {
+		t.Errorf("FrameRate = %v, want 29.97", got.FrameRate)
+	}
+	if got.AudioCodec != "pcm_s24le" || got.AudioSample != 48000 || got.AudioChannels != 2 {
+		t.Errorf("audio = %q @ %d x%d", got.AudioCodec, got.AudioSample, got.AudioChannels)
+	}
+	if got.Duration != 125.458 || got.Bitrate != 700000000 {
+		t.Errorf("duration/bitrate = %v/%v", got.Duration, got.Bitrate)
+	}
+	if got.Timecode != "01:00:00;00" || got.ReelName != "A001" {
+		t.Errorf("timecode/reel = %q/%q, want the tmcd track's", got.Timecode, got.ReelName)
+	}
+	if got.Container != "mov,mp4,m4a,3gp,3g2,mj2" {
+		t.Errorf("Container = %q", got.Container)
+	}
+}
+
+func TestParseFrameRate(t *testing.T) {
+	tests := map[string]float64{"25/1": 25, "24": 24, "0/0": 0, "": 0}
+	for in, want := range tests {
+		if got := parseFrameRate(in); got != want {
+			t.Errorf("parseFrameRate(%q) = %v, want %v", in, got, want)
+		}
+	}
+}
+
+func TestLastKeyframeBefore(t *testing.T) {
+	out := "0.000000\n2.002000,\n4.004000\n\n6.006000\n"
+	if got, ok := lastKeyframeBefore(out, 5); !ok || got != 4.004 {
+		t.Errorf("lastKeyframeBefore(5) = %v, %v; want 4.004", got, ok)
+	}
+	if got, ok := lastKeyframeBefore(out, 6.006); !ok || got != 6.006 {
+		t.Errorf("lastKeyframeBefore(6.006) = %v, %v; want 6.006", got, ok)
+	}
+	if _, ok := lastKeyframeBefore("", 5); ok {
+		t.Error("lastKeyframeBefore on empty output reported a keyframe")
+	}
+}
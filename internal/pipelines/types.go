@@ -2,7 +2,11 @@
 // Python CLI commands (doctor, faces, speech) with structured result parsing.
 package pipelines
 
-import "time"
+import (
+	"encoding/json"
+	"math"
+	"time"
+)
 
 // Capabilities represents what the installed Python pipelines can do,
 // as reported by the `doctor --json` command.
@@ -84,6 +88,49 @@ type SceneOutputPayload struct {
 	Scenes          []SceneBoundary `json:"scenes"`
 }
 
+// SpeechOutputPayload is the speech pipeline's transcript.
+type SpeechOutputPayload struct {
+	PipelineOutput
+	Language string          `json:"language,omitempty"`
+	Segments []SpeechSegment `json:"segments"`
+}
+
+// SpeechSegment is one transcribed utterance. Older pipeline versions time
+// segments in float seconds ("start", "end") rather than milliseconds; both
+// are read into StartMs and EndMs.
+type SpeechSegment struct {
+	StartMs int    `json:"start_ms"`
+	EndMs   int    `json:"end_ms"`
+	Text    string `json:"text"`
+}
+
+func (s *SpeechSegment) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		StartMs *int     `json:"start_ms"`
+		EndMs   *int     `json:"end_ms"`
+		Start   *float64 `json:"start"`
+		End     *float64 `json:"end"`
+		Text    string   `json:"text"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	*s = SpeechSegment{Text: raw.Text}
+	switch {
+	case raw.StartMs != nil:
+		s.StartMs = *raw.StartMs
+	case raw.Start != nil:
+		s.StartMs = int(math.Round(*raw.Start * 1000))
+	}
+	switch {
+	case raw.EndMs != nil:
+		s.EndMs = *raw.EndMs
+	case raw.End != nil:
+		s.EndMs = int(math.Round(*raw.End * 1000))
+	}
+	return nil
+}
+
 type SceneBoundary struct {
 	SceneID             string   `json:"scene_id"`
 	Index               int      `json:"index"`
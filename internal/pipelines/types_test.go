@@ -0,0 +1,32 @@
+package pipelines
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestSpeechSegmentUnmarshal(t *testing.T) {
+	data := []byte(`{"segments": [
+		{"start_ms": 1200, "end_ms": 3400, "text": "milliseconds"},
+		{"start": 3.4, "end": 5.25, "text": "seconds"},
+		{"start_ms": 6000, "start": 9.0, "end_ms": 7000, "text": "both"}
+	]}`)
+
+	var out SpeechOutputPayload
+	if err := json.Unmarshal(data, &out); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	want := []SpeechSegment{
+		{StartMs: 1200, EndMs: 3400, Text: "milliseconds"},
+		{StartMs: 3400, EndMs: 5250, Text: "seconds"},
+		{StartMs: 6000, EndMs: 7000, Text: "both"},
+	}
+	if len(out.Segments) != len(want) {
+		t.Fatalf("segments = %+v", out.Segments)
+	}
+	for i := range want {
+		if out.Segments[i] != want[i] {
+			t.Errorf("segment %d = %+v, want %+v", i, out.Segments[i], want[i])
+		}
+	}
+}
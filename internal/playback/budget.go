@@ -0,0 +1,184 @@
+package playback
+
+import (
+	"context"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// cacheBudgetInterval is how often the budget is enforced in the background.
+// Caches may overshoot by what is written in between.
+const cacheBudgetInterval = time.Minute
+
+// CacheEntry is one evictable unit of a cache: all HLS segments, clips or
+// frames cached for a file, or one proxy.
+type CacheEntry struct {
+	Key      string
+	Size     int64
+	LastUsed time.Time
+	// InUse entries are being written and are never evicted.
+	InUse bool
+}
+
+// CacheSource is a cache whose entries count against a CacheBudget.
+type CacheSource interface {
+	CacheEntries() ([]CacheEntry, error)
+	EvictCacheEntry(key string) error
+}
+
+// CacheBudget keeps every cache under the cache dir within one byte budget,
+// evicting the least recently used entries first, whichever cache they
+// belong to.
+type CacheBudget struct {
+	maxBytes int64
+	logger   *slog.Logger
+
+	mu      sync.Mutex
+	sources []CacheSource
+}
+
+// NewCacheBudget returns a budget of maxBytes; 0 or less means no cap.
+func NewCacheBudget(maxBytes int64, logger *slog.Logger) *CacheBudget {
+	return &CacheBudget{maxBytes: maxBytes, logger: logger}
+}
+
+// MaxBytes returns the budget, or 0 when there is no cap.
+func (b *CacheBudget) MaxBytes() int64 {
+	if b.maxBytes < 0 {
+		return 0
+	}
+	return b.maxBytes
+}
+
+// Add puts a cache under the budget.
+func (b *CacheBudget) Add(s CacheSource) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.sources = append(b.sources, s)
+}
+
+// Run enforces the budget every cacheBudgetInterval until ctx is cancelled.
+func (b *CacheBudget) Run(ctx context.Context) {
+	b.Enforce()
+	ticker := time.NewTicker(cacheBudgetInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			b.Enforce()
+		}
+	}
+}
+
+// Enforce evicts least recently used entries until the caches together fit
+// the budget, and returns how many were evicted.
+func (b *CacheBudget) Enforce() int {
+	if b.maxBytes <= 0 {
+		return 0
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	type owned struct {
+		CacheEntry
+		source CacheSource
+	}
+	var entries []owned
+	var total int64
+	for _, s := range b.sources {
+		list, err := s.CacheEntries()
+		if err != nil {
+			b.logger.Warn("cache budget: cannot list entries", "error", err)
+			continue
+		}
+		for _, e := range list {
+			total += e.Size
+			entries = append(entries, owned{CacheEntry: e, source: s})
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].LastUsed.Before(entries[j].LastUsed) })
+
+	evicted := 0
+	for _, e := range entries {
+		if total <= b.maxBytes {
+			break
+		}
+		if e.InUse {
+			continue
+		}
+		if err := e.source.EvictCacheEntry(e.Key); err != nil {
+			b.logger.Warn("cache budget: evict failed", "key", e.Key, "error", err)
+			continue
+		}
+		total -= e.Size
+		evicted++
+		b.logger.Info("cache entry evicted", "key", e.Key, "bytes", e.Size)
+	}
+	return evicted
+}
+
+// dirCacheEntries lists the per-file directories under dir as cache
+// entries. An entry was last used at the newest mtime in it; cache hits
+// touch the directory itself. Entries with a build in flight are in use.
+func dirCacheEntries(dir string, builds *buildGroup) ([]CacheEntry, error) {
+	dirs, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []CacheEntry
+	for _, d := range dirs {
+		if !d.IsDir() {
+			continue
+		}
+		root := filepath.Join(dir, d.Name())
+		entry := CacheEntry{Key: d.Name(), InUse: builds.busy(root + string(filepath.Separator))}
+		filepath.WalkDir(root, func(path string, de fs.DirEntry, err error) error {
+			if err != nil {
+				return nil
+			}
+			info, err := de.Info()
+			if err != nil {
+				return nil
+			}
+			if !de.IsDir() {
+				entry.Size += info.Size()
+			}
+			if info.ModTime().After(entry.LastUsed) {
+				entry.LastUsed = info.ModTime()
+			}
+			return nil
+		})
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// touchEntry marks the file's cache entry under cacheDir as used now.
+func touchEntry(cacheDir, fileID string) {
+	now := time.Now()
+	os.Chtimes(filepath.Join(cacheDir, fileID), now, now)
+}
+
+// busy reports whether a build is in flight for a key starting with prefix.
+func (g *buildGroup) busy(prefix string) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for key := range g.inflight {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
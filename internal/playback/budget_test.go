@@ -0,0 +1,95 @@
+package playback
+
+import (
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// dirCache is a cache laid out like the HLS, clip and frame caches.
+type dirCache struct {
+	dir    string
+	builds buildGroup
+}
+
+func (c *dirCache) CacheEntries() ([]CacheEntry, error) {
+	return dirCacheEntries(c.dir, &c.builds)
+}
+
+func (c *dirCache) EvictCacheEntry(key string) error {
+	return os.RemoveAll(filepath.Join(c.dir, key))
+}
+
+func writeCacheEntry(t *testing.T, dir, key string, size int, used time.Time) {
+	t.Helper()
+	path := filepath.Join(dir, key, "0", "out.bin")
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, make([]byte, size), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range []string{path, filepath.Dir(path), filepath.Join(dir, key)} {
+		if err := os.Chtimes(p, used, used); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestCacheBudget_EvictsLeastRecentlyUsedAcrossCaches(t *testing.T) {
+	hls := &dirCache{dir: t.TempDir()}
+	frames := &dirCache{dir: t.TempDir()}
+	now := time.Now()
+	writeCacheEntry(t, hls.dir, "old", 100, now.Add(-3*time.Hour))
+	writeCacheEntry(t, frames.dir, "older", 100, now.Add(-4*time.Hour))
+	writeCacheEntry(t, hls.dir, "new", 100, now.Add(-time.Hour))
+	writeCacheEntry(t, frames.dir, "played", 100, now.Add(-5*time.Hour))
+	touchEntry(frames.dir, "played")
+
+	budget := NewCacheBudget(250, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	budget.Add(hls)
+	budget.Add(frames)
+	if n := budget.Enforce(); n != 2 {
+		t.Fatalf("Enforce evicted %d entries, want 2", n)
+	}
+
+	for _, tc := range []struct {
+		dir, key string
+		kept     bool
+	}{
+		{frames.dir, "older", false},
+		{hls.dir, "old", false},
+		{hls.dir, "new", true},
+		{frames.dir, "played", true},
+	} {
+		_, err := os.Stat(filepath.Join(tc.dir, tc.key))
+		if kept := err == nil; kept != tc.kept {
+			t.Errorf("%s kept = %v, want %v", tc.key, kept, tc.kept)
+		}
+	}
+}
+
+func TestCacheBudget_SkipsEntriesBeingBuilt(t *testing.T) {
+	cache := &dirCache{dir: t.TempDir()}
+	now := time.Now()
+	writeCacheEntry(t, cache.dir, "building", 100, now.Add(-2*time.Hour))
+	writeCacheEntry(t, cache.dir, "idle", 100, now.Add(-time.Hour))
+
+	key := filepath.Join(cache.dir, "building", "1", "out.bin")
+	b, _ := cache.builds.claim(key)
+	defer cache.builds.finish(key, b, nil)
+
+	budget := NewCacheBudget(150, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	budget.Add(cache)
+	budget.Enforce()
+
+	if _, err := os.Stat(filepath.Join(cache.dir, "building")); err != nil {
+		t.Errorf("entry being built was evicted: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(cache.dir, "idle")); !os.IsNotExist(err) {
+		t.Errorf("idle entry kept, want evicted")
+	}
+}
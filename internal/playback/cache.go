@@ -0,0 +1,138 @@
+package playback
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/heimdex/heimdex-agent/internal/pipeline"
+)
+
+// probeCache remembers probe results per path until the file's size or
+// mtime changes.
+type probeCache struct {
+	probe func(filePath string) (*pipeline.ProbeResult, error)
+
+	mu      sync.Mutex
+	entries map[string]cachedProbe
+}
+
+type cachedProbe struct {
+	stamp  string
+	result *pipeline.ProbeResult
+}
+
+func newProbeCache(probe func(filePath string) (*pipeline.ProbeResult, error)) *probeCache {
+	return &probeCache{probe: probe, entries: make(map[string]cachedProbe)}
+}
+
+// Prober probes media files.
+type Prober interface {
+	Probe(filePath string) (*pipeline.ProbeResult, error)
+}
+
+// NewProber returns a Prober that caches p's results per path until the
+// file's size or mtime changes.
+func NewProber(p Prober) Prober {
+	return newProbeCache(p.Probe)
+}
+
+func (c *probeCache) Probe(filePath string) (*pipeline.ProbeResult, error) {
+	return c.get(filePath)
+}
+
+func (c *probeCache) get(filePath string) (*pipeline.ProbeResult, error) {
+	stamp, err := fileStamp(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	cached, ok := c.entries[filePath]
+	c.mu.Unlock()
+	if ok && cached.stamp == stamp {
+		return cached.result, nil
+	}
+
+	result, err := c.probe(filePath)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	c.entries[filePath] = cachedProbe{stamp: stamp, result: result}
+	c.mu.Unlock()
+	return result, nil
+}
+
+// buildGroup lets concurrent requests for the same cached output share one
+// ffmpeg run.
+type buildGroup struct {
+	mu       sync.Mutex
+	inflight map[string]*build
+}
+
+type build struct {
+	done chan struct{}
+	err  error
+}
+
+// claim returns the in-flight build for key, registering a new one when
+// none is running. owner is true when the caller must run the build and
+// then call finish.
+func (g *buildGroup) claim(key string) (b *build, owner bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.inflight == nil {
+		g.inflight = make(map[string]*build)
+	}
+	if b, ok := g.inflight[key]; ok {
+		return b, false
+	}
+	b = &build{done: make(chan struct{})}
+	g.inflight[key] = b
+	return b, true
+}
+
+func (g *buildGroup) finish(key string, b *build, err error) {
+	b.err = err
+	g.mu.Lock()
+	delete(g.inflight, key)
+	g.mu.Unlock()
+	close(b.done)
+}
+
+// do runs fn once for key and waits for it, joining a build already in
+// flight.
+func (g *buildGroup) do(key string, fn func() error) error {
+	b, owner := g.claim(key)
+	if owner {
+		g.finish(key, b, fn())
+	}
+	<-b.done
+	return b.err
+}
+
+// fileStamp identifies a version of a file by size and mtime. Cached
+// outputs live under a directory named after it.
+func fileStamp(filePath string) (string, error) {
+	info, err := os.Stat(filePath)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%x-%x", info.Size(), info.ModTime().UnixNano()), nil
+}
+
+// pruneStale removes the subdirectories of dir other than keep, i.e. outputs
+// cached for older versions of a file.
+func pruneStale(dir, keep string) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+	for _, e := range entries {
+		if e.IsDir() && e.Name() != keep {
+			os.RemoveAll(filepath.Join(dir, e.Name()))
+		}
+	}
+}
@@ -0,0 +1,197 @@
+package playback
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/heimdex/heimdex-agent/internal/pipeline"
+)
+
+const (
+	// MaxClipDuration bounds a single clip request.
+	MaxClipDuration = 30 * time.Minute
+
+	// keyframeTolerance is how far before the requested start a keyframe may
+	// sit for a stream copy to count as aligned: about one frame at 25fps.
+	keyframeTolerance = 0.04
+
+	clipTimeout = 10 * time.Minute
+)
+
+var ErrInvalidClip = errors.New("invalid clip bounds")
+
+// ClipCutter is the subset of ffmpeg clips need.
+type ClipCutter interface {
+	Probe(filePath string) (*pipeline.ProbeResult, error)
+	KeyframeBefore(ctx context.Context, filePath string, at float64) (float64, error)
+	CutClip(ctx context.Context, filePath, outputPath string, start, duration float64, copyStreams bool) error
+}
+
+// ClipService serves a time range of a file as a standalone video.
+type ClipService interface {
+	ServeClip(w http.ResponseWriter, r *http.Request, fileID, filePath string, startMs, endMs int) error
+}
+
+// Clip modes reported in the X-Clip-Mode header.
+const (
+	ClipModeRemux     = "remux"
+	ClipModeTranscode = "transcode"
+)
+
+// Clips cuts a range of a file into a faststart MP4 whose duration is the
+// range's, so the browser's timeline covers only the clip. Streams are
+// copied when the browser can decode them and a keyframe sits at the start;
+// otherwise the range is encoded to H.264/AAC. Clips are cached under
+// cacheDir/<file_id>/<stamp>/ and served with byte-range support.
+type Clips struct {
+	cutter   ClipCutter
+	cacheDir string
+	files    *Server
+	logger   *slog.Logger
+
+	probes *probeCache
+	builds buildGroup
+}
+
+func NewClips(cutter ClipCutter, cacheDir string, logger *slog.Logger) *Clips {
+	return &Clips{
+		cutter:   cutter,
+		cacheDir: cacheDir,
+		files:    NewServer(logger),
+		logger:   logger,
+		probes:   newProbeCache(cutter.Probe),
+	}
+}
+
+func (c *Clips) ServeClip(w http.ResponseWriter, r *http.Request, fileID, filePath string, startMs, endMs int) error {
+	probe, err := c.probes.get(filePath)
+	if err != nil {
+		return err
+	}
+	start, end, err := ClampClip(startMs, endMs, probe.Duration)
+	if err != nil {
+		return err
+	}
+
+	stamp, err := fileStamp(filePath)
+	if err != nil {
+		return err
+	}
+	dir := filepath.Join(c.cacheDir, fileID, stamp)
+	name := fmt.Sprintf("%d-%d", int(math.Round(start*1000)), int(math.Round(end*1000)))
+	clipPath := filepath.Join(dir, name+".mp4")
+
+	mode, err := c.ensureClip(filePath, clipPath, start, end, probe)
+	if err != nil {
+		return err
+	}
+	touchEntry(c.cacheDir, fileID)
+
+	if mode != "" {
+		w.Header().Set("X-Clip-Mode", mode)
+	}
+	w.Header().Set("Cache-Control", "private, max-age=86400")
+	return c.files.ServeFile(w, r, clipPath)
+}
+
+// CacheEntries lists each file's cached clips for the cache budget.
+func (c *Clips) CacheEntries() ([]CacheEntry, error) {
+	return dirCacheEntries(c.cacheDir, &c.builds)
+}
+
+// EvictCacheEntry removes all clips cached for the file key.
+func (c *Clips) EvictCacheEntry(key string) error {
+	return os.RemoveAll(filepath.Join(c.cacheDir, key))
+}
+
+// ensureClip cuts the clip unless it is cached. mode is empty for a cache
+// hit.
+func (c *Clips) ensureClip(filePath, clipPath string, start, end float64, probe *pipeline.ProbeResult) (string, error) {
+	if _, err := os.Stat(clipPath); err == nil {
+		return "", nil
+	}
+
+	var mode string
+	err := c.builds.do(clipPath, func() error {
+		if _, err := os.Stat(clipPath); err == nil {
+			return nil
+		}
+		stampDir := filepath.Dir(clipPath)
+		pruneStale(filepath.Dir(stampDir), filepath.Base(stampDir))
+		if err := os.MkdirAll(stampDir, 0o755); err != nil {
+			return fmt.Errorf("create clip dir: %w", err)
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), clipTimeout)
+		defer cancel()
+
+		cutStart, copyStreams := c.planCut(ctx, filePath, start, probe)
+		mode = ClipModeTranscode
+		if copyStreams {
+			mode = ClipModeRemux
+		}
+
+		began := time.Now()
+		tmpPath := clipPath + ".tmp"
+		if err := c.cutter.CutClip(ctx, filePath, tmpPath, cutStart, end-cutStart, copyStreams); err != nil {
+			os.Remove(tmpPath)
+			return fmt.Errorf("cut clip: %w", err)
+		}
+		if err := os.Rename(tmpPath, clipPath); err != nil {
+			os.Remove(tmpPath)
+			return fmt.Errorf("store clip: %w", err)
+		}
+		c.logger.Debug("clip cut", "path", filePath, "start", start, "end", end, "mode", mode, "took", time.Since(began))
+		return nil
+	})
+	return mode, err
+}
+
+// planCut decides whether the clip can be stream-copied. It can when the
+// browser decodes the source as-is and a keyframe falls within a frame of
+// start; the cut then begins at that keyframe.
+func (c *Clips) planCut(ctx context.Context, filePath string, start float64, probe *pipeline.ProbeResult) (float64, bool) {
+	if direct, _ := DirectPlayable(filePath, probe); !direct || probe.Codec == "" {
+		return start, false
+	}
+	if start == 0 {
+		return 0, true
+	}
+	kf, err := c.cutter.KeyframeBefore(ctx, filePath, start)
+	if err != nil {
+		c.logger.Debug("keyframe lookup failed; transcoding clip", "path", filePath, "error", err)
+		return start, false
+	}
+	if start-kf > keyframeTolerance {
+		return start, false
+	}
+	return kf, true
+}
+
+// ClampClip validates a clip range in milliseconds against the media
+// duration in seconds and returns it in seconds. An end past the media is
+// clamped to it; an unknown duration leaves the end as requested.
+func ClampClip(startMs, endMs int, duration float64) (start, end float64, err error) {
+	if startMs < 0 || endMs <= startMs {
+		return 0, 0, fmt.Errorf("%w: start_ms must be >= 0 and less than end_ms", ErrInvalidClip)
+	}
+	start = float64(startMs) / 1000
+	end = float64(endMs) / 1000
+	if duration > 0 {
+		if start >= duration {
+			return 0, 0, fmt.Errorf("%w: start_ms is past the end of the media", ErrInvalidClip)
+		}
+		end = math.Min(end, duration)
+	}
+	if time.Duration((end-start)*float64(time.Second)) > MaxClipDuration {
+		return 0, 0, fmt.Errorf("%w: clips are limited to %s", ErrInvalidClip, MaxClipDuration)
+	}
+	return start, end, nil
+}
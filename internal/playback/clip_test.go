@@ -0,0 +1,121 @@
+package playback
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/heimdex/heimdex-agent/internal/pipeline"
+)
+
+type fakeCutter struct {
+	mu       sync.Mutex
+	probe    *pipeline.ProbeResult
+	keyframe float64
+	cuts     []fakeCut
+}
+
+type fakeCut struct {
+	start, duration float64
+	copyStreams     bool
+}
+
+func (f *fakeCutter) Probe(string) (*pipeline.ProbeResult, error) {
+	return f.probe, nil
+}
+
+func (f *fakeCutter) KeyframeBefore(context.Context, string, float64) (float64, error) {
+	return f.keyframe, nil
+}
+
+func (f *fakeCutter) CutClip(_ context.Context, _, outputPath string, start, duration float64, copyStreams bool) error {
+	f.mu.Lock()
+	f.cuts = append(f.cuts, fakeCut{start, duration, copyStreams})
+	f.mu.Unlock()
+	return os.WriteFile(outputPath, []byte("mp4"), 0o644)
+}
+
+func newTestClips(t *testing.T, name string, probe *pipeline.ProbeResult, keyframe float64) (*Clips, *fakeCutter, string) {
+	t.Helper()
+	src := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(src, []byte("video"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	fc := &fakeCutter{probe: probe, keyframe: keyframe}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	return NewClips(fc, t.TempDir(), logger), fc, src
+}
+
+func TestClips_RemuxWhenKeyframeAligned(t *testing.T) {
+	probe := &pipeline.ProbeResult{Duration: 60, Codec: "h264", AudioCodec: "aac"}
+	clips, fc, src := newTestClips(t, "a.mp4", probe, 9.98)
+
+	rr := httptest.NewRecorder()
+	if err := clips.ServeClip(rr, httptest.NewRequest("GET", "/", nil), "file-1", src, 10000, 15000); err != nil {
+		t.Fatalf("ServeClip: %v", err)
+	}
+	if got := rr.Header().Get("X-Clip-Mode"); got != ClipModeRemux {
+		t.Errorf("X-Clip-Mode = %q, want %q", got, ClipModeRemux)
+	}
+	if len(fc.cuts) != 1 || !fc.cuts[0].copyStreams || fc.cuts[0].start != 9.98 {
+		t.Fatalf("cuts = %+v, want one stream copy from the keyframe", fc.cuts)
+	}
+	if rr.Body.String() != "mp4" {
+		t.Errorf("body = %q", rr.Body.String())
+	}
+
+	// A second request is served from the cache.
+	rr = httptest.NewRecorder()
+	if err := clips.ServeClip(rr, httptest.NewRequest("GET", "/", nil), "file-1", src, 10000, 15000); err != nil {
+		t.Fatalf("ServeClip: %v", err)
+	}
+	if len(fc.cuts) != 1 {
+		t.Errorf("clip cut %d times, want 1", len(fc.cuts))
+	}
+}
+
+func TestClips_TranscodeWhenKeyframeFarOrCodecUnsupported(t *testing.T) {
+	probe := &pipeline.ProbeResult{Duration: 60, Codec: "h264", AudioCodec: "aac"}
+	clips, fc, src := newTestClips(t, "a.mp4", probe, 8)
+	if err := clips.ServeClip(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil), "file-1", src, 10000, 15000); err != nil {
+		t.Fatalf("ServeClip: %v", err)
+	}
+	if len(fc.cuts) != 1 || fc.cuts[0].copyStreams || fc.cuts[0].start != 10 || fc.cuts[0].duration != 5 {
+		t.Errorf("cuts = %+v, want a 5s transcode from 10s", fc.cuts)
+	}
+
+	probe = &pipeline.ProbeResult{Duration: 60, Codec: "prores"}
+	clips, fc, src = newTestClips(t, "a.mov", probe, 10)
+	if err := clips.ServeClip(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil), "file-1", src, 10000, 15000); err != nil {
+		t.Fatalf("ServeClip: %v", err)
+	}
+	if len(fc.cuts) != 1 || fc.cuts[0].copyStreams {
+		t.Errorf("cuts = %+v, want prores transcoded", fc.cuts)
+	}
+}
+
+func TestClampClip(t *testing.T) {
+	start, end, err := ClampClip(5000, 90000, 60)
+	if err != nil || start != 5 || end != 60 {
+		t.Errorf("ClampClip past end = %v, %v, %v; want 5, 60", start, end, err)
+	}
+	for _, tc := range []struct {
+		start, end int
+		duration   float64
+	}{
+		{-1, 1000, 60},
+		{2000, 2000, 60},
+		{61000, 62000, 60},
+		{0, 31 * 60 * 1000, 0},
+	} {
+		if _, _, err := ClampClip(tc.start, tc.end, tc.duration); !errors.Is(err, ErrInvalidClip) {
+			t.Errorf("ClampClip(%d, %d, %v) err = %v, want ErrInvalidClip", tc.start, tc.end, tc.duration, err)
+		}
+	}
+}
@@ -0,0 +1,193 @@
+package playback
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/heimdex/heimdex-agent/internal/pipeline"
+)
+
+// Frame output formats.
+const (
+	FrameFormatJPEG = "jpeg"
+	FrameFormatWebP = "webp"
+)
+
+const (
+	// MaxFrameWidth bounds the requested frame width.
+	MaxFrameWidth = 3840
+
+	// frameConcurrency is how many frame extractions run at once. Scrubbing
+	// fires many requests; the rest wait up to frameQueueTimeout for a slot.
+	frameConcurrency  = 2
+	frameQueueTimeout = 10 * time.Second
+	frameTimeout      = 30 * time.Second
+)
+
+var (
+	ErrInvalidFrame = errors.New("invalid frame request")
+	ErrFrameBusy    = errors.New("too many frame requests")
+)
+
+// FrameExtractor is the subset of ffmpeg frames need.
+type FrameExtractor interface {
+	Probe(filePath string) (*pipeline.ProbeResult, error)
+	ExtractFrame(ctx context.Context, filePath, outputPath string, at float64, width int) error
+}
+
+// FrameService renders single frames of a file as images.
+type FrameService interface {
+	ServeFrame(w http.ResponseWriter, r *http.Request, fileID, filePath string, atMs, width int, format string) error
+}
+
+// Frames extracts still images at arbitrary times. Images are cached under
+// cacheDir/<file_id>/<stamp>/<t_ms>_w<width>.<ext>, and at most
+// frameConcurrency ffmpeg processes run at a time.
+type Frames struct {
+	extractor FrameExtractor
+	cacheDir  string
+	logger    *slog.Logger
+
+	probes       *probeCache
+	builds       buildGroup
+	slots        chan struct{}
+	queueTimeout time.Duration
+}
+
+func NewFrames(extractor FrameExtractor, cacheDir string, logger *slog.Logger) *Frames {
+	return &Frames{
+		extractor:    extractor,
+		cacheDir:     cacheDir,
+		logger:       logger,
+		probes:       newProbeCache(extractor.Probe),
+		slots:        make(chan struct{}, frameConcurrency),
+		queueTimeout: frameQueueTimeout,
+	}
+}
+
+// FrameFormat picks the output format: format when given, otherwise WebP
+// if the client accepts it and JPEG if not.
+func FrameFormat(format, accept string) (string, error) {
+	switch format {
+	case FrameFormatJPEG, "jpg":
+		return FrameFormatJPEG, nil
+	case FrameFormatWebP:
+		return FrameFormatWebP, nil
+	case "":
+		if strings.Contains(accept, "image/webp") {
+			return FrameFormatWebP, nil
+		}
+		return FrameFormatJPEG, nil
+	}
+	return "", fmt.Errorf("%w: format must be jpeg or webp", ErrInvalidFrame)
+}
+
+func (f *Frames) ServeFrame(w http.ResponseWriter, r *http.Request, fileID, filePath string, atMs, width int, format string) error {
+	if atMs < 0 {
+		return fmt.Errorf("%w: t_ms must be >= 0", ErrInvalidFrame)
+	}
+	if width < 0 || width > MaxFrameWidth {
+		return fmt.Errorf("%w: w must be between 1 and %d", ErrInvalidFrame, MaxFrameWidth)
+	}
+	ext, contentType := ".jpg", "image/jpeg"
+	if format == FrameFormatWebP {
+		ext, contentType = ".webp", "image/webp"
+	}
+
+	probe, err := f.probes.get(filePath)
+	if err != nil {
+		return err
+	}
+	at := float64(atMs) / 1000
+	if probe.Duration > 0 && at >= probe.Duration {
+		return fmt.Errorf("%w: t_ms is past the end of the media", ErrInvalidFrame)
+	}
+
+	stamp, err := fileStamp(filePath)
+	if err != nil {
+		return err
+	}
+	framePath := filepath.Join(f.cacheDir, fileID, stamp, fmt.Sprintf("%d_w%d%s", atMs, width, ext))
+	if err := f.ensureFrame(r.Context(), filePath, framePath, at, width); err != nil {
+		return err
+	}
+	touchEntry(f.cacheDir, fileID)
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Cache-Control", "private, max-age=86400")
+	http.ServeFile(w, r, framePath)
+	return nil
+}
+
+// CacheEntries lists each file's cached frames for the cache budget.
+func (f *Frames) CacheEntries() ([]CacheEntry, error) {
+	return dirCacheEntries(f.cacheDir, &f.builds)
+}
+
+// EvictCacheEntry removes all frames cached for the file key.
+func (f *Frames) EvictCacheEntry(key string) error {
+	return os.RemoveAll(filepath.Join(f.cacheDir, key))
+}
+
+// ensureFrame extracts the frame unless it is cached, waiting for a free
+// ffmpeg slot first.
+func (f *Frames) ensureFrame(ctx context.Context, filePath, framePath string, at float64, width int) error {
+	if _, err := os.Stat(framePath); err == nil {
+		return nil
+	}
+	return f.builds.do(framePath, func() error {
+		if _, err := os.Stat(framePath); err == nil {
+			return nil
+		}
+		if err := f.acquire(ctx); err != nil {
+			return err
+		}
+		defer f.release()
+
+		stampDir := filepath.Dir(framePath)
+		pruneStale(filepath.Dir(stampDir), filepath.Base(stampDir))
+		if err := os.MkdirAll(stampDir, 0o755); err != nil {
+			return fmt.Errorf("create frame dir: %w", err)
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), frameTimeout)
+		defer cancel()
+
+		// Keep the extension on the temp file; ffmpeg picks the encoder by it.
+		ext := filepath.Ext(framePath)
+		tmpPath := strings.TrimSuffix(framePath, ext) + ".tmp" + ext
+		if err := f.extractor.ExtractFrame(ctx, filePath, tmpPath, at, width); err != nil {
+			os.Remove(tmpPath)
+			return fmt.Errorf("extract frame: %w", err)
+		}
+		if err := os.Rename(tmpPath, framePath); err != nil {
+			os.Remove(tmpPath)
+			return fmt.Errorf("store frame: %w", err)
+		}
+		return nil
+	})
+}
+
+func (f *Frames) acquire(ctx context.Context) error {
+	timer := time.NewTimer(f.queueTimeout)
+	defer timer.Stop()
+	select {
+	case f.slots <- struct{}{}:
+		return nil
+	case <-timer.C:
+		return ErrFrameBusy
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (f *Frames) release() {
+	<-f.slots
+}
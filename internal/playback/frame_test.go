@@ -0,0 +1,137 @@
+package playback
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/heimdex/heimdex-agent/internal/pipeline"
+)
+
+type fakeExtractor struct {
+	mu      sync.Mutex
+	calls   []string
+	release chan struct{}
+}
+
+func (f *fakeExtractor) Probe(string) (*pipeline.ProbeResult, error) {
+	return &pipeline.ProbeResult{Duration: 60}, nil
+}
+
+func (f *fakeExtractor) ExtractFrame(_ context.Context, _, outputPath string, _ float64, _ int) error {
+	f.mu.Lock()
+	f.calls = append(f.calls, filepath.Base(outputPath))
+	f.mu.Unlock()
+	if f.release != nil {
+		<-f.release
+	}
+	return os.WriteFile(outputPath, []byte("img"), 0o644)
+}
+
+func newTestFrames(t *testing.T) (*Frames, *fakeExtractor, string) {
+	t.Helper()
+	src := filepath.Join(t.TempDir(), "clip.mov")
+	if err := os.WriteFile(src, []byte("video"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	fe := &fakeExtractor{}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	return NewFrames(fe, t.TempDir(), logger), fe, src
+}
+
+func TestFrames_CachesByTimeSizeAndFormat(t *testing.T) {
+	frames, fe, src := newTestFrames(t)
+
+	serve := func(atMs, width int, format string) *httptest.ResponseRecorder {
+		t.Helper()
+		rr := httptest.NewRecorder()
+		if err := frames.ServeFrame(rr, httptest.NewRequest("GET", "/", nil), "file-1", src, atMs, width, format); err != nil {
+			t.Fatalf("ServeFrame: %v", err)
+		}
+		return rr
+	}
+
+	rr := serve(1500, 320, FrameFormatWebP)
+	if rr.Header().Get("Content-Type") != "image/webp" || rr.Body.String() != "img" {
+		t.Errorf("response = %q %q", rr.Header().Get("Content-Type"), rr.Body.String())
+	}
+	serve(1500, 320, FrameFormatWebP)
+	serve(1500, 320, FrameFormatJPEG)
+	serve(1500, 640, FrameFormatJPEG)
+
+	want := []string{"1500_w320.tmp.webp", "1500_w320.tmp.jpg", "1500_w640.tmp.jpg"}
+	if len(fe.calls) != len(want) {
+		t.Fatalf("extractions = %v, want %v", fe.calls, want)
+	}
+	for i := range want {
+		if fe.calls[i] != want[i] {
+			t.Errorf("extraction %d = %q, want %q", i, fe.calls[i], want[i])
+		}
+	}
+}
+
+func TestFrames_RejectsBadRequests(t *testing.T) {
+	frames, _, src := newTestFrames(t)
+	for _, tc := range []struct{ atMs, width int }{{-1, 0}, {60000, 0}, {0, MaxFrameWidth + 1}} {
+		err := frames.ServeFrame(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil), "file-1", src, tc.atMs, tc.width, FrameFormatJPEG)
+		if !errors.Is(err, ErrInvalidFrame) {
+			t.Errorf("ServeFrame(%d, %d) err = %v, want ErrInvalidFrame", tc.atMs, tc.width, err)
+		}
+	}
+}
+
+func TestFrames_LimitsConcurrentExtractions(t *testing.T) {
+	frames, fe, src := newTestFrames(t)
+	frames.queueTimeout = 50 * time.Millisecond
+	fe.release = make(chan struct{})
+
+	var wg sync.WaitGroup
+	for i := 0; i < frameConcurrency; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			frames.ServeFrame(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil), "file-1", src, i*1000, 0, FrameFormatJPEG)
+		}(i)
+	}
+	for {
+		fe.mu.Lock()
+		n := len(fe.calls)
+		fe.mu.Unlock()
+		if n == frameConcurrency {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	err := frames.ServeFrame(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil), "file-1", src, 30000, 0, FrameFormatJPEG)
+	if !errors.Is(err, ErrFrameBusy) {
+		t.Errorf("err = %v, want ErrFrameBusy while all slots are taken", err)
+	}
+
+	close(fe.release)
+	wg.Wait()
+}
+
+func TestFrameFormat(t *testing.T) {
+	tests := []struct{ format, accept, want string }{
+		{"", "image/avif,image/webp,*/*", FrameFormatWebP},
+		{"", "image/jpeg", FrameFormatJPEG},
+		{"jpg", "image/webp", FrameFormatJPEG},
+		{"webp", "", FrameFormatWebP},
+	}
+	for _, tt := range tests {
+		if got, err := FrameFormat(tt.format, tt.accept); err != nil || got != tt.want {
+			t.Errorf("FrameFormat(%q, %q) = %q, %v; want %q", tt.format, tt.accept, got, err, tt.want)
+		}
+	}
+	if _, err := FrameFormat("png", ""); !errors.Is(err, ErrInvalidFrame) {
+		t.Errorf("FrameFormat(png) err = %v, want ErrInvalidFrame", err)
+	}
+}
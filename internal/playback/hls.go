@@ -0,0 +1,291 @@
+package playback
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/heimdex/heimdex-agent/internal/pipeline"
+)
+
+// SegmentDuration is the target length of each HLS segment in seconds.
+const SegmentDuration = 6.0
+
+const segmentTimeout = 2 * time.Minute
+
+var (
+	ErrSegmentOutOfRange = errors.New("segment out of range")
+	ErrUnknownDuration   = errors.New("media duration unknown")
+)
+
+// Transcoder is the subset of ffmpeg HLS needs.
+type Transcoder interface {
+	Probe(filePath string) (*pipeline.ProbeResult, error)
+	TranscodeSegment(ctx context.Context, filePath, outputPath string, start, duration float64) error
+}
+
+// HLSService serves files browsers cannot decode as HLS.
+type HLSService interface {
+	Probe(filePath string) (*pipeline.ProbeResult, error)
+	ServePlaylist(w http.ResponseWriter, r *http.Request, filePath string) error
+	ServeSegment(w http.ResponseWriter, r *http.Request, fileID, filePath string, index int) error
+}
+
+// HLS publishes a VOD playlist of fixed-length segments and transcodes each
+// segment to H.264/AAC the first time it is requested. Players seek by
+// requesting the segment at the target time, so only watched segments are
+// ever encoded. Segments are cached under cacheDir/<file_id>/<stamp>, where
+// the stamp changes when the source file does.
+type HLS struct {
+	transcoder Transcoder
+	cacheDir   string
+	logger     *slog.Logger
+
+	probes *probeCache
+	builds buildGroup
+}
+
+func NewHLS(transcoder Transcoder, cacheDir string, logger *slog.Logger) *HLS {
+	return &HLS{
+		transcoder: transcoder,
+		cacheDir:   cacheDir,
+		logger:     logger,
+		probes:     newProbeCache(transcoder.Probe),
+	}
+}
+
+// Probe returns the probe result for filePath, cached until the file's size
+// or mtime changes.
+func (h *HLS) Probe(filePath string) (*pipeline.ProbeResult, error) {
+	return h.probes.get(filePath)
+}
+
+func (h *HLS) ServePlaylist(w http.ResponseWriter, r *http.Request, filePath string) error {
+	probe, err := h.Probe(filePath)
+	if err != nil {
+		return err
+	}
+	if probe.Duration <= 0 {
+		return ErrUnknownDuration
+	}
+
+	w.Header().Set("Content-Type", "application/vnd.apple.mpegurl")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	if r.Method != http.MethodHead {
+		var query string
+		if token := r.URL.Query().Get("token"); token != "" {
+			query = url.Values{"token": {token}}.Encode()
+		}
+		w.Write([]byte(Playlist(probe.Duration, query)))
+	}
+	return nil
+}
+
+func (h *HLS) ServeSegment(w http.ResponseWriter, r *http.Request, fileID, filePath string, index int) error {
+	probe, err := h.Probe(filePath)
+	if err != nil {
+		return err
+	}
+	if probe.Duration <= 0 {
+		return ErrUnknownDuration
+	}
+	if index < 0 || index >= SegmentCount(probe.Duration) {
+		return ErrSegmentOutOfRange
+	}
+
+	segPath, err := h.segment(fileID, filePath, index, probe.Duration)
+	if err != nil {
+		return err
+	}
+	touchEntry(h.cacheDir, fileID)
+	if index+1 < SegmentCount(probe.Duration) {
+		h.prefetch(fileID, filePath, index+1, probe.Duration)
+	}
+
+	w.Header().Set("Content-Type", "video/mp2t")
+	w.Header().Set("Cache-Control", "private, max-age=86400")
+	http.ServeFile(w, r, segPath)
+	return nil
+}
+
+// CacheEntries lists each file's cached segments for the cache budget.
+func (h *HLS) CacheEntries() ([]CacheEntry, error) {
+	return dirCacheEntries(h.cacheDir, &h.builds)
+}
+
+// EvictCacheEntry removes all segments cached for the file key.
+func (h *HLS) EvictCacheEntry(key string) error {
+	return os.RemoveAll(filepath.Join(h.cacheDir, key))
+}
+
+// segment returns the cached segment, transcoding it first if needed.
+// Concurrent requests for the same segment share one ffmpeg run.
+func (h *HLS) segment(fileID, filePath string, index int, duration float64) (string, error) {
+	segPath, stamp, err := h.segmentPath(fileID, filePath, index)
+	if err != nil {
+		return "", err
+	}
+	if _, err := os.Stat(segPath); err == nil {
+		return segPath, nil
+	}
+
+	b, owner := h.builds.claim(segPath)
+	if owner {
+		h.run(b, fileID, stamp, filePath, segPath, index, duration)
+	}
+	<-b.done
+	if b.err != nil {
+		return "", b.err
+	}
+	return segPath, nil
+}
+
+// prefetch starts encoding the next segment while the player plays the
+// current one.
+func (h *HLS) prefetch(fileID, filePath string, index int, duration float64) {
+	segPath, stamp, err := h.segmentPath(fileID, filePath, index)
+	if err != nil {
+		return
+	}
+	if _, err := os.Stat(segPath); err == nil {
+		return
+	}
+
+	b, owner := h.builds.claim(segPath)
+	if !owner {
+		return
+	}
+	go func() {
+		h.run(b, fileID, stamp, filePath, segPath, index, duration)
+		if b.err != nil {
+			h.logger.Warn("hls prefetch failed", "path", filePath, "segment", index, "error", b.err)
+		}
+	}()
+}
+
+func (h *HLS) segmentPath(fileID, filePath string, index int) (segPath, stamp string, err error) {
+	stamp, err = fileStamp(filePath)
+	if err != nil {
+		return "", "", err
+	}
+	return filepath.Join(h.cacheDir, fileID, stamp, SegmentName(index)), stamp, nil
+}
+
+func (h *HLS) run(b *build, fileID, stamp, filePath, segPath string, index int, duration float64) {
+	if index == 0 {
+		pruneStale(filepath.Join(h.cacheDir, fileID), stamp)
+	}
+	h.builds.finish(segPath, b, h.transcode(filePath, segPath, index, duration))
+}
+
+func (h *HLS) transcode(filePath, segPath string, index int, duration float64) error {
+	start := float64(index) * SegmentDuration
+	length := math.Min(SegmentDuration, duration-start)
+
+	ctx, cancel := context.WithTimeout(context.Background(), segmentTimeout)
+	defer cancel()
+
+	if err := os.MkdirAll(filepath.Dir(segPath), 0o755); err != nil {
+		return fmt.Errorf("create segment dir: %w", err)
+	}
+
+	began := time.Now()
+	tmpPath := segPath + ".tmp"
+	if err := h.transcoder.TranscodeSegment(ctx, filePath, tmpPath, start, length); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("transcode segment %d: %w", index, err)
+	}
+	if err := os.Rename(tmpPath, segPath); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("store segment %d: %w", index, err)
+	}
+	h.logger.Debug("hls segment transcoded", "path", filePath, "segment", index, "took", time.Since(began))
+	return nil
+}
+
+// SegmentCount returns the number of segments covering duration seconds.
+func SegmentCount(duration float64) int {
+	if duration <= 0 {
+		return 0
+	}
+	return int(math.Ceil(duration / SegmentDuration))
+}
+
+// SegmentName returns the playlist URI of segment index.
+func SegmentName(index int) string {
+	return fmt.Sprintf("segment_%05d.ts", index)
+}
+
+// ParseSegmentName is the inverse of SegmentName.
+func ParseSegmentName(name string) (int, bool) {
+	digits := strings.TrimSuffix(strings.TrimPrefix(name, "segment_"), ".ts")
+	index, err := strconv.Atoi(digits)
+	if err != nil || SegmentName(index) != name {
+		return 0, false
+	}
+	return index, true
+}
+
+// Playlist renders a VOD playlist for duration seconds of media. Segment
+// URIs are relative to the playlist, with query appended when it is set so
+// a playlist fetched with a playback token passes it on to its segments.
+func Playlist(duration float64, query string) string {
+	var b strings.Builder
+	b.WriteString("#EXTM3U\n")
+	b.WriteString("#EXT-X-VERSION:3\n")
+	b.WriteString("#EXT-X-PLAYLIST-TYPE:VOD\n")
+	fmt.Fprintf(&b, "#EXT-X-TARGETDURATION:%d\n", int(math.Ceil(SegmentDuration)))
+	b.WriteString("#EXT-X-MEDIA-SEQUENCE:0\n")
+
+	count := SegmentCount(duration)
+	for i := 0; i < count; i++ {
+		length := math.Min(SegmentDuration, duration-float64(i)*SegmentDuration)
+		uri := SegmentName(i)
+		if query != "" {
+			uri += "?" + query
+		}
+		fmt.Fprintf(&b, "#EXTINF:%.3f,\n%s\n", length, uri)
+	}
+	b.WriteString("#EXT-X-ENDLIST\n")
+	return b.String()
+}
+
+var (
+	directPlayContainers = map[string]bool{".mp4": true, ".m4v": true, ".mov": true, ".webm": true}
+	directPlayVideo      = map[string]bool{"h264": true, "vp8": true, "vp9": true, "av1": true}
+	directPlayAudio      = map[string]bool{"": true, "aac": true, "mp3": true, "opus": true, "vorbis": true}
+	directPlayPixFmts    = map[string]bool{"": true, "yuv420p": true, "yuvj420p": true}
+)
+
+// DirectPlayable reports whether browsers can play filePath as-is. When
+// they cannot, reason names the first unsupported property. An unknown
+// video codec is given the benefit of the doubt.
+func DirectPlayable(filePath string, probe *pipeline.ProbeResult) (ok bool, reason string) {
+	ext := strings.ToLower(filepath.Ext(filePath))
+	if !directPlayContainers[ext] {
+		return false, "container " + strings.TrimPrefix(ext, ".")
+	}
+	if probe == nil || probe.Codec == "" {
+		return true, ""
+	}
+	if !directPlayVideo[probe.Codec] {
+		return false, "video codec " + probe.Codec
+	}
+	if !directPlayPixFmts[probe.PixelFormat] {
+		return false, "pixel format " + probe.PixelFormat
+	}
+	if !directPlayAudio[probe.AudioCodec] {
+		return false, "audio codec " + probe.AudioCodec
+	}
+	return true, ""
+}
@@ -0,0 +1,214 @@
+package playback
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/heimdex/heimdex-agent/internal/pipeline"
+)
+
+type fakeTranscoder struct {
+	mu       sync.Mutex
+	probe    *pipeline.ProbeResult
+	probes   int
+	segments []float64
+}
+
+func (f *fakeTranscoder) Probe(string) (*pipeline.ProbeResult, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.probes++
+	return f.probe, nil
+}
+
+func (f *fakeTranscoder) TranscodeSegment(_ context.Context, _, outputPath string, start, _ float64) error {
+	f.mu.Lock()
+	f.segments = append(f.segments, start)
+	f.mu.Unlock()
+	return os.WriteFile(outputPath, []byte("ts"), 0o644)
+}
+
+func (f *fakeTranscoder) starts() []float64 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]float64(nil), f.segments...)
+}
+
+func newTestHLS(t *testing.T, duration float64) (*HLS, *fakeTranscoder, string) {
+	t.Helper()
+	src := filepath.Join(t.TempDir(), "clip.mov")
+	if err := os.WriteFile(src, []byte("video"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	ft := &fakeTranscoder{probe: &pipeline.ProbeResult{Duration: duration, Codec: "prores"}}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	return NewHLS(ft, t.TempDir(), logger), ft, src
+}
+
+func TestPlaylist(t *testing.T) {
+	got := Playlist(13.5, "")
+	for _, want := range []string{
+		"#EXTM3U\n",
+		"#EXT-X-PLAYLIST-TYPE:VOD\n",
+		"#EXT-X-TARGETDURATION:6\n",
+		"#EXTINF:6.000,\nsegment_00000.ts\n",
+		"#EXTINF:6.000,\nsegment_00001.ts\n",
+		"#EXTINF:1.500,\nsegment_00002.ts\n",
+		"#EXT-X-ENDLIST\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("playlist missing %q:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "segment_00003.ts") {
+		t.Errorf("playlist has too many segments:\n%s", got)
+	}
+}
+
+func TestPlaylist_CarriesQuery(t *testing.T) {
+	got := Playlist(7, "token=abc")
+	for _, want := range []string{"segment_00000.ts?token=abc\n", "segment_00001.ts?token=abc\n"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("playlist missing %q:\n%s", want, got)
+		}
+	}
+}
+
+func TestParseSegmentName(t *testing.T) {
+	tests := []struct {
+		name  string
+		index int
+		ok    bool
+	}{
+		{"segment_00000.ts", 0, true},
+		{"segment_00042.ts", 42, true},
+		{"segment_42.ts", 0, false},
+		{"index.m3u8", 0, false},
+		{"segment_00001.mp4", 0, false},
+	}
+	for _, tt := range tests {
+		index, ok := ParseSegmentName(tt.name)
+		if index != tt.index || ok != tt.ok {
+			t.Errorf("ParseSegmentName(%q) = %d, %v; want %d, %v", tt.name, index, ok, tt.index, tt.ok)
+		}
+	}
+}
+
+func TestDirectPlayable(t *testing.T) {
+	tests := []struct {
+		name  string
+		path  string
+		probe *pipeline.ProbeResult
+		want  bool
+	}{
+		{"h264 mp4", "a.mp4", &pipeline.ProbeResult{Codec: "h264", PixelFormat: "yuv420p", AudioCodec: "aac"}, true},
+		{"vp9 webm", "a.webm", &pipeline.ProbeResult{Codec: "vp9", AudioCodec: "opus"}, true},
+		{"prores mov", "a.mov", &pipeline.ProbeResult{Codec: "prores", AudioCodec: "pcm_s16le"}, false},
+		{"hevc mp4", "a.MP4", &pipeline.ProbeResult{Codec: "hevc", AudioCodec: "aac"}, false},
+		{"10-bit h264", "a.mp4", &pipeline.ProbeResult{Codec: "h264", PixelFormat: "yuv422p10le"}, false},
+		{"pcm audio", "a.mov", &pipeline.ProbeResult{Codec: "h264", AudioCodec: "pcm_s16le"}, false},
+		{"mkv container", "a.mkv", &pipeline.ProbeResult{Codec: "h264", AudioCodec: "aac"}, false},
+		{"unknown codec", "a.mp4", &pipeline.ProbeResult{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, reason := DirectPlayable(tt.path, tt.probe)
+			if got != tt.want {
+				t.Errorf("DirectPlayable = %v (%s), want %v", got, reason, tt.want)
+			}
+			if !got && reason == "" {
+				t.Error("expected a reason for the fallback")
+			}
+		})
+	}
+}
+
+func TestHLS_ServeSegment_TranscodesOnceAndCaches(t *testing.T) {
+	h, ft, src := newTestHLS(t, 20)
+
+	for i := 0; i < 2; i++ {
+		rr := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/segment_00002.ts", nil)
+		if err := h.ServeSegment(rr, req, "file-1", src, 2); err != nil {
+			t.Fatalf("ServeSegment: %v", err)
+		}
+		if rr.Code != http.StatusOK || rr.Body.String() != "ts" {
+			t.Fatalf("status = %d body = %q", rr.Code, rr.Body.String())
+		}
+		if ct := rr.Header().Get("Content-Type"); ct != "video/mp2t" {
+			t.Errorf("Content-Type = %q", ct)
+		}
+	}
+
+	// The first request also prefetches segment 3; wait for it by
+	// requesting it.
+	if _, err := h.segment("file-1", src, 3, 20); err != nil {
+		t.Fatal(err)
+	}
+
+	counts := map[float64]int{}
+	for _, start := range ft.starts() {
+		counts[start]++
+	}
+	if counts[12] != 1 {
+		t.Errorf("segment 2 transcoded %d times, want 1", counts[12])
+	}
+	if counts[18] != 1 {
+		t.Errorf("segment 3 transcoded %d times, want 1", counts[18])
+	}
+	if ft.probes != 1 {
+		t.Errorf("probed %d times, want 1 (cached)", ft.probes)
+	}
+}
+
+func TestHLS_ServeSegment_OutOfRange(t *testing.T) {
+	h, _, src := newTestHLS(t, 10)
+
+	rr := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/segment_00002.ts", nil)
+	err := h.ServeSegment(rr, req, "file-1", src, 2)
+	if !errors.Is(err, ErrSegmentOutOfRange) {
+		t.Fatalf("err = %v, want ErrSegmentOutOfRange", err)
+	}
+}
+
+func TestHLS_ServePlaylist_UnknownDuration(t *testing.T) {
+	h, _, src := newTestHLS(t, 0)
+
+	rr := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/index.m3u8", nil)
+	if err := h.ServePlaylist(rr, req, src); !errors.Is(err, ErrUnknownDuration) {
+		t.Fatalf("err = %v, want ErrUnknownDuration", err)
+	}
+}
+
+func TestHLS_SegmentCacheInvalidatedWhenFileChanges(t *testing.T) {
+	h, ft, src := newTestHLS(t, 5)
+
+	if _, err := h.segment("file-1", src, 0, 5); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(src, []byte("re-exported video"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := h.segment("file-1", src, 0, 5); err != nil {
+		t.Fatal(err)
+	}
+
+	if n := len(ft.starts()); n != 2 {
+		t.Errorf("transcoded %d times, want 2", n)
+	}
+	entries, _ := os.ReadDir(filepath.Join(h.cacheDir, "file-1"))
+	if len(entries) != 1 {
+		t.Errorf("cache has %d versions, want stale one pruned", len(entries))
+	}
+}
@@ -12,6 +12,10 @@ var (
 	ErrUnsatisfiable = errors.New("range not satisfiable")
 )
 
+// maxRanges bounds a multi-range request. Requests asking for more are
+// answered with the whole file, as RFC 9110 allows.
+const maxRanges = 16
+
 type Range struct {
 	Start int64
 	End   int64
@@ -25,6 +29,7 @@ func (r Range) ContentRange(total int64) string {
 	return fmt.Sprintf("bytes %d-%d/%d", r.Start, r.End, total)
 }
 
+// ParseRange parses the first range of a Range header.
 func ParseRange(header string, size int64) (*Range, error) {
 	if header == "" {
 		return nil, nil
@@ -40,6 +45,49 @@ func ParseRange(header string, size int64) (*Range, error) {
 		rangeSpec = strings.TrimSpace(rangeSpec[:idx])
 	}
 
+	return parseRangeSpec(rangeSpec, size)
+}
+
+// ParseRanges parses every range of a Range header. Unsatisfiable ranges
+// are dropped; ErrUnsatisfiable is returned only when none remain. A nil
+// slice means the header is absent.
+func ParseRanges(header string, size int64) ([]Range, error) {
+	if header == "" {
+		return nil, nil
+	}
+
+	if !strings.HasPrefix(header, "bytes=") {
+		return nil, ErrInvalidRange
+	}
+
+	specs := strings.Split(strings.TrimPrefix(header, "bytes="), ",")
+	if len(specs) > maxRanges {
+		return nil, ErrInvalidRange
+	}
+
+	var ranges []Range
+	for _, spec := range specs {
+		spec = strings.TrimSpace(spec)
+		if spec == "" {
+			continue
+		}
+		r, err := parseRangeSpec(spec, size)
+		if err == ErrUnsatisfiable {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		ranges = append(ranges, *r)
+	}
+
+	if len(ranges) == 0 {
+		return nil, ErrUnsatisfiable
+	}
+	return ranges, nil
+}
+
+func parseRangeSpec(rangeSpec string, size int64) (*Range, error) {
 	parts := strings.Split(rangeSpec, "-")
 	if len(parts) != 2 {
 		return nil, ErrInvalidRange
@@ -1,7 +1,18 @@
 package playback
 
 import (
+	"io"
+	"log/slog"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestParseRange(t *testing.T) {
@@ -107,3 +118,177 @@ func TestRange_ContentRange(t *testing.T) {
 		}
 	}
 }
+
+func TestParseRanges(t *testing.T) {
+	got, err := ParseRanges("bytes=0-99, 200-299,-50", 1000)
+	if err != nil {
+		t.Fatalf("ParseRanges() error = %v", err)
+	}
+	want := []Range{{0, 99}, {200, 299}, {950, 999}}
+	if len(got) != len(want) {
+		t.Fatalf("ParseRanges() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("range %d = %v, want %v", i, got[i], want[i])
+		}
+	}
+
+	// Unsatisfiable ranges are dropped while any remain.
+	got, err = ParseRanges("bytes=0-9,5000-6000", 1000)
+	if err != nil || len(got) != 1 || got[0] != (Range{0, 9}) {
+		t.Errorf("ParseRanges() = %v, %v; want [{0 9}]", got, err)
+	}
+	if _, err := ParseRanges("bytes=5000-6000,7000-", 1000); err != ErrUnsatisfiable {
+		t.Errorf("ParseRanges() error = %v, want ErrUnsatisfiable", err)
+	}
+	if _, err := ParseRanges("bytes=0-9,abc", 1000); err != ErrInvalidRange {
+		t.Errorf("ParseRanges() error = %v, want ErrInvalidRange", err)
+	}
+	if _, err := ParseRanges("bytes="+strings.Repeat("0-1,", maxRanges)+"0-1", 1000); err != ErrInvalidRange {
+		t.Errorf("ParseRanges() with too many ranges error = %v, want ErrInvalidRange", err)
+	}
+	if got, err := ParseRanges("", 1000); got != nil || err != nil {
+		t.Errorf("ParseRanges(\"\") = %v, %v; want nil, nil", got, err)
+	}
+}
+
+func newTestFile(t *testing.T) (string, time.Time) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "clip.mp4")
+	if err := os.WriteFile(path, []byte("0123456789abcdefghij"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	mtime := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	if err := os.Chtimes(path, mtime, mtime); err != nil {
+		t.Fatal(err)
+	}
+	return path, mtime
+}
+
+func serveTestFile(t *testing.T, path string, req *http.Request) *httptest.ResponseRecorder {
+	t.Helper()
+	rr := httptest.NewRecorder()
+	s := NewServer(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err := s.ServeFile(rr, req, path); err != nil {
+		t.Fatalf("ServeFile() error = %v", err)
+	}
+	return rr
+}
+
+func TestServeFile_MultipleRanges(t *testing.T) {
+	path, _ := newTestFile(t)
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Range", "bytes=0-3,10-12")
+	rr := serveTestFile(t, path, req)
+
+	if rr.Code != http.StatusPartialContent {
+		t.Fatalf("status = %d, want 206", rr.Code)
+	}
+	mediaType, params, err := mime.ParseMediaType(rr.Header().Get("Content-Type"))
+	if err != nil || mediaType != "multipart/byteranges" {
+		t.Fatalf("Content-Type = %q", rr.Header().Get("Content-Type"))
+	}
+	if cl := rr.Header().Get("Content-Length"); cl != strconv.Itoa(rr.Body.Len()) {
+		t.Errorf("Content-Length = %s, body is %d bytes", cl, rr.Body.Len())
+	}
+
+	mr := multipart.NewReader(rr.Body, params["boundary"])
+	want := []struct{ contentRange, body string }{
+		{"bytes 0-3/20", "0123"},
+		{"bytes 10-12/20", "abc"},
+	}
+	for _, w := range want {
+		part, err := mr.NextPart()
+		if err != nil {
+			t.Fatalf("NextPart() error = %v", err)
+		}
+		body, _ := io.ReadAll(part)
+		if got := part.Header.Get("Content-Range"); got != w.contentRange || string(body) != w.body {
+			t.Errorf("part = %q %q, want %q %q", got, body, w.contentRange, w.body)
+		}
+		if ct := part.Header.Get("Content-Type"); ct != "video/mp4" {
+			t.Errorf("part Content-Type = %q", ct)
+		}
+	}
+	if _, err := mr.NextPart(); err != io.EOF {
+		t.Errorf("extra part: %v", err)
+	}
+}
+
+func TestServeFile_ConditionalRequests(t *testing.T) {
+	path, mtime := newTestFile(t)
+
+	rr := serveTestFile(t, path, httptest.NewRequest(http.MethodGet, "/", nil))
+	etag := rr.Header().Get("ETag")
+	if rr.Code != http.StatusOK || etag == "" {
+		t.Fatalf("status = %d, ETag = %q", rr.Code, etag)
+	}
+	if lm := rr.Header().Get("Last-Modified"); lm != mtime.Format(http.TimeFormat) {
+		t.Errorf("Last-Modified = %q", lm)
+	}
+
+	tests := []struct {
+		name       string
+		headers    map[string]string
+		wantStatus int
+		wantBody   string
+	}{
+		{"if-none-match hit", map[string]string{"If-None-Match": etag}, http.StatusNotModified, ""},
+		{"if-none-match weak hit", map[string]string{"If-None-Match": `"other", W/` + etag}, http.StatusNotModified, ""},
+		{"if-none-match miss", map[string]string{"If-None-Match": `"other"`}, http.StatusOK, "0123456789abcdefghij"},
+		{"if-none-match wins over date", map[string]string{"If-None-Match": `"other"`, "If-Modified-Since": mtime.Format(http.TimeFormat)}, http.StatusOK, "0123456789abcdefghij"},
+		{"if-modified-since not modified", map[string]string{"If-Modified-Since": mtime.Format(http.TimeFormat)}, http.StatusNotModified, ""},
+		{"if-modified-since modified", map[string]string{"If-Modified-Since": mtime.Add(-time.Hour).Format(http.TimeFormat)}, http.StatusOK, "0123456789abcdefghij"},
+		{"if-range etag match", map[string]string{"Range": "bytes=0-1", "If-Range": etag}, http.StatusPartialContent, "01"},
+		{"if-range etag stale", map[string]string{"Range": "bytes=0-1", "If-Range": `"stale"`}, http.StatusOK, "0123456789abcdefghij"},
+		{"if-range weak etag", map[string]string{"Range": "bytes=0-1", "If-Range": "W/" + etag}, http.StatusOK, "0123456789abcdefghij"},
+		{"if-range date match", map[string]string{"Range": "bytes=0-1", "If-Range": mtime.Format(http.TimeFormat)}, http.StatusPartialContent, "01"},
+		{"if-range date stale", map[string]string{"Range": "bytes=0-1", "If-Range": mtime.Add(-time.Hour).Format(http.TimeFormat)}, http.StatusOK, "0123456789abcdefghij"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			rr := serveTestFile(t, path, req)
+			if rr.Code != tt.wantStatus || rr.Body.String() != tt.wantBody {
+				t.Errorf("got %d %q, want %d %q", rr.Code, rr.Body.String(), tt.wantStatus, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestServeFile_PresetETag(t *testing.T) {
+	path, mtime := newTestFile(t)
+	etag := ETag("abcdef0123456789abcdef", 20, mtime)
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("If-None-Match", etag)
+	rr := httptest.NewRecorder()
+	rr.Header().Set("ETag", etag)
+	if err := NewServer(slog.New(slog.NewTextHandler(io.Discard, nil))).ServeFile(rr, req, path); err != nil {
+		t.Fatal(err)
+	}
+	if rr.Code != http.StatusNotModified || rr.Header().Get("ETag") != etag {
+		t.Errorf("status = %d, ETag = %q; want 304 with %q", rr.Code, rr.Header().Get("ETag"), etag)
+	}
+}
+
+func TestServeFile_Head(t *testing.T) {
+	path, _ := newTestFile(t)
+
+	rr := serveTestFile(t, path, httptest.NewRequest(http.MethodHead, "/", nil))
+	if rr.Code != http.StatusOK || rr.Body.Len() != 0 || rr.Header().Get("Content-Length") != "20" {
+		t.Errorf("HEAD = %d, %d body bytes, Content-Length %q", rr.Code, rr.Body.Len(), rr.Header().Get("Content-Length"))
+	}
+
+	req := httptest.NewRequest(http.MethodHead, "/", nil)
+	req.Header.Set("Range", "bytes=0-3,10-12")
+	rr = serveTestFile(t, path, req)
+	if rr.Code != http.StatusPartialContent || rr.Body.Len() != 0 || rr.Header().Get("Content-Length") == "" {
+		t.Errorf("HEAD multi-range = %d, %d body bytes, Content-Length %q", rr.Code, rr.Body.Len(), rr.Header().Get("Content-Length"))
+	}
+}
@@ -5,9 +5,13 @@ import (
 	"io"
 	"log/slog"
 	"mime"
+	"mime/multipart"
 	"net/http"
+	"net/textproto"
 	"os"
 	"path/filepath"
+	"strings"
+	"time"
 )
 
 type PlaybackService interface {
@@ -22,6 +26,21 @@ func NewServer(logger *slog.Logger) *Server {
 	return &Server{logger: logger}
 }
 
+// ETag returns a strong validator for a file's contents. The fingerprint
+// only covers the head of the file, so the size and modification time on
+// disk are mixed in to catch edits further along, including ones made
+// since the file was last scanned.
+func ETag(fingerprint string, size int64, mtime time.Time) string {
+	if len(fingerprint) > 16 {
+		fingerprint = fingerprint[:16]
+	}
+	return fmt.Sprintf(`"%s-%x-%x"`, fingerprint, size, mtime.UnixNano())
+}
+
+// ServeFile streams filePath with single and multiple byte-range support
+// and answers conditional requests. An ETag already set on w is used as
+// the validator; otherwise one is derived from the file's size and
+// modification time.
 func (s *Server) ServeFile(w http.ResponseWriter, r *http.Request, filePath string) error {
 	file, err := os.Open(filePath)
 	if err != nil {
@@ -39,16 +58,35 @@ func (s *Server) ServeFile(w http.ResponseWriter, r *http.Request, filePath stri
 	}
 
 	size := stat.Size()
+	modTime := stat.ModTime()
 	contentType := mime.TypeByExtension(filepath.Ext(filePath))
 	if contentType == "" {
 		contentType = "application/octet-stream"
 	}
 
+	etag := w.Header().Get("ETag")
+	if etag == "" {
+		etag = fmt.Sprintf(`"%x-%x"`, size, modTime.UnixNano())
+		w.Header().Set("ETag", etag)
+	}
+	w.Header().Set("Last-Modified", modTime.UTC().Format(http.TimeFormat))
 	w.Header().Set("Accept-Ranges", "bytes")
+
+	if notModified(r, etag, modTime) {
+		h := w.Header()
+		h.Del("Content-Type")
+		h.Del("Content-Length")
+		w.WriteHeader(http.StatusNotModified)
+		return nil
+	}
+
 	w.Header().Set("Content-Type", contentType)
 
 	rangeHeader := r.Header.Get("Range")
-	parsedRange, err := ParseRange(rangeHeader, size)
+	if !ifRangeMatches(r, etag, modTime) {
+		rangeHeader = ""
+	}
+	ranges, err := ParseRanges(rangeHeader, size)
 
 	if err == ErrUnsatisfiable {
 		w.Header().Set("Content-Range", fmt.Sprintf("bytes */%d", size))
@@ -60,21 +98,128 @@ func (s *Server) ServeFile(w http.ResponseWriter, r *http.Request, filePath stri
 		return err
 	}
 
-	if parsedRange == nil {
+	sendBody := r.Method != http.MethodHead
+
+	switch len(ranges) {
+	case 0:
 		w.Header().Set("Content-Length", fmt.Sprintf("%d", size))
 		w.WriteHeader(http.StatusOK)
-		io.Copy(w, file)
+		if sendBody {
+			io.Copy(w, file)
+		}
+		return nil
+
+	case 1:
+		rng := ranges[0]
+		w.Header().Set("Content-Length", fmt.Sprintf("%d", rng.ContentLength()))
+		w.Header().Set("Content-Range", rng.ContentRange(size))
+		w.WriteHeader(http.StatusPartialContent)
+		if !sendBody {
+			return nil
+		}
+		if _, err := file.Seek(rng.Start, io.SeekStart); err != nil {
+			return fmt.Errorf("failed to seek: %w", err)
+		}
+		io.CopyN(w, file, rng.ContentLength())
 		return nil
 	}
 
-	w.Header().Set("Content-Length", fmt.Sprintf("%d", parsedRange.ContentLength()))
-	w.Header().Set("Content-Range", parsedRange.ContentRange(size))
+	return serveMultipart(w, file, ranges, size, contentType, sendBody)
+}
+
+// serveMultipart writes a multipart/byteranges body with one part per
+// range. Content-Length is computed up front by rendering the part headers
+// into a counter.
+func serveMultipart(w http.ResponseWriter, file *os.File, ranges []Range, size int64, contentType string, sendBody bool) error {
+	var counter countingWriter
+	mw := multipart.NewWriter(&counter)
+	for _, rng := range ranges {
+		mw.CreatePart(partHeader(rng, size, contentType))
+		counter += countingWriter(rng.ContentLength())
+	}
+	mw.Close()
+
+	boundary := mw.Boundary()
+	w.Header().Set("Content-Type", "multipart/byteranges; boundary="+boundary)
+	w.Header().Set("Content-Length", fmt.Sprintf("%d", int64(counter)))
 	w.WriteHeader(http.StatusPartialContent)
+	if !sendBody {
+		return nil
+	}
 
-	if _, err := file.Seek(parsedRange.Start, io.SeekStart); err != nil {
-		return fmt.Errorf("failed to seek: %w", err)
+	mw = multipart.NewWriter(w)
+	if err := mw.SetBoundary(boundary); err != nil {
+		return err
 	}
+	for _, rng := range ranges {
+		part, err := mw.CreatePart(partHeader(rng, size, contentType))
+		if err != nil {
+			return err
+		}
+		if _, err := file.Seek(rng.Start, io.SeekStart); err != nil {
+			return fmt.Errorf("failed to seek: %w", err)
+		}
+		if _, err := io.CopyN(part, file, rng.ContentLength()); err != nil {
+			return err
+		}
+	}
+	return mw.Close()
+}
+
+func partHeader(rng Range, size int64, contentType string) textproto.MIMEHeader {
+	return textproto.MIMEHeader{
+		"Content-Range": {rng.ContentRange(size)},
+		"Content-Type":  {contentType},
+	}
+}
+
+type countingWriter int64
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	*c += countingWriter(len(p))
+	return len(p), nil
+}
 
-	io.CopyN(w, file, parsedRange.ContentLength())
-	return nil
+// notModified reports whether a GET or HEAD can be answered with 304.
+// If-None-Match takes precedence over If-Modified-Since.
+func notModified(r *http.Request, etag string, modTime time.Time) bool {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		return etagListMatches(inm, etag)
+	}
+	if ims := r.Header.Get("If-Modified-Since"); ims != "" {
+		t, err := http.ParseTime(ims)
+		return err == nil && !modTime.Truncate(time.Second).After(t)
+	}
+	return false
+}
+
+// ifRangeMatches reports whether the Range header should be honoured: when
+// there is no If-Range, or it names the current entity. Entity tags are
+// compared strongly; dates must match the modification time exactly.
+func ifRangeMatches(r *http.Request, etag string, modTime time.Time) bool {
+	ir := strings.TrimSpace(r.Header.Get("If-Range"))
+	if ir == "" {
+		return true
+	}
+	if strings.HasPrefix(ir, `"`) || strings.HasPrefix(ir, "W/") {
+		return !strings.HasPrefix(ir, "W/") && ir == etag
+	}
+	t, err := http.ParseTime(ir)
+	return err == nil && modTime.Truncate(time.Second).Equal(t)
+}
+
+// etagListMatches reports whether an If-None-Match list contains etag,
+// using the weak comparison: W/ prefixes are ignored on both sides.
+func etagListMatches(list, etag string) bool {
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(list, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
 }
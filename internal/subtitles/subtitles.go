@@ -0,0 +1,132 @@
+// Package subtitles writes timed transcript cues as SubRip (.srt) and WebVTT
+// captions, and cuts and re-times them for an edit.
+package subtitles
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	FormatSRT = "srt"
+	FormatVTT = "vtt"
+)
+
+// MaxCueChars is the most text one cue holds: two lines of the 42 characters
+// broadcast caption guidelines allow.
+const MaxCueChars = 84
+
+// Cue is one caption, timed in milliseconds.
+type Cue struct {
+	StartMs int
+	EndMs   int
+	Text    string
+}
+
+// Split breaks cues longer than maxChars at word boundaries, sharing each
+// cue's time among its pieces by length. Scene transcripts cover a whole
+// scene and would otherwise fill the screen for minutes.
+func Split(cues []Cue, maxChars int) []Cue {
+	out := make([]Cue, 0, len(cues))
+	for _, c := range cues {
+		words := strings.Fields(c.Text)
+		if len(words) == 0 {
+			continue
+		}
+		var chunks []string
+		line := words[0]
+		for _, w := range words[1:] {
+			if utf8.RuneCountInString(line)+1+utf8.RuneCountInString(w) > maxChars {
+				chunks = append(chunks, line)
+				line = w
+				continue
+			}
+			line += " " + w
+		}
+		chunks = append(chunks, line)
+
+		total := 0
+		for _, ch := range chunks {
+			total += utf8.RuneCountInString(ch)
+		}
+		span, done, start := c.EndMs-c.StartMs, 0, c.StartMs
+		for _, ch := range chunks {
+			done += utf8.RuneCountInString(ch)
+			end := c.StartMs + span*done/total
+			out = append(out, Cue{StartMs: start, EndMs: end, Text: ch})
+			start = end
+		}
+	}
+	return out
+}
+
+// Rebase keeps the parts of cues inside [startMs, endMs) of the source and
+// moves them to start at offsetMs, as a clip of that range placed at
+// offsetMs on an export timeline would show them.
+func Rebase(cues []Cue, startMs, endMs, offsetMs int) []Cue {
+	var out []Cue
+	for _, c := range cues {
+		s, e := max(c.StartMs, startMs), min(c.EndMs, endMs)
+		if e <= s {
+			continue
+		}
+		out = append(out, Cue{StartMs: s - startMs + offsetMs, EndMs: e - startMs + offsetMs, Text: c.Text})
+	}
+	return out
+}
+
+// Write writes cues in format, FormatSRT or FormatVTT.
+func Write(w io.Writer, format string, cues []Cue) error {
+	switch format {
+	case FormatSRT:
+		return WriteSRT(w, cues)
+	case FormatVTT:
+		return WriteVTT(w, cues)
+	default:
+		return fmt.Errorf("subtitles: unknown format %q", format)
+	}
+}
+
+// WriteSRT writes cues as SubRip: numbered blocks with comma decimal
+// timestamps.
+func WriteSRT(w io.Writer, cues []Cue) error {
+	bw := bufio.NewWriter(w)
+	for i, c := range cues {
+		fmt.Fprintf(bw, "%d\n%s --> %s\n%s\n\n", i+1, timestamp(c.StartMs, ','), timestamp(c.EndMs, ','), cueText(c.Text))
+	}
+	return bw.Flush()
+}
+
+// WriteVTT writes cues as WebVTT. "-->" and markup characters in the text
+// are escaped so transcripts cannot end a cue or open a tag.
+func WriteVTT(w io.Writer, cues []Cue) error {
+	bw := bufio.NewWriter(w)
+	bw.WriteString("WEBVTT\n\n")
+	escaper := strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+	for _, c := range cues {
+		fmt.Fprintf(bw, "%s --> %s\n%s\n\n", timestamp(c.StartMs, '.'), timestamp(c.EndMs, '.'), escaper.Replace(cueText(c.Text)))
+	}
+	return bw.Flush()
+}
+
+// cueText drops blank lines, which end a cue in both formats.
+func cueText(s string) string {
+	lines := strings.FieldsFunc(s, func(r rune) bool { return r == '\n' || r == '\r' })
+	kept := lines[:0]
+	for _, l := range lines {
+		if l = strings.TrimSpace(l); l != "" {
+			kept = append(kept, l)
+		}
+	}
+	return strings.Join(kept, "\n")
+}
+
+func timestamp(ms int, sep byte) string {
+	if ms < 0 {
+		ms = 0
+	}
+	return fmt.Sprintf("%02d:%02d:%02d%c%03d", ms/3600000, ms/60000%60, ms/1000%60, sep, ms%1000)
+}
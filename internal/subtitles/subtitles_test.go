@@ -0,0 +1,85 @@
+package subtitles
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestSplit(t *testing.T) {
+	cues := []Cue{
+		{StartMs: 0, EndMs: 1000, Text: "short"},
+		{StartMs: 1000, EndMs: 3000, Text: "aaaa bbbb cccc dddd"},
+		{StartMs: 3000, EndMs: 4000, Text: "  "},
+	}
+
+	got := Split(cues, 9)
+	want := []Cue{
+		{StartMs: 0, EndMs: 1000, Text: "short"},
+		{StartMs: 1000, EndMs: 2000, Text: "aaaa bbbb"},
+		{StartMs: 2000, EndMs: 3000, Text: "cccc dddd"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("Split = %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("cue %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestRebase(t *testing.T) {
+	cues := []Cue{
+		{StartMs: 0, EndMs: 900, Text: "before"},
+		{StartMs: 500, EndMs: 1500, Text: "straddles start"},
+		{StartMs: 2000, EndMs: 2500, Text: "inside"},
+		{StartMs: 2800, EndMs: 3500, Text: "straddles end"},
+		{StartMs: 3000, EndMs: 4000, Text: "after"},
+	}
+
+	got := Rebase(cues, 1000, 3000, 10000)
+	want := []Cue{
+		{StartMs: 10000, EndMs: 10500, Text: "straddles start"},
+		{StartMs: 11000, EndMs: 11500, Text: "inside"},
+		{StartMs: 11800, EndMs: 12000, Text: "straddles end"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("Rebase = %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("cue %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestWriteSRT(t *testing.T) {
+	var b bytes.Buffer
+	cues := []Cue{
+		{StartMs: 1500, EndMs: 3723004, Text: "Hello\n\nworld"},
+		{StartMs: 4000000, EndMs: 4000001, Text: "again"},
+	}
+	if err := WriteSRT(&b, cues); err != nil {
+		t.Fatalf("WriteSRT: %v", err)
+	}
+	want := "1\n00:00:01,500 --> 01:02:03,004\nHello\nworld\n\n2\n01:06:40,000 --> 01:06:40,001\nagain\n\n"
+	if b.String() != want {
+		t.Errorf("WriteSRT =\n%q\nwant\n%q", b.String(), want)
+	}
+}
+
+func TestWriteVTT(t *testing.T) {
+	var b bytes.Buffer
+	if err := Write(&b, FormatVTT, []Cue{{StartMs: 0, EndMs: 2000, Text: "a --> b <i> & c"}}); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	want := "WEBVTT\n\n00:00:00.000 --> 00:00:02.000\na --&gt; b &lt;i&gt; &amp; c\n\n"
+	if b.String() != want {
+		t.Errorf("WriteVTT =\n%q\nwant\n%q", b.String(), want)
+	}
+
+	if err := Write(&b, "ass", nil); err == nil || !strings.Contains(err.Error(), "unknown format") {
+		t.Errorf("Write(ass) error = %v", err)
+	}
+}
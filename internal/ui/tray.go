@@ -1,22 +1,30 @@
 package ui
 
 import (
+	"context"
 	"fmt"
 	"log/slog"
 	"sync"
+	"time"
 
 	"github.com/getlantern/systray"
 	"github.com/heimdex/heimdex-agent/internal/catalog"
+	"github.com/heimdex/heimdex-agent/internal/cloud"
 )
 
 type Tray struct {
 	catalogSvc catalog.CatalogService
 	runner     *catalog.Runner
+	cloudAuth  cloud.AuthService
 	logger     *slog.Logger
 
 	statusItem  *systray.MenuItem
 	sourcesItem *systray.MenuItem
 	pauseItem   *systray.MenuItem
+	cloudItem   *systray.MenuItem
+	codeItem    *systray.MenuItem
+	syncItem    *systray.MenuItem
+	syncToggle  *systray.MenuItem
 
 	mu sync.Mutex
 
@@ -27,6 +35,7 @@ type Tray struct {
 type TrayConfig struct {
 	CatalogService catalog.CatalogService
 	Runner         *catalog.Runner
+	CloudAuth      cloud.AuthService
 	Logger         *slog.Logger
 	OnAddFolder    func() error
 	OnQuit         func()
@@ -36,6 +45,7 @@ func NewTray(cfg TrayConfig) *Tray {
 	return &Tray{
 		catalogSvc:  cfg.CatalogService,
 		runner:      cfg.Runner,
+		cloudAuth:   cfg.CloudAuth,
 		logger:      cfg.Logger,
 		onAddFolder: cfg.OnAddFolder,
 		onQuit:      cfg.OnQuit,
@@ -65,6 +75,30 @@ func (t *Tray) onReady() {
 
 	systray.AddSeparator()
 
+	// Menu items cannot be removed, so the cloud items always exist and are
+	// hidden when cloud sync is off.
+	t.cloudItem = systray.AddMenuItem("Sign in to Heimdex Cloud...", "Link this device to your Heimdex account")
+	t.codeItem = systray.AddMenuItem("", "Enter this code in your browser")
+	t.codeItem.Disable()
+	t.codeItem.Hide()
+	t.syncItem = systray.AddMenuItem("Cloud: Syncing", "Cloud sync state")
+	t.syncItem.Disable()
+	t.syncToggle = systray.AddMenuItem("Pause Cloud Sync", "Stop uploading to Heimdex Cloud")
+	if t.cloudAuth == nil {
+		t.cloudItem.Hide()
+	} else if t.cloudAuth.IsAuthenticated() {
+		t.cloudItem.SetTitle("Sign out of Heimdex Cloud")
+	}
+	if t.cloudAuth == nil || t.outbox() == nil {
+		t.syncItem.Hide()
+		t.syncToggle.Hide()
+	} else {
+		t.refreshCloudSync()
+		go t.watchCloudSync()
+	}
+
+	systray.AddSeparator()
+
 	quitItem := systray.AddMenuItem("Quit", "Quit Heimdex Agent")
 
 	go func() {
@@ -74,6 +108,10 @@ func (t *Tray) onReady() {
 				t.togglePause()
 			case <-addFolderItem.ClickedCh:
 				t.handleAddFolder()
+			case <-t.cloudItem.ClickedCh:
+				t.toggleCloudLogin()
+			case <-t.syncToggle.ClickedCh:
+				t.toggleCloudSync()
 			case <-quitItem.ClickedCh:
 				t.logger.Info("quit requested from tray")
 				if t.onQuit != nil {
@@ -111,6 +149,94 @@ func (t *Tray) togglePause() {
 	}
 }
 
+func (t *Tray) toggleCloudLogin() {
+	if t.cloudAuth == nil {
+		return
+	}
+
+	if t.cloudAuth.IsAuthenticated() {
+		if err := t.cloudAuth.Logout(context.Background()); err != nil {
+			t.logger.Error("cloud logout failed", "error", err)
+			return
+		}
+		t.cloudItem.SetTitle("Sign in to Heimdex Cloud...")
+		return
+	}
+
+	code, started, err := t.cloudAuth.ResumeOrLogin(t.logger, func(err error) {
+		t.codeItem.Hide()
+		if err == nil {
+			t.cloudItem.SetTitle("Sign out of Heimdex Cloud")
+		}
+	})
+	if err != nil {
+		t.logger.Error("cloud login failed", "error", err)
+		return
+	}
+	if !started {
+		return
+	}
+
+	t.codeItem.SetTitle(fmt.Sprintf("Code %s at %s", code.UserCode, code.VerificationURI))
+	t.codeItem.Show()
+}
+
+func (t *Tray) outbox() *catalog.Outbox {
+	if t.runner == nil {
+		return nil
+	}
+	return t.runner.Outbox()
+}
+
+func (t *Tray) toggleCloudSync() {
+	outbox := t.outbox()
+	if outbox == nil {
+		return
+	}
+	paused := outbox.PauseReason() == catalog.SyncPauseUser
+	if err := outbox.SetPaused(context.Background(), !paused); err != nil {
+		t.logger.Error("failed to change cloud sync pause", "error", err)
+		return
+	}
+	t.refreshCloudSync()
+}
+
+// watchCloudSync keeps the cloud sync item current as connectivity changes.
+func (t *Tray) watchCloudSync() {
+	ticker := time.NewTicker(5 * time.Second)
+	defer ticker.Stop()
+	for range ticker.C {
+		t.refreshCloudSync()
+	}
+}
+
+func (t *Tray) refreshCloudSync() {
+	outbox := t.outbox()
+	if outbox == nil {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	reason := outbox.PauseReason()
+	switch reason {
+	case catalog.SyncPauseUser:
+		t.syncItem.SetTitle("Cloud: Paused")
+	case catalog.SyncPauseOffline:
+		t.syncItem.SetTitle("Cloud: Paused (offline)")
+	case catalog.SyncPauseMetered:
+		t.syncItem.SetTitle("Cloud: Metered (large uploads paused)")
+	default:
+		t.syncItem.SetTitle("Cloud: Syncing")
+	}
+	if reason == catalog.SyncPauseUser {
+		t.syncToggle.SetTitle("Resume Cloud Sync")
+	} else {
+		t.syncToggle.SetTitle("Pause Cloud Sync")
+	}
+}
+
 func (t *Tray) handleAddFolder() {
 	if t.onAddFolder != nil {
 		if err := t.onAddFolder(); err != nil {
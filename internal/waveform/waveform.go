@@ -0,0 +1,235 @@
+// Package waveform computes audio peaks in the formats of the BBC's
+// audiowaveform tool, so existing waveform renderers (e.g. peaks.js) can
+// draw them directly.
+package waveform
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+var (
+	ErrNotPCM          = errors.New("waveform: input is not 16-bit PCM WAV")
+	ErrBadScale        = errors.New("waveform: samples per pixel must be a multiple of the stored scale")
+	ErrUnsupportedData = errors.New("waveform: unsupported .dat file")
+)
+
+// Peaks is a mono min/max waveform: for each run of SamplesPerPixel input
+// samples, Data holds the smallest and then the largest 16-bit sample.
+type Peaks struct {
+	SampleRate      int
+	SamplesPerPixel int
+	Data            []int16
+}
+
+// Length is the number of min/max pairs.
+func (p *Peaks) Length() int {
+	return len(p.Data) / 2
+}
+
+// Compute reads a 16-bit PCM WAV stream and reduces it to peaks. Channels
+// are mixed down to mono.
+func Compute(r io.Reader, samplesPerPixel int) (*Peaks, error) {
+	if samplesPerPixel <= 0 {
+		return nil, fmt.Errorf("waveform: samples per pixel must be positive")
+	}
+	br := bufio.NewReader(r)
+	sampleRate, channels, err := readWAVHeader(br)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &Peaks{SampleRate: sampleRate, SamplesPerPixel: samplesPerPixel}
+	frame := make([]byte, 2*channels)
+	var lo, hi int16 = math.MaxInt16, math.MinInt16
+	n := 0
+	for {
+		if _, err := io.ReadFull(br, frame); err != nil {
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				break
+			}
+			return nil, fmt.Errorf("waveform: read samples: %w", err)
+		}
+		var sum int
+		for c := 0; c < channels; c++ {
+			sum += int(int16(binary.LittleEndian.Uint16(frame[2*c:])))
+		}
+		v := int16(sum / channels)
+		lo, hi = min(lo, v), max(hi, v)
+		n++
+		if n == samplesPerPixel {
+			p.Data = append(p.Data, lo, hi)
+			lo, hi, n = math.MaxInt16, math.MinInt16, 0
+		}
+	}
+	if n > 0 {
+		p.Data = append(p.Data, lo, hi)
+	}
+	return p, nil
+}
+
+// readWAVHeader skips to the data chunk and returns the format. Sizes of
+// the RIFF and data chunks are not trusted, since streamed WAVs leave them
+// unset.
+func readWAVHeader(r io.Reader) (sampleRate, channels int, err error) {
+	var riff [12]byte
+	if _, err := io.ReadFull(r, riff[:]); err != nil || string(riff[0:4]) != "RIFF" || string(riff[8:12]) != "WAVE" {
+		return 0, 0, ErrNotPCM
+	}
+	for {
+		var hdr [8]byte
+		if _, err := io.ReadFull(r, hdr[:]); err != nil {
+			return 0, 0, ErrNotPCM
+		}
+		size := int64(binary.LittleEndian.Uint32(hdr[4:]))
+		switch string(hdr[0:4]) {
+		case "fmt ":
+			if size < 16 {
+				return 0, 0, ErrNotPCM
+			}
+			fmtChunk := make([]byte, size+size%2)
+			if _, err := io.ReadFull(r, fmtChunk); err != nil {
+				return 0, 0, ErrNotPCM
+			}
+			format := binary.LittleEndian.Uint16(fmtChunk[0:])
+			channels = int(binary.LittleEndian.Uint16(fmtChunk[2:]))
+			sampleRate = int(binary.LittleEndian.Uint32(fmtChunk[4:]))
+			bits := binary.LittleEndian.Uint16(fmtChunk[14:])
+			// 0xFFFE is WAVE_FORMAT_EXTENSIBLE, which ffmpeg uses for
+			// more than two channels.
+			if (format != 1 && format != 0xFFFE) || bits != 16 || channels < 1 {
+				return 0, 0, ErrNotPCM
+			}
+		case "data":
+			if channels == 0 {
+				return 0, 0, ErrNotPCM
+			}
+			return sampleRate, channels, nil
+		default:
+			if _, err := io.CopyN(io.Discard, r, size+size%2); err != nil {
+				return 0, 0, ErrNotPCM
+			}
+		}
+	}
+}
+
+// Downsample merges runs of pixels so each covers samplesPerPixel input
+// samples, which must be a multiple of p.SamplesPerPixel.
+func (p *Peaks) Downsample(samplesPerPixel int) (*Peaks, error) {
+	if samplesPerPixel == p.SamplesPerPixel {
+		return p, nil
+	}
+	if samplesPerPixel < p.SamplesPerPixel || samplesPerPixel%p.SamplesPerPixel != 0 {
+		return nil, ErrBadScale
+	}
+	factor := samplesPerPixel / p.SamplesPerPixel
+	out := &Peaks{SampleRate: p.SampleRate, SamplesPerPixel: samplesPerPixel}
+	for i := 0; i < p.Length(); i += factor {
+		lo, hi := p.Data[2*i], p.Data[2*i+1]
+		for j := i + 1; j < min(i+factor, p.Length()); j++ {
+			lo, hi = min(lo, p.Data[2*j]), max(hi, p.Data[2*j+1])
+		}
+		out.Data = append(out.Data, lo, hi)
+	}
+	return out, nil
+}
+
+// jsonPeaks is audiowaveform's JSON format, version 2.
+type jsonPeaks struct {
+	Version         int     `json:"version"`
+	Channels        int     `json:"channels"`
+	SampleRate      int     `json:"sample_rate"`
+	SamplesPerPixel int     `json:"samples_per_pixel"`
+	Bits            int     `json:"bits"`
+	Length          int     `json:"length"`
+	Data            []int16 `json:"data"`
+}
+
+// WriteJSON writes p in audiowaveform's JSON format.
+func (p *Peaks) WriteJSON(w io.Writer) error {
+	data := p.Data
+	if data == nil {
+		data = []int16{}
+	}
+	return json.NewEncoder(w).Encode(jsonPeaks{
+		Version:         2,
+		Channels:        1,
+		SampleRate:      p.SampleRate,
+		SamplesPerPixel: p.SamplesPerPixel,
+		Bits:            16,
+		Length:          p.Length(),
+		Data:            data,
+	})
+}
+
+// maxDatLength bounds the pairs read from a .dat file, about 60 hours at
+// 100 pixels per second.
+const maxDatLength = 1 << 25
+
+// datHeader is audiowaveform's binary (.dat) header, version 2. A zero
+// flags field means 16-bit samples.
+type datHeader struct {
+	Version         int32
+	Flags           uint32
+	SampleRate      int32
+	SamplesPerPixel int32
+	Length          uint32
+	Channels        int32
+}
+
+// WriteBinary writes p in audiowaveform's binary .dat format.
+func (p *Peaks) WriteBinary(w io.Writer) error {
+	hdr := datHeader{
+		Version:         2,
+		SampleRate:      int32(p.SampleRate),
+		SamplesPerPixel: int32(p.SamplesPerPixel),
+		Length:          uint32(p.Length()),
+		Channels:        1,
+	}
+	if err := binary.Write(w, binary.LittleEndian, hdr); err != nil {
+		return err
+	}
+	return binary.Write(w, binary.LittleEndian, p.Data)
+}
+
+// ReadBinary reads a mono 16-bit .dat file written by WriteBinary or by
+// audiowaveform.
+func ReadBinary(r io.Reader) (*Peaks, error) {
+	var version int32
+	if err := binary.Read(r, binary.LittleEndian, &version); err != nil {
+		return nil, ErrUnsupportedData
+	}
+	var rest struct {
+		Flags           uint32
+		SampleRate      int32
+		SamplesPerPixel int32
+		Length          uint32
+	}
+	if err := binary.Read(r, binary.LittleEndian, &rest); err != nil {
+		return nil, ErrUnsupportedData
+	}
+	channels := int32(1)
+	if version == 2 {
+		if err := binary.Read(r, binary.LittleEndian, &channels); err != nil {
+			return nil, ErrUnsupportedData
+		}
+	}
+	if (version != 1 && version != 2) || rest.Flags&1 != 0 || channels != 1 || rest.SamplesPerPixel <= 0 || rest.Length > maxDatLength {
+		return nil, ErrUnsupportedData
+	}
+
+	p := &Peaks{
+		SampleRate:      int(rest.SampleRate),
+		SamplesPerPixel: int(rest.SamplesPerPixel),
+		Data:            make([]int16, 2*int(rest.Length)),
+	}
+	if err := binary.Read(r, binary.LittleEndian, p.Data); err != nil {
+		return nil, ErrUnsupportedData
+	}
+	return p, nil
+}
@@ -0,0 +1,111 @@
+package waveform
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"slices"
+	"testing"
+)
+
+// wav builds a 16-bit PCM WAV with an extra chunk before the data, as
+// ffmpeg writes a LIST chunk.
+func wav(sampleRate, channels int, samples []int16) []byte {
+	var data bytes.Buffer
+	binary.Write(&data, binary.LittleEndian, samples)
+
+	var b bytes.Buffer
+	b.WriteString("RIFF")
+	binary.Write(&b, binary.LittleEndian, uint32(0xFFFFFFFF))
+	b.WriteString("WAVEfmt ")
+	binary.Write(&b, binary.LittleEndian, uint32(16))
+	binary.Write(&b, binary.LittleEndian, uint16(1))
+	binary.Write(&b, binary.LittleEndian, uint16(channels))
+	binary.Write(&b, binary.LittleEndian, uint32(sampleRate))
+	binary.Write(&b, binary.LittleEndian, uint32(sampleRate*channels*2))
+	binary.Write(&b, binary.LittleEndian, uint16(channels*2))
+	binary.Write(&b, binary.LittleEndian, uint16(16))
+	b.WriteString("LIST")
+	binary.Write(&b, binary.LittleEndian, uint32(3))
+	b.WriteString("abc\x00")
+	b.WriteString("data")
+	binary.Write(&b, binary.LittleEndian, uint32(data.Len()))
+	b.Write(data.Bytes())
+	return b.Bytes()
+}
+
+func TestCompute(t *testing.T) {
+	samples := []int16{1, -5, 7, 3, -2, 0, 100, -100, 4}
+	p, err := Compute(bytes.NewReader(wav(8000, 1, samples)), 4)
+	if err != nil {
+		t.Fatalf("Compute: %v", err)
+	}
+	want := []int16{-5, 7, -100, 100, 4, 4}
+	if p.SampleRate != 8000 || p.SamplesPerPixel != 4 || !slices.Equal(p.Data, want) {
+		t.Errorf("peaks = %+v, want data %v", p, want)
+	}
+}
+
+func TestCompute_MixesChannels(t *testing.T) {
+	p, err := Compute(bytes.NewReader(wav(8000, 2, []int16{100, 300, -100, -300})), 2)
+	if err != nil {
+		t.Fatalf("Compute: %v", err)
+	}
+	if !slices.Equal(p.Data, []int16{-200, 200}) {
+		t.Errorf("data = %v, want [-200 200]", p.Data)
+	}
+}
+
+func TestCompute_RejectsNonPCM(t *testing.T) {
+	if _, err := Compute(bytes.NewReader([]byte("ID3 not a wav file")), 4); err != ErrNotPCM {
+		t.Errorf("err = %v, want ErrNotPCM", err)
+	}
+}
+
+func TestBinaryRoundTrip(t *testing.T) {
+	p := &Peaks{SampleRate: 16000, SamplesPerPixel: 160, Data: []int16{-3, 4, -10, 12}}
+	var buf bytes.Buffer
+	if err := p.WriteBinary(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if buf.Len() != 24+8 {
+		t.Errorf("dat size = %d, want 32", buf.Len())
+	}
+	got, err := ReadBinary(&buf)
+	if err != nil {
+		t.Fatalf("ReadBinary: %v", err)
+	}
+	if got.SampleRate != 16000 || got.SamplesPerPixel != 160 || !slices.Equal(got.Data, p.Data) {
+		t.Errorf("round trip = %+v", got)
+	}
+}
+
+func TestDownsample(t *testing.T) {
+	p := &Peaks{SampleRate: 16000, SamplesPerPixel: 100, Data: []int16{-1, 1, -5, 2, -2, 9}}
+	got, err := p.Downsample(200)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.SamplesPerPixel != 200 || !slices.Equal(got.Data, []int16{-5, 2, -2, 9}) {
+		t.Errorf("Downsample(200) = %+v", got)
+	}
+	if _, err := p.Downsample(150); err != ErrBadScale {
+		t.Errorf("Downsample(150) err = %v, want ErrBadScale", err)
+	}
+}
+
+func TestWriteJSON(t *testing.T) {
+	var buf bytes.Buffer
+	(&Peaks{SampleRate: 16000, SamplesPerPixel: 160}).WriteJSON(&buf)
+
+	var got map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got["version"] != 2.0 || got["bits"] != 16.0 || got["length"] != 0.0 {
+		t.Errorf("json = %v", got)
+	}
+	if data, ok := got["data"].([]interface{}); !ok || len(data) != 0 {
+		t.Errorf("data = %v, want []", got["data"])
+	}
+}